@@ -0,0 +1,155 @@
+// Command volcano-drift reports how far tenant branches have drifted from
+// main and optionally merges main into the ones that are conflict-free.
+//
+//	volcano-drift -repo ./repos                    # every customer/* branch
+//	volcano-drift -repo ./repos acme-corp          # one tenant
+//	volcano-drift -server http://localhost:8080    # ask a running server
+//	volcano-drift -repo ./repos -merge -author "Ops Bot <ops@example.com>"
+//
+// The exit status is 1 on errors and 2 when any reported branch conflicts
+// with main, so the report can gate a CI job.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/mail"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/client"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
+
+func main() {
+	repoPath := flag.String("repo", "repos", "path to the runtime repository")
+	server := flag.String("server", "", "query this server's API instead of a local repository")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	merge := flag.Bool("merge", false, "merge main into every reported branch that has no conflicts")
+	author := flag.String("author", "", `merge commit author, "Name <email>" (required with -merge)`)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: volcano-drift [flags] [tenant...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	svc, err := service(*repoPath, *server)
+	if err != nil {
+		fail(err)
+	}
+	ctx := context.Background()
+
+	report, err := collect(ctx, svc, flag.Args())
+	if err != nil {
+		fail(err)
+	}
+
+	if *merge {
+		addr, err := mail.ParseAddress(*author)
+		if err != nil {
+			fail(fmt.Errorf("-merge needs -author \"Name <email>\": %v", err))
+		}
+		who := api.CommitAuthor{Name: addr.Name, Email: addr.Address}
+		for _, t := range report.Tenants {
+			if t.CommitsBehind == 0 || !t.Mergeable {
+				continue
+			}
+			resp, err := svc.MergeMain(ctx, t.Tenant, api.MergeMainRequest{Author: who, ExpectedHead: t.Commit})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "volcano-drift: %s: %v\n", t.Tenant, err)
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "merged main into %s: %.12s (%d files updated)\n", resp.Branch, resp.Commit, len(resp.Updated))
+		}
+		// Report the state after merging
+		if report, err = collect(ctx, svc, flag.Args()); err != nil {
+			fail(err)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		printReport(report)
+	}
+
+	for _, t := range report.Tenants {
+		if t.Conflicts > 0 {
+			os.Exit(2)
+		}
+	}
+}
+
+// service talks to a server when one is given, otherwise to the repository
+func service(repoPath, server string) (api.DriftService, error) {
+	if server != "" {
+		c, err := client.New(server)
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	repo, err := gitnative.OpenRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return registry.NewDrift(repo, nil), nil
+}
+
+// collect reports on the named tenants, or on every tenant branch
+func collect(ctx context.Context, svc api.DriftService, tenants []string) (*api.DriftResponse, error) {
+	if len(tenants) == 0 {
+		return svc.Drift(ctx, "")
+	}
+	var report *api.DriftResponse
+	for _, tenant := range tenants {
+		resp, err := svc.Drift(ctx, tenant)
+		if err != nil {
+			return nil, err
+		}
+		if report == nil {
+			report = resp
+		} else {
+			report.Tenants = append(report.Tenants, resp.Tenants...)
+		}
+	}
+	return report, nil
+}
+
+func printReport(report *api.DriftResponse) {
+	fmt.Printf("main at %.12s\n", report.MainCommit)
+	if len(report.Tenants) == 0 {
+		fmt.Println("no tenant branches")
+		return
+	}
+	for _, t := range report.Tenants {
+		state := "up to date"
+		switch {
+		case t.Conflicts > 0:
+			state = fmt.Sprintf("%d conflicts, needs a manual merge", t.Conflicts)
+		case t.CommitsBehind > 0:
+			state = "can be merged automatically"
+		}
+		fmt.Printf("\n%s (%.12s): %d commits behind main; %d behind, %d overridden; %s\n",
+			t.Branch, t.Commit, t.CommitsBehind, t.Behind, t.Overridden, state)
+		if len(t.Files) == 0 {
+			continue
+		}
+		w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+		for _, f := range t.Files {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", f.Status, f.Path, f.Detail)
+		}
+		w.Flush()
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "volcano-drift: %v\n", err)
+	os.Exit(1)
+}
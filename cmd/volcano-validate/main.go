@@ -0,0 +1,126 @@
+// Command volcano-validate checks workflow, tool and config definitions the
+// way a reload would, so a broken file is caught before it is committed
+// rather than rejected by a running server.
+//
+//	volcano-validate                        # the working tree in .
+//	volcano-validate ./runtime              # another working tree
+//	volcano-validate -commit HEAD           # a commit of the repository in .
+//	volcano-validate -repo ./repos -commit customer/acme-corp
+//
+// Each problem is printed as path:line: pointer: message, where pointer is
+// the JSON pointer of the offending value. The exit status is 1 when there
+// are problems. To run it as a pre-commit hook:
+//
+//	#!/bin/sh
+//	# .git/hooks/pre-commit
+//	exec volcano-validate "$(git rev-parse --show-toplevel)"
+//
+// The hook checks the working tree, including changes that are not staged.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
+
+func main() {
+	repoPath := flag.String("repo", ".", "repository to read -commit from")
+	commit := flag.String("commit", "", "validate this commit or branch instead of a working tree")
+	asJSON := flag.Bool("json", false, "print the problems as JSON")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: volcano-validate [flags] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() > 1 || (flag.NArg() == 1 && *commit != "") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var errs []registry.FileError
+	if *commit != "" {
+		repo, err := gitnative.OpenRepo(*repoPath)
+		if err != nil {
+			fail(err)
+		}
+		defer repo.Close()
+		ctx := context.Background()
+		sha, err := repo.Resolve(ctx, *commit)
+		if err != nil {
+			fail(err)
+		}
+		errs = registry.ValidateCommit(ctx, repo, sha)
+	} else {
+		dir := "."
+		if flag.NArg() == 1 {
+			dir = flag.Arg(0)
+		}
+		files, err := readTree(dir)
+		if err != nil {
+			fail(err)
+		}
+		errs = registry.ValidateFiles(files)
+	}
+
+	if *asJSON {
+		if errs == nil {
+			errs = []registry.FileError{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(errs)
+	} else {
+		for _, e := range errs {
+			fmt.Println(e.Error())
+		}
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
+
+// readTree reads the definition files under dir, keyed by slash-separated
+// path relative to dir. Hidden directories such as .git are skipped.
+func readTree(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if gitnative.ClassifyPath(rel) == gitnative.KindOther {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[rel] = data
+		return nil
+	})
+	return files, err
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "volcano-validate: %v\n", err)
+	os.Exit(1)
+}
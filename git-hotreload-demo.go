@@ -1,118 +1,207 @@
+//go:build ignore
+
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"time"
-)
 
-// WorkflowDefinition represents a workflow loaded from git
-type WorkflowDefinition struct {
-	Name          string    `json:"name"`
-	Version       string    `json:"version"`
-	TaskQueue     string    `json:"task_queue"`
-	LastModified  time.Time `json:"last_modified"`
-	Configuration struct {
-		MaxDuration string `json:"max_duration"`
-		RetryPolicy struct {
-			MaxAttempts int `json:"maximum_attempts"`
-		} `json:"retry_policy"`
-	} `json:"configuration"`
-}
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
 
 func main() {
 	log.Println("🔄 Git-Native Hot-Reload Demonstration")
 	log.Println("======================================")
 	log.Println("")
-	
-	// Create a temporary workflow directory
-	workflowDir := "./temp-workflows"
-	os.MkdirAll(workflowDir, 0755)
-	defer os.RemoveAll(workflowDir) // Clean up
-	
+
+	// Create a temporary runtime repository
+	repoDir, err := ioutil.TempDir("", "volcano-runtime-")
+	if err != nil {
+		log.Fatalf("❌ Failed to create repository: %v", err)
+	}
+	defer os.RemoveAll(repoDir) // Clean up
+	git(repoDir, "init", "-q", "-b", "main")
+
 	// Initial workflow definition
-	initialWorkflow := WorkflowDefinition{
-		Name:         "DataPipelineWorkflow",
-		Version:      "1.0.0",
-		TaskQueue:    "volcano-workflows",
-		LastModified: time.Now(),
-	}
-	initialWorkflow.Configuration.MaxDuration = "1h"
-	initialWorkflow.Configuration.RetryPolicy.MaxAttempts = 3
-	
-	// Write initial workflow file
-	workflowFile := filepath.Join(workflowDir, "data-pipeline.json")
-	writeWorkflow(workflowFile, initialWorkflow)
-	
-	log.Println("📁 Initial workflow configuration:")
-	log.Printf("   Name: %s", initialWorkflow.Name)
-	log.Printf("   Version: %s", initialWorkflow.Version)
-	log.Printf("   Max Duration: %s", initialWorkflow.Configuration.MaxDuration)
-	log.Printf("   Retry Attempts: %d", initialWorkflow.Configuration.RetryPolicy.MaxAttempts)
-	log.Println("")
-	
-	// Simulate file watcher
-	log.Println("👀 Starting file watcher...")
-	
-	// Simulate hot-reload after 2 seconds
+	initialWorkflow := registry.WorkflowDefinition{
+		Name:        "DataPipelineWorkflow",
+		Version:     "1.0.0",
+		TaskQueue:   "volcano-workflows",
+		MaxDuration: "1h",
+	}
+	initialWorkflow.RetryPolicy.MaximumAttempts = 3
+
+	// Commit initial workflow file
+	workflowFile := filepath.Join(repoDir, "workflows", "data-pipeline.json")
+	if err := writeWorkflow(workflowFile, initialWorkflow); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	git(repoDir, "add", "-A")
+	git(repoDir, "commit", "-q", "-m", "Add DataPipelineWorkflow 1.0.0")
+
+	// Load the registry from the branch head
+	repo, err := gitnative.OpenRepo(repoDir)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	head, ref, err := gitnative.ReadHEAD(repo.GitDir())
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	reg := registry.New(repo, ref)
+	audit, err := registry.OpenReloadLog("", 0)
+	if err != nil {
+		log.Fatalf("❌ Failed to open reload log: %v", err)
+	}
+	reg.LogReloads(audit)
+	if _, err := reg.Load(context.Background(), head); err != nil {
+		log.Fatalf("❌ Initial load failed: %v", err)
+	}
+	printWorkflow("📁 Initial workflow configuration:", reg.Current())
+
+	// Watch the branch for new commits
+	log.Printf("👀 Starting git watcher on %s...", ref)
+	watcher, err := gitnative.NewWatcher(repoDir, gitnative.WatcherOptions{})
+	if err != nil {
+		log.Fatalf("❌ Failed to start watcher: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Push a broken commit, then a good one
 	go func() {
-		time.Sleep(2 * time.Second)
-		
-		log.Println("📝 Detected file change! Updating workflow...")
-		
+		time.Sleep(time.Second)
+		log.Println("📝 Committing a truncated workflow file...")
+		if err := ioutil.WriteFile(workflowFile, []byte(`{"name": "DataPipelineWorkflow", "version": `), 0644); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		git(repoDir, "commit", "-q", "-am", "Half-written pipeline update")
+
+		time.Sleep(time.Second)
+		log.Println("📝 Committing workflow update...")
+
 		// Updated workflow definition
-		updatedWorkflow := WorkflowDefinition{
-			Name:         "DataPipelineWorkflow",
-			Version:      "1.1.0", // Version bump
-			TaskQueue:    "volcano-workflows",
-			LastModified: time.Now(),
+		updatedWorkflow := initialWorkflow
+		updatedWorkflow.Version = "1.1.0"               // Version bump
+		updatedWorkflow.MaxDuration = "2h"              // Changed
+		updatedWorkflow.RetryPolicy.MaximumAttempts = 5 // Changed
+		if err := writeWorkflow(workflowFile, updatedWorkflow); err != nil {
+			log.Fatalf("❌ %v", err)
 		}
-		updatedWorkflow.Configuration.MaxDuration = "2h" // Changed
-		updatedWorkflow.Configuration.RetryPolicy.MaxAttempts = 5 // Changed
-		
-		writeWorkflow(workflowFile, updatedWorkflow)
-		
-		log.Println("✅ Hot-reload complete! New configuration:")
-		log.Printf("   Version: %s (was 1.0.0)", updatedWorkflow.Version)
-		log.Printf("   Max Duration: %s (was 1h)", updatedWorkflow.Configuration.MaxDuration)
-		log.Printf("   Retry Attempts: %d (was 3)", updatedWorkflow.Configuration.RetryPolicy.MaxAttempts)
-		log.Println("   🚀 ZERO DOWNTIME - Workflow updated without restart!")
+		git(repoDir, "commit", "-q", "-am", "Bump DataPipelineWorkflow to 1.1.0")
 	}()
-	
-	// Simulate monitoring for 3 seconds
-	for i := 0; i < 3; i++ {
-		time.Sleep(1 * time.Second)
-		
-		// Read current workflow
-		current := readWorkflow(workflowFile)
-		if current != nil {
-			log.Printf("⏱️  [%ds] Current version: %s", i+1, current.Version)
+
+	err = watcher.Watch(ctx, func(event gitnative.CommitEvent) {
+		log.Printf("🔔 %s moved %.8s → %.8s", event.Ref, event.OldSHA, event.NewSHA)
+		for _, change := range event.Changes {
+			log.Printf("   %s %s (%s)", change.Type, change.Path, change.Kind)
 		}
+
+		res, err := reg.Apply(ctx, event)
+		var rejected *registry.ReloadError
+		switch {
+		case errors.As(err, &rejected):
+			log.Printf("🛑 Commit %.8s rejected, still serving %.8s:", rejected.Commit, reg.Current().Commit)
+			for _, fe := range rejected.Errors {
+				log.Printf("   %s", fe)
+			}
+			return
+		case err != nil:
+			log.Fatalf("❌ Reload failed: %v", err)
+		}
+
+		printWorkflow(fmt.Sprintf("✅ Hot-reload complete in %v! New configuration:", res.Duration), reg.Current())
+		log.Println("   🚀 ZERO DOWNTIME - Workflow updated without restart!")
+
+		// Double-check the file on disk matches what the registry serves
+		onDisk, err := readWorkflow(workflowFile)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("   On disk: %s %s", onDisk.Name, onDisk.Version)
+		cancel()
+	})
+	if err != nil {
+		log.Fatalf("❌ Watcher failed: %v", err)
 	}
-	
+
 	log.Println("")
 	log.Println("🎯 Hot-Reload Benefits Demonstrated:")
 	log.Println("   ✅ Configuration updated without service restart")
+	log.Println("   ✅ Invalid commits rejected, last good commit kept serving")
 	log.Println("   ✅ Version tracking maintained")
 	log.Println("   ✅ Immediate effect on new workflow executions")
 	log.Println("   ✅ Git-based audit trail of all changes")
+	history, _ := audit.Reloads(context.Background(), api.ReloadFilter{})
+	for _, rec := range history.Reloads {
+		log.Printf("   📜 %s %.8s by %s at %s (%d files, %d errors)", rec.Outcome, rec.NewCommit, rec.Author.Name,
+			rec.StartedAt.Format(time.RFC3339), len(rec.Files), len(rec.Errors))
+	}
 }
 
-func writeWorkflow(path string, workflow WorkflowDefinition) {
-	data, _ := json.MarshalIndent(workflow, "", "  ")
-	ioutil.WriteFile(path, data, 0644)
+func printWorkflow(title string, snap *registry.Snapshot) {
+	wf, ok := snap.Workflow("DataPipelineWorkflow")
+	if !ok {
+		log.Fatalf("❌ DataPipelineWorkflow missing at %.8s", snap.Commit)
+	}
+	log.Println(title)
+	log.Printf("   Commit: %.8s", snap.Commit)
+	log.Printf("   Name: %s", wf.Name)
+	log.Printf("   Version: %s", wf.Version)
+	log.Printf("   Max Duration: %s", wf.MaxDuration)
+	log.Printf("   Retry Attempts: %d", wf.RetryPolicy.MaximumAttempts)
+	log.Println("")
 }
 
-func readWorkflow(path string) *WorkflowDefinition {
+// git runs a git command in the demo repository, standing in for a user
+// pushing to the runtime repo
+func git(dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Volcano Demo", "GIT_AUTHOR_EMAIL=demo@volcano.local",
+		"GIT_COMMITTER_NAME=Volcano Demo", "GIT_COMMITTER_EMAIL=demo@volcano.local")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		log.Fatalf("❌ git %v: %v\n%s", args, err, out)
+	}
+}
+
+// writeWorkflow writes the file via a temp file and rename so a reader never
+// sees it half-written
+func writeWorkflow(path string, workflow registry.WorkflowDefinition) error {
+	data, err := json.MarshalIndent(workflow, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", workflow.Name, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+func readWorkflow(path string) (*registry.WorkflowDefinition, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("read %s: %w", path, err)
 	}
-	
-	var workflow WorkflowDefinition
-	json.Unmarshal(data, &workflow)
-	return &workflow
-}
\ No newline at end of file
+	return registry.ParseWorkflow(filepath.Base(path), data)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// States of an approval
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// Approval is a workflow stage waiting for, or given, a human decision
+type Approval struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+	Workflow   string `json:"workflow"`
+	Stage      string `json:"stage"`
+	CustomerID string `json:"customer_id,omitempty"`
+	Message    string `json:"message,omitempty"`
+	// Groups may decide; escalation adds to them
+	Groups    []string `json:"groups"`
+	Escalated bool     `json:"escalated,omitempty"`
+	Status    string   `json:"status"`
+	// Default is the decision applied at Deadline, approve or reject
+	Default     string     `json:"default"`
+	RequestedAt time.Time  `json:"requested_at"`
+	EscalateAt  *time.Time `json:"escalate_at,omitempty"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+	// Decision is set once the workflow has accepted one
+	Decision *ApprovalDecision `json:"decision,omitempty"`
+}
+
+// ApprovalDecision is how an approval was decided
+type ApprovalDecision struct {
+	Approved bool   `json:"approved"`
+	Approver string `json:"approver,omitempty"`
+	Group    string `json:"group,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+	// TimedOut marks the default applied when nobody decided in time
+	TimedOut  bool      `json:"timed_out,omitempty"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// ApprovalFilter selects approvals. Every set field must match.
+type ApprovalFilter struct {
+	Tenant string `query:"tenant"`
+	// Group matches approvals the group may decide
+	Group string `query:"group"`
+	// Status defaults to pending
+	Status string `query:"status"`
+}
+
+// Validate checks the filter's values
+func (f *ApprovalFilter) Validate() error {
+	switch f.Status {
+	case "":
+		f.Status = ApprovalPending
+	case ApprovalPending, ApprovalApproved, ApprovalRejected:
+	default:
+		return fmt.Errorf("%w: status must be %s, %s or %s", ErrInvalidArgument, ApprovalPending, ApprovalApproved, ApprovalRejected)
+	}
+	return nil
+}
+
+// ApprovalsResponse is returned by GET /api/v1/approvals
+type ApprovalsResponse struct {
+	Success   bool       `json:"success"`
+	Total     int        `json:"total"`
+	Approvals []Approval `json:"approvals"`
+}
+
+// ApprovalDecisionRequest is the body of POST
+// /api/v1/approvals/{workflow_id}/{stage}
+type ApprovalDecisionRequest struct {
+	// Decision is approve or reject
+	Decision string `json:"decision"`
+	// Group picks which of the approval's groups the caller decides for
+	// when they belong to several; it defaults to the first. The approver
+	// is always the authenticated caller.
+	Group   string `json:"group,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// Validate reports whether the decision is complete
+func (r ApprovalDecisionRequest) Validate() error {
+	if r.Decision != "approve" && r.Decision != "reject" {
+		return fmt.Errorf("%w: decision must be approve or reject", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// ApprovalResponse is returned when a decision was delivered. The
+// approval stays pending until the workflow accepts the decision.
+type ApprovalResponse struct {
+	Success  bool     `json:"success"`
+	Approval Approval `json:"approval"`
+}
+
+// ApprovalService lists approvals and delivers decisions to workflows.
+// Decide records by, the authenticated caller, as the approver and must
+// refuse callers outside the approval's groups with ErrPermissionDenied.
+type ApprovalService interface {
+	Approvals(ctx context.Context, filter ApprovalFilter) (*ApprovalsResponse, error)
+	Decide(ctx context.Context, workflowID, stage string, by Principal, req ApprovalDecisionRequest) (*ApprovalResponse, error)
+}
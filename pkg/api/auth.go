@@ -0,0 +1,75 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Principal is an authenticated caller
+type Principal struct {
+	// Subject names the caller, e.g. a user name or email
+	Subject string `json:"subject"`
+	// Groups are the groups the identity provider says the caller is in
+	Groups []string `json:"groups,omitempty"`
+	// Email authors the commits the caller's writes create
+	Email string `json:"email,omitempty"`
+	// Tenant is the tenant whose branch the caller's writes go to. Empty
+	// means main's maintainers, who may also merge main into any tenant
+	// branch and sync from the remote.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// author is the commit author for the caller's writes
+func (p Principal) author() (CommitAuthor, error) {
+	a := CommitAuthor{Name: p.Subject, Email: p.Email}
+	if err := a.Validate(); err != nil {
+		return CommitAuthor{}, fmt.Errorf("%w: credentials for %q cannot author commits", ErrPermissionDenied, p.Subject)
+	}
+	return a, nil
+}
+
+// Authenticator resolves the caller of a request, typically from a token
+// checked against an identity provider. Requests without valid credentials
+// return an error wrapping ErrUnauthenticated; any other error is treated
+// as the authenticator failing.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// StaticTokens authenticates "Authorization: Bearer <token>" against a
+// fixed set of tokens, for deployments without an identity provider
+type StaticTokens map[string]Principal
+
+// Authenticate implements Authenticator
+func (t StaticTokens) Authenticate(r *http.Request) (Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return Principal{}, fmt.Errorf("%w: a bearer token is required", ErrUnauthenticated)
+	}
+	// Compare digests so the time taken does not depend on how much of a
+	// token matched
+	sum := sha256.Sum256([]byte(token))
+	for known, p := range t {
+		want := sha256.Sum256([]byte(known))
+		if subtle.ConstantTimeCompare(sum[:], want[:]) == 1 {
+			return p, nil
+		}
+	}
+	return Principal{}, fmt.Errorf("%w: unknown bearer token", ErrUnauthenticated)
+}
+
+// authenticate resolves the caller of r, refusing principals without a
+// subject
+func authenticate(a Authenticator, r *http.Request) (Principal, error) {
+	p, err := a.Authenticate(r)
+	if err != nil {
+		return Principal{}, err
+	}
+	if p.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: credentials do not name a subject", ErrUnauthenticated)
+	}
+	return p, nil
+}
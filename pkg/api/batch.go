@@ -0,0 +1,283 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// BatchOptions bounds the work a single batch call may do
+type BatchOptions struct {
+	// MaxItems rejects batches larger than this (default 10000)
+	MaxItems int
+	// MaxConcurrency caps parallel fast-path evaluations (default 16)
+	MaxConcurrency int
+	// ItemTimeout bounds each item's execution (default 30s)
+	ItemTimeout time.Duration
+}
+
+// BatchResponse is returned by POST /api/v1/execute/batch for JSON input
+type BatchResponse struct {
+	Success   bool              `json:"success"`
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Duration  string            `json:"duration"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+// BatchItemResult is the outcome of one item, reported at its input index
+type BatchItemResult struct {
+	Index int `json:"index"`
+	ExecuteResponse
+}
+
+// BatchHandler serves POST /api/v1/execute/batch.
+//
+// The body is either a JSON array of ExecuteRequest objects or, with
+// Content-Type application/x-ndjson, one request per line. Fast-path items
+// are evaluated in parallel; durable items are started one at a time so a
+// large batch cannot flood Temporal. Results always come back in input
+// order, as a BatchResponse for JSON input or one BatchItemResult per line
+// for NDJSON input. A failing item never fails the batch.
+type BatchHandler struct {
+	Executor Executor
+	Options  BatchOptions
+}
+
+// NewBatchHandler creates a batch handler with default options
+func NewBatchHandler(executor Executor) *BatchHandler {
+	return &BatchHandler{Executor: executor}
+}
+
+func (h *BatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "batch execute requires POST")
+		return
+	}
+
+	ndjson := isNDJSON(r.Header.Get("Content-Type"))
+	items, err := decodeBatch(r.Body, ndjson, h.maxItems())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	start := time.Now()
+	results := h.run(r.Context(), items)
+
+	if ndjson {
+		h.stream(w, results)
+		return
+	}
+
+	resp := BatchResponse{
+		Success: true,
+		Total:   len(items),
+		Results: make([]BatchItemResult, 0, len(items)),
+	}
+	for _, slot := range results {
+		res := slot.wait()
+		if res.Success {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, res)
+	}
+	resp.Duration = time.Since(start).String()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// stream writes each result as soon as it and every earlier item are done
+func (h *BatchHandler) stream(w http.ResponseWriter, results []*batchSlot) {
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for _, slot := range results {
+		if err := enc.Encode(slot.wait()); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// batchItem is a decoded input line; err is set when the item itself is bad
+type batchItem struct {
+	req ExecuteRequest
+	err error
+}
+
+type batchSlot struct {
+	done   chan struct{}
+	result BatchItemResult
+}
+
+func (s *batchSlot) wait() BatchItemResult {
+	<-s.done
+	return s.result
+}
+
+func (s *batchSlot) finish(index int, resp *ExecuteResponse, err error) {
+	s.result.Index = index
+	switch {
+	case err != nil:
+		s.result.ExecuteResponse = ExecuteResponse{Success: false, Error: err.Error()}
+	case resp == nil:
+		s.result.ExecuteResponse = ExecuteResponse{Success: false, Error: "executor returned no response"}
+	default:
+		s.result.ExecuteResponse = *resp
+	}
+	close(s.done)
+}
+
+// run schedules every item and returns one slot per item in input order
+func (h *BatchHandler) run(ctx context.Context, items []batchItem) []*batchSlot {
+	slots := make([]*batchSlot, len(items))
+	var durable []int
+
+	sem := make(chan struct{}, h.maxConcurrency())
+	for i, item := range items {
+		slots[i] = &batchSlot{done: make(chan struct{})}
+		if item.err != nil {
+			slots[i].finish(i, nil, item.err)
+			continue
+		}
+		if h.Executor.Route(item.req) == TemporalPath {
+			durable = append(durable, i)
+			continue
+		}
+
+		go func(i int, req ExecuteRequest) {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				resp, err := h.execute(ctx, req)
+				slots[i].finish(i, resp, err)
+			case <-ctx.Done():
+				slots[i].finish(i, nil, ctx.Err())
+			}
+		}(i, item.req)
+	}
+
+	go func() {
+		for _, i := range durable {
+			if err := ctx.Err(); err != nil {
+				slots[i].finish(i, nil, err)
+				continue
+			}
+			resp, err := h.execute(ctx, items[i].req)
+			slots[i].finish(i, resp, err)
+		}
+	}()
+
+	return slots
+}
+
+func (h *BatchHandler) execute(ctx context.Context, req ExecuteRequest) (resp *ExecuteResponse, err error) {
+	ctx, cancel := context.WithTimeout(ctx, h.itemTimeout())
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			resp, err = nil, fmt.Errorf("executor panic: %v", r)
+		}
+	}()
+	return h.Executor.Execute(ctx, req)
+}
+
+// decodeBatch reads the whole batch up front so a syntax error anywhere is
+// rejected before any item runs. Items that are valid JSON but not a valid
+// request are kept and reported individually.
+func decodeBatch(body io.Reader, ndjson bool, maxItems int) ([]batchItem, error) {
+	dec := json.NewDecoder(body)
+
+	if !ndjson {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch body: %w", err)
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return nil, errors.New("batch body must be a JSON array of execute requests")
+		}
+	}
+
+	var items []batchItem
+	for {
+		if !ndjson && !dec.More() {
+			break
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if ndjson && err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("invalid batch item %d: %w", len(items), err)
+		}
+		if len(items) == maxItems {
+			return nil, fmt.Errorf("batch exceeds maximum of %d items", maxItems)
+		}
+		items = append(items, parseBatchItem(raw))
+	}
+
+	if !ndjson {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("invalid batch body: %w", err)
+		}
+	}
+	if len(items) == 0 {
+		return nil, errors.New("batch contains no items")
+	}
+	return items, nil
+}
+
+func parseBatchItem(raw json.RawMessage) batchItem {
+	var req ExecuteRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return batchItem{err: fmt.Errorf("invalid execute request: %w", err)}
+	}
+	if err := req.Validate(); err != nil {
+		return batchItem{err: err}
+	}
+	return batchItem{req: req}
+}
+
+func isNDJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == ndjsonContentType || mediaType == "application/ndjson")
+}
+
+func (h *BatchHandler) maxItems() int {
+	if h.Options.MaxItems > 0 {
+		return h.Options.MaxItems
+	}
+	return 10000
+}
+
+func (h *BatchHandler) maxConcurrency() int {
+	if h.Options.MaxConcurrency > 0 {
+		return h.Options.MaxConcurrency
+	}
+	return 16
+}
+
+func (h *BatchHandler) itemTimeout() time.Duration {
+	if h.Options.ItemTimeout > 0 {
+		return h.Options.ItemTimeout
+	}
+	return 30 * time.Second
+}
+
+var _ http.Handler = (*BatchHandler)(nil)
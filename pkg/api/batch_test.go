@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeExecutor struct {
+	inFlight    int32
+	maxInFlight int32
+}
+
+func (f *fakeExecutor) Route(req ExecuteRequest) ExecutionPath {
+	if strings.HasPrefix(req.Text, "run ") {
+		return TemporalPath
+	}
+	return FastPath
+}
+
+func (f *fakeExecutor) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteResponse, error) {
+	n := atomic.AddInt32(&f.inFlight, 1)
+	defer atomic.AddInt32(&f.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&f.maxInFlight)
+		if n <= max || atomic.CompareAndSwapInt32(&f.maxInFlight, max, n) {
+			break
+		}
+	}
+
+	if strings.Contains(req.Text, "fail") {
+		return nil, errors.New("cannot evaluate")
+	}
+	time.Sleep(5 * time.Millisecond)
+	if f.Route(req) == TemporalPath {
+		return &ExecuteResponse{Success: true, WorkflowID: "wf-" + req.SessionID}, nil
+	}
+	return &ExecuteResponse{Success: true, Result: req.Text, Deterministic: true}, nil
+}
+
+func TestBatchHandlerPreservesOrderAndItemErrors(t *testing.T) {
+	exec := &fakeExecutor{}
+	h := &BatchHandler{Executor: exec, Options: BatchOptions{MaxConcurrency: 3}}
+
+	var items []ExecuteRequest
+	for i := 0; i < 20; i++ {
+		items = append(items, ExecuteRequest{Text: "calculate " + string(rune('a'+i))})
+	}
+	items[4].Text = "calculate fail"
+	items[7] = ExecuteRequest{Text: "run data pipeline", SessionID: "s7"}
+	items[9].Text = ""
+	body, _ := json.Marshal(items)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/execute/batch", strings.NewReader(string(body))))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Total != 20 || resp.Failed != 2 || resp.Succeeded != 18 {
+		t.Fatalf("unexpected counts: %+v", resp)
+	}
+	for i, res := range resp.Results {
+		if res.Index != i {
+			t.Fatalf("result %d has index %d", i, res.Index)
+		}
+	}
+	if resp.Results[4].Error != "cannot evaluate" || resp.Results[9].Error != "invalid argument: text is required" {
+		t.Errorf("item errors not reported: %+v %+v", resp.Results[4], resp.Results[9])
+	}
+	if resp.Results[7].WorkflowID != "wf-s7" {
+		t.Errorf("durable item not executed: %+v", resp.Results[7])
+	}
+	if exec.maxInFlight > 4 {
+		t.Errorf("concurrency exceeded: %d in flight", exec.maxInFlight)
+	}
+}
+
+func TestBatchHandlerNDJSON(t *testing.T) {
+	h := NewBatchHandler(&fakeExecutor{})
+	body := "{\"text\":\"calculate 1 + 1\"}\n{\"text\":5}\n{\"text\":\"calculate 2 + 2\"}\n"
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/execute/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != ndjsonContentType {
+		t.Fatalf("content type = %q", ct)
+	}
+	var got []BatchItemResult
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var res BatchItemResult
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, res)
+	}
+	if len(got) != 3 || !got[0].Success || got[1].Success || !got[2].Success {
+		t.Fatalf("unexpected results: %+v", got)
+	}
+}
+
+func TestBatchHandlerRejectsBadBodies(t *testing.T) {
+	h := &BatchHandler{Executor: &fakeExecutor{}, Options: BatchOptions{MaxItems: 2}}
+	for name, body := range map[string]string{
+		"not an array": `{"text":"calculate 1 + 1"}`,
+		"empty":        `[]`,
+		"truncated":    `[{"text":"calculate 1 + 1"},`,
+		"too many":     `[{"text":"a"},{"text":"b"},{"text":"c"}]`,
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/execute/batch", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, rec.Code)
+		}
+	}
+}
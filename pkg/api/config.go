@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// ConfigResolver maps a config_ref to the runtime repository commit that
+// will answer the request
+type ConfigResolver interface {
+	// ResolveConfig returns the commit for ref (a SHA, tag or branch). An
+	// empty ref means the commit currently being served. Unknown refs wrap
+	// ErrInvalidArgument.
+	ResolveConfig(ctx context.Context, ref string) (string, error)
+}
+
+type configCommitKey struct{}
+
+// WithConfigCommit returns a context pinned to commit
+func WithConfigCommit(ctx context.Context, commit string) context.Context {
+	return context.WithValue(ctx, configCommitKey{}, commit)
+}
+
+// ConfigCommit returns the commit the request is pinned to. Executors load
+// tool and workflow definitions at this commit.
+func ConfigCommit(ctx context.Context) (string, bool) {
+	commit, ok := ctx.Value(configCommitKey{}).(string)
+	return commit, ok && commit != ""
+}
+
+// noConfig is used when no resolver is configured
+type noConfig struct{}
+
+func (noConfig) ResolveConfig(_ context.Context, ref string) (string, error) {
+	if ref != "" {
+		return "", fmt.Errorf("%w: config_ref is not supported without a config repository", ErrInvalidArgument)
+	}
+	return "", nil
+}
+
+// pinned wraps the executor and workflow service so both transports resolve
+// config_ref the same way, check workflow inputs and report the commit used
+func (s Services) pinned() Services {
+	config := s.Config
+	if config == nil {
+		config = noConfig{}
+	}
+	if s.Executor != nil {
+		s.Executor = pinnedExecutor{Executor: s.Executor, config: config}
+	}
+	if s.Workflows != nil {
+		s.Workflows = pinnedWorkflows{WorkflowService: s.Workflows, config: config, inputs: s.Inputs}
+	}
+	return s
+}
+
+type pinnedExecutor struct {
+	Executor
+	config ConfigResolver
+}
+
+func (e pinnedExecutor) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteResponse, error) {
+	commit, err := e.config.ResolveConfig(ctx, req.ConfigRef)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.Executor.Execute(WithConfigCommit(ctx, commit), req)
+	if resp != nil && resp.ConfigCommit == "" {
+		resp.ConfigCommit = commit
+	}
+	return resp, err
+}
+
+type pinnedWorkflows struct {
+	WorkflowService
+	config ConfigResolver
+	inputs InputValidator
+}
+
+func (w pinnedWorkflows) ExecuteWorkflow(ctx context.Context, req WorkflowExecuteRequest) (*WorkflowExecuteResponse, error) {
+	commit, err := w.config.ResolveConfig(ctx, req.ConfigRef)
+	if err != nil {
+		return nil, err
+	}
+	ctx = WithConfigCommit(ctx, commit)
+	if w.inputs != nil {
+		if err := w.inputs.ValidateInput(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := w.WorkflowService.ExecuteWorkflow(ctx, req)
+	if resp != nil && resp.ConfigCommit == "" {
+		resp.ConfigCommit = commit
+	}
+	return resp, err
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CommitAuthor identifies who made a configuration change
+type CommitAuthor struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// Validate checks the author can be written to a commit
+func (a CommitAuthor) Validate() error {
+	switch {
+	case strings.TrimSpace(a.Name) == "" || !strings.Contains(a.Email, "@"):
+		return fmt.Errorf("%w: author name and email are required", ErrInvalidArgument)
+	case strings.ContainsAny(a.Name+a.Email, "<>\n"):
+		return fmt.Errorf("%w: author contains invalid characters", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// ConfigWriteRequest is the body of PUT and DELETE /api/v1/config/{path}
+type ConfigWriteRequest struct {
+	// Content is the new file contents (PUT only)
+	Content string `json:"content,omitempty"`
+	Message string `json:"message"`
+	// Author is set from the authenticated caller, never from the body
+	Author CommitAuthor `json:"-"`
+	// ExpectedParent is the branch head the change was based on. When set,
+	// the write fails with 409 if the branch has moved. An If-Match header
+	// is accepted in its place.
+	ExpectedParent string `json:"expected_parent,omitempty"`
+}
+
+// Validate checks the fields every write needs; put also requires content
+func (r ConfigWriteRequest) Validate(put bool) error {
+	switch {
+	case strings.TrimSpace(r.Message) == "":
+		return fmt.Errorf("%w: message is required", ErrInvalidArgument)
+	case put && r.Content == "":
+		return fmt.Errorf("%w: content is required", ErrInvalidArgument)
+	}
+	return r.Author.Validate()
+}
+
+// ConfigWriteResponse reports the commit a write created
+type ConfigWriteResponse struct {
+	Success bool   `json:"success"`
+	Branch  string `json:"branch"`
+	Path    string `json:"path"`
+	// Commit is the new branch head; equal to Parent when nothing changed
+	Commit  string `json:"commit"`
+	Parent  string `json:"parent"`
+	Changed bool   `json:"changed"`
+	// Reloaded reports whether the registry serving the branch swapped to
+	// the new commit
+	Reloaded bool `json:"reloaded"`
+}
+
+// ConfigWriter commits configuration changes to the runtime repository.
+// The tenant is taken from the context (see Tenant).
+type ConfigWriter interface {
+	WriteConfig(ctx context.Context, path string, req ConfigWriteRequest) (*ConfigWriteResponse, error)
+	DeleteConfig(ctx context.Context, path string, req ConfigWriteRequest) (*ConfigWriteResponse, error)
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context for requests made on behalf of tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns the tenant from the X-Tenant-ID header, or "" for the
+// shared main branch. Writes take it from the authenticated caller instead.
+func Tenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
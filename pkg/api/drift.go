@@ -0,0 +1,80 @@
+package api
+
+import "context"
+
+// Drift statuses of a shared file on a tenant branch
+const (
+	// DriftBehind: main changed the file and the tenant did not, so merging
+	// main brings the fix in cleanly
+	DriftBehind = "behind"
+	// DriftOverridden: the tenant changed the file and main did not
+	DriftOverridden = "overridden"
+	// DriftConflict: both sides changed the file since the fork
+	DriftConflict = "conflict"
+)
+
+// DriftFile is one shared file whose tenant version differs from main
+type DriftFile struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	// Detail says what each side did, e.g. "modified on main"
+	Detail string `json:"detail"`
+}
+
+// TenantDrift compares one tenant branch with main
+type TenantDrift struct {
+	Tenant     string `json:"tenant"`
+	Branch     string `json:"branch"`
+	Commit     string `json:"commit"`
+	BaseCommit string `json:"base_commit"`
+	// CommitsBehind counts main commits not yet merged into the branch
+	CommitsBehind int         `json:"commits_behind"`
+	Behind        int         `json:"behind"`
+	Overridden    int         `json:"overridden"`
+	Conflicts     int         `json:"conflicts"`
+	Files         []DriftFile `json:"files"`
+	// Mergeable is true when main can be merged without conflicts
+	Mergeable bool `json:"mergeable"`
+}
+
+// DriftResponse is returned by GET /api/v1/drift and
+// GET /api/v1/tenants/{tenant}/drift
+type DriftResponse struct {
+	Success    bool          `json:"success"`
+	MainCommit string        `json:"main_commit"`
+	Tenants    []TenantDrift `json:"tenants"`
+}
+
+// MergeMainRequest is the body of POST /api/v1/tenants/{tenant}/merge
+type MergeMainRequest struct {
+	// Author is set from the authenticated caller, never from the body
+	Author CommitAuthor `json:"-"`
+	// Message defaults to "Merge main into customer/<tenant>"
+	Message string `json:"message,omitempty"`
+	// ExpectedHead fails the merge with 409 if the tenant branch has moved
+	ExpectedHead string `json:"expected_head,omitempty"`
+}
+
+// MergeMainResponse reports the merge commit created on a tenant branch
+type MergeMainResponse struct {
+	Success bool   `json:"success"`
+	Tenant  string `json:"tenant"`
+	Branch  string `json:"branch"`
+	// Commit is the new branch head; equal to Parent when the branch
+	// already contained main
+	Commit     string `json:"commit"`
+	Parent     string `json:"parent"`
+	MainCommit string `json:"main_commit"`
+	Merged     bool   `json:"merged"`
+	// Updated lists the files taken from main
+	Updated []string `json:"updated"`
+}
+
+// DriftService reports and repairs drift between main and tenant branches
+type DriftService interface {
+	// Drift compares tenant's branch with main, or every tenant branch
+	// when tenant is empty
+	Drift(ctx context.Context, tenant string) (*DriftResponse, error)
+	MergeMain(ctx context.Context, tenant string, req MergeMainRequest) (*MergeMainResponse, error)
+}
@@ -0,0 +1,259 @@
+package api
+
+//go:generate sh -c "cd ../.. && protoc -I proto --go_out=. --go_opt=module=github.com/Caia-Tech/volcano-llm --go-grpc_out=. --go-grpc_opt=module=github.com/Caia-Tech/volcano-llm volcano/v1/volcano.proto"
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api/volcanov1"
+)
+
+// GRPCServer serves volcano.v1.VolcanoService on top of the same Services
+// the REST handlers use, so validation and behaviour match across transports.
+type GRPCServer struct {
+	volcanov1.UnimplementedVolcanoServiceServer
+	services Services
+}
+
+// NewGRPCServer creates a gRPC adapter for services
+func NewGRPCServer(services Services) *GRPCServer {
+	return &GRPCServer{services: services.pinned()}
+}
+
+// Register attaches the service to a grpc.Server
+func (s *GRPCServer) Register(g *grpc.Server) {
+	volcanov1.RegisterVolcanoServiceServer(g, s)
+}
+
+func (s *GRPCServer) Execute(ctx context.Context, in *volcanov1.ExecuteRequest) (*volcanov1.ExecuteResponse, error) {
+	req := ExecuteRequest{Text: in.GetText(), SessionID: in.GetSessionId(), ConfigRef: in.GetConfigRef()}
+	if err := req.Validate(); err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp, err := s.services.Executor.Execute(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if resp == nil {
+		return nil, status.Error(codes.Internal, "executor returned no response")
+	}
+	result, err := toValue(resp.Result)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &volcanov1.ExecuteResponse{
+		Success:       resp.Success,
+		Result:        result,
+		Deterministic: resp.Deterministic,
+		WorkflowId:    resp.WorkflowID,
+		RunId:         resp.RunID,
+		Duration:      resp.Duration,
+		Error:         resp.Error,
+		ConfigCommit:  resp.ConfigCommit,
+	}, nil
+}
+
+func (s *GRPCServer) ExecuteWorkflow(ctx context.Context, in *volcanov1.ExecuteWorkflowRequest) (*volcanov1.ExecuteWorkflowResponse, error) {
+	req := WorkflowExecuteRequest{
+		WorkflowType: in.GetWorkflowType(),
+		CustomerID:   in.GetCustomerId(),
+		Parameters:   in.GetParameters().AsMap(),
+		Async:        in.GetAsync(),
+		ConfigRef:    in.GetConfigRef(),
+	}
+	if err := req.Validate(); err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp, err := s.services.Workflows.ExecuteWorkflow(ctx, req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if resp == nil {
+		return nil, status.Error(codes.Internal, "workflow service returned no response")
+	}
+	result, err := toValue(resp.Result)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &volcanov1.ExecuteWorkflowResponse{
+		Success:      resp.Success,
+		WorkflowId:   resp.WorkflowID,
+		RunId:        resp.RunID,
+		Status:       resp.Status,
+		Result:       result,
+		Error:        resp.Error,
+		ConfigCommit: resp.ConfigCommit,
+	}, nil
+}
+
+func (s *GRPCServer) SignalWorkflow(ctx context.Context, in *volcanov1.SignalWorkflowRequest) (*volcanov1.SignalWorkflowResponse, error) {
+	req := SignalRequest{SignalName: in.GetSignalName(), Data: in.GetData().AsInterface()}
+	if err := req.Validate(); err != nil {
+		return nil, grpcError(err)
+	}
+	if err := s.services.Workflows.SignalWorkflow(ctx, workflowRef(in.GetWorkflow()), req); err != nil {
+		return nil, grpcError(err)
+	}
+	return &volcanov1.SignalWorkflowResponse{Success: true}, nil
+}
+
+func (s *GRPCServer) QueryWorkflow(ctx context.Context, in *volcanov1.QueryWorkflowRequest) (*volcanov1.QueryWorkflowResponse, error) {
+	req := QueryRequest{QueryType: in.GetQueryType()}
+	for _, arg := range in.GetArgs() {
+		req.Args = append(req.Args, arg.AsInterface())
+	}
+	if err := req.Validate(); err != nil {
+		return nil, grpcError(err)
+	}
+
+	result, err := s.services.Workflows.QueryWorkflow(ctx, workflowRef(in.GetWorkflow()), req)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	value, err := toValue(result)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &volcanov1.QueryWorkflowResponse{Success: true, Result: value}, nil
+}
+
+func (s *GRPCServer) CancelWorkflow(ctx context.Context, in *volcanov1.CancelWorkflowRequest) (*volcanov1.CancelWorkflowResponse, error) {
+	if err := s.services.Workflows.CancelWorkflow(ctx, workflowRef(in.GetWorkflow())); err != nil {
+		return nil, grpcError(err)
+	}
+	return &volcanov1.CancelWorkflowResponse{Success: true}, nil
+}
+
+func (s *GRPCServer) GetWorkflowStatus(ctx context.Context, in *volcanov1.GetWorkflowStatusRequest) (*volcanov1.WorkflowStatus, error) {
+	st, err := s.services.Workflows.WorkflowStatus(ctx, workflowRef(in.GetWorkflow()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if st == nil {
+		return nil, status.Error(codes.Internal, "workflow service returned no status")
+	}
+	out := &volcanov1.WorkflowStatus{
+		Success:      st.Success,
+		WorkflowId:   st.WorkflowID,
+		RunId:        st.RunID,
+		WorkflowType: st.WorkflowType,
+		Status:       st.Status,
+		StartTime:    timestamppb.New(st.StartTime),
+		TaskQueue:    st.TaskQueue,
+	}
+	if st.CloseTime != nil {
+		out.CloseTime = timestamppb.New(*st.CloseTime)
+	}
+	return out, nil
+}
+
+func (s *GRPCServer) WatchWorkflow(in *volcanov1.WatchWorkflowRequest, stream grpc.ServerStreamingServer[volcanov1.WorkflowProgress]) error {
+	err := s.services.Workflows.WatchWorkflow(stream.Context(), workflowRef(in.GetWorkflow()), func(p WorkflowProgress) error {
+		return stream.Send(&volcanov1.WorkflowProgress{
+			WorkflowId: p.WorkflowID,
+			RunId:      p.RunID,
+			Status:     p.Status,
+			Stage:      p.Stage,
+			Percent:    p.Percent,
+			Message:    p.Message,
+			Timestamp:  timestamppb.New(p.Timestamp),
+		})
+	})
+	if err != nil {
+		return grpcError(err)
+	}
+	return nil
+}
+
+func (s *GRPCServer) ListDefinitions(ctx context.Context, _ *volcanov1.ListDefinitionsRequest) (*volcanov1.ListDefinitionsResponse, error) {
+	defs, err := s.services.Definitions.ListDefinitions(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	out := &volcanov1.ListDefinitionsResponse{Success: true, Total: int32(len(defs))}
+	for _, d := range defs {
+		out.Definitions = append(out.Definitions, &volcanov1.WorkflowDefinition{
+			Name:         d.Name,
+			Version:      d.Version,
+			TaskQueue:    d.TaskQueue,
+			LastModified: timestamppb.New(d.LastModified),
+		})
+	}
+	return out, nil
+}
+
+func (s *GRPCServer) Reload(ctx context.Context, in *volcanov1.ReloadRequest) (*volcanov1.ReloadResponse, error) {
+	resp, err := s.services.Definitions.Reload(ctx, ReloadRequest{
+		Type:       in.GetType(),
+		Repository: in.GetRepository(),
+		FilePath:   in.GetFilePath(),
+		Timestamp:  in.GetTimestamp().AsTime(),
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	if resp == nil {
+		return nil, status.Error(codes.Internal, "definition service returned no response")
+	}
+	return &volcanov1.ReloadResponse{Success: resp.Success, Message: resp.Message, Reloaded: int32(resp.Reloaded)}, nil
+}
+
+func workflowRef(ref *volcanov1.WorkflowRef) WorkflowRef {
+	return WorkflowRef{WorkflowID: ref.GetWorkflowId(), RunID: ref.GetRunId()}
+}
+
+// toValue converts an arbitrary result into a protobuf Value, going through
+// JSON for types structpb does not know (structs, typed maps and slices).
+func toValue(v interface{}) (*structpb.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if value, err := structpb.NewValue(v); err == nil {
+		return value, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode result: %w", err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("encode result: %w", err)
+	}
+	return structpb.NewValue(generic)
+}
+
+// grpcError maps service errors onto gRPC status codes
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidArgument):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, ErrUnauthenticated):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api/volcanov1"
+)
+
+type fakeWorkflows struct {
+	signals []SignalRequest
+}
+
+func (f *fakeWorkflows) ExecuteWorkflow(ctx context.Context, req WorkflowExecuteRequest) (*WorkflowExecuteResponse, error) {
+	if req.WorkflowType == "NonExistentWorkflow" {
+		return nil, fmt.Errorf("%w: workflow type %q", ErrNotFound, req.WorkflowType)
+	}
+	return &WorkflowExecuteResponse{
+		Success:    true,
+		WorkflowID: req.WorkflowType + "-" + req.CustomerID,
+		RunID:      "run-1",
+		Status:     "Running",
+		Result:     map[string]int{"days": int(req.Parameters["processing_days"].(float64))},
+	}, nil
+}
+
+func (f *fakeWorkflows) SignalWorkflow(ctx context.Context, ref WorkflowRef, req SignalRequest) error {
+	f.signals = append(f.signals, req)
+	return nil
+}
+
+func (f *fakeWorkflows) QueryWorkflow(ctx context.Context, ref WorkflowRef, req QueryRequest) (interface{}, error) {
+	return map[string]interface{}{"progress": 0.5}, nil
+}
+
+func (f *fakeWorkflows) CancelWorkflow(ctx context.Context, ref WorkflowRef) error { return nil }
+
+func (f *fakeWorkflows) WorkflowStatus(ctx context.Context, ref WorkflowRef) (*WorkflowStatus, error) {
+	return &WorkflowStatus{Success: true, WorkflowID: ref.WorkflowID, RunID: ref.RunID, Status: "Running", StartTime: time.Now()}, nil
+}
+
+func (f *fakeWorkflows) WatchWorkflow(ctx context.Context, ref WorkflowRef, send func(WorkflowProgress) error) error {
+	for i, stage := range []string{"extract", "validate", "transform"} {
+		if err := send(WorkflowProgress{WorkflowID: ref.WorkflowID, Stage: stage, Percent: float64(i+1) / 3 * 100}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dialTestServer(t *testing.T, services Services) volcanov1.VolcanoServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	NewGRPCServer(services).Register(srv)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return volcanov1.NewVolcanoServiceClient(conn)
+}
+
+func TestGRPCServerSharesServices(t *testing.T) {
+	workflows := &fakeWorkflows{}
+	client := dialTestServer(t, Services{Executor: &fakeExecutor{}, Workflows: workflows})
+	ctx := context.Background()
+
+	exec, err := client.Execute(ctx, &volcanov1.ExecuteRequest{Text: "calculate 42 + 58"})
+	if err != nil || !exec.Success || exec.Result.GetStringValue() != "calculate 42 + 58" {
+		t.Fatalf("Execute = %v, %v", exec, err)
+	}
+
+	if _, err := client.Execute(ctx, &volcanov1.ExecuteRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("empty text: got %v, want InvalidArgument", err)
+	}
+
+	params, _ := structpb.NewStruct(map[string]interface{}{"processing_days": 3})
+	wf, err := client.ExecuteWorkflow(ctx, &volcanov1.ExecuteWorkflowRequest{
+		WorkflowType: "LongRunningAnalyticsWorkflow",
+		CustomerId:   "signal-test-customer",
+		Parameters:   params,
+		Async:        true,
+	})
+	if err != nil || wf.WorkflowId != "LongRunningAnalyticsWorkflow-signal-test-customer" {
+		t.Fatalf("ExecuteWorkflow = %v, %v", wf, err)
+	}
+	if days := wf.Result.GetStructValue().GetFields()["days"].GetNumberValue(); days != 3 {
+		t.Errorf("typed result not converted: %v", wf.Result)
+	}
+
+	_, err = client.ExecuteWorkflow(ctx, &volcanov1.ExecuteWorkflowRequest{WorkflowType: "NonExistentWorkflow", CustomerId: "c"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("unknown workflow: got %v, want NotFound", err)
+	}
+
+	ref := &volcanov1.WorkflowRef{WorkflowId: wf.WorkflowId, RunId: wf.RunId}
+	if _, err := client.SignalWorkflow(ctx, &volcanov1.SignalWorkflowRequest{Workflow: ref, SignalName: "pause", Data: structpb.NewBoolValue(true)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(workflows.signals) != 1 || workflows.signals[0].Data != true {
+		t.Errorf("signal not delivered: %+v", workflows.signals)
+	}
+}
+
+func TestGRPCWatchWorkflowStreams(t *testing.T) {
+	client := dialTestServer(t, Services{Workflows: &fakeWorkflows{}})
+
+	stream, err := client.WatchWorkflow(context.Background(), &volcanov1.WatchWorkflowRequest{
+		Workflow: &volcanov1.WorkflowRef{WorkflowId: "wf-1", RunId: "run-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stages []string
+	for {
+		p, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		stages = append(stages, p.Stage)
+	}
+	if len(stages) != 3 || stages[2] != "transform" {
+		t.Errorf("stages = %v", stages)
+	}
+}
+
+type nilExecutor struct{ *fakeExecutor }
+
+func (nilExecutor) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteResponse, error) {
+	return nil, nil
+}
+
+func TestGRPCExecuteNilResponse(t *testing.T) {
+	client := dialTestServer(t, Services{Executor: nilExecutor{&fakeExecutor{}}})
+
+	_, err := client.Execute(context.Background(), &volcanov1.ExecuteRequest{Text: "calculate 1 + 1"})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("nil response: got %v, want Internal", err)
+	}
+}
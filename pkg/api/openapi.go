@@ -0,0 +1,189 @@
+package api
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// OpenAPIVersion is the version reported in the generated document's info block
+const OpenAPIVersion = "0.1.0"
+
+var pathParamPattern = regexp.MustCompile(`\{([a-z_]+)(?:\.\.\.)?\}`)
+
+// OpenAPI builds an OpenAPI 3 document from the route table. Every named
+// struct becomes a component schema, so the document only changes when a
+// route or one of its Go types changes.
+func OpenAPI(routes []Route) map[string]interface{} {
+	g := &schemaGen{components: map[string]interface{}{}}
+	paths := map[string]interface{}{}
+
+	for _, r := range routes {
+		op := map[string]interface{}{
+			"operationId": r.Name,
+			"summary":     r.Summary,
+			"responses": map[string]interface{}{
+				"200": jsonContent("Success", g.schema(r.Response)),
+				"default": jsonContent("Error",
+					g.schema(reflect.TypeOf(ErrorResponse{}))),
+			},
+		}
+
+		var params []interface{}
+		for _, m := range pathParamPattern.FindAllStringSubmatch(r.Path, -1) {
+			params = append(params, map[string]interface{}{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		if r.Query != nil {
+			for _, f := range queryParams(r.Query) {
+				params = append(params, map[string]interface{}{
+					"name":   f.Tag.Get("query"),
+					"in":     "query",
+					"schema": g.schema(f.Type),
+				})
+			}
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if r.Request != nil {
+			body := jsonContent("", g.schema(r.Request))
+			delete(body, "description")
+			body["required"] = true
+			if r.Name == "executeBatch" {
+				body["content"].(map[string]interface{})[ndjsonContentType] = map[string]interface{}{
+					"schema": g.schema(reflect.TypeOf(ExecuteRequest{})),
+				}
+			}
+			op["requestBody"] = body
+		}
+
+		// ServeMux wildcards like {path...} are plain parameters in OpenAPI
+		specPath := strings.ReplaceAll(r.Path, "...}", "}")
+		item, ok := paths[specPath].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[specPath] = item
+		}
+		item[strings.ToLower(r.Method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Volcano LLM API",
+			"version":     OpenAPIVersion,
+			"description": "Deterministic execution and git-native Temporal workflows",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.components,
+		},
+	}
+}
+
+func jsonContent(description string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+type schemaGen struct {
+	components map[string]interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema for t, registering named structs as components
+func (g *schemaGen) schema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := g.schema(t.Elem())
+		if _, isRef := s["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{s}, "nullable": true}
+		}
+		s["nullable"] = true
+		return s
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Interface:
+		return map[string]interface{}{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, done := g.components[t.Name()]; !done {
+			// Reserve the name first so self-referencing types terminate
+			g.components[t.Name()] = map[string]interface{}{}
+			g.components[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []interface{}
+	g.addFields(t, props, &required)
+
+	s := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// addFields walks t's JSON-visible fields, flattening embedded structs the
+// way encoding/json does. Fields without omitempty are required.
+func (g *schemaGen) addFields(t reflect.Type, props map[string]interface{}, required *[]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.addFields(f.Type, props, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
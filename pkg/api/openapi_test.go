@@ -0,0 +1,71 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+var updateSpec = flag.Bool("update", false, "rewrite docs/openapi.json from the route table")
+
+const publishedSpec = "../../docs/openapi.json"
+
+// TestOpenAPISpecMatchesHandlers fails whenever a route or one of its
+// request/response types changes without docs/openapi.json being
+// regenerated. Run `go test ./pkg/api -run OpenAPI -update` to refresh it.
+func TestOpenAPISpecMatchesHandlers(t *testing.T) {
+	generated, err := json.MarshalIndent(OpenAPI(NewServer(Services{}).Routes()), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated = append(generated, '\n')
+
+	if *updateSpec {
+		if err := os.WriteFile(publishedSpec, generated, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	published, err := os.ReadFile(publishedSpec)
+	if err != nil {
+		t.Fatalf("read published spec: %v", err)
+	}
+	if !bytes.Equal(published, generated) {
+		t.Fatalf("%s is out of date with the handler types; rerun with -update", publishedSpec)
+	}
+}
+
+func TestOpenAPIServedFromRouteTable(t *testing.T) {
+	srv := NewServer(Services{})
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage
+		Components struct {
+			Schemas map[string]json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range srv.Routes() {
+		specPath := strings.ReplaceAll(r.Path, "...}", "}")
+		if _, ok := doc.Paths[specPath][strings.ToLower(r.Method)]; !ok {
+			t.Errorf("route %s %s missing from spec", r.Method, r.Path)
+		}
+	}
+	for _, name := range []string{"ExecuteRequest", "WorkflowExecuteRequest", "BatchItemResult", "ErrorResponse"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s missing", name)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import "context"
+
+// KeyOverride is one value in a merged file that the tenant branch changed.
+// Key is a dotted path into the document; Action is set, append or delete.
+type KeyOverride struct {
+	Key    string `json:"key"`
+	Action string `json:"action"`
+}
+
+// ProvenanceEntry says where one effective definition file came from
+type ProvenanceEntry struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Name string `json:"name,omitempty"`
+	// Source is main, tenant or merged, or deleted for a main file the
+	// tenant branch removes
+	Source string `json:"source"`
+	// Commit is the commit the file was read from
+	Commit string `json:"commit"`
+	// Overrides lists the tenant's changes to a merged file; every other
+	// value comes from main
+	Overrides []KeyOverride `json:"overrides,omitempty"`
+}
+
+// ProvenanceResponse is returned by GET /api/v1/tenants/{tenant}/provenance
+type ProvenanceResponse struct {
+	Success bool   `json:"success"`
+	Tenant  string `json:"tenant"`
+	Branch  string `json:"branch"`
+	// TenantCommit is empty when the tenant has no branch yet and simply
+	// inherits main
+	MainCommit   string `json:"main_commit"`
+	TenantCommit string `json:"tenant_commit,omitempty"`
+	// BaseCommit is where the tenant branch forked from main
+	BaseCommit string            `json:"base_commit,omitempty"`
+	Entries    []ProvenanceEntry `json:"entries"`
+}
+
+// ProvenanceService resolves a tenant's effective configuration
+type ProvenanceService interface {
+	Provenance(ctx context.Context, tenant string) (*ProvenanceResponse, error)
+}
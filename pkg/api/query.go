@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// queryParams returns the fields of t tagged `query:"name"`, or nil if t
+// is not a struct read from the query string
+func queryParams(t reflect.Type) []reflect.StructField {
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var out []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Tag.Get("query") != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// decodeQuery fills the query-tagged fields of dst (a pointer to struct).
+// Times are RFC 3339.
+func decodeQuery(values url.Values, dst interface{}) error {
+	v := reflect.ValueOf(dst).Elem()
+	for _, f := range queryParams(v.Type()) {
+		name := f.Tag.Get("query")
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+		field := v.FieldByIndex(f.Index)
+		switch {
+		case f.Type == timeType:
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return fmt.Errorf("%w: %s must be an RFC 3339 time", ErrInvalidArgument, name)
+			}
+			field.Set(reflect.ValueOf(t))
+		case f.Type.Kind() == reflect.String:
+			field.SetString(raw)
+		case f.Type.Kind() == reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("%w: %s must be an integer", ErrInvalidArgument, name)
+			}
+			field.SetInt(int64(n))
+		case f.Type.Kind() == reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("%w: %s must be true or false", ErrInvalidArgument, name)
+			}
+			field.SetBool(b)
+		default:
+			return fmt.Errorf("query parameter %s has unsupported type %s", name, f.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Outcomes of a reload attempt
+const (
+	ReloadApplied  = "applied"
+	ReloadRejected = "rejected"
+)
+
+// ReloadFile is one file a reload attempt changed
+type ReloadFile struct {
+	Path    string `json:"path"`
+	OldPath string `json:"old_path,omitempty"`
+	// Change is added, modified, deleted or renamed
+	Change string `json:"change"`
+	Kind   string `json:"kind"`
+}
+
+// ReloadIssue is a validation error that caused a rejection
+type ReloadIssue struct {
+	Path    string `json:"path"`
+	Pointer string `json:"pointer,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// ReloadRecord is one entry of the reload audit log
+type ReloadRecord struct {
+	ID     int64  `json:"id"`
+	Branch string `json:"branch"`
+	// Tenant is empty for main
+	Tenant    string        `json:"tenant,omitempty"`
+	OldCommit string        `json:"old_commit,omitempty"`
+	NewCommit string        `json:"new_commit"`
+	Author    CommitAuthor  `json:"author"`
+	Message   string        `json:"message"`
+	Outcome   string        `json:"outcome"`
+	Files     []ReloadFile  `json:"files"`
+	Errors    []ReloadIssue `json:"errors,omitempty"`
+	Duration  string        `json:"duration"`
+	StartedAt time.Time     `json:"started_at"`
+}
+
+// ReloadFilter selects audit log entries. Every set field must match.
+type ReloadFilter struct {
+	Tenant string `query:"tenant"`
+	Branch string `query:"branch"`
+	// File matches a changed or failing path, exactly or as a path.Match
+	// pattern such as tools/*.json
+	File    string    `query:"file"`
+	Outcome string    `query:"outcome"`
+	Since   time.Time `query:"since"`
+	Until   time.Time `query:"until"`
+	// Limit caps the entries returned, newest first (default 100, max 1000)
+	Limit int `query:"limit"`
+}
+
+// Validate checks the filter's values
+func (f *ReloadFilter) Validate() error {
+	switch {
+	case f.Outcome != "" && f.Outcome != ReloadApplied && f.Outcome != ReloadRejected:
+		return fmt.Errorf("%w: outcome must be %s or %s", ErrInvalidArgument, ReloadApplied, ReloadRejected)
+	case f.Limit < 0 || f.Limit > 1000:
+		return fmt.Errorf("%w: limit must be between 1 and 1000", ErrInvalidArgument)
+	case !f.Since.IsZero() && !f.Until.IsZero() && f.Until.Before(f.Since):
+		return fmt.Errorf("%w: until is before since", ErrInvalidArgument)
+	}
+	if f.Limit == 0 {
+		f.Limit = 100
+	}
+	return nil
+}
+
+// ReloadsResponse is returned by GET /api/v1/reloads
+type ReloadsResponse struct {
+	Success bool `json:"success"`
+	// Total counts the matching entries before Limit was applied
+	Total   int            `json:"total"`
+	Reloads []ReloadRecord `json:"reloads"`
+}
+
+// ReloadHistory queries the reload audit log
+type ReloadHistory interface {
+	Reloads(ctx context.Context, filter ReloadFilter) (*ReloadsResponse, error)
+}
@@ -0,0 +1,464 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/health"
+)
+
+// Route is one /api/v1 endpoint. Request and Response are the Go types the
+// handler decodes and encodes; the OpenAPI document is generated from them.
+// Query is set instead of Request when the request comes from the query
+// string.
+type Route struct {
+	Name     string
+	Method   string
+	Path     string
+	Summary  string
+	Request  reflect.Type
+	Query    reflect.Type
+	Response reflect.Type
+	Handler  http.Handler
+}
+
+// Server routes /api/v1 requests to the shared Services
+type Server struct {
+	services Services
+	routes   []Route
+	mux      *http.ServeMux
+
+	specOnce sync.Once
+	spec     []byte
+	specErr  error
+}
+
+// NewServer builds the REST router for services
+func NewServer(services Services) *Server {
+	s := &Server{services: services.pinned(), mux: http.NewServeMux()}
+	s.routes = s.buildRoutes()
+	for _, r := range s.routes {
+		s.mux.Handle(r.Method+" "+r.Path, r.Handler)
+	}
+
+	checker := services.Health
+	if checker == nil {
+		checker = health.NewChecker()
+	}
+	s.mux.Handle("GET /health/live", checker.LiveHandler())
+	s.mux.Handle("GET /health/ready", checker.ReadyHandler())
+	// Kept for probes configured before the live/ready split
+	s.mux.Handle("GET /health", checker.LiveHandler())
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if tenant := r.Header.Get("X-Tenant-ID"); tenant != "" {
+		r = r.WithContext(WithTenant(r.Context(), tenant))
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// Routes returns the route table in registration order
+func (s *Server) Routes() []Route {
+	return append([]Route(nil), s.routes...)
+}
+
+func (s *Server) buildRoutes() []Route {
+	return []Route{
+		jsonRoute("execute", "POST", "/api/v1/execute",
+			"Execute a natural-language request on the fast or durable path", s.execute),
+		{
+			Name:     "executeBatch",
+			Method:   "POST",
+			Path:     "/api/v1/execute/batch",
+			Summary:  "Execute many requests in one call (JSON array or NDJSON)",
+			Request:  reflect.TypeOf([]ExecuteRequest(nil)),
+			Response: reflect.TypeOf(BatchResponse{}),
+			Handler:  NewBatchHandler(s.services.Executor),
+		},
+		jsonRoute("executeWorkflow", "POST", "/api/v1/temporal/workflows/execute",
+			"Start a Temporal workflow", s.executeWorkflow),
+		jsonRoute("listDefinitions", "GET", "/api/v1/temporal/workflows/definitions",
+			"List workflow definitions loaded from git", s.listDefinitions),
+		jsonRoute("workflowStatus", "GET", "/api/v1/temporal/workflows/{workflow_id}/runs/{run_id}/status",
+			"Describe a workflow run", s.workflowStatus),
+		jsonRoute("signalWorkflow", "POST", "/api/v1/temporal/workflows/{workflow_id}/runs/{run_id}/signal",
+			"Send a signal to a workflow run", s.signalWorkflow),
+		jsonRoute("queryWorkflow", "POST", "/api/v1/temporal/workflows/{workflow_id}/runs/{run_id}/query",
+			"Query a workflow run", s.queryWorkflow),
+		jsonRoute("cancelWorkflow", "POST", "/api/v1/temporal/workflows/{workflow_id}/runs/{run_id}/cancel",
+			"Request cancellation of a workflow run", s.cancelWorkflow),
+		jsonRoute("reload", "POST", "/api/v1/temporal/reload",
+			"Trigger a git-native hot-reload", s.reload),
+		jsonRoute("writeConfig", "PUT", "/api/v1/config/{path...}",
+			"Commit a new version of a tool, workflow or config file to the caller's branch", s.writeConfig),
+		jsonRoute("deleteConfig", "DELETE", "/api/v1/config/{path...}",
+			"Commit the removal of a tool, workflow or config file from the caller's branch", s.deleteConfig),
+		jsonRoute("tenantProvenance", "GET", "/api/v1/tenants/{tenant}/provenance",
+			"Show the tenant's effective definitions and which branch each came from", s.tenantProvenance),
+		jsonRoute("driftReport", "GET", "/api/v1/drift",
+			"Compare every tenant branch with main", s.driftReport),
+		jsonRoute("tenantDrift", "GET", "/api/v1/tenants/{tenant}/drift",
+			"Compare one tenant branch with main", s.tenantDrift),
+		jsonRoute("mergeMain", "POST", "/api/v1/tenants/{tenant}/merge",
+			"Merge main into a tenant branch when it is conflict-free", s.mergeMain),
+		jsonRoute("sync", "POST", "/api/v1/sync",
+			"Fetch the runtime repository's remote and fast-forward its branches", s.sync),
+		{
+			Name:     "pushWebhook",
+			Method:   "POST",
+			Path:     "/api/v1/webhooks/git",
+			Summary:  "GitHub/Gitea push webhook (HMAC-SHA256 signed) that triggers a sync",
+			Request:  reflect.TypeOf(PushEvent{}),
+			Response: reflect.TypeOf(SyncResponse{}),
+			Handler:  http.HandlerFunc(s.pushWebhook),
+		},
+		jsonRoute("reloads", "GET", "/api/v1/reloads",
+			"Query the audit log of reload attempts", s.reloads),
+		jsonRoute("listApprovals", "GET", "/api/v1/approvals",
+			"List workflow stages waiting for a human decision", s.listApprovals),
+		jsonRoute("decideApproval", "POST", "/api/v1/approvals/{workflow_id}/{stage}",
+			"Approve or reject a workflow stage waiting for a decision", s.decideApproval),
+		jsonRoute("workersStatus", "GET", "/api/v1/temporal/workers/status",
+			"List Temporal workers polling the runtime's task queues", s.workersStatus),
+		jsonRoute("metrics", "GET", "/api/v1/temporal/metrics",
+			"Runtime and Temporal metrics", s.metrics),
+		{
+			Name:     "openapi",
+			Method:   "GET",
+			Path:     "/api/v1/openapi.json",
+			Summary:  "This OpenAPI document",
+			Response: reflect.TypeOf(map[string]interface{}(nil)),
+			Handler:  http.HandlerFunc(s.serveOpenAPI),
+		},
+	}
+}
+
+// noBody is the request type of routes that do not read a body
+type noBody struct{}
+
+// jsonRoute wraps a typed handler: the body is decoded into Req (unless Req
+// is noBody, or a struct with query-tagged fields, which is filled from the
+// query string), the result is written as JSON and errors map to status
+// codes.
+func jsonRoute[Req, Resp any](name, method, path, summary string, fn func(ctx context.Context, r *http.Request, req Req) (Resp, error)) Route {
+	route := Route{
+		Name:     name,
+		Method:   method,
+		Path:     path,
+		Summary:  summary,
+		Response: reflect.TypeOf((*Resp)(nil)).Elem(),
+	}
+
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+	fromQuery := len(queryParams(reqType)) > 0
+	hasBody := reqType != reflect.TypeOf(noBody{}) && !fromQuery
+	if hasBody {
+		route.Request = reqType
+	}
+	if fromQuery {
+		route.Query = reqType
+	}
+
+	route.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Req
+		if hasBody && r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+				return
+			}
+		}
+		if fromQuery {
+			if err := decodeQuery(r.URL.Query(), &req); err != nil {
+				writeError(w, httpStatus(err), err.Error())
+				return
+			}
+		}
+
+		resp, err := fn(r.Context(), r, req)
+		if err != nil {
+			writeError(w, httpStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+	return route
+}
+
+// httpStatus maps service errors onto HTTP status codes
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+func pathRef(r *http.Request) WorkflowRef {
+	return WorkflowRef{WorkflowID: r.PathValue("workflow_id"), RunID: r.PathValue("run_id")}
+}
+
+func (s *Server) execute(ctx context.Context, _ *http.Request, req ExecuteRequest) (*ExecuteResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.services.Executor.Execute(ctx, req)
+}
+
+func (s *Server) executeWorkflow(ctx context.Context, _ *http.Request, req WorkflowExecuteRequest) (*WorkflowExecuteResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.services.Workflows.ExecuteWorkflow(ctx, req)
+}
+
+func (s *Server) listDefinitions(ctx context.Context, _ *http.Request, _ noBody) (*DefinitionsResponse, error) {
+	defs, err := s.services.Definitions.ListDefinitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if defs == nil {
+		defs = []DefinitionSummary{}
+	}
+	return &DefinitionsResponse{Success: true, Total: len(defs), Definitions: defs}, nil
+}
+
+func (s *Server) workflowStatus(ctx context.Context, r *http.Request, _ noBody) (*WorkflowStatus, error) {
+	return s.services.Workflows.WorkflowStatus(ctx, pathRef(r))
+}
+
+func (s *Server) signalWorkflow(ctx context.Context, r *http.Request, req SignalRequest) (*SuccessResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.services.Workflows.SignalWorkflow(ctx, pathRef(r), req); err != nil {
+		return nil, err
+	}
+	return &SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) queryWorkflow(ctx context.Context, r *http.Request, req QueryRequest) (*QueryResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	result, err := s.services.Workflows.QueryWorkflow(ctx, pathRef(r), req)
+	if err != nil {
+		return nil, err
+	}
+	return &QueryResponse{Success: true, Result: result}, nil
+}
+
+func (s *Server) cancelWorkflow(ctx context.Context, r *http.Request, _ noBody) (*SuccessResponse, error) {
+	if err := s.services.Workflows.CancelWorkflow(ctx, pathRef(r)); err != nil {
+		return nil, err
+	}
+	return &SuccessResponse{Success: true}, nil
+}
+
+func (s *Server) reload(ctx context.Context, _ *http.Request, req ReloadRequest) (*ReloadResponse, error) {
+	return s.services.Definitions.Reload(ctx, req)
+}
+
+func (s *Server) writeConfig(ctx context.Context, r *http.Request, req ConfigWriteRequest) (*ConfigWriteResponse, error) {
+	if s.services.ConfigWriter == nil {
+		return nil, fmt.Errorf("%w: config writes are not enabled", ErrNotFound)
+	}
+	ctx, author, err := s.writer(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Author = author
+	req.ExpectedParent = expectedParent(r, req.ExpectedParent)
+	if err := req.Validate(true); err != nil {
+		return nil, err
+	}
+	return s.services.ConfigWriter.WriteConfig(ctx, r.PathValue("path"), req)
+}
+
+func (s *Server) deleteConfig(ctx context.Context, r *http.Request, req ConfigWriteRequest) (*ConfigWriteResponse, error) {
+	if s.services.ConfigWriter == nil {
+		return nil, fmt.Errorf("%w: config writes are not enabled", ErrNotFound)
+	}
+	ctx, author, err := s.writer(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Author = author
+	req.ExpectedParent = expectedParent(r, req.ExpectedParent)
+	if err := req.Validate(false); err != nil {
+		return nil, err
+	}
+	return s.services.ConfigWriter.DeleteConfig(ctx, r.PathValue("path"), req)
+}
+
+func (s *Server) tenantProvenance(ctx context.Context, r *http.Request, _ noBody) (*ProvenanceResponse, error) {
+	if s.services.Provenance == nil {
+		return nil, fmt.Errorf("%w: tenant overlays are not enabled", ErrNotFound)
+	}
+	return s.services.Provenance.Provenance(ctx, r.PathValue("tenant"))
+}
+
+func (s *Server) driftReport(ctx context.Context, _ *http.Request, _ noBody) (*DriftResponse, error) {
+	if s.services.Drift == nil {
+		return nil, fmt.Errorf("%w: drift reports are not enabled", ErrNotFound)
+	}
+	return s.services.Drift.Drift(ctx, "")
+}
+
+func (s *Server) tenantDrift(ctx context.Context, r *http.Request, _ noBody) (*DriftResponse, error) {
+	if s.services.Drift == nil {
+		return nil, fmt.Errorf("%w: drift reports are not enabled", ErrNotFound)
+	}
+	return s.services.Drift.Drift(ctx, r.PathValue("tenant"))
+}
+
+func (s *Server) mergeMain(ctx context.Context, r *http.Request, req MergeMainRequest) (*MergeMainResponse, error) {
+	if s.services.Drift == nil {
+		return nil, fmt.Errorf("%w: drift reports are not enabled", ErrNotFound)
+	}
+	by, err := s.caller(r)
+	if err != nil {
+		return nil, err
+	}
+	tenant := r.PathValue("tenant")
+	if by.Tenant != "" && by.Tenant != tenant {
+		return nil, fmt.Errorf("%w: %s may only merge into customer/%s", ErrPermissionDenied, by.Subject, by.Tenant)
+	}
+	if req.Author, err = by.author(); err != nil {
+		return nil, err
+	}
+	req.ExpectedHead = expectedParent(r, req.ExpectedHead)
+	return s.services.Drift.MergeMain(ctx, tenant, req)
+}
+
+func (s *Server) sync(ctx context.Context, r *http.Request, _ noBody) (*SyncResponse, error) {
+	if s.services.Sync == nil {
+		return nil, fmt.Errorf("%w: no remote is configured", ErrNotFound)
+	}
+	by, err := s.caller(r)
+	if err != nil {
+		return nil, err
+	}
+	// A sync moves main and every tenant branch
+	if by.Tenant != "" {
+		return nil, fmt.Errorf("%w: %s cannot sync every branch", ErrPermissionDenied, by.Subject)
+	}
+	return s.services.Sync.Sync(ctx, "api", nil)
+}
+
+func (s *Server) reloads(ctx context.Context, _ *http.Request, filter ReloadFilter) (*ReloadsResponse, error) {
+	if s.services.Reloads == nil {
+		return nil, fmt.Errorf("%w: the reload audit log is not enabled", ErrNotFound)
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return s.services.Reloads.Reloads(ctx, filter)
+}
+
+func (s *Server) listApprovals(ctx context.Context, _ *http.Request, filter ApprovalFilter) (*ApprovalsResponse, error) {
+	if s.services.Approvals == nil {
+		return nil, fmt.Errorf("%w: approvals are not enabled", ErrNotFound)
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	return s.services.Approvals.Approvals(ctx, filter)
+}
+
+func (s *Server) decideApproval(ctx context.Context, r *http.Request, req ApprovalDecisionRequest) (*ApprovalResponse, error) {
+	if s.services.Approvals == nil {
+		return nil, fmt.Errorf("%w: approvals are not enabled", ErrNotFound)
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	by, err := s.caller(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.services.Approvals.Decide(ctx, r.PathValue("workflow_id"), r.PathValue("stage"), by, req)
+}
+
+// caller authenticates the caller of a route that changes state; without an
+// Authenticator those routes are not enabled
+func (s *Server) caller(r *http.Request) (Principal, error) {
+	if s.services.Authenticator == nil {
+		return Principal{}, fmt.Errorf("%w: %s %s is not enabled without an authenticator", ErrNotFound, r.Method, r.URL.Path)
+	}
+	return authenticate(s.services.Authenticator, r)
+}
+
+// writer authenticates the caller of a config write, returning a context
+// scoped to their tenant and the author their commits carry. An
+// X-Tenant-ID header naming another tenant is refused rather than ignored.
+func (s *Server) writer(ctx context.Context, r *http.Request) (context.Context, CommitAuthor, error) {
+	by, err := s.caller(r)
+	if err != nil {
+		return nil, CommitAuthor{}, err
+	}
+	if tenant := r.Header.Get("X-Tenant-ID"); tenant != "" && tenant != by.Tenant {
+		return nil, CommitAuthor{}, fmt.Errorf("%w: %s cannot write for tenant %q", ErrPermissionDenied, by.Subject, tenant)
+	}
+	author, err := by.author()
+	if err != nil {
+		return nil, CommitAuthor{}, err
+	}
+	return WithTenant(ctx, by.Tenant), author, nil
+}
+
+// expectedParent lets an If-Match header stand in for expected_parent
+func expectedParent(r *http.Request, fromBody string) string {
+	if fromBody != "" {
+		return fromBody
+	}
+	return strings.Trim(r.Header.Get("If-Match"), `"`)
+}
+
+func (s *Server) workersStatus(ctx context.Context, _ *http.Request, _ noBody) (*WorkersResponse, error) {
+	workers, err := s.services.Status.Workers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if workers == nil {
+		workers = []WorkerInfo{}
+	}
+	return &WorkersResponse{Success: true, Total: len(workers), Workers: workers}, nil
+}
+
+func (s *Server) metrics(ctx context.Context, _ *http.Request, _ noBody) (*MetricsResponse, error) {
+	metrics, err := s.services.Status.Metrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &MetricsResponse{Success: true, Metrics: metrics}, nil
+}
+
+func (s *Server) serveOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	s.specOnce.Do(func() {
+		s.spec, s.specErr = json.MarshalIndent(OpenAPI(s.routes), "", "  ")
+	})
+	if s.specErr != nil {
+		writeError(w, http.StatusInternalServerError, s.specErr.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(s.spec)
+}
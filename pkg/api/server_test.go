@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerRoutesToServices(t *testing.T) {
+	workflows := &fakeWorkflows{}
+	srv := NewServer(Services{Executor: &fakeExecutor{}, Workflows: workflows})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"execute", "POST", "/api/v1/execute", `{"text":"calculate 42 + 58"}`, 200, `"result":"calculate 42 + 58"`},
+		{"execute without text", "POST", "/api/v1/execute", `{}`, 400, `"success":false`},
+		{"malformed workflow", "POST", "/api/v1/temporal/workflows/execute", `{"invalid_field":"x"}`, 400, `workflow_type is required`},
+		{"unknown workflow", "POST", "/api/v1/temporal/workflows/execute",
+			`{"workflow_type":"NonExistentWorkflow","customer_id":"c"}`, 404, `"error":`},
+		{"signal", "POST", "/api/v1/temporal/workflows/wf-1/runs/run-1/signal", `{"signal_name":"pause","data":true}`, 200, `"success":true`},
+		{"status", "GET", "/api/v1/temporal/workflows/wf-1/runs/run-1/status", ``, 200, `"workflow_id":"wf-1"`},
+		{"cancel", "POST", "/api/v1/temporal/workflows/wf-1/runs/run-1/cancel", ``, 200, `"success":true`},
+		{"bad json", "POST", "/api/v1/execute", `{`, 400, `invalid request body`},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: got %d %s", tt.name, rec.Code, rec.Body.String())
+		}
+	}
+
+	if len(workflows.signals) != 1 || workflows.signals[0].SignalName != "pause" {
+		t.Errorf("signal not delivered: %+v", workflows.signals)
+	}
+}
+
+func TestServerQueryWrapsResult(t *testing.T) {
+	srv := NewServer(Services{Workflows: &fakeWorkflows{}})
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/temporal/workflows/wf-1/runs/run-1/query",
+		strings.NewReader(`{"query_type":"progress"}`)))
+
+	var resp QueryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Success || resp.Result.(map[string]interface{})["progress"] != 0.5 {
+		t.Errorf("unexpected query response: %+v", resp)
+	}
+}
+
+type fakeConfig map[string]string
+
+func (f fakeConfig) ResolveConfig(_ context.Context, ref string) (string, error) {
+	if commit, ok := f[ref]; ok {
+		return commit, nil
+	}
+	return "", fmt.Errorf("%w: config_ref %q does not name a commit", ErrInvalidArgument, ref)
+}
+
+// commitEchoExecutor answers with the commit the request was pinned to
+type commitEchoExecutor struct{}
+
+func (commitEchoExecutor) Route(ExecuteRequest) ExecutionPath { return FastPath }
+
+func (commitEchoExecutor) Execute(ctx context.Context, _ ExecuteRequest) (*ExecuteResponse, error) {
+	commit, _ := ConfigCommit(ctx)
+	return &ExecuteResponse{Success: true, Result: commit}, nil
+}
+
+func TestServerPinsConfigCommit(t *testing.T) {
+	config := fakeConfig{"": "c0ffee", "v1.0.0": "0ldc0de"}
+	srv := NewServer(Services{Executor: commitEchoExecutor{}, Workflows: &fakeWorkflows{}, Config: config})
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"current", "/api/v1/execute", `{"text":"x"}`, 200, `"result":"c0ffee","config_commit":"c0ffee"`},
+		{"tag", "/api/v1/execute", `{"text":"x","config_ref":"v1.0.0"}`, 200, `"result":"0ldc0de","config_commit":"0ldc0de"`},
+		{"unknown ref", "/api/v1/execute", `{"text":"x","config_ref":"nope"}`, 400, `config_ref \"nope\"`},
+		{"batch", "/api/v1/execute/batch", `[{"text":"x","config_ref":"v1.0.0"},{"text":"y"}]`, 200, `"config_commit":"0ldc0de"`},
+		{"workflow", "/api/v1/temporal/workflows/execute",
+			`{"workflow_type":"DataPipelineWorkflow","customer_id":"c","parameters":{"processing_days":1},"config_ref":"v1.0.0"}`, 200, `"config_commit":"0ldc0de"`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: got %d %s", tt.name, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Without a resolver, pinning is refused rather than silently ignored
+	srv = NewServer(Services{Executor: commitEchoExecutor{}})
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/execute", strings.NewReader(`{"text":"x","config_ref":"main"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("config_ref without resolver: got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// inputCheck is an InputValidator that requires processing_days
+type inputCheck struct{ commits []string }
+
+func (c *inputCheck) ValidateInput(ctx context.Context, req WorkflowExecuteRequest) error {
+	commit, _ := ConfigCommit(ctx)
+	c.commits = append(c.commits, commit)
+	if _, ok := req.Parameters["processing_days"]; !ok {
+		return fmt.Errorf("%w: parameters do not match the inputs of workflow %s: /processing_days: is required", ErrInvalidArgument, req.WorkflowType)
+	}
+	return nil
+}
+
+func TestServerChecksWorkflowInputs(t *testing.T) {
+	inputs := &inputCheck{}
+	srv := NewServer(Services{Workflows: &fakeWorkflows{}, Config: fakeConfig{"": "c0ffee"}, Inputs: inputs})
+	for _, tt := range []struct {
+		body   string
+		status int
+		want   string
+	}{
+		{`{"workflow_type":"LongRunningAnalyticsWorkflow","customer_id":"c","parameters":{"processing_days":1}}`, 200, `"success":true`},
+		{`{"workflow_type":"LongRunningAnalyticsWorkflow","customer_id":"c","parameters":{"test_mode":true}}`, 400,
+			`"error":"invalid argument: parameters do not match the inputs of workflow LongRunningAnalyticsWorkflow: /processing_days: is required"`},
+		// Requests that are malformed anyway are refused before the check
+		{`{"workflow_type":"LongRunningAnalyticsWorkflow"}`, 400, `customer_id is required`},
+	} {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/temporal/workflows/execute", strings.NewReader(tt.body)))
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: got %d %s", tt.body, rec.Code, rec.Body.String())
+		}
+	}
+	if len(inputs.commits) != 2 || inputs.commits[0] != "c0ffee" {
+		t.Errorf("inputs checked at %v", inputs.commits)
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Outcomes of syncing one branch from the remote
+const (
+	SyncUpdated   = "updated"
+	SyncUnchanged = "unchanged"
+	// SyncRejected: the remote commit failed verification; the branch
+	// stays where it was
+	SyncRejected = "rejected"
+	// SyncDiverged: the local branch has commits the remote lacks, so it
+	// cannot be fast-forwarded
+	SyncDiverged = "diverged"
+)
+
+// BranchSync is the outcome for one branch
+type BranchSync struct {
+	Branch    string `json:"branch"`
+	OldCommit string `json:"old_commit,omitempty"`
+	NewCommit string `json:"new_commit"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	// Reloaded reports whether a registry serving the branch picked up the
+	// new commit
+	Reloaded bool `json:"reloaded"`
+}
+
+// SyncResponse is returned by POST /api/v1/sync and the push webhook
+type SyncResponse struct {
+	Success bool `json:"success"`
+	// Trigger is schedule, webhook or api; ping for a webhook ping
+	Trigger        string       `json:"trigger"`
+	Remote         string       `json:"remote"`
+	FetchedObjects int          `json:"fetched_objects"`
+	Branches       []BranchSync `json:"branches"`
+	SyncedAt       time.Time    `json:"synced_at"`
+}
+
+// SyncService fetches the runtime repository's remote and fast-forwards
+// local branches
+type SyncService interface {
+	// Sync fetches and fast-forwards the configured branches, or only refs
+	// when given (as named by a push event)
+	Sync(ctx context.Context, trigger string, refs []string) (*SyncResponse, error)
+}
+
+// PushEvent is the part of a GitHub or Gitea push webhook the runtime reads
+type PushEvent struct {
+	Ref        string `json:"ref"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// maxWebhookBody bounds push payloads, which list every pushed commit
+const maxWebhookBody = 25 << 20
+
+// pushWebhook verifies the payload's HMAC signature against the shared
+// secret and syncs the pushed ref. It is not a jsonRoute because the
+// signature covers the raw body.
+func (s *Server) pushWebhook(w http.ResponseWriter, r *http.Request) {
+	if s.services.Sync == nil || s.services.WebhookSecret == "" {
+		writeError(w, http.StatusNotFound, "push webhooks are not enabled")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody+1))
+	if err != nil || len(body) > maxWebhookBody {
+		writeError(w, http.StatusBadRequest, "unreadable or oversized payload")
+		return
+	}
+	if !validSignature(r.Header, body, s.services.WebhookSecret) {
+		writeError(w, http.StatusUnauthorized, "missing or invalid webhook signature")
+		return
+	}
+
+	event := r.Header.Get("X-GitHub-Event")
+	if event == "" {
+		event = r.Header.Get("X-Gitea-Event")
+	}
+	if event == "ping" {
+		writeJSON(w, http.StatusOK, &SyncResponse{Success: true, Trigger: "ping", Branches: []BranchSync{}, SyncedAt: time.Now()})
+		return
+	}
+	if event != "" && event != "push" {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unsupported event %q", event))
+		return
+	}
+
+	var push PushEvent
+	if err := json.Unmarshal(body, &push); err != nil || !strings.HasPrefix(push.Ref, "refs/heads/") {
+		writeError(w, http.StatusBadRequest, "payload is not a branch push")
+		return
+	}
+	resp, err := s.services.Sync.Sync(r.Context(), "webhook", []string{push.Ref})
+	if err != nil {
+		writeError(w, httpStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// validSignature checks X-Hub-Signature-256 (GitHub, also sent by Gitea)
+// or X-Gitea-Signature, both HMAC-SHA256 of the body
+func validSignature(h http.Header, body []byte, secret string) bool {
+	sig := strings.TrimPrefix(h.Get("X-Hub-Signature-256"), "sha256=")
+	if sig == "" {
+		sig = h.Get("X-Gitea-Signature")
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil || len(got) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+// ExecutionPath is the route the intelligent router picks for a request
+type ExecutionPath int
+
+const (
+	// FastPath serves simple queries in-process within the <100ms SLA
+	FastPath ExecutionPath = iota
+	// TemporalPath hands the request to a durable Temporal workflow
+	TemporalPath
+)
+
+// ExecuteRequest is the body of POST /api/v1/execute
+type ExecuteRequest struct {
+	Text      string `json:"text"`
+	SessionID string `json:"session_id,omitempty"`
+	// ConfigRef pins the request to a commit, tag or branch of the runtime
+	// repository; empty uses the commit currently served
+	ConfigRef string `json:"config_ref,omitempty"`
+}
+
+// Validate reports whether the request can be executed
+func (r ExecuteRequest) Validate() error {
+	if r.Text == "" {
+		return fmt.Errorf("%w: text is required", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// ExecuteResponse is returned by POST /api/v1/execute
+type ExecuteResponse struct {
+	Success       bool        `json:"success"`
+	Result        interface{} `json:"result,omitempty"`
+	Deterministic bool        `json:"deterministic,omitempty"`
+	WorkflowID    string      `json:"workflow_id,omitempty"`
+	RunID         string      `json:"run_id,omitempty"`
+	Duration      string      `json:"duration,omitempty"`
+	Error         string      `json:"error,omitempty"`
+	// ConfigCommit is the runtime repository commit that answered the request
+	ConfigCommit string `json:"config_commit,omitempty"`
+}
+
+// Executor is implemented by the server's intelligent router. Route must be
+// cheap and side-effect free; Execute does the actual work.
+type Executor interface {
+	Route(req ExecuteRequest) ExecutionPath
+	Execute(ctx context.Context, req ExecuteRequest) (*ExecuteResponse, error)
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/health"
+)
+
+var (
+	// ErrInvalidArgument marks errors caused by a bad request (400 / InvalidArgument)
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrNotFound marks errors for unknown workflows or definitions (404 / NotFound)
+	ErrNotFound = errors.New("not found")
+	// ErrConflict marks writes based on stale state (409 / Aborted)
+	ErrConflict = errors.New("conflict")
+	// ErrUnauthenticated marks requests without valid credentials (401 / Unauthenticated)
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrPermissionDenied marks callers not allowed to act (403 / PermissionDenied)
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// WorkflowRef identifies a single workflow run
+type WorkflowRef struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+}
+
+// WorkflowExecuteRequest is the body of POST /api/v1/temporal/workflows/execute
+type WorkflowExecuteRequest struct {
+	WorkflowType string                 `json:"workflow_type"`
+	CustomerID   string                 `json:"customer_id"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+	Async        bool                   `json:"async"`
+	// ConfigRef pins the definition to a commit, tag or branch
+	ConfigRef string `json:"config_ref,omitempty"`
+}
+
+// Validate reports whether the request can be handed to Temporal
+func (r WorkflowExecuteRequest) Validate() error {
+	if r.WorkflowType == "" {
+		return fmt.Errorf("%w: workflow_type is required", ErrInvalidArgument)
+	}
+	if r.CustomerID == "" {
+		return fmt.Errorf("%w: customer_id is required", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// WorkflowExecuteResponse is returned when a workflow is started. Result is
+// only set for synchronous (async=false) executions.
+type WorkflowExecuteResponse struct {
+	Success    bool        `json:"success"`
+	WorkflowID string      `json:"workflow_id,omitempty"`
+	RunID      string      `json:"run_id,omitempty"`
+	Status     string      `json:"status,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	// ConfigCommit is the commit the workflow definition was read from
+	ConfigCommit string `json:"config_commit,omitempty"`
+}
+
+// SignalRequest is the body of POST .../runs/{run_id}/signal
+type SignalRequest struct {
+	SignalName string      `json:"signal_name"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// Validate reports whether the signal can be delivered
+func (r SignalRequest) Validate() error {
+	if r.SignalName == "" {
+		return fmt.Errorf("%w: signal_name is required", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// QueryRequest is the body of POST .../runs/{run_id}/query
+type QueryRequest struct {
+	QueryType string        `json:"query_type"`
+	Args      []interface{} `json:"args,omitempty"`
+}
+
+// Validate reports whether the query can be issued
+func (r QueryRequest) Validate() error {
+	if r.QueryType == "" {
+		return fmt.Errorf("%w: query_type is required", ErrInvalidArgument)
+	}
+	return nil
+}
+
+// QueryResponse carries the decoded query result
+type QueryResponse struct {
+	Success bool        `json:"success"`
+	Result  interface{} `json:"result"`
+}
+
+// SuccessResponse acknowledges requests that return no data (signal, cancel)
+type SuccessResponse struct {
+	Success bool `json:"success"`
+}
+
+// WorkflowStatus is returned by GET .../runs/{run_id}/status
+type WorkflowStatus struct {
+	Success      bool       `json:"success"`
+	WorkflowID   string     `json:"workflow_id"`
+	RunID        string     `json:"run_id"`
+	WorkflowType string     `json:"workflow_type"`
+	Status       string     `json:"status"`
+	StartTime    time.Time  `json:"start_time"`
+	CloseTime    *time.Time `json:"close_time,omitempty"`
+	TaskQueue    string     `json:"task_queue"`
+}
+
+// WorkflowProgress is one update on a running workflow
+type WorkflowProgress struct {
+	WorkflowID string    `json:"workflow_id"`
+	RunID      string    `json:"run_id"`
+	Status     string    `json:"status"`
+	Stage      string    `json:"stage,omitempty"`
+	Percent    float64   `json:"percent"`
+	Message    string    `json:"message,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// DefinitionSummary describes a workflow definition loaded from git
+type DefinitionSummary struct {
+	Name         string    `json:"name"`
+	Version      string    `json:"version"`
+	TaskQueue    string    `json:"task_queue"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// DefinitionsResponse is returned by GET /api/v1/temporal/workflows/definitions
+type DefinitionsResponse struct {
+	Success     bool                `json:"success"`
+	Total       int                 `json:"total"`
+	Definitions []DefinitionSummary `json:"definitions"`
+}
+
+// ReloadRequest is the body of POST /api/v1/temporal/reload
+type ReloadRequest struct {
+	Type       string    `json:"type"`
+	Repository string    `json:"repository"`
+	FilePath   string    `json:"file_path"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// ReloadResponse reports the outcome of a hot-reload
+type ReloadResponse struct {
+	Success  bool   `json:"success"`
+	Message  string `json:"message,omitempty"`
+	Reloaded int    `json:"reloaded"`
+}
+
+// WorkerInfo describes one Temporal worker polling a task queue
+type WorkerInfo struct {
+	Identity       string    `json:"identity"`
+	TaskQueue      string    `json:"task_queue"`
+	LastAccessTime time.Time `json:"last_access_time"`
+}
+
+// WorkersResponse is returned by GET /api/v1/temporal/workers/status
+type WorkersResponse struct {
+	Success bool         `json:"success"`
+	Total   int          `json:"total"`
+	Workers []WorkerInfo `json:"workers"`
+}
+
+// MetricsResponse is returned by GET /api/v1/temporal/metrics
+type MetricsResponse struct {
+	Success bool                   `json:"success"`
+	Metrics map[string]interface{} `json:"metrics"`
+}
+
+// WorkflowService is the durable (Temporal) path behind every transport
+type WorkflowService interface {
+	ExecuteWorkflow(ctx context.Context, req WorkflowExecuteRequest) (*WorkflowExecuteResponse, error)
+	SignalWorkflow(ctx context.Context, ref WorkflowRef, req SignalRequest) error
+	QueryWorkflow(ctx context.Context, ref WorkflowRef, req QueryRequest) (interface{}, error)
+	CancelWorkflow(ctx context.Context, ref WorkflowRef) error
+	WorkflowStatus(ctx context.Context, ref WorkflowRef) (*WorkflowStatus, error)
+	// WatchWorkflow calls send for every progress update until the run
+	// closes, ctx is done, or send returns an error.
+	WatchWorkflow(ctx context.Context, ref WorkflowRef, send func(WorkflowProgress) error) error
+}
+
+// InputValidator checks a workflow's parameters before it starts, against
+// the input schema of its definition at the request's config commit.
+// Parameters that do not match fail with ErrInvalidArgument; workflows
+// without a definition or schema pass.
+type InputValidator interface {
+	ValidateInput(ctx context.Context, req WorkflowExecuteRequest) error
+}
+
+// DefinitionService exposes the git-native workflow registry
+type DefinitionService interface {
+	ListDefinitions(ctx context.Context) ([]DefinitionSummary, error)
+	Reload(ctx context.Context, req ReloadRequest) (*ReloadResponse, error)
+}
+
+// StatusService reports on the Temporal side of the runtime
+type StatusService interface {
+	Workers(ctx context.Context) ([]WorkerInfo, error)
+	Metrics(ctx context.Context) (map[string]interface{}, error)
+}
+
+// Services are the implementations shared by the REST and gRPC transports
+type Services struct {
+	Executor    Executor
+	Workflows   WorkflowService
+	Definitions DefinitionService
+	Status      StatusService
+	// ConfigWriter backs PUT/DELETE /api/v1/config/{path}
+	ConfigWriter ConfigWriter
+	// Provenance backs GET /api/v1/tenants/{tenant}/provenance
+	Provenance ProvenanceService
+	// Drift backs the drift report and merge-from-main endpoints
+	Drift DriftService
+	// Sync backs POST /api/v1/sync and, when WebhookSecret is set, the
+	// push webhook. Unsigned webhooks are never accepted.
+	Sync          SyncService
+	WebhookSecret string
+	// Reloads backs GET /api/v1/reloads
+	Reloads ReloadHistory
+	// Approvals backs the /api/v1/approvals endpoints
+	Approvals ApprovalService
+	// Authenticator resolves the caller of every route that changes state:
+	// config writes, merges from main, syncs and approval decisions. Those
+	// routes are not enabled without it.
+	Authenticator Authenticator
+	// Inputs checks workflow parameters on every transport before a run
+	// starts; nil starts runs unchecked
+	Inputs InputValidator
+	// Config pins execute and workflow-start requests to a commit; nil
+	// rejects requests that set config_ref
+	Config ConfigResolver
+	// Health backs /health/live and /health/ready. Build it with
+	// health.NewRuntimeChecker; nil keeps /health/ready at 503.
+	Health *health.Checker
+}
@@ -0,0 +1,439 @@
+// Package client is a typed Go client for the Volcano LLM /api/v1 REST API.
+//
+//	c, err := client.New("http://localhost:8080", client.WithTenant("acme-corp"))
+//	resp, err := c.Execute(ctx, api.ExecuteRequest{Text: "calculate 42 + 58"})
+//
+// Failed calls return *APIError, which matches api.ErrInvalidArgument,
+// api.ErrNotFound, api.ErrConflict, api.ErrUnauthenticated and
+// api.ErrPermissionDenied with errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+)
+
+// Client talks to one Volcano LLM server. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retry      RetryPolicy
+	tenantID   string
+	token      string
+	userAgent  string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient replaces the default http.Client (30s timeout)
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetry replaces the default retry policy
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// WithTenant sends X-Tenant-ID on every request so it is served from the
+// tenant's branch
+func WithTenant(tenantID string) Option {
+	return func(c *Client) { c.tenantID = tenantID }
+}
+
+// WithBearerToken sends "Authorization: Bearer <token>" on every request,
+// identifying the caller to the endpoints that change state, such as
+// WriteConfig, MergeMain, Sync and DecideApproval
+func WithBearerToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithUserAgent overrides the User-Agent header
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New creates a client for the server at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retry:      DefaultRetryPolicy,
+		userAgent:  "volcano-llm-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Execute runs a natural-language request on the fast or durable path. Like
+// ExecuteWorkflow it is never retried: the durable path starts a workflow,
+// and a 504 may arrive after the start succeeded.
+func (c *Client) Execute(ctx context.Context, req api.ExecuteRequest) (*api.ExecuteResponse, error) {
+	var resp api.ExecuteResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/execute", req, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ExecuteBatch runs many requests in one round trip. Per-item failures are
+// reported in the results, not as an error. Not retried, for the same
+// reason as Execute.
+func (c *Client) ExecuteBatch(ctx context.Context, reqs []api.ExecuteRequest) (*api.BatchResponse, error) {
+	var resp api.BatchResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/execute/batch", reqs, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ExecuteWorkflow starts a Temporal workflow. It is never retried, since a
+// retry after a lost response could start a second run.
+func (c *Client) ExecuteWorkflow(ctx context.Context, req api.WorkflowExecuteRequest) (*api.WorkflowExecuteResponse, error) {
+	var resp api.WorkflowExecuteResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/temporal/workflows/execute", req, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SignalWorkflow sends a signal such as pause or resume. Signals are not
+// retried because a duplicate signal is observable by the workflow.
+func (c *Client) SignalWorkflow(ctx context.Context, ref api.WorkflowRef, req api.SignalRequest) error {
+	return c.do(ctx, http.MethodPost, runPath(ref, "signal"), req, nil, false)
+}
+
+// QueryWorkflow runs a query and decodes its result into result, which
+// should be a pointer (or nil to discard it)
+func (c *Client) QueryWorkflow(ctx context.Context, ref api.WorkflowRef, req api.QueryRequest, result interface{}) error {
+	var resp struct {
+		Success bool            `json:"success"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := c.do(ctx, http.MethodPost, runPath(ref, "query"), req, &resp, true); err != nil {
+		return err
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("decode query result: %w", err)
+	}
+	return nil
+}
+
+// CancelWorkflow requests cancellation of a workflow run
+func (c *Client) CancelWorkflow(ctx context.Context, ref api.WorkflowRef) error {
+	return c.do(ctx, http.MethodPost, runPath(ref, "cancel"), nil, nil, true)
+}
+
+// WorkflowStatus describes a workflow run
+func (c *Client) WorkflowStatus(ctx context.Context, ref api.WorkflowRef) (*api.WorkflowStatus, error) {
+	var resp api.WorkflowStatus
+	if err := c.do(ctx, http.MethodGet, runPath(ref, "status"), nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListDefinitions lists the workflow definitions loaded from git
+func (c *Client) ListDefinitions(ctx context.Context) (*api.DefinitionsResponse, error) {
+	var resp api.DefinitionsResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/temporal/workflows/definitions", nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Reload triggers a git-native hot-reload. It is not retried, since each
+// attempt is recorded in the reload audit log.
+func (c *Client) Reload(ctx context.Context, req api.ReloadRequest) (*api.ReloadResponse, error) {
+	var resp api.ReloadResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/temporal/reload", req, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Workers lists the Temporal workers polling the runtime's task queues
+func (c *Client) Workers(ctx context.Context) (*api.WorkersResponse, error) {
+	var resp api.WorkersResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/temporal/workers/status", nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Metrics returns runtime and Temporal metrics
+func (c *Client) Metrics(ctx context.Context) (*api.MetricsResponse, error) {
+	var resp api.MetricsResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/temporal/metrics", nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// WriteConfig commits new contents for a tool, workflow or config file to
+// the branch of the caller WithBearerToken identifies, authored as them.
+// Writes are only retried when ExpectedParent is set,
+// since the precondition turns a duplicate into a 409.
+func (c *Client) WriteConfig(ctx context.Context, path string, req api.ConfigWriteRequest) (*api.ConfigWriteResponse, error) {
+	var resp api.ConfigWriteResponse
+	if err := c.do(ctx, http.MethodPut, configPath(path), req, &resp, req.ExpectedParent != ""); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DeleteConfig commits the removal of a file from the caller's branch
+func (c *Client) DeleteConfig(ctx context.Context, path string, req api.ConfigWriteRequest) (*api.ConfigWriteResponse, error) {
+	var resp api.ConfigWriteResponse
+	if err := c.do(ctx, http.MethodDelete, configPath(path), req, &resp, req.ExpectedParent != ""); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Provenance shows tenant's effective definitions and whether each came from
+// main, the tenant's branch or a merge of both
+func (c *Client) Provenance(ctx context.Context, tenant string) (*api.ProvenanceResponse, error) {
+	var resp api.ProvenanceResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/tenants/"+url.PathEscape(tenant)+"/provenance", nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Drift compares tenant's branch with main, or every tenant branch when
+// tenant is empty
+func (c *Client) Drift(ctx context.Context, tenant string) (*api.DriftResponse, error) {
+	path := "/api/v1/drift"
+	if tenant != "" {
+		path = "/api/v1/tenants/" + url.PathEscape(tenant) + "/drift"
+	}
+	var resp api.DriftResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MergeMain merges main into tenant's branch if that is conflict-free.
+// Like WriteConfig it is only retried when ExpectedHead is set.
+func (c *Client) MergeMain(ctx context.Context, tenant string, req api.MergeMainRequest) (*api.MergeMainResponse, error) {
+	var resp api.MergeMainResponse
+	path := "/api/v1/tenants/" + url.PathEscape(tenant) + "/merge"
+	if err := c.do(ctx, http.MethodPost, path, req, &resp, req.ExpectedHead != ""); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Sync makes the server fetch its remote and fast-forward its branches. It
+// is not retried, since a sync can move branches and trigger reloads.
+func (c *Client) Sync(ctx context.Context) (*api.SyncResponse, error) {
+	var resp api.SyncResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/sync", nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Reloads queries the reload audit log, newest first
+func (c *Client) Reloads(ctx context.Context, filter api.ReloadFilter) (*api.ReloadsResponse, error) {
+	q := url.Values{}
+	for k, v := range map[string]string{"tenant": filter.Tenant, "branch": filter.Branch, "file": filter.File, "outcome": filter.Outcome} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	if !filter.Since.IsZero() {
+		q.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		q.Set("until", filter.Until.Format(time.RFC3339))
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", fmt.Sprint(filter.Limit))
+	}
+	path := "/api/v1/reloads"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var resp api.ReloadsResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Approvals lists workflow stages waiting for, or given, a decision
+func (c *Client) Approvals(ctx context.Context, filter api.ApprovalFilter) (*api.ApprovalsResponse, error) {
+	q := url.Values{}
+	for k, v := range map[string]string{"tenant": filter.Tenant, "group": filter.Group, "status": filter.Status} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	path := "/api/v1/approvals"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var resp api.ApprovalsResponse
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DecideApproval approves or rejects a workflow's approval stage as the
+// caller WithBearerToken identifies
+func (c *Client) DecideApproval(ctx context.Context, workflowID, stage string, req api.ApprovalDecisionRequest) (*api.ApprovalResponse, error) {
+	var resp api.ApprovalResponse
+	path := "/api/v1/approvals/" + url.PathEscape(workflowID) + "/" + url.PathEscape(stage)
+	if err := c.do(ctx, http.MethodPost, path, req, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func configPath(p string) string {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return "/api/v1/config/" + strings.Join(parts, "/")
+}
+
+func runPath(ref api.WorkflowRef, action string) string {
+	return fmt.Sprintf("/api/v1/temporal/workflows/%s/runs/%s/%s",
+		url.PathEscape(ref.WorkflowID), url.PathEscape(ref.RunID), action)
+}
+
+// do sends one API call, retrying idempotent calls according to c.retry,
+// and decodes a 2xx body into out (if non-nil)
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}, idempotent bool) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+	}
+
+	attempts := 1
+	if idempotent && c.retry.MaxAttempts > 1 {
+		attempts = c.retry.MaxAttempts
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		retryAfter, err := c.send(ctx, method, path, body, out)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if attempt == attempts || !retryable(err) {
+			break
+		}
+		if err := sleep(ctx, c.retry.backoff(attempt, retryAfter)); err != nil {
+			return err
+		}
+	}
+	return lastErr
+}
+
+// send performs a single attempt. retryAfter is the server's Retry-After
+// hint, if any.
+func (c *Client) send(ctx context.Context, method, path string, body []byte, out interface{}) (retryAfter time.Duration, err error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, reader)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.tenantID != "" {
+		req.Header.Set("X-Tenant-ID", c.tenantID)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, &TransportError{Method: method, Path: path, Err: err}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, &TransportError{Method: method, Path: path, Err: err}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return parseRetryAfter(resp.Header.Get("Retry-After")), newAPIError(method, path, resp.StatusCode, data)
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return 0, fmt.Errorf("decode %s %s response: %w", method, path, err)
+		}
+	}
+	return 0, nil
+}
+
+func retryable(err error) bool {
+	var transport *TransportError
+	if errors.As(err, &transport) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
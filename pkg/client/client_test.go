@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+)
+
+type testExecutor struct{}
+
+func (testExecutor) Route(api.ExecuteRequest) api.ExecutionPath { return api.FastPath }
+
+func (testExecutor) Execute(ctx context.Context, req api.ExecuteRequest) (*api.ExecuteResponse, error) {
+	return &api.ExecuteResponse{Success: true, Result: 100, Deterministic: true}, nil
+}
+
+type testWorkflows struct {
+	api.WorkflowService
+	started int32
+}
+
+func (w *testWorkflows) ExecuteWorkflow(ctx context.Context, req api.WorkflowExecuteRequest) (*api.WorkflowExecuteResponse, error) {
+	atomic.AddInt32(&w.started, 1)
+	if req.WorkflowType == "NonExistentWorkflow" {
+		return nil, fmt.Errorf("%w: workflow type %s", api.ErrNotFound, req.WorkflowType)
+	}
+	return &api.WorkflowExecuteResponse{Success: true, WorkflowID: "wf-1", RunID: "run-1"}, nil
+}
+
+func (w *testWorkflows) QueryWorkflow(ctx context.Context, ref api.WorkflowRef, req api.QueryRequest) (interface{}, error) {
+	return map[string]interface{}{"processed_days": 2, "total_days": 3}, nil
+}
+
+func TestClientAgainstServer(t *testing.T) {
+	ts := httptest.NewServer(api.NewServer(api.Services{Executor: testExecutor{}, Workflows: &testWorkflows{}}))
+	defer ts.Close()
+
+	c, err := New(ts.URL, WithHTTPClient(ts.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	resp, err := c.Execute(ctx, api.ExecuteRequest{Text: "calculate 42 + 58"})
+	if err != nil || !resp.Success || resp.Result != float64(100) {
+		t.Fatalf("Execute = %+v, %v", resp, err)
+	}
+
+	wf, err := c.ExecuteWorkflow(ctx, api.WorkflowExecuteRequest{WorkflowType: "LongRunningAnalyticsWorkflow", CustomerID: "c"})
+	if err != nil || wf.WorkflowID != "wf-1" {
+		t.Fatalf("ExecuteWorkflow = %+v, %v", wf, err)
+	}
+
+	var progress struct {
+		ProcessedDays int `json:"processed_days"`
+		TotalDays     int `json:"total_days"`
+	}
+	ref := api.WorkflowRef{WorkflowID: wf.WorkflowID, RunID: wf.RunID}
+	if err := c.QueryWorkflow(ctx, ref, api.QueryRequest{QueryType: "progress"}, &progress); err != nil {
+		t.Fatal(err)
+	}
+	if progress.ProcessedDays != 2 || progress.TotalDays != 3 {
+		t.Errorf("query result = %+v", progress)
+	}
+
+	_, err = c.ExecuteWorkflow(ctx, api.WorkflowExecuteRequest{WorkflowType: "NonExistentWorkflow", CustomerID: "c"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("unknown workflow error = %v", err)
+	}
+
+	_, err = c.Execute(ctx, api.ExecuteRequest{})
+	if !errors.Is(err, api.ErrInvalidArgument) {
+		t.Errorf("empty text error = %v", err)
+	}
+}
+
+func TestClientRetriesIdempotentCalls(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, `{"success":false,"error":"warming up"}`, http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"success":true,"total":0,"definitions":[]}`))
+	}))
+	defer ts.Close()
+
+	c, _ := New(ts.URL, WithRetry(RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond}))
+	defs, err := c.ListDefinitions(context.Background())
+	if err != nil || !defs.Success {
+		t.Fatalf("ListDefinitions = %+v, %v", defs, err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestClientDoesNotRetryWorkflowStart(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	defer ts.Close()
+
+	c, _ := New(ts.URL, WithRetry(RetryPolicy{MaxAttempts: 5, InitialInterval: time.Millisecond}))
+	ctx := context.Background()
+	starts := map[string]func() error{
+		"ExecuteWorkflow": func() error {
+			_, err := c.ExecuteWorkflow(ctx, api.WorkflowExecuteRequest{WorkflowType: "DataPipelineWorkflow", CustomerID: "c"})
+			return err
+		},
+		"Execute": func() error {
+			_, err := c.Execute(ctx, api.ExecuteRequest{Text: "run pipeline"})
+			return err
+		},
+		"ExecuteBatch": func() error {
+			_, err := c.ExecuteBatch(ctx, []api.ExecuteRequest{{Text: "run pipeline"}})
+			return err
+		},
+		"Reload": func() error {
+			_, err := c.Reload(ctx, api.ReloadRequest{Type: "manual"})
+			return err
+		},
+		"Sync": func() error {
+			_, err := c.Sync(ctx)
+			return err
+		},
+	}
+	for name, call := range starts {
+		atomic.StoreInt32(&calls, 0)
+		err := call()
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.Temporary() {
+			t.Fatalf("%s: err = %v", name, err)
+		}
+		if n := atomic.LoadInt32(&calls); n != 1 {
+			t.Errorf("%s attempted %d times", name, n)
+		}
+	}
+}
+
+type testReloads struct{ got api.ReloadFilter }
+
+func (r *testReloads) Reloads(ctx context.Context, f api.ReloadFilter) (*api.ReloadsResponse, error) {
+	r.got = f
+	return &api.ReloadsResponse{Success: true, Reloads: []api.ReloadRecord{}}, nil
+}
+
+func TestClientReloadFilter(t *testing.T) {
+	reloads := &testReloads{}
+	ts := httptest.NewServer(api.NewServer(api.Services{Reloads: reloads}))
+	defer ts.Close()
+	c, _ := New(ts.URL, WithHTTPClient(ts.Client()))
+
+	since := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	want := api.ReloadFilter{Tenant: "acme-corp", File: "tools/*.json", Outcome: api.ReloadRejected, Since: since, Limit: 5}
+	if _, err := c.Reloads(context.Background(), want); err != nil {
+		t.Fatal(err)
+	}
+	if !reloads.got.Since.Equal(since) || reloads.got.File != want.File || reloads.got.Limit != 5 || reloads.got.Tenant != "acme-corp" {
+		t.Errorf("server saw %+v", reloads.got)
+	}
+}
+
+type testApprovals struct {
+	api.ApprovalService
+	by api.Principal
+}
+
+func (a *testApprovals) Decide(ctx context.Context, workflowID, stage string, by api.Principal, req api.ApprovalDecisionRequest) (*api.ApprovalResponse, error) {
+	a.by = by
+	return &api.ApprovalResponse{Success: true, Approval: api.Approval{WorkflowID: workflowID, Stage: stage}}, nil
+}
+
+func TestClientDecideApprovalAuthenticates(t *testing.T) {
+	approvals := &testApprovals{}
+	tokens := api.StaticTokens{"s3cret": {Subject: "ann", Groups: []string{"release-managers"}}}
+	ts := httptest.NewServer(api.NewServer(api.Services{Approvals: approvals, Authenticator: tokens}))
+	defer ts.Close()
+	ctx := context.Background()
+	req := api.ApprovalDecisionRequest{Decision: "approve"}
+
+	for token, want := range map[string]error{"": api.ErrUnauthenticated, "guess": api.ErrUnauthenticated, "s3cret": nil} {
+		c, err := New(ts.URL, WithHTTPClient(ts.Client()), WithBearerToken(token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.DecideApproval(ctx, "wf-1", "signoff", req); !errors.Is(err, want) {
+			t.Errorf("token %q: err = %v, want %v", token, err, want)
+		}
+	}
+	if approvals.by.Subject != "ann" {
+		t.Errorf("decided by %+v", approvals.by)
+	}
+}
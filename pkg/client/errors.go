@@ -0,0 +1,75 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+)
+
+// APIError is returned when the server answers with a non-2xx status
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Message is the server's "error" field, or the raw body if it was not JSON
+	Message string
+}
+
+func newAPIError(method, path string, status int, body []byte) *APIError {
+	e := &APIError{Method: method, Path: path, StatusCode: status}
+
+	var resp api.ErrorResponse
+	if err := json.Unmarshal(body, &resp); err == nil && resp.Error != "" {
+		e.Message = resp.Error
+	} else {
+		e.Message = string(body)
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Unwrap lets errors.Is match the same sentinels the server uses
+func (e *APIError) Unwrap() error {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return api.ErrInvalidArgument
+	case http.StatusNotFound:
+		return api.ErrNotFound
+	case http.StatusConflict:
+		return api.ErrConflict
+	case http.StatusUnauthorized:
+		return api.ErrUnauthenticated
+	case http.StatusForbidden:
+		return api.ErrPermissionDenied
+	}
+	return nil
+}
+
+// Temporary reports whether retrying the same call may succeed
+func (e *APIError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// TransportError is returned when no HTTP response was received
+type TransportError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Method, e.Path, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls retries of idempotent calls after transport errors
+// and 429/502/503/504 responses. Field names follow temporal.RetryPolicy.
+type RetryPolicy struct {
+	MaxAttempts        int
+	InitialInterval    time.Duration
+	BackoffCoefficient float64
+	MaximumInterval    time.Duration
+}
+
+// DefaultRetryPolicy retries up to 3 times over roughly a second
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:        3,
+	InitialInterval:    200 * time.Millisecond,
+	BackoffCoefficient: 2.0,
+	MaximumInterval:    5 * time.Second,
+}
+
+// NoRetry disables retries
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+// backoff returns the wait before attempt+1. A server Retry-After hint wins
+// when it is longer than the computed interval.
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	d := p.InitialInterval
+	coeff := p.BackoffCoefficient
+	if coeff < 1 {
+		coeff = 1
+	}
+	for i := 1; i < attempt; i++ {
+		d = time.Duration(float64(d) * coeff)
+		if p.MaximumInterval > 0 && d > p.MaximumInterval {
+			d = p.MaximumInterval
+			break
+		}
+	}
+	if retryAfter > d {
+		d = retryAfter
+	}
+	return d
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
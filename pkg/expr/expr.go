@@ -0,0 +1,688 @@
+// Package expr is the expression language of workflow definitions, used by
+// `when` conditions and `switch` cases. Expressions read input parameters
+// and earlier stage outputs:
+//
+//	stages.extract.CountRows.rows > 0 && params.region == "eu"
+//	len(params.sources) >= 2 || !params.dry_run
+//
+// Literals are numbers, 'single' or "double" quoted strings, true, false
+// and null. Operators are || && ! == != < <= > >= + - * / % with the usual
+// precedence, plus parentheses, field access (a.b), indexing (a[0],
+// a["key"]) and the functions len(x) and contains(collection, x). Reading a
+// missing field yields null rather than an error.
+//
+// Evaluation has no side effects and does not depend on map order, time or
+// randomness, so it is safe inside Temporal workflow code.
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Expr is a parsed expression
+type Expr struct {
+	src  string
+	root node
+}
+
+// SyntaxError is a parse error at a 1-based column of the source
+type SyntaxError struct {
+	Column  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Message)
+}
+
+// Parse parses src
+func Parse(src string) (*Expr, error) {
+	p := &parser{src: src}
+	p.next()
+	if p.tok.kind == tokEOF {
+		return nil, &SyntaxError{Column: 1, Message: "empty expression"}
+	}
+	root := p.parseOr()
+	if p.err == nil && p.tok.kind != tokEOF {
+		p.fail("unexpected %s", p.tok)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates e. Names resolve against env; numbers are float64.
+func (e *Expr) Eval(env map[string]interface{}) (interface{}, error) {
+	return e.root.eval(env)
+}
+
+// Bool evaluates e, which must yield true or false
+func (e *Expr) Bool(env map[string]interface{}) (bool, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("yields %s, not true or false", describe(v))
+	}
+	return b, nil
+}
+
+// Paths returns the name paths e reads, such as [stages extract CountRows
+// rows], up to the first segment that is not a literal
+func (e *Expr) Paths() [][]string {
+	var out [][]string
+	var walk func(n node)
+	walk = func(n node) {
+		switch n := n.(type) {
+		case *nameNode:
+			out = append(out, []string{n.name})
+		case *accessNode:
+			if path := accessPath(n); path != nil {
+				out = append(out, path)
+				return
+			}
+			walk(n.target)
+			walk(n.key)
+		case *unaryNode:
+			walk(n.operand)
+		case *binaryNode:
+			walk(n.left)
+			walk(n.right)
+		case *callNode:
+			for _, a := range n.args {
+				walk(a)
+			}
+		}
+	}
+	walk(e.root)
+	return out
+}
+
+// accessPath returns the literal path of a chain like a.b["c"], or nil
+func accessPath(n node) []string {
+	switch n := n.(type) {
+	case *nameNode:
+		return []string{n.name}
+	case *accessNode:
+		key, ok := n.key.(*literalNode)
+		if !ok {
+			return nil
+		}
+		s, ok := key.value.(string)
+		if !ok {
+			return nil
+		}
+		if base := accessPath(n.target); base != nil {
+			return append(base, s)
+		}
+	}
+	return nil
+}
+
+// Tokens
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNumber
+	tokString
+	tokName
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+var operators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ".", ","}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+	err error
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	p.failAt(p.tok.pos, format, args...)
+}
+
+func (p *parser) failAt(pos int, format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = &SyntaxError{Column: pos + 1, Message: fmt.Sprintf(format, args...)}
+	}
+}
+
+func (p *parser) next() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9':
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = token{kind: tokNumber, text: p.src[start:p.pos], pos: start}
+	case c == '"' || c == '\'':
+		p.pos++
+		var b strings.Builder
+		for {
+			if p.pos >= len(p.src) {
+				p.failAt(start, "unterminated string")
+				p.tok = token{kind: tokEOF, pos: p.pos}
+				return
+			}
+			ch := p.src[p.pos]
+			p.pos++
+			if ch == c {
+				break
+			}
+			if ch == '\\' && p.pos < len(p.src) {
+				ch = p.src[p.pos]
+				p.pos++
+			}
+			b.WriteByte(ch)
+		}
+		p.tok = token{kind: tokString, text: b.String(), pos: start}
+	case isNameStart(c):
+		for p.pos < len(p.src) && (isNameStart(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		p.tok = token{kind: tokName, text: p.src[start:p.pos], pos: start}
+	default:
+		for _, op := range operators {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				p.pos += len(op)
+				p.tok = token{kind: tokOp, text: op, pos: start}
+				return
+			}
+		}
+		p.failAt(start, "unexpected character %q", c)
+		p.tok = token{kind: tokEOF, pos: p.pos}
+	}
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (p *parser) is(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+func (p *parser) expect(op string) {
+	if !p.is(op) {
+		p.fail("expected %q, found %s", op, p.tok)
+		return
+	}
+	p.next()
+}
+
+func (p *parser) parseOr() node {
+	n := p.parseAnd()
+	for p.err == nil && p.is("||") {
+		p.next()
+		n = &binaryNode{op: "||", left: n, right: p.parseAnd()}
+	}
+	return n
+}
+
+func (p *parser) parseAnd() node {
+	n := p.parseCompare()
+	for p.err == nil && p.is("&&") {
+		p.next()
+		n = &binaryNode{op: "&&", left: n, right: p.parseCompare()}
+	}
+	return n
+}
+
+func (p *parser) parseCompare() node {
+	n := p.parseAdd()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.err == nil && p.is(op) {
+			p.next()
+			return &binaryNode{op: op, left: n, right: p.parseAdd()}
+		}
+	}
+	return n
+}
+
+func (p *parser) parseAdd() node {
+	n := p.parseMul()
+	for p.err == nil && (p.is("+") || p.is("-")) {
+		op := p.tok.text
+		p.next()
+		n = &binaryNode{op: op, left: n, right: p.parseMul()}
+	}
+	return n
+}
+
+func (p *parser) parseMul() node {
+	n := p.parseUnary()
+	for p.err == nil && (p.is("*") || p.is("/") || p.is("%")) {
+		op := p.tok.text
+		p.next()
+		n = &binaryNode{op: op, left: n, right: p.parseUnary()}
+	}
+	return n
+}
+
+func (p *parser) parseUnary() node {
+	if p.is("!") || p.is("-") {
+		op := p.tok.text
+		p.next()
+		return &unaryNode{op: op, operand: p.parseUnary()}
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() node {
+	n := p.parsePrimary()
+	for p.err == nil {
+		switch {
+		case p.is("."):
+			p.next()
+			if p.tok.kind != tokName {
+				p.fail("expected a field name after '.', found %s", p.tok)
+				return n
+			}
+			n = &accessNode{target: n, key: &literalNode{value: p.tok.text}}
+			p.next()
+		case p.is("["):
+			p.next()
+			key := p.parseOr()
+			p.expect("]")
+			n = &accessNode{target: n, key: key}
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+func (p *parser) parsePrimary() node {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		p.next()
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			p.failAt(tok.pos, "invalid number %s", tok.text)
+		}
+		return &literalNode{value: f}
+	case tokString:
+		p.next()
+		return &literalNode{value: tok.text}
+	case tokName:
+		p.next()
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}
+		case "false":
+			return &literalNode{value: false}
+		case "null":
+			return &literalNode{value: nil}
+		}
+		if !p.is("(") {
+			return &nameNode{name: tok.text}
+		}
+		fn, ok := functions[tok.text]
+		if !ok {
+			p.failAt(tok.pos, "unknown function %s", tok.text)
+			return nil
+		}
+		p.next()
+		call := &callNode{name: tok.text, fn: fn}
+		for p.err == nil && !p.is(")") {
+			if len(call.args) > 0 {
+				p.expect(",")
+			}
+			call.args = append(call.args, p.parseOr())
+		}
+		p.expect(")")
+		if len(call.args) != fn.arity {
+			p.failAt(tok.pos, "%s takes %d argument(s), not %d", tok.text, fn.arity, len(call.args))
+		}
+		return call
+	}
+	if p.is("(") {
+		p.next()
+		n := p.parseOr()
+		p.expect(")")
+		return n
+	}
+	p.fail("unexpected %s", tok)
+	return nil
+}
+
+// Evaluation
+
+type node interface {
+	eval(env map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) { return n.value, nil }
+
+type nameNode struct{ name string }
+
+func (n *nameNode) eval(env map[string]interface{}) (interface{}, error) {
+	v, ok := env[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown name %q", n.name)
+	}
+	return normalize(v), nil
+}
+
+type accessNode struct{ target, key node }
+
+func (n *accessNode) eval(env map[string]interface{}) (interface{}, error) {
+	target, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	key, err := n.key.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch t := target.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		s, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot index an object with %s", describe(key))
+		}
+		return normalize(t[s]), nil
+	case []interface{}:
+		f, ok := key.(float64)
+		if !ok || f != math.Trunc(f) {
+			return nil, fmt.Errorf("cannot index a list with %s", describe(key))
+		}
+		if f < 0 || int(f) >= len(t) {
+			return nil, nil
+		}
+		return normalize(t[int(f)]), nil
+	}
+	return nil, fmt.Errorf("cannot read %v from %s", key, describe(target))
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("! needs true or false, not %s", describe(v))
+		}
+		return !b, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("- needs a number, not %s", describe(v))
+	}
+	return -f, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		lb, ok := l.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s needs true or false, not %s", n.op, describe(l))
+		}
+		if lb == (n.op == "||") {
+			return lb, nil
+		}
+		r, err := n.right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := r.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s needs true or false, not %s", n.op, describe(r))
+		}
+		return rb, nil
+	}
+
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	}
+
+	lf, lnum := l.(float64)
+	rf, rnum := r.(float64)
+	ls, lstr := l.(string)
+	rs, rstr := r.(string)
+	switch {
+	case lnum && rnum:
+		switch n.op {
+		case "<":
+			return lf < rf, nil
+		case "<=":
+			return lf <= rf, nil
+		case ">":
+			return lf > rf, nil
+		case ">=":
+			return lf >= rf, nil
+		case "+":
+			return lf + rf, nil
+		case "-":
+			return lf - rf, nil
+		case "*":
+			return lf * rf, nil
+		case "/", "%":
+			if rf == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if n.op == "%" {
+				return math.Mod(lf, rf), nil
+			}
+			return lf / rf, nil
+		}
+	case lstr && rstr:
+		switch n.op {
+		case "<":
+			return ls < rs, nil
+		case "<=":
+			return ls <= rs, nil
+		case ">":
+			return ls > rs, nil
+		case ">=":
+			return ls >= rs, nil
+		case "+":
+			return ls + rs, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot apply %s to %s and %s", n.op, describe(l), describe(r))
+}
+
+type function struct {
+	arity int
+	call  func(args []interface{}) (interface{}, error)
+}
+
+var functions = map[string]function{
+	"len": {1, func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case nil:
+			return 0.0, nil
+		case string:
+			return float64(len(v)), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("len needs a string, list or object, not %s", describe(args[0]))
+	}},
+	"contains": {2, func(args []interface{}) (interface{}, error) {
+		switch c := args[0].(type) {
+		case nil:
+			return false, nil
+		case string:
+			s, ok := args[1].(string)
+			return ok && strings.Contains(c, s), nil
+		case []interface{}:
+			for _, item := range c {
+				if equal(normalize(item), args[1]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			s, ok := args[1].(string)
+			_, found := c[s]
+			return ok && found, nil
+		}
+		return nil, fmt.Errorf("contains needs a string, list or object, not %s", describe(args[0]))
+	}},
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (n *callNode) eval(env map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return n.fn.call(args)
+}
+
+// normalize converts Go values to the JSON-like types expressions work on:
+// float64 numbers, []interface{} lists and map[string]interface{} objects
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool, string, float64, []interface{}, map[string]interface{}:
+		return v
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case uint:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case []string:
+		out := make([]interface{}, len(v))
+		for i, s := range v {
+			out[i] = s
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]interface{}, len(v))
+		for k, s := range v {
+			out[k] = s
+		}
+		return out
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = rv.Index(i).Interface()
+		}
+		return out
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			out := make(map[string]interface{}, rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				out[iter.Key().String()] = iter.Value().Interface()
+			}
+			return out
+		}
+	}
+	return v
+}
+
+func equal(a, b interface{}) bool {
+	a, b = normalize(a), normalize(b)
+	switch a.(type) {
+	case []interface{}, map[string]interface{}:
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
+
+func describe(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return "the number " + strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return "the string " + strconv.Quote(v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", v)
+}
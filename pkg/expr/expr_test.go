@@ -0,0 +1,110 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	env := map[string]interface{}{
+		"params": map[string]interface{}{
+			"region":  "eu",
+			"dry_run": false,
+			"sources": []string{"crm", "erp"},
+			"limit":   10,
+		},
+		"stages": map[string]interface{}{
+			"extract": map[string]interface{}{
+				"CountRows": map[string]interface{}{"rows": 0.0, "tables": []interface{}{"a", "b"}},
+			},
+		},
+	}
+	for src, want := range map[string]interface{}{
+		`stages.extract.CountRows.rows > 0`:                     false,
+		`stages.extract.CountRows.rows == 0 && !params.dry_run`: true,
+		`params.region == "eu" || params.missing.deeper`:        true,
+		`params.missing == null`:                                true,
+		`params.missing.deeper`:                                 nil,
+		`len(params.sources) >= 2`:                              true,
+		`contains(params.sources, 'erp')`:                       true,
+		`contains(stages.extract.CountRows.tables, "c")`:        false,
+		`stages["extract"].CountRows.tables[1]`:                 "b",
+		`stages.extract.CountRows.tables[5]`:                    nil,
+		`params.limit * 2 + 1`:                                  21.0,
+		`-params.limit % 3`:                                     -1.0,
+		`(1 + 2) * 3 == 9`:                                      true,
+		`"a" + 'b' < "ac"`:                                      true,
+		`params.sources == stages.extract.CountRows.tables`:     false,
+		`1 == "1"`: false,
+	} {
+		e, err := Parse(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		got, err := e.Eval(env)
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s = %v, %v; want %v", src, got, err, want)
+		}
+	}
+
+	for src, msg := range map[string]string{
+		`params.region > 1`:      "cannot apply > to the string",
+		`params.limit && true`:   "&& needs true or false",
+		`nope == 1`:              `unknown name "nope"`,
+		`params.limit / 0`:       "division by zero",
+		`params.region.x.y == 1`: "cannot read x from the string",
+		`len(params.limit) == 1`: "len needs",
+	} {
+		_, err := Parse(src)
+		if err == nil {
+			_, err = mustParse(t, src).Eval(env)
+		}
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: error %v, want %q", src, err, msg)
+		}
+	}
+
+	if _, err := mustParse(t, "params.limit").Bool(env); err == nil || !strings.Contains(err.Error(), "not true or false") {
+		t.Errorf("Bool on a number: %v", err)
+	}
+}
+
+func mustParse(t *testing.T, src string) *Expr {
+	t.Helper()
+	e, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestParseErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		``:          "column 1: empty expression",
+		`a ==`:      "column 5: unexpected end of expression",
+		`a == 'x`:   "column 6: unterminated string",
+		`a = 1`:     `column 3: unexpected character '='`,
+		`(a`:        `column 3: expected ")"`,
+		`a.`:        "column 3: expected a field name",
+		`size(a)`:   "column 1: unknown function size",
+		`len(a, b)`: "column 1: len takes 1 argument(s), not 2",
+		`a b`:       `column 3: unexpected "b"`,
+		`1.2.3 > 0`: "column 1: invalid number 1.2.3",
+	} {
+		_, err := Parse(src)
+		if err == nil || !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("%q: %v, want %s", src, err, want)
+		}
+	}
+}
+
+func TestPaths(t *testing.T) {
+	e := mustParse(t, `stages.extract.Count.rows > params.min && len(stages["load"][params.key]) > 0`)
+	got := fmt.Sprint(e.Paths())
+	want := "[[stages extract Count rows] [params min] [stages load] [params key]]"
+	if got != want {
+		t.Errorf("paths = %s, want %s", got, want)
+	}
+}
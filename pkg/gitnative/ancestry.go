@@ -0,0 +1,139 @@
+package gitnative
+
+import (
+	"context"
+	"sort"
+)
+
+// MergeBase returns the best common ancestor of commits a and b, or "" when
+// their histories are unrelated. When there are several best candidates
+// (criss-cross merges) the most recently committed one is returned.
+func (r *Repo) MergeBase(ctx context.Context, a, b string) (string, error) {
+	ancestors, err := r.ancestors(ctx, []string{a}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// Walk back from b, stopping at the first common commits on each path
+	var candidates []string
+	if _, err := r.ancestors(ctx, []string{b}, func(id string) bool {
+		if ancestors[id] {
+			candidates = append(candidates, id)
+			return false
+		}
+		return true
+	}); err != nil {
+		return "", err
+	}
+
+	// Drop candidates reachable from another candidate
+	var best []string
+	for _, c := range candidates {
+		redundant := false
+		for _, other := range candidates {
+			if other == c {
+				continue
+			}
+			reach, err := r.ancestors(ctx, []string{other}, nil)
+			if err != nil {
+				return "", err
+			}
+			if reach[c] {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			best = append(best, c)
+		}
+	}
+	if len(best) == 0 {
+		return "", nil
+	}
+
+	when := map[string]int64{}
+	for _, c := range best {
+		commit, err := r.objects.Commit(c)
+		if err != nil {
+			return "", err
+		}
+		when[c] = commit.Committer.When.Unix()
+	}
+	sort.SliceStable(best, func(i, j int) bool {
+		if when[best[i]] != when[best[j]] {
+			return when[best[i]] > when[best[j]]
+		}
+		return best[i] < best[j]
+	})
+	return best[0], nil
+}
+
+// IsAncestor reports whether ancestor is reachable from commit (a commit
+// is its own ancestor)
+func (r *Repo) IsAncestor(ctx context.Context, ancestor, commit string) (bool, error) {
+	found := false
+	_, err := r.ancestors(ctx, []string{commit}, func(id string) bool {
+		if id == ancestor {
+			found = true
+		}
+		return !found
+	})
+	return found, err
+}
+
+// CountExclusive returns the number of commits reachable from include but
+// not from exclude, like git rev-list --count exclude..include
+func (r *Repo) CountExclusive(ctx context.Context, include, exclude string) (int, error) {
+	ids, err := r.Exclusive(ctx, include, exclude)
+	return len(ids), err
+}
+
+// Exclusive returns the commits reachable from include but not from
+// exclude, like git rev-list exclude..include, nearest first. An empty
+// exclude returns the whole history of include.
+func (r *Repo) Exclusive(ctx context.Context, include, exclude string) ([]string, error) {
+	excluded := map[string]bool{}
+	if exclude != "" {
+		var err error
+		if excluded, err = r.ancestors(ctx, []string{exclude}, nil); err != nil {
+			return nil, err
+		}
+	}
+	var ids []string
+	_, err := r.ancestors(ctx, []string{include}, func(id string) bool {
+		if excluded[id] {
+			return false
+		}
+		ids = append(ids, id)
+		return true
+	})
+	return ids, err
+}
+
+// ancestors returns every commit reachable from start, including start.
+// visit, if set, is called for each commit and stops the walk below it
+// when it returns false.
+func (r *Repo) ancestors(ctx context.Context, start []string, visit func(id string) bool) (map[string]bool, error) {
+	seen := map[string]bool{}
+	queue := append([]string(nil), start...)
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if visit != nil && !visit(id) {
+			continue
+		}
+		c, err := r.objects.Commit(id)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, c.Parents...)
+	}
+	return seen, nil
+}
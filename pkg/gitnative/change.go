@@ -0,0 +1,80 @@
+package gitnative
+
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// ChangeType is how a file changed between two commits
+type ChangeType string
+
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeModified ChangeType = "modified"
+	ChangeDeleted  ChangeType = "deleted"
+	ChangeRenamed  ChangeType = "renamed"
+)
+
+// FileKind is the runtime registry a path belongs to. Library files are
+// stage templates shared by workflow definitions.
+type FileKind string
+
+const (
+	KindTool     FileKind = "tool"
+	KindWorkflow FileKind = "workflow"
+	KindConfig   FileKind = "config"
+	KindLibrary  FileKind = "library"
+	KindOther    FileKind = "other"
+)
+
+// Change is one changed path. OldPath is only set for renames.
+type Change struct {
+	Type    ChangeType `json:"type"`
+	Kind    FileKind   `json:"kind"`
+	Path    string     `json:"path"`
+	OldPath string     `json:"old_path,omitempty"`
+}
+
+// CommitEvent reports that Ref moved from OldSHA to NewSHA. OldSHA is empty
+// for a new ref and NewSHA is empty when the ref was deleted.
+type CommitEvent struct {
+	Ref        string    `json:"ref"`
+	OldSHA     string    `json:"old_sha"`
+	NewSHA     string    `json:"new_sha"`
+	Changes    []Change  `json:"changes"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// ChangesOfKind filters the event's changes to one registry
+func (e CommitEvent) ChangesOfKind(kind FileKind) []Change {
+	var out []Change
+	for _, c := range e.Changes {
+		if c.Kind == kind {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// ClassifyPath maps a repository path to its registry. Both the runtime
+// layout (tools/x.json) and the monorepo layout (repos/tools/x.json) are
+// recognised.
+func ClassifyPath(p string) FileKind {
+	p = strings.TrimPrefix(path.Clean(p), "repos/")
+	dir, _, ok := strings.Cut(p, "/")
+	if !ok {
+		return KindOther
+	}
+	switch dir {
+	case "tools":
+		return KindTool
+	case "workflows":
+		return KindWorkflow
+	case "configs":
+		return KindConfig
+	case "library":
+		return KindLibrary
+	}
+	return KindOther
+}
@@ -0,0 +1,23 @@
+package gitnative
+
+import "context"
+
+// DiffCommits lists the paths that differ between two commits, with exact
+// renames detected. An empty oldSHA compares against the empty tree, so
+// every file in newSHA is reported as added.
+func DiffCommits(ctx context.Context, gitDir, oldSHA, newSHA string) ([]Change, error) {
+	objects, err := OpenObjectStore(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	defer objects.Close()
+	repo := &Repo{gitDir: gitDir, objects: objects}
+	return repo.Diff(ctx, oldSHA, newSHA)
+}
+
+func short(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
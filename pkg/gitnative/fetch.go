@@ -0,0 +1,495 @@
+package gitnative
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Remote is a repository to fetch from: a local path or file:// URL (for
+// example a bare repository on a shared volume), or an http(s) URL that
+// speaks git's smart HTTP protocol.
+type Remote struct {
+	// Name namespaces the tracking refs, refs/remotes/<Name>/...; default
+	// "origin"
+	Name string
+	URL  string
+	// Username and Password are sent as HTTP basic auth. A token can be
+	// given as the password alone.
+	Username string
+	Password string
+	// HTTPClient defaults to a client with a 2 minute timeout
+	HTTPClient *http.Client
+}
+
+func (r Remote) name() string {
+	if r.Name == "" {
+		return "origin"
+	}
+	return r.Name
+}
+
+// Redacted returns the URL without credentials, for logs and responses
+func (r Remote) Redacted() string {
+	u, err := url.Parse(r.URL)
+	if err != nil || u.User == nil {
+		return r.URL
+	}
+	u.User = nil
+	return u.String()
+}
+
+// TrackingRef returns where Fetch records the remote's branch, e.g.
+// refs/heads/main -> refs/remotes/origin/main
+func (r Remote) TrackingRef(branch string) string {
+	return "refs/remotes/" + r.name() + "/" + strings.TrimPrefix(branch, "refs/heads/")
+}
+
+// FetchResult reports what Fetch downloaded
+type FetchResult struct {
+	// Refs maps each matching remote branch to its commit
+	Refs map[string]string
+	// Objects is the number of objects written to the local store
+	Objects int
+}
+
+// Fetch downloads the commits of the remote branches matching patterns
+// (full names or path.Match patterns such as refs/heads/customer/*) and
+// records each tip as a tracking ref (see Remote.TrackingRef). Local
+// branches are left alone; moving them is up to the caller.
+func (r *Repo) Fetch(ctx context.Context, remote Remote, patterns []string) (*FetchResult, error) {
+	var (
+		res *FetchResult
+		err error
+	)
+	switch u, _ := url.Parse(remote.URL); {
+	case u != nil && (u.Scheme == "http" || u.Scheme == "https"):
+		res, err = r.fetchHTTP(ctx, remote, patterns)
+	case u != nil && u.Scheme == "file":
+		res, err = r.fetchLocal(ctx, u.Path, patterns)
+	case u != nil && u.Scheme != "" && len(u.Scheme) > 1:
+		err = fmt.Errorf("unsupported remote URL scheme %q (use a path, file:// or http(s)://)", u.Scheme)
+	default:
+		res, err = r.fetchLocal(ctx, remote.URL, patterns)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", remote.Redacted(), err)
+	}
+
+	for _, branch := range sortedRefNames(res.Refs) {
+		tracking := remote.TrackingRef(branch)
+		old, err := ResolveRef(r.gitDir, tracking)
+		if errors.Is(err, ErrRefNotFound) {
+			old, err = "", nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if old == res.Refs[branch] {
+			continue
+		}
+		if err := UpdateRef(r.gitDir, tracking, old, res.Refs[branch]); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// fetchLocal copies the objects reachable from the matching branches of
+// the repository at path
+func (r *Repo) fetchLocal(ctx context.Context, path string, patterns []string) (*FetchResult, error) {
+	src, err := OpenRepo(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	refs, err := ListRefs(src.gitDir)
+	if err != nil {
+		return nil, err
+	}
+	res := &FetchResult{Refs: map[string]string{}}
+	for ref, sha := range refs {
+		if strings.HasPrefix(ref, "refs/heads/") && MatchRef(patterns, ref) {
+			res.Refs[ref] = sha
+		}
+	}
+
+	var roots []string
+	for _, ref := range sortedRefNames(res.Refs) {
+		roots = append(roots, res.Refs[ref])
+	}
+	if res.Objects, err = r.objects.copyClosure(ctx, roots, src.objects.Read); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// copyClosure writes every object reachable from roots that the store
+// lacks, reading them with read. Objects are written depth-first after
+// everything they reference, so an interrupted copy never leaves a commit
+// without its tree; anything already present is assumed complete.
+func (s *ObjectStore) copyClosure(ctx context.Context, roots []string, read func(id string) (*Object, error)) (int, error) {
+	type frame struct {
+		obj  *Object
+		id   string
+		done bool
+	}
+	var stack []frame
+	for _, id := range roots {
+		stack = append(stack, frame{id: id})
+	}
+	seen := map[string]bool{}
+	written := 0
+	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f.done {
+			if _, err := s.Write(f.obj.Type, f.obj.Data); err != nil {
+				return written, err
+			}
+			written++
+			continue
+		}
+		if seen[f.id] || s.exists(f.id) {
+			continue
+		}
+		seen[f.id] = true
+
+		obj, err := read(f.id)
+		if err != nil {
+			return written, err
+		}
+		stack = append(stack, frame{obj: obj, id: f.id, done: true})
+		refs, err := references(obj)
+		if err != nil {
+			return written, err
+		}
+		for _, id := range refs {
+			stack = append(stack, frame{id: id})
+		}
+	}
+	return written, nil
+}
+
+// references lists the objects obj points at
+func references(obj *Object) ([]string, error) {
+	switch obj.Type {
+	case ObjectCommit:
+		c, err := ParseCommit(obj.ID, obj.Data)
+		if err != nil {
+			return nil, err
+		}
+		return append([]string{c.Tree}, c.Parents...), nil
+	case ObjectTree:
+		entries, err := ParseTree(obj.ID, obj.Data)
+		if err != nil {
+			return nil, err
+		}
+		var ids []string
+		for _, e := range entries {
+			if e.Mode != modeGitlink {
+				ids = append(ids, e.ID)
+			}
+		}
+		return ids, nil
+	case ObjectTag:
+		t, err := ParseTag(obj.ID, obj.Data)
+		if err != nil {
+			return nil, err
+		}
+		return []string{t.Object}, nil
+	}
+	return nil, nil
+}
+
+// fetchHTTP runs one stateless round of the smart HTTP protocol (v0): read
+// the advertised refs, send wants and our branch tips as haves, and unpack
+// the pack the server returns
+func (r *Repo) fetchHTTP(ctx context.Context, remote Remote, patterns []string) (*FetchResult, error) {
+	client := remote.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+	base := strings.TrimSuffix(remote.URL, "/")
+	send := func(req *http.Request) (*http.Response, error) {
+		if remote.Username != "" || remote.Password != "" {
+			user := remote.Username
+			if user == "" {
+				user = "git"
+			}
+			req.SetBasicAuth(user, remote.Password)
+		}
+		req.Header.Set("User-Agent", "volcano-llm/gitnative")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+		}
+		return resp, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/info/refs?service=git-upload-pack", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := send(req)
+	if err != nil {
+		return nil, err
+	}
+	advertised, caps, err := readAdvertisement(resp)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &FetchResult{Refs: map[string]string{}}
+	var wants []string
+	for ref, sha := range advertised {
+		if !strings.HasPrefix(ref, "refs/heads/") || !MatchRef(patterns, ref) {
+			continue
+		}
+		res.Refs[ref] = sha
+		if !r.objects.exists(sha) {
+			wants = append(wants, sha)
+		}
+	}
+	if len(wants) == 0 {
+		return res, nil
+	}
+	sort.Strings(wants)
+
+	var body bytes.Buffer
+	want := []string{"ofs-delta", "side-band-64k", "no-progress"}
+	var use []string
+	for _, c := range want {
+		if caps[c] {
+			use = append(use, c)
+		}
+	}
+	use = append(use, "agent=volcano-llm")
+	for i, sha := range dedupe(wants) {
+		line := "want " + sha
+		if i == 0 {
+			line += " " + strings.Join(use, " ")
+		}
+		body.Write(pktLine(line + "\n"))
+	}
+	body.WriteString("0000")
+	haves, err := r.haves()
+	if err != nil {
+		return nil, err
+	}
+	for _, sha := range haves {
+		body.Write(pktLine("have " + sha + "\n"))
+	}
+	body.Write(pktLine("done\n"))
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, base+"/git-upload-pack", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-git-upload-pack-request")
+	req.Header.Set("Accept", "application/x-git-upload-pack-result")
+	resp, err = send(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	pack, err := readPackResponse(bufio.NewReader(resp.Body), caps["side-band-64k"])
+	if err != nil {
+		return nil, err
+	}
+	objects, err := r.objects.parsePack(pack)
+	if err != nil {
+		return nil, err
+	}
+	read := func(id string) (*Object, error) {
+		if obj, ok := objects[id]; ok {
+			return obj, nil
+		}
+		return nil, fmt.Errorf("%w: server did not send %s", ErrObjectNotFound, short(id))
+	}
+	if res.Objects, err = r.objects.copyClosure(ctx, wants, read); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// haves lists the commits the server can assume we have: our branch and
+// tracking ref tips, most useful first and capped to keep the request small
+func (r *Repo) haves() ([]string, error) {
+	refs, err := ListRefs(r.gitDir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, ref := range sortedRefNames(refs) {
+		if strings.HasPrefix(ref, "refs/heads/") || strings.HasPrefix(ref, "refs/remotes/") {
+			out = append(out, refs[ref])
+		}
+	}
+	out = dedupe(out)
+	if len(out) > 256 {
+		out = out[:256]
+	}
+	return out, nil
+}
+
+// readAdvertisement parses the info/refs response of a smart HTTP server
+func readAdvertisement(resp *http.Response) (refs map[string]string, caps map[string]bool, err error) {
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-git-upload-pack-advertisement" {
+		return nil, nil, fmt.Errorf("server does not speak smart HTTP (content type %q)", ct)
+	}
+	br := bufio.NewReader(resp.Body)
+	line, err := readPkt(br)
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.TrimSpace(string(line)) != "# service=git-upload-pack" {
+		return nil, nil, fmt.Errorf("unexpected advertisement header %q", line)
+	}
+	if line, err = readPkt(br); err != nil || line != nil {
+		return nil, nil, fmt.Errorf("advertisement: missing flush after header")
+	}
+
+	refs, caps = map[string]string{}, map[string]bool{}
+	for first := true; ; first = false {
+		line, err := readPkt(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		if line == nil {
+			return refs, caps, nil
+		}
+		text := strings.TrimSuffix(string(line), "\n")
+		if first {
+			var capList string
+			text, capList, _ = strings.Cut(text, "\x00")
+			for _, c := range strings.Fields(capList) {
+				caps[c] = true
+			}
+		}
+		sha, ref, ok := strings.Cut(text, " ")
+		if !ok || !isHexSHA(sha) || strings.HasSuffix(ref, "^{}") || ref == "capabilities^{}" {
+			continue
+		}
+		refs[ref] = sha
+	}
+}
+
+// readPackResponse skips the ACK/NAK lines of an upload-pack response and
+// returns the pack, demultiplexing side-band channels when negotiated
+func readPackResponse(br *bufio.Reader, sideBand bool) ([]byte, error) {
+	for {
+		if !sideBand {
+			if peek, err := br.Peek(4); err == nil && string(peek) == "PACK" {
+				return io.ReadAll(br)
+			}
+		}
+		line, err := readPkt(br)
+		if err != nil {
+			return nil, err
+		}
+		text := string(line)
+		switch {
+		case line == nil, strings.HasPrefix(text, "NAK"), strings.HasPrefix(text, "ACK "):
+			continue
+		case strings.HasPrefix(text, "ERR "):
+			return nil, fmt.Errorf("remote error: %s", strings.TrimSpace(text[4:]))
+		case sideBand:
+			return readSideBand(br, line)
+		default:
+			return nil, fmt.Errorf("unexpected upload-pack line %q", text)
+		}
+	}
+}
+
+// readSideBand collects channel 1 until the closing flush, starting with
+// the already read packet first
+func readSideBand(br *bufio.Reader, first []byte) ([]byte, error) {
+	var pack bytes.Buffer
+	for line := first; line != nil; {
+		if len(line) > 0 {
+			switch line[0] {
+			case 1:
+				pack.Write(line[1:])
+			case 2:
+				// progress messages
+			case 3:
+				return nil, fmt.Errorf("remote error: %s", strings.TrimSpace(string(line[1:])))
+			default:
+				return nil, fmt.Errorf("unknown side-band channel %d", line[0])
+			}
+		}
+		var err error
+		if line, err = readPkt(br); err != nil {
+			return nil, err
+		}
+	}
+	return pack.Bytes(), nil
+}
+
+// pktLine encodes s as a pkt-line
+func pktLine(s string) []byte {
+	return []byte(fmt.Sprintf("%04x%s", len(s)+4, s))
+}
+
+// readPkt reads one pkt-line; a flush packet returns nil
+func readPkt(br *bufio.Reader) ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("read pkt-line: %w", err)
+	}
+	var n int
+	if _, err := fmt.Sscanf(string(hdr[:]), "%04x", &n); err != nil {
+		return nil, fmt.Errorf("bad pkt-line length %q", hdr)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if n < 4 {
+		return []byte{}, nil // delimiter packets carry nothing we use
+	}
+	line := make([]byte, n-4)
+	if _, err := io.ReadFull(br, line); err != nil {
+		return nil, fmt.Errorf("read pkt-line: %w", err)
+	}
+	return line, nil
+}
+
+func sortedRefNames(refs map[string]string) []string {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func dedupe(ids []string) []string {
+	seen := map[string]bool{}
+	out := ids[:0:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
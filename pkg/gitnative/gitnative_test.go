@@ -0,0 +1,600 @@
+package gitnative
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRepo is a throwaway repository driven through the git CLI, the way a
+// user or CI job would commit to the runtime repo
+type testRepo struct {
+	t    *testing.T
+	path string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := &testRepo{t: t, path: t.TempDir()}
+	r.git("init", "-q", "-b", "main")
+	return r
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.path
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Volcano Test", "GIT_AUTHOR_EMAIL=test@volcano.local",
+		"GIT_COMMITTER_NAME=Volcano Test", "GIT_COMMITTER_EMAIL=test@volcano.local",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+r.path)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *testRepo) write(path, content string) {
+	r.t.Helper()
+	full := filepath.Join(r.path, path)
+	os.MkdirAll(filepath.Dir(full), 0755)
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testRepo) commit(msg string) string {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", msg)
+	return r.git("rev-parse", "HEAD")
+}
+
+func TestWatcherReportsTypedChanges(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("tools/calculator.json", `{"name":"Calculator","version":"1.0"}`)
+	repo.write("workflows/data-pipeline.json", `{"name":"DataPipelineWorkflow","version":"1.0.0"}`)
+	repo.write("workflows/legacy.json", `{"name":"Legacy"}`)
+	repo.write("configs/runtime.yaml", "precision: 2\nmode: strict\nregion: us-east\n")
+	first := repo.commit("initial")
+
+	w, err := NewWatcher(repo.path, WatcherOptions{PollInterval: time.Hour, Debounce: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Current()["refs/heads/main"] != first {
+		t.Fatalf("snapshot = %v", w.Current())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan CommitEvent, 4)
+	go w.Watch(ctx, func(e CommitEvent) { events <- e })
+	// Readers may poll Current while Watch updates it
+	go func() {
+		for ctx.Err() == nil {
+			w.Current()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	repo.write("workflows/data-pipeline.json", `{"name":"DataPipelineWorkflow","version":"1.1.0"}`)
+	os.Remove(filepath.Join(repo.path, "workflows/legacy.json"))
+	repo.git("mv", "configs/runtime.yaml", "configs/runtime-v2.yaml")
+	repo.write("tools/converter.json", `{"name":"Converter"}`)
+	second := repo.commit("update pipeline")
+
+	var event CommitEvent
+	select {
+	case event = <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no commit event from inotify")
+	}
+
+	if event.Ref != "refs/heads/main" || event.OldSHA != first || event.NewSHA != second {
+		t.Fatalf("event = %+v", event)
+	}
+	if w.Current()["refs/heads/main"] != second {
+		t.Errorf("Current after event = %v", w.Current())
+	}
+
+	got := map[string]Change{}
+	for _, c := range event.Changes {
+		got[c.Path] = c
+	}
+	want := []Change{
+		{Type: ChangeModified, Kind: KindWorkflow, Path: "workflows/data-pipeline.json"},
+		{Type: ChangeDeleted, Kind: KindWorkflow, Path: "workflows/legacy.json"},
+		{Type: ChangeRenamed, Kind: KindConfig, Path: "configs/runtime-v2.yaml", OldPath: "configs/runtime.yaml"},
+		{Type: ChangeAdded, Kind: KindTool, Path: "tools/converter.json"},
+	}
+	if len(event.Changes) != len(want) {
+		t.Fatalf("changes = %+v", event.Changes)
+	}
+	for _, w := range want {
+		if got[w.Path] != w {
+			t.Errorf("change for %s = %+v, want %+v", w.Path, got[w.Path], w)
+		}
+	}
+}
+
+func TestWatcherPollsBranchPatterns(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.write("tools/calculator.json", `{}`)
+	repo.commit("initial")
+
+	w, err := NewWatcher(repo.path, WatcherOptions{Refs: []string{"refs/heads/customer/*"}, PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo.git("checkout", "-q", "-b", "customer/acme-corp")
+	repo.write("tools/acme-po-validator.json", `{"name":"ACMEPOValidator"}`)
+	sha := repo.commit("Add ACME PO validation rules")
+	repo.git("pack-refs", "--all")
+
+	var events []CommitEvent
+	w.scan(context.Background(), func(e CommitEvent) { events = append(events, e) })
+
+	if len(events) != 1 || events[0].Ref != "refs/heads/customer/acme-corp" || events[0].NewSHA != sha {
+		t.Fatalf("events = %+v", events)
+	}
+	// A brand-new branch is diffed against the empty tree
+	var paths []string
+	for _, c := range events[0].Changes {
+		paths = append(paths, c.Path)
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != "tools/acme-po-validator.json,tools/calculator.json" {
+		t.Errorf("paths = %v", paths)
+	}
+}
+
+func TestClassifyPath(t *testing.T) {
+	for p, want := range map[string]FileKind{
+		"tools/calculator.json":         KindTool,
+		"repos/tools/calculator.json":   KindTool,
+		"workflows/pipeline.yaml":       KindWorkflow,
+		"configs/runtime.yaml":          KindConfig,
+		"library/extract-validate.yaml": KindLibrary,
+		"README.md":                     KindOther,
+		"docs/tools/calculator.md":      KindOther,
+		"repos/workflows/a/b/c.json":    KindWorkflow,
+		"repos/configs/../tools/x.json": KindTool,
+	} {
+		if got := ClassifyPath(p); got != want {
+			t.Errorf("ClassifyPath(%q) = %s, want %s", p, got, want)
+		}
+	}
+}
+
+// packedRepo builds a history with delta-compressed packs, a loose commit
+// on top and an annotated tag
+func packedRepo(t *testing.T) (*testRepo, []string) {
+	repo := newTestRepo(t)
+	var commits []string
+	body := strings.Repeat("precision: 2\nmode: strict\nregion: us-east\n", 200)
+	for i := 0; i < 6; i++ {
+		repo.write("configs/runtime.yaml", body+strings.Repeat("# revision\n", i))
+		repo.write("tools/calculator.json", `{"name":"Calculator","version":"1.`+string(rune('0'+i))+`"}`)
+		commits = append(commits, repo.commit("revision"))
+	}
+	repo.git("tag", "-a", "v1.0.0", "-m", "release", commits[2])
+	repo.git("repack", "-adf", "--depth=10", "--window=10")
+	repo.git("pack-refs", "--all")
+
+	repo.write("workflows/data-pipeline.yaml", "name: DataPipelineWorkflow\n")
+	commits = append(commits, repo.commit("loose"))
+	return repo, commits
+}
+
+func TestObjectStoreMatchesGit(t *testing.T) {
+	repo, _ := packedRepo(t)
+	store, err := OpenObjectStore(filepath.Join(repo.path, ".git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	all := repo.git("cat-file", "--batch-all-objects", "--batch-check=%(objectname) %(objecttype)")
+	n := 0
+	for _, line := range strings.Split(all, "\n") {
+		id, typ, _ := strings.Cut(line, " ")
+		obj, err := store.Read(id)
+		if err != nil {
+			t.Fatalf("read %s: %v", id, err)
+		}
+		cmd := exec.Command("git", "cat-file", typ, id)
+		cmd.Dir = repo.path
+		want, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if obj.Type.String() != typ || string(obj.Data) != string(want) {
+			t.Errorf("%s: got %s (%d bytes), want %s (%d bytes)", id, obj.Type, len(obj.Data), typ, len(want))
+		}
+		n++
+	}
+	if n < 20 {
+		t.Fatalf("only %d objects checked", n)
+	}
+}
+
+func TestRepoResolveAndRead(t *testing.T) {
+	repo, commits := packedRepo(t)
+	r, err := OpenRepo(repo.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ctx := context.Background()
+	head := commits[len(commits)-1]
+
+	for rev, want := range map[string]string{
+		"HEAD":             head,
+		"main":             head,
+		"refs/heads/main":  head,
+		"v1.0.0":           commits[2],
+		"tags/v1.0.0":      commits[2],
+		commits[1][:7]:     commits[1],
+		commits[3]:         commits[3],
+		"refs/tags/v1.0.0": commits[2],
+	} {
+		if got, err := r.Resolve(ctx, rev); err != nil || got != want {
+			t.Errorf("Resolve(%q) = %.8s, %v; want %.8s", rev, got, err, want)
+		}
+	}
+	for _, rev := range []string{"nope", "../config", "main..v1.0.0", "0000000"} {
+		if _, err := r.Resolve(ctx, rev); !errors.Is(err, ErrRefNotFound) {
+			t.Errorf("Resolve(%q) = %v, want ErrRefNotFound", rev, err)
+		}
+	}
+
+	data, err := r.ReadFile(ctx, commits[2], "tools/calculator.json")
+	if err != nil || string(data) != `{"name":"Calculator","version":"1.2"}` {
+		t.Errorf("ReadFile = %q, %v", data, err)
+	}
+	if _, err := r.ReadFile(ctx, commits[2], "workflows/data-pipeline.yaml"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("missing file: %v", err)
+	}
+	if _, err := r.ReadFile(ctx, head, "tools"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("directory read as file: %v", err)
+	}
+
+	files, err := r.ListFiles(ctx, head)
+	if err != nil || strings.Join(files, ",") != "configs/runtime.yaml,tools/calculator.json,workflows/data-pipeline.yaml" {
+		t.Errorf("ListFiles = %v, %v", files, err)
+	}
+
+	c, err := r.Commit(head)
+	if err != nil || c.Message != "loose\n" || len(c.Parents) != 1 || c.Parents[0] != commits[len(commits)-2] || c.Author.Email != "test@volcano.local" {
+		t.Errorf("Commit = %+v, %v", c, err)
+	}
+}
+
+func TestFetchFromLocalAndHTTPRemotes(t *testing.T) {
+	src, _ := packedRepo(t)
+	remoteDir := t.TempDir()
+	src.git("clone", "-q", "--bare", src.path, filepath.Join(remoteDir, "runtime.git"))
+	bare := &testRepo{t: t, path: filepath.Join(remoteDir, "runtime.git")}
+
+	out, err := exec.Command("git", "--exec-path").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := filepath.Join(strings.TrimSpace(string(out)), "git-http-backend")
+	if _, err := os.Stat(backend); err != nil {
+		t.Skip("git-http-backend not installed")
+	}
+	server := httptest.NewServer(&cgi.Handler{
+		Path: backend,
+		Env:  []string{"GIT_PROJECT_ROOT=" + remoteDir, "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	defer server.Close()
+
+	for name, url := range map[string]string{
+		"local": bare.path,
+		"file":  "file://" + bare.path,
+		"http":  server.URL + "/runtime.git",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dst := newTestRepo(t)
+			r, err := OpenRepo(dst.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			ctx := context.Background()
+			remote := Remote{URL: url}
+
+			res, err := r.Fetch(ctx, remote, []string{"refs/heads/*"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := bare.git("rev-parse", "main")
+			if res.Refs["refs/heads/main"] != want || res.Objects == 0 {
+				t.Fatalf("fetch = %+v", res)
+			}
+			if got := dst.git("rev-parse", "refs/remotes/origin/main"); got != want {
+				t.Errorf("tracking ref = %s", got)
+			}
+			dst.git("fsck", "--strict", "--no-dangling")
+
+			// A second fetch only transfers what is new
+			if res, err := r.Fetch(ctx, remote, []string{"refs/heads/*"}); err != nil || res.Objects != 0 {
+				t.Errorf("refetch = %+v, %v", res, err)
+			}
+			src.write("tools/calculator.json", `{"name":"Calculator","version":"2.0-`+name+`"}`)
+			next := src.commit("bump " + name)
+			src.git("push", "-q", bare.path, "main")
+
+			res, err = r.Fetch(ctx, remote, []string{"refs/heads/main"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Refs["refs/heads/main"] != next || res.Objects != 4 {
+				t.Errorf("incremental fetch = %+v, want 4 objects", res)
+			}
+			dst.git("fsck", "--strict", "--no-dangling")
+		})
+	}
+}
+
+// run executes a tool the test needs, skipping the test if it is missing
+func run(t *testing.T, name string, args ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath(name); err != nil {
+		t.Skipf("%s not installed", name)
+	}
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s %v: %v\n%s", name, args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestVerifyCommitSignatures(t *testing.T) {
+	repo := newTestRepo(t)
+
+	// gpg's agent socket lives in GNUPGHOME, which must be a short path
+	gnupg, err := os.MkdirTemp("", "gpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GNUPGHOME", gnupg)
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		os.RemoveAll(gnupg)
+	})
+	pgpKey := func(uid, algo string) string {
+		run(t, "gpg", "--batch", "--passphrase", "", "--quick-gen-key", uid, algo, "sign", "never")
+		return run(t, "gpg", "--armor", "--export", uid)
+	}
+	sshKey := func(typ string) string {
+		key := filepath.Join(t.TempDir(), "id")
+		run(t, "ssh-keygen", "-q", "-t", typ, "-N", "", "-C", typ, "-f", key)
+		return key
+	}
+	ada, bob := pgpKey("Ada <ada@example.com>", "ed25519"), pgpKey("Bob <bob@example.com>", "rsa3072")
+	rsaKey, edKey, ecKey := sshKey("rsa"), sshKey("ed25519"), sshKey("ecdsa")
+	readPub := func(key string) string {
+		b, err := os.ReadFile(key + ".pub")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	var kr Keyring
+	for _, err := range []error{
+		kr.AddOpenPGPKeys("Ada", ada),
+		kr.AddSSHKey("ops-rsa", readPub(rsaKey)),
+		kr.AddSSHKey("ops-ed25519", readPub(edKey)),
+		kr.AddSSHKey("ops-ecdsa", readPub(ecKey)),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := kr.AddSSHKey("bad", "ssh-ed25519 AAAA"); err == nil {
+		t.Error("truncated ssh key accepted")
+	}
+	var bobRing Keyring
+	if err := bobRing.AddOpenPGPKeys("Bob", bob); err != nil {
+		t.Fatal(err)
+	}
+
+	commit := func(msg string, sign ...string) string {
+		repo.write("configs/"+msg+".yaml", "x: 1\n")
+		repo.git("add", "-A")
+		repo.git(append(append([]string{"-c", "gpg.program=gpg"}, sign...), "commit", "-q", "-m", msg)...)
+		return repo.git("rev-parse", "HEAD")
+	}
+	sshSign := func(key string) []string {
+		return []string{"-c", "gpg.format=ssh", "-c", "user.signingkey=" + key, "-c", "commit.gpgsign=true"}
+	}
+	pgpSign := func(uid string) []string {
+		return []string{"-c", "user.signingkey=" + uid, "-c", "commit.gpgsign=true"}
+	}
+
+	store, err := OpenObjectStore(filepath.Join(repo.path, ".git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	for _, tt := range []struct {
+		name, commit string
+		ring         *Keyring
+		signer       string
+		err          error
+	}{
+		{"unsigned", commit("unsigned"), &kr, "", ErrUnsigned},
+		{"openpgp ed25519", commit("ada", pgpSign("ada@example.com")...), &kr, "Ada", nil},
+		{"openpgp rsa", commit("bob", pgpSign("bob@example.com")...), &bobRing, "Bob", nil},
+		{"openpgp unknown", repo.git("rev-parse", "HEAD"), &kr, "", ErrUnknownSigner},
+		{"ssh rsa", commit("rsa", sshSign(rsaKey)...), &kr, "ops-rsa", nil},
+		{"ssh ed25519", commit("ed", sshSign(edKey)...), &kr, "ops-ed25519", nil},
+		{"ssh ecdsa", commit("ec", sshSign(ecKey)...), &kr, "ops-ecdsa", nil},
+		{"ssh unknown", repo.git("rev-parse", "HEAD"), &bobRing, "", ErrUnknownSigner},
+	} {
+		c, err := store.Commit(tt.commit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := tt.ring.VerifyCommit(c)
+		if !errors.Is(err, tt.err) || (key != nil && key.Name != tt.signer) {
+			t.Errorf("%s: key = %+v, err = %v", tt.name, key, err)
+		}
+	}
+
+	// Changing any signed byte breaks the signature
+	for _, id := range []string{repo.git("rev-parse", "HEAD~1"), repo.git("rev-parse", "HEAD~4")} {
+		obj, err := store.Read(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tampered, err := ParseCommit(id, append(obj.Data[:len(obj.Data):len(obj.Data)], "extra\n"...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kr.VerifyCommit(tampered); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("tampered %.8s: err = %v", id, err)
+		}
+	}
+}
+
+func TestParsePackRejectsImpossibleCount(t *testing.T) {
+	// A valid header and checksum claiming 2^32-1 objects in an empty body
+	pack := append([]byte("PACK"), 0, 0, 0, 2, 0xff, 0xff, 0xff, 0xff)
+	sum := sha1.Sum(pack)
+	pack = append(pack, sum[:]...)
+
+	var s ObjectStore
+	if _, err := s.parsePack(pack); err == nil || !strings.Contains(err.Error(), "claims") {
+		t.Fatalf("parsePack = %v", err)
+	}
+}
+
+// testPack frames entries as a version 2 pack with a valid checksum
+func testPack(count int, entries ...[]byte) []byte {
+	pack := append([]byte("PACK"), 0, 0, 0, 2, 0, 0, 0, byte(count))
+	for _, e := range entries {
+		pack = append(pack, e...)
+	}
+	sum := sha1.Sum(pack)
+	return append(pack, sum[:]...)
+}
+
+// testEntry encodes one undeltified entry declaring size bytes
+func testEntry(typ ObjectType, size uint64, data []byte) []byte {
+	c := byte(typ)<<4 | byte(size&0x0f)
+	size >>= 4
+	var e []byte
+	for ; size != 0; size >>= 7 {
+		e = append(e, c|0x80)
+		c = byte(size & 0x7f)
+	}
+	e = append(e, c)
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return append(e, buf.Bytes()...)
+}
+
+func TestParsePackRejectsBadSizes(t *testing.T) {
+	overlong := append([]byte{byte(ObjectBlob)<<4 | 0x8f}, bytes.Repeat([]byte{0xff}, 10)...)
+	for name, tc := range map[string]struct {
+		pack []byte
+		want string
+	}{
+		"larger than declared":  {testPack(1, testEntry(ObjectBlob, 3, []byte("0123456789"))), "size mismatch"},
+		"smaller than declared": {testPack(1, testEntry(ObjectBlob, 30, []byte("0123456789"))), "size mismatch"},
+		"beyond the pack":       {testPack(1, testEntry(ObjectBlob, 1<<40, []byte("0123456789"))), "declares"},
+		"overlong size":         {testPack(1, append(overlong, 0x01, 0, 0)), "overflows"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var s ObjectStore
+			if _, err := s.parsePack(tc.pack); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("parsePack = %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	var s ObjectStore
+	objects, err := s.parsePack(testPack(1, testEntry(ObjectBlob, 10, []byte("0123456789"))))
+	if err != nil || len(objects) != 1 {
+		t.Fatalf("parsePack = %v, %v", objects, err)
+	}
+}
+
+func TestApplyDeltaRejectsBadSizes(t *testing.T) {
+	base := []byte("0123456789")
+	for name, tc := range map[string]struct {
+		delta []byte
+		want  string
+	}{
+		// src 10, dst 2^40, one copy
+		"target beyond delta": {[]byte{10, 0x80, 0x80, 0x80, 0x80, 0x80, 0x20, 0x90, 10}, "delta target"},
+		"overlong varint":     {append(bytes.Repeat([]byte{0xff}, 10), 0x01), "overflows"},
+		"insert past target":  {[]byte{10, 2, 3, 'a', 'b', 'c'}, "overruns"},
+		"copy past target":    {[]byte{10, 2, 0x90, 10}, "overruns"},
+		"copy out of base":    {[]byte{10, 20, 0x90, 20}, "out of range"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := applyDelta(base, tc.delta); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("applyDelta = %v, want %q", err, tc.want)
+			}
+		})
+	}
+
+	// src 10, dst 5: copy 3 from offset 2, insert "ab"
+	out, err := applyDelta(base, []byte{10, 5, 0x91, 2, 3, 2, 'a', 'b'})
+	if err != nil || string(out) != "234ab" {
+		t.Fatalf("applyDelta = %q, %v", out, err)
+	}
+}
+
+func FuzzApplyDelta(f *testing.F) {
+	f.Add([]byte("0123456789"), []byte{10, 5, 0x91, 2, 3, 2, 'a', 'b'})
+	f.Add([]byte("0123456789"), []byte{10, 0x80, 0x80, 0x80, 0x80, 0x80, 0x20, 0x90, 10})
+	f.Fuzz(func(t *testing.T, base, delta []byte) {
+		out, err := applyDelta(base, delta)
+		if err == nil && len(out) > len(delta)*maxDeltaCopy {
+			t.Fatalf("%d byte target from a %d byte delta", len(out), len(delta))
+		}
+	})
+}
+
+func FuzzParsePack(f *testing.F) {
+	f.Add(testPack(1, testEntry(ObjectBlob, 10, []byte("0123456789"))))
+	f.Add(testPack(1, testEntry(ObjectBlob, 1<<40, []byte("0123456789"))))
+	f.Fuzz(func(t *testing.T, pack []byte) {
+		if len(pack) < 32 {
+			return
+		}
+		// Re-sign the body so inputs get past the checksum
+		body := pack[:len(pack)-20]
+		sum := sha1.Sum(body)
+		copy(pack[len(pack)-20:], sum[:])
+		var s ObjectStore
+		s.parsePack(pack)
+	})
+}
@@ -0,0 +1,260 @@
+package gitnative
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrObjectNotFound is returned when an object is in neither the loose
+// object directory nor any pack
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectType is the type of a git object
+type ObjectType int
+
+const (
+	ObjectCommit ObjectType = 1
+	ObjectTree   ObjectType = 2
+	ObjectBlob   ObjectType = 3
+	ObjectTag    ObjectType = 4
+)
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjectCommit:
+		return "commit"
+	case ObjectTree:
+		return "tree"
+	case ObjectBlob:
+		return "blob"
+	case ObjectTag:
+		return "tag"
+	}
+	return "unknown"
+}
+
+func parseObjectType(s string) (ObjectType, error) {
+	switch s {
+	case "commit":
+		return ObjectCommit, nil
+	case "tree":
+		return ObjectTree, nil
+	case "blob":
+		return ObjectBlob, nil
+	case "tag":
+		return ObjectTag, nil
+	}
+	return 0, fmt.Errorf("unknown object type %q", s)
+}
+
+// Object is a decompressed, fully resolved object. Data must not be
+// modified: objects are shared through the store's cache.
+type Object struct {
+	ID   string
+	Type ObjectType
+	Data []byte
+}
+
+// Signature is the identity and time on an author or committer line
+type Signature struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	When  time.Time `json:"when"`
+}
+
+func (s Signature) String() string {
+	return fmt.Sprintf("%s <%s> %d %s", s.Name, s.Email, s.When.Unix(), s.When.Format("-0700"))
+}
+
+// parseSignature parses "Name <email> 1700000000 +0100"
+func parseSignature(line string) Signature {
+	var sig Signature
+	open, close := strings.IndexByte(line, '<'), strings.LastIndexByte(line, '>')
+	if open < 0 || close < open {
+		sig.Name = line
+		return sig
+	}
+	sig.Name = strings.TrimSpace(line[:open])
+	sig.Email = line[open+1 : close]
+
+	fields := strings.Fields(line[close+1:])
+	if len(fields) == 2 {
+		secs, _ := strconv.ParseInt(fields[0], 10, 64)
+		loc := time.UTC
+		if tz, err := time.Parse("-0700", fields[1]); err == nil {
+			_, offset := tz.Zone()
+			loc = time.FixedZone(fields[1], offset)
+		}
+		sig.When = time.Unix(secs, 0).In(loc)
+	}
+	return sig
+}
+
+// Commit is a parsed commit object
+type Commit struct {
+	ID        string
+	Tree      string
+	Parents   []string
+	Author    Signature
+	Committer Signature
+	Message   string
+	// GPGSig is the armored OpenPGP or SSH signature, empty if unsigned
+	GPGSig string
+
+	// payload is the object without its signature, which is what was signed
+	payload []byte
+}
+
+// SignedPayload returns the bytes the signature covers: the commit object
+// with its gpgsig header removed
+func (c *Commit) SignedPayload() []byte {
+	return c.payload
+}
+
+// ParseCommit parses the body of a commit object
+func ParseCommit(id string, data []byte) (*Commit, error) {
+	c := &Commit{ID: id}
+	headers, message, _ := bytes.Cut(data, []byte("\n\n"))
+	c.Message = string(message)
+
+	// Multi-line header values continue on lines starting with a space
+	var sig []string
+	inSig := false
+	for _, line := range strings.Split(string(headers), "\n") {
+		if cont, ok := strings.CutPrefix(line, " "); ok {
+			if inSig {
+				sig = append(sig, cont)
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		inSig = key == "gpgsig"
+		switch key {
+		case "tree":
+			c.Tree = value
+		case "parent":
+			c.Parents = append(c.Parents, value)
+		case "author":
+			c.Author = parseSignature(value)
+		case "committer":
+			c.Committer = parseSignature(value)
+		case "gpgsig":
+			sig = append(sig, value)
+		}
+	}
+	if !isHexSHA(c.Tree) {
+		return nil, fmt.Errorf("commit %s: missing tree", short(id))
+	}
+	c.payload = data
+	if sig != nil {
+		c.GPGSig = strings.Join(sig, "\n") + "\n"
+		c.payload = stripHeader(data, "gpgsig")
+	}
+	return c, nil
+}
+
+// stripHeader removes a header and its continuation lines from an object
+func stripHeader(data []byte, key string) []byte {
+	headers, message, _ := bytes.Cut(data, []byte("\n\n"))
+	var out bytes.Buffer
+	skipping := false
+	for _, line := range bytes.SplitAfter(headers, []byte("\n")) {
+		if len(line) > 0 && line[0] == ' ' {
+			if !skipping {
+				out.Write(line)
+			}
+			continue
+		}
+		skipping = bytes.HasPrefix(line, []byte(key+" "))
+		if !skipping {
+			out.Write(line)
+		}
+	}
+	if b := out.Bytes(); len(b) > 0 && b[len(b)-1] != '\n' {
+		out.WriteByte('\n')
+	}
+	out.WriteByte('\n')
+	out.Write(message)
+	return out.Bytes()
+}
+
+// TreeEntry is one entry of a tree object
+type TreeEntry struct {
+	Mode uint32
+	Name string
+	ID   string
+}
+
+const (
+	modeTree    = 0o040000
+	modeGitlink = 0o160000
+)
+
+// IsTree reports whether the entry is a subdirectory
+func (e TreeEntry) IsTree() bool {
+	return e.Mode == modeTree
+}
+
+// ParseTree parses the body of a tree object
+func ParseTree(id string, data []byte) ([]TreeEntry, error) {
+	var entries []TreeEntry
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || len(data) < nul+21 {
+			return nil, fmt.Errorf("tree %s: malformed entry", short(id))
+		}
+		mode, err := strconv.ParseUint(string(data[:sp]), 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("tree %s: bad mode %q", short(id), data[:sp])
+		}
+		entries = append(entries, TreeEntry{
+			Mode: uint32(mode),
+			Name: string(data[sp+1 : nul]),
+			ID:   hex.EncodeToString(data[nul+1 : nul+21]),
+		})
+		data = data[nul+21:]
+	}
+	return entries, nil
+}
+
+// Tag is a parsed annotated tag object
+type Tag struct {
+	ID     string
+	Object string
+	Type   ObjectType
+	Name   string
+	Tagger Signature
+}
+
+// ParseTag parses the body of an annotated tag object
+func ParseTag(id string, data []byte) (*Tag, error) {
+	t := &Tag{ID: id}
+	headers, _, _ := bytes.Cut(data, []byte("\n\n"))
+	for _, line := range strings.Split(string(headers), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "object":
+			t.Object = value
+		case "type":
+			typ, err := parseObjectType(value)
+			if err != nil {
+				return nil, fmt.Errorf("tag %s: %w", short(id), err)
+			}
+			t.Type = typ
+		case "tag":
+			t.Name = value
+		case "tagger":
+			t.Tagger = parseSignature(value)
+		}
+	}
+	if !isHexSHA(t.Object) {
+		return nil, fmt.Errorf("tag %s: missing object", short(id))
+	}
+	return t, nil
+}
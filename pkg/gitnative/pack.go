@@ -0,0 +1,455 @@
+package gitnative
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	packOfsDelta = 6
+	packRefDelta = 7
+
+	// maxDeltaChain guards against corrupt packs with delta cycles
+	maxDeltaChain = 10000
+)
+
+// pack is one packfile and its version 2 index. The index is read into
+// memory; the pack is read on demand.
+type pack struct {
+	name    string
+	f       *os.File
+	ids     [][20]byte // sorted, as in the index
+	offsets []int64
+	fanout  [256]uint32
+}
+
+func openPack(idxPath string) (*pack, error) {
+	idx, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx) < 8+256*4 || !bytes.Equal(idx[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(idx[4:8]) != 2 {
+		return nil, fmt.Errorf("%s: unsupported pack index version", idxPath)
+	}
+
+	p := &pack{name: idxPath}
+	for i := range p.fanout {
+		p.fanout[i] = binary.BigEndian.Uint32(idx[8+i*4:])
+	}
+	n := int(p.fanout[255])
+	namesAt := 8 + 256*4
+	offsetsAt := namesAt + n*20 + n*4
+	largeAt := offsetsAt + n*4
+	if len(idx) < largeAt+40 {
+		return nil, fmt.Errorf("%s: truncated pack index", idxPath)
+	}
+
+	p.ids = make([][20]byte, n)
+	p.offsets = make([]int64, n)
+	for i := 0; i < n; i++ {
+		copy(p.ids[i][:], idx[namesAt+i*20:])
+		off := binary.BigEndian.Uint32(idx[offsetsAt+i*4:])
+		if off&0x80000000 != 0 {
+			at := largeAt + int(off&0x7fffffff)*8
+			if at+8 > len(idx) {
+				return nil, fmt.Errorf("%s: bad large offset", idxPath)
+			}
+			p.offsets[i] = int64(binary.BigEndian.Uint64(idx[at:]))
+		} else {
+			p.offsets[i] = int64(off)
+		}
+	}
+
+	packPath := strings.TrimSuffix(idxPath, ".idx") + ".pack"
+	if p.f, err = os.Open(packPath); err != nil {
+		return nil, err
+	}
+	var header [12]byte
+	if _, err := p.f.ReadAt(header[:], 0); err != nil || !bytes.Equal(header[:4], []byte("PACK")) {
+		p.f.Close()
+		return nil, fmt.Errorf("%s: not a packfile", packPath)
+	}
+	return p, nil
+}
+
+func (p *pack) close() error {
+	return p.f.Close()
+}
+
+// find returns the offset of id in the pack
+func (p *pack) find(id [20]byte) (int64, bool) {
+	lo := 0
+	if id[0] > 0 {
+		lo = int(p.fanout[id[0]-1])
+	}
+	hi := int(p.fanout[id[0]])
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.ids[lo+i][:], id[:]) >= 0
+	})
+	if i < hi && p.ids[i] == id {
+		return p.offsets[i], true
+	}
+	return 0, false
+}
+
+// withPrefix returns the IDs starting with the hex prefix
+func (p *pack) withPrefix(prefix string) []string {
+	var out []string
+	first, err := hex.DecodeString(prefix[:2])
+	if err != nil {
+		return nil
+	}
+	lo := 0
+	if first[0] > 0 {
+		lo = int(p.fanout[first[0]-1])
+	}
+	for i := lo; i < int(p.fanout[first[0]]); i++ {
+		id := hex.EncodeToString(p.ids[i][:])
+		if strings.HasPrefix(id, prefix) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// entry reads the object header at offset: its type, inflated size and the
+// offset of its (compressed) data. Delta entries also return their base.
+type packEntry struct {
+	typ        int
+	size       int64
+	dataOffset int64
+	baseOffset int64    // OFS_DELTA
+	baseID     [20]byte // REF_DELTA
+}
+
+func (p *pack) entry(offset int64) (packEntry, error) {
+	r := bufio.NewReader(io.NewSectionReader(p.f, offset, 64))
+	read := int64(0)
+	next := func() (byte, error) {
+		read++
+		return r.ReadByte()
+	}
+
+	c, err := next()
+	if err != nil {
+		return packEntry{}, err
+	}
+	e := packEntry{typ: int(c>>4) & 7, size: int64(c & 0x0f)}
+	for shift := uint(4); c&0x80 != 0; shift += 7 {
+		if c, err = next(); err != nil {
+			return packEntry{}, err
+		}
+		if shift > 63-7 {
+			return packEntry{}, errors.New("pack entry size overflows")
+		}
+		e.size |= int64(c&0x7f) << shift
+	}
+
+	switch e.typ {
+	case packOfsDelta:
+		if c, err = next(); err != nil {
+			return packEntry{}, err
+		}
+		rel := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = next(); err != nil {
+				return packEntry{}, err
+			}
+			rel = ((rel + 1) << 7) | int64(c&0x7f)
+		}
+		e.baseOffset = offset - rel
+	case packRefDelta:
+		if _, err := io.ReadFull(r, e.baseID[:]); err != nil {
+			return packEntry{}, err
+		}
+		read += 20
+	}
+	e.dataOffset = offset + read
+	return e, nil
+}
+
+// inflate decompresses size bytes starting at offset
+func (p *pack) inflate(offset, size int64) ([]byte, error) {
+	zr, err := zlib.NewReader(bufio.NewReader(io.NewSectionReader(p.f, offset, 1<<62)))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(zr, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Limits on what a fetched pack may expand to. Runtime repositories hold
+// definitions, not large assets, so these are far above any real object.
+const (
+	// maxObjectSize bounds one object, inflated or rebuilt from a delta
+	maxObjectSize = 256 << 20
+	// maxPackObjects bounds the objects one pack resolves to, in total
+	maxPackObjects = 1 << 30
+	// maxDeflateRatio bounds how much zlib can expand its input
+	maxDeflateRatio = 1032
+	// maxDeltaCopy is the most one delta copy instruction can produce
+	maxDeltaCopy = 0x10000
+)
+
+// applyDelta rebuilds a target object from base and a git delta
+func applyDelta(base, delta []byte) ([]byte, error) {
+	varint := func() (int, error) {
+		n, shift := 0, uint(0)
+		for {
+			if len(delta) == 0 {
+				return 0, errors.New("truncated delta header")
+			}
+			c := delta[0]
+			delta = delta[1:]
+			if shift > 63-7 {
+				return 0, errors.New("delta size overflows")
+			}
+			n |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				return n, nil
+			}
+		}
+	}
+
+	srcSize, err := varint()
+	if err != nil {
+		return nil, err
+	}
+	if srcSize != len(base) {
+		return nil, fmt.Errorf("delta base size %d, have %d", srcSize, len(base))
+	}
+	dstSize, err := varint()
+	if err != nil {
+		return nil, err
+	}
+	// Each remaining byte of the delta yields at most one full copy
+	if dstSize > maxObjectSize || dstSize > len(delta)*maxDeltaCopy {
+		return nil, fmt.Errorf("delta target of %d bytes from a %d byte delta", dstSize, len(delta))
+	}
+
+	out := make([]byte, 0, dstSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			var off, n int
+			for i := uint(0); i < 4; i++ {
+				if op&(1<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, errors.New("truncated delta copy")
+					}
+					off |= int(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			for i := uint(0); i < 3; i++ {
+				if op&(0x10<<i) != 0 {
+					if len(delta) == 0 {
+						return nil, errors.New("truncated delta copy")
+					}
+					n |= int(delta[0]) << (8 * i)
+					delta = delta[1:]
+				}
+			}
+			if n == 0 {
+				n = 0x10000
+			}
+			if off+n > len(base) {
+				return nil, errors.New("delta copy out of range")
+			}
+			if len(out)+n > dstSize {
+				return nil, errors.New("delta overruns its target size")
+			}
+			out = append(out, base[off:off+n]...)
+		case op != 0:
+			if int(op) > len(delta) {
+				return nil, errors.New("truncated delta insert")
+			}
+			if len(out)+int(op) > dstSize {
+				return nil, errors.New("delta overruns its target size")
+			}
+			out = append(out, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, errors.New("reserved delta opcode")
+		}
+	}
+	if len(out) != dstSize {
+		return nil, fmt.Errorf("delta produced %d bytes, want %d", len(out), dstSize)
+	}
+	return out, nil
+}
+
+// minPackEntry is a lower bound on the encoded size of one pack entry
+const minPackEntry = 2
+
+// parsePack decodes a complete pack stream, as received from a fetch, into
+// objects keyed by ID. A REF_DELTA base may be anywhere in the pack or, for
+// thin packs, already in the store.
+func (s *ObjectStore) parsePack(data []byte) (map[string]*Object, error) {
+	if len(data) < 32 || string(data[:4]) != "PACK" {
+		return nil, errors.New("pack: bad signature")
+	}
+	if v := binary.BigEndian.Uint32(data[4:8]); v != 2 && v != 3 {
+		return nil, fmt.Errorf("pack: unsupported version %d", v)
+	}
+	body := data[:len(data)-20]
+	if sum := sha1.Sum(body); !bytes.Equal(sum[:], data[len(data)-20:]) {
+		return nil, errors.New("pack: checksum mismatch")
+	}
+	count := int(binary.BigEndian.Uint32(data[8:12]))
+	// Every entry takes at least a header byte and a zlib stream, so a
+	// count the body cannot hold is a bad (or hostile) pack, not a reason
+	// to allocate for four billion entries
+	if count > (len(body)-12)/minPackEntry {
+		return nil, fmt.Errorf("pack: header claims %d objects in %d bytes", count, len(body))
+	}
+
+	type rawEntry struct {
+		typ        int
+		data       []byte
+		baseOffset int64
+		baseID     string
+	}
+	entries := make([]rawEntry, 0, count)
+	byOffset := map[int64]int{}
+	r := bytes.NewReader(body[12:])
+	for i := 0; i < count; i++ {
+		offset := int64(len(body) - r.Len())
+		byOffset[offset] = i
+
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("pack: entry %d: %w", i, err)
+		}
+		e := rawEntry{typ: int(c>>4) & 7}
+		size := int64(c & 0x0f)
+		for shift := uint(4); c&0x80 != 0; shift += 7 {
+			if c, err = r.ReadByte(); err != nil {
+				return nil, fmt.Errorf("pack: entry %d: %w", i, err)
+			}
+			if shift > 63-7 {
+				return nil, fmt.Errorf("pack: entry %d: size overflows", i)
+			}
+			size |= int64(c&0x7f) << shift
+		}
+		switch e.typ {
+		case packOfsDelta:
+			if c, err = r.ReadByte(); err != nil {
+				return nil, fmt.Errorf("pack: entry %d: %w", i, err)
+			}
+			rel := int64(c & 0x7f)
+			for c&0x80 != 0 {
+				if c, err = r.ReadByte(); err != nil {
+					return nil, fmt.Errorf("pack: entry %d: %w", i, err)
+				}
+				rel = ((rel + 1) << 7) | int64(c&0x7f)
+			}
+			e.baseOffset = offset - rel
+		case packRefDelta:
+			var id [20]byte
+			if _, err := io.ReadFull(r, id[:]); err != nil {
+				return nil, fmt.Errorf("pack: entry %d: %w", i, err)
+			}
+			e.baseID = hex.EncodeToString(id[:])
+		}
+
+		// The declared size is only allocated once the bytes left could
+		// inflate to it
+		if size > maxObjectSize || size > int64(r.Len())*maxDeflateRatio {
+			return nil, fmt.Errorf("pack: entry %d: declares %d bytes with %d left", i, size, r.Len())
+		}
+
+		// bytes.Reader is an io.ByteReader, so zlib stops exactly at the
+		// end of this entry's stream
+		zr, err := zlib.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("pack: entry %d: %w", i, err)
+		}
+		e.data = make([]byte, size)
+		if _, err := io.ReadFull(zr, e.data); err != nil {
+			return nil, fmt.Errorf("pack: entry %d: size mismatch: %w", i, err)
+		}
+		// Reading to the end checks the stream's checksum and that it
+		// holds nothing beyond the declared size
+		if n, err := io.ReadFull(zr, make([]byte, 1)); n != 0 || err != io.EOF {
+			return nil, fmt.Errorf("pack: entry %d: size mismatch", i)
+		}
+		zr.Close()
+		entries = append(entries, e)
+	}
+
+	// Resolve deltas; a base normally precedes its deltas, so this is
+	// usually a single pass
+	resolved := make([]*Object, len(entries))
+	objects := make(map[string]*Object, len(entries))
+	var total int64
+	for pending := len(entries); pending > 0; {
+		progressed := false
+		for i, e := range entries {
+			if resolved[i] != nil {
+				continue
+			}
+			var obj *Object
+			switch e.typ {
+			case int(ObjectCommit), int(ObjectTree), int(ObjectBlob), int(ObjectTag):
+				obj = &Object{Type: ObjectType(e.typ), Data: e.data}
+			case packOfsDelta, packRefDelta:
+				var base *Object
+				if e.typ == packOfsDelta {
+					j, ok := byOffset[e.baseOffset]
+					if !ok {
+						return nil, fmt.Errorf("pack: entry %d: delta base not in pack", i)
+					}
+					base = resolved[j]
+				} else if base = objects[e.baseID]; base == nil && s.exists(e.baseID) {
+					var err error
+					if base, err = s.Read(e.baseID); err != nil {
+						return nil, err
+					}
+				}
+				if base == nil {
+					continue
+				}
+				// Small deltas can each copy a large base many times over,
+				// so the rebuilt objects share one budget
+				if total > maxPackObjects {
+					return nil, fmt.Errorf("pack: objects exceed %d bytes", maxPackObjects)
+				}
+				data, err := applyDelta(base.Data, e.data)
+				if err != nil {
+					return nil, fmt.Errorf("pack: entry %d: %w", i, err)
+				}
+				obj = &Object{Type: base.Type, Data: data}
+			default:
+				return nil, fmt.Errorf("pack: entry %d: unknown type %d", i, e.typ)
+			}
+			total += int64(len(obj.Data))
+			obj.ID = hashObject(obj.Type, obj.Data)
+			resolved[i] = obj
+			objects[obj.ID] = obj
+			pending--
+			progressed = true
+		}
+		if !progressed {
+			return nil, fmt.Errorf("pack: %d deltas have no base", pending)
+		}
+	}
+	return objects, nil
+}
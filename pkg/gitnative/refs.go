@@ -0,0 +1,197 @@
+// Package gitnative is the git-native runtime layer: it reads the runtime
+// repository directly and turns commits into configuration changes.
+package gitnative
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrRefNotFound is returned when a ref does not exist in the repository
+var ErrRefNotFound = errors.New("ref not found")
+
+// GitDir returns the git directory for repoPath. It accepts a working tree
+// (with a .git directory or a "gitdir:" file) or a bare repository.
+func GitDir(repoPath string) (string, error) {
+	dotGit := filepath.Join(repoPath, ".git")
+	info, err := os.Stat(dotGit)
+	switch {
+	case err == nil && info.IsDir():
+		return dotGit, nil
+	case err == nil:
+		data, err := os.ReadFile(dotGit)
+		if err != nil {
+			return "", err
+		}
+		line := strings.TrimSpace(string(data))
+		if !strings.HasPrefix(line, "gitdir: ") {
+			return "", fmt.Errorf("%s: unrecognised .git file", repoPath)
+		}
+		dir := strings.TrimPrefix(line, "gitdir: ")
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(repoPath, dir)
+		}
+		return dir, nil
+	}
+
+	if _, err := os.Stat(filepath.Join(repoPath, "HEAD")); err == nil {
+		if _, err := os.Stat(filepath.Join(repoPath, "objects")); err == nil {
+			return repoPath, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a git repository", repoPath)
+}
+
+// ReadHEAD returns the commit HEAD points at and, unless HEAD is detached,
+// the branch ref it goes through (e.g. refs/heads/main).
+func ReadHEAD(gitDir string) (sha, ref string, err error) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", "", fmt.Errorf("read HEAD: %w", err)
+	}
+	head := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(head, "ref: ") {
+		if !isHexSHA(head) {
+			return "", "", fmt.Errorf("HEAD: malformed value %q", head)
+		}
+		return head, "", nil
+	}
+
+	ref = strings.TrimPrefix(head, "ref: ")
+	sha, err = ResolveRef(gitDir, ref)
+	return sha, ref, err
+}
+
+// ResolveRef resolves a full ref name (refs/heads/main, refs/tags/v1) or
+// HEAD to a commit SHA, following symbolic refs and packed-refs.
+func ResolveRef(gitDir, name string) (string, error) {
+	if name == "HEAD" {
+		sha, _, err := ReadHEAD(gitDir)
+		return sha, err
+	}
+
+	for depth := 0; depth < 10; depth++ {
+		data, err := os.ReadFile(filepath.Join(gitDir, filepath.FromSlash(name)))
+		if errors.Is(err, os.ErrNotExist) {
+			refs, err := PackedRefs(gitDir)
+			if err != nil {
+				return "", err
+			}
+			if sha, ok := refs[name]; ok {
+				return sha, nil
+			}
+			return "", fmt.Errorf("%w: %s", ErrRefNotFound, name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", name, err)
+		}
+
+		value := strings.TrimSpace(string(data))
+		if strings.HasPrefix(value, "ref: ") {
+			name = strings.TrimPrefix(value, "ref: ")
+			continue
+		}
+		if !isHexSHA(value) {
+			return "", fmt.Errorf("%s: malformed value %q", name, value)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("%s: symbolic ref loop", name)
+}
+
+// PackedRefs parses packed-refs. Peeled tag lines (^sha) are skipped so
+// tags resolve to the tag object, as loose refs do.
+func PackedRefs(gitDir string) (map[string]string, error) {
+	refs := map[string]string{}
+	f, err := os.Open(filepath.Join(gitDir, "packed-refs"))
+	if errors.Is(err, os.ErrNotExist) {
+		return refs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read packed-refs: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		sha, name, ok := strings.Cut(line, " ")
+		if ok && isHexSHA(sha) {
+			refs[name] = sha
+		}
+	}
+	return refs, scanner.Err()
+}
+
+// ListRefs returns every branch and tag with its SHA, loose refs taking
+// precedence over packed ones
+func ListRefs(gitDir string) (map[string]string, error) {
+	refs, err := PackedRefs(gitDir)
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Join(gitDir, "refs")
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(path, ".lock") {
+			return nil
+		}
+		rel, err := filepath.Rel(gitDir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		sha, err := ResolveRef(gitDir, name)
+		if err != nil {
+			// A ref being rewritten can vanish between the walk and the read
+			if errors.Is(err, ErrRefNotFound) {
+				return nil
+			}
+			return err
+		}
+		refs[name] = sha
+		return nil
+	})
+	return refs, err
+}
+
+// MatchRef reports whether ref equals or matches (with path.Match) any of
+// patterns, e.g. refs/heads/customer/*
+func MatchRef(patterns []string, ref string) bool {
+	for _, pattern := range patterns {
+		if pattern == ref {
+			return true
+		}
+		if ok, _ := path.Match(pattern, ref); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isHexSHA(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
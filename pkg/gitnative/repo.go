@@ -0,0 +1,167 @@
+package gitnative
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrFileNotFound is returned when a path does not exist at a commit
+var ErrFileNotFound = errors.New("file not found")
+
+// Repo reads files at arbitrary commits straight from the object database,
+// without a working-tree checkout or the git binary
+type Repo struct {
+	gitDir  string
+	objects *ObjectStore
+}
+
+// OpenRepo opens the repository at path (working tree or bare)
+func OpenRepo(path string) (*Repo, error) {
+	gitDir, err := GitDir(path)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := OpenObjectStore(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{gitDir: gitDir, objects: objects}, nil
+}
+
+// Close releases open pack files
+func (r *Repo) Close() error {
+	return r.objects.Close()
+}
+
+// GitDir returns the repository's git directory
+func (r *Repo) GitDir() string {
+	return r.gitDir
+}
+
+// Objects returns the repository's object store
+func (r *Repo) Objects() *ObjectStore {
+	return r.objects
+}
+
+// Resolve returns the commit a revision names: a full or abbreviated SHA,
+// HEAD, a branch, a tag (peeled to its commit) or a full ref name. Names are
+// tried in git's order: refs/<rev>, refs/tags, refs/heads, refs/remotes.
+func (r *Repo) Resolve(ctx context.Context, rev string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	// Ref names become file paths below the git dir, so refuse anything
+	// git itself would not accept as a ref name
+	if rev == "" || strings.Contains(rev, "..") || strings.ContainsAny(rev, "\\ :~^?*[") || strings.HasPrefix(rev, "/") || strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("%w: %q", ErrRefNotFound, rev)
+	}
+
+	candidates := []string{"refs/" + rev, "refs/tags/" + rev, "refs/heads/" + rev, "refs/remotes/" + rev, "refs/remotes/" + rev + "/HEAD"}
+	if rev == "HEAD" || strings.HasPrefix(rev, "refs/") {
+		candidates = append([]string{rev}, candidates...)
+	}
+	id := ""
+	for _, name := range candidates {
+		if sha, err := ResolveRef(r.gitDir, name); err == nil {
+			id = sha
+			break
+		}
+	}
+	if id == "" {
+		expanded, err := r.objects.Expand(rev)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrRefNotFound, rev)
+		}
+		id = expanded
+	}
+
+	obj, err := r.objects.Peel(id)
+	if errors.Is(err, ErrObjectNotFound) {
+		return "", fmt.Errorf("%w: %s", ErrRefNotFound, rev)
+	}
+	if err != nil {
+		return "", err
+	}
+	if obj.Type != ObjectCommit {
+		return "", fmt.Errorf("%s names a %s, not a commit", rev, obj.Type)
+	}
+	return obj.ID, nil
+}
+
+// Commit reads a commit object
+func (r *Repo) Commit(id string) (*Commit, error) {
+	return r.objects.Commit(id)
+}
+
+// ListFiles returns every file path in the tree of commit
+func (r *Repo) ListFiles(ctx context.Context, commit string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tree, err := r.objects.commitTree(commit)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	err = r.objects.walkTree(tree, "", func(p string, _ TreeEntry) error {
+		paths = append(paths, p)
+		return ctx.Err()
+	})
+	return paths, err
+}
+
+// Blobs maps every file path in the tree of commit to its blob ID, which
+// is enough to tell whether a file differs between commits
+func (r *Repo) Blobs(ctx context.Context, commit string) (map[string]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tree, err := r.objects.commitTree(commit)
+	if err != nil {
+		return nil, err
+	}
+	blobs := map[string]string{}
+	err = r.objects.walkTree(tree, "", func(p string, e TreeEntry) error {
+		blobs[p] = e.ID
+		return ctx.Err()
+	})
+	return blobs, err
+}
+
+// ReadFile returns the contents of path at commit
+func (r *Repo) ReadFile(ctx context.Context, commit, path string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tree, err := r.objects.commitTree(commit)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := r.objects.lookup(tree, path)
+	if errors.Is(err, ErrFileNotFound) || err == nil && (entry.IsTree() || entry.Mode == modeGitlink) {
+		return nil, fmt.Errorf("%w: %s at %s", ErrFileNotFound, path, short(commit))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.objects.Blob(entry.ID)
+}
+
+// Diff lists the paths changed between two commits. An empty oldSHA
+// compares against the empty tree, so every file in newSHA is added.
+func (r *Repo) Diff(ctx context.Context, oldSHA, newSHA string) ([]Change, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	oldTree, err := r.objects.commitTree(oldSHA)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := r.objects.commitTree(newSHA)
+	if err != nil {
+		return nil, err
+	}
+	return r.objects.diffTrees(oldTree, newTree)
+}
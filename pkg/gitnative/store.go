@@ -0,0 +1,413 @@
+package gitnative
+
+import (
+	"bytes"
+	"compress/zlib"
+	"container/list"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultCacheBytes is the object cache budget of a store opened with
+// OpenObjectStore
+const DefaultCacheBytes = 64 << 20
+
+// ObjectStore reads objects straight from a repository's object database
+// (loose objects and packs) without the git binary. Resolved objects are
+// cached by ID, so repeatedly loading the same trees and blobs is cheap.
+// It is safe for concurrent use.
+type ObjectStore struct {
+	dir string
+
+	mu    sync.Mutex
+	packs map[string]*pack // keyed by .idx path
+
+	cache *objectCache
+}
+
+// OpenObjectStore opens the object database of gitDir
+func OpenObjectStore(gitDir string) (*ObjectStore, error) {
+	s := &ObjectStore{
+		dir:   filepath.Join(gitDir, "objects"),
+		packs: map[string]*pack{},
+		cache: newObjectCache(DefaultCacheBytes),
+	}
+	if _, err := os.Stat(s.dir); err != nil {
+		return nil, fmt.Errorf("open object store: %w", err)
+	}
+	if err := s.rescan(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close releases the pack file handles
+func (s *ObjectStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var first error
+	for name, p := range s.packs {
+		if err := p.close(); err != nil && first == nil {
+			first = err
+		}
+		delete(s.packs, name)
+	}
+	return first
+}
+
+// rescan opens packs added since the last scan (by fetch or gc) and drops
+// packs that were removed
+func (s *ObjectStore) rescan() error {
+	idxs, err := filepath.Glob(filepath.Join(s.dir, "pack", "*.idx"))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	present := map[string]bool{}
+	for _, idx := range idxs {
+		present[idx] = true
+		if _, ok := s.packs[idx]; ok {
+			continue
+		}
+		p, err := openPack(idx)
+		if err != nil {
+			// A pack being written has its .idx renamed into place last,
+			// but be lenient with stray files
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		s.packs[idx] = p
+	}
+	for name, p := range s.packs {
+		if !present[name] {
+			p.close()
+			delete(s.packs, name)
+		}
+	}
+	return nil
+}
+
+func (s *ObjectStore) packList() []*pack {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.packs))
+	for name := range s.packs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]*pack, len(names))
+	for i, name := range names {
+		out[i] = s.packs[name]
+	}
+	return out
+}
+
+// Read returns the object with the given full hex ID
+func (s *ObjectStore) Read(id string) (*Object, error) {
+	if !isHexSHA(id) {
+		return nil, fmt.Errorf("%w: %q is not an object ID", ErrObjectNotFound, id)
+	}
+	if obj, ok := s.cache.get(id); ok {
+		return obj, nil
+	}
+
+	obj, err := s.read(id)
+	if errors.Is(err, ErrObjectNotFound) {
+		// The object may have arrived in a new pack
+		if err := s.rescan(); err != nil {
+			return nil, err
+		}
+		obj, err = s.read(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(id, obj)
+	return obj, nil
+}
+
+func (s *ObjectStore) read(id string) (*Object, error) {
+	obj, err := s.readLoose(id)
+	if !errors.Is(err, os.ErrNotExist) {
+		return obj, err
+	}
+
+	var raw [20]byte
+	hex.Decode(raw[:], []byte(id))
+	for _, p := range s.packList() {
+		if offset, ok := p.find(raw); ok {
+			typ, data, err := s.readPacked(p, offset, 0)
+			if err != nil {
+				return nil, fmt.Errorf("object %s: %w", short(id), err)
+			}
+			return &Object{ID: id, Type: typ, Data: data}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, id)
+}
+
+func (s *ObjectStore) readLoose(id string) (*Object, error) {
+	f, err := os.Open(filepath.Join(s.dir, id[:2], id[2:]))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("object %s: %w", short(id), err)
+	}
+	defer zr.Close()
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("object %s: %w", short(id), err)
+	}
+
+	header, data, ok := bytes.Cut(raw, []byte{0})
+	typeName, sizeStr, ok2 := bytes.Cut(header, []byte(" "))
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("object %s: malformed header", short(id))
+	}
+	typ, err := parseObjectType(string(typeName))
+	if err != nil {
+		return nil, fmt.Errorf("object %s: %w", short(id), err)
+	}
+	if size, err := strconv.Atoi(string(sizeStr)); err != nil || size != len(data) {
+		return nil, fmt.Errorf("object %s: size mismatch", short(id))
+	}
+	return &Object{ID: id, Type: typ, Data: data}, nil
+}
+
+// readPacked resolves the object at offset, following delta chains. Delta
+// bases are cached by pack position since they are shared by many objects.
+func (s *ObjectStore) readPacked(p *pack, offset int64, depth int) (ObjectType, []byte, error) {
+	if depth > maxDeltaChain {
+		return 0, nil, errors.New("delta chain too long")
+	}
+	key := p.name + "@" + strconv.FormatInt(offset, 10)
+	if obj, ok := s.cache.get(key); ok {
+		return obj.Type, obj.Data, nil
+	}
+
+	e, err := p.entry(offset)
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := p.inflate(e.dataOffset, e.size)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var typ ObjectType
+	switch e.typ {
+	case int(ObjectCommit), int(ObjectTree), int(ObjectBlob), int(ObjectTag):
+		typ = ObjectType(e.typ)
+	case packOfsDelta, packRefDelta:
+		var base []byte
+		if e.typ == packOfsDelta {
+			typ, base, err = s.readPacked(p, e.baseOffset, depth+1)
+		} else {
+			var obj *Object
+			obj, err = s.Read(hex.EncodeToString(e.baseID[:]))
+			if obj != nil {
+				typ, base = obj.Type, obj.Data
+			}
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("delta base: %w", err)
+		}
+		if data, err = applyDelta(base, data); err != nil {
+			return 0, nil, err
+		}
+	default:
+		return 0, nil, fmt.Errorf("unknown pack entry type %d", e.typ)
+	}
+
+	if depth > 0 {
+		s.cache.put(key, &Object{Type: typ, Data: data})
+	}
+	return typ, data, nil
+}
+
+// Has reports whether the object exists
+func (s *ObjectStore) Has(id string) bool {
+	_, err := s.Read(id)
+	return err == nil
+}
+
+// exists reports whether id is stored loose or in a known pack, without
+// reading it or rescanning for new packs
+func (s *ObjectStore) exists(id string) bool {
+	if _, ok := s.cache.get(id); ok {
+		return true
+	}
+	if _, err := os.Stat(filepath.Join(s.dir, id[:2], id[2:])); err == nil {
+		return true
+	}
+	var raw [20]byte
+	hex.Decode(raw[:], []byte(id))
+	for _, p := range s.packList() {
+		if _, ok := p.find(raw); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand turns an abbreviated hex ID (at least 4 characters) into the full
+// ID of the single object it matches
+func (s *ObjectStore) Expand(prefix string) (string, error) {
+	if isHexSHA(prefix) {
+		return prefix, nil
+	}
+	if len(prefix) < 4 || len(prefix) > 40 || !isHex(prefix) {
+		return "", fmt.Errorf("%w: %q", ErrObjectNotFound, prefix)
+	}
+
+	matches := map[string]bool{}
+	entries, _ := os.ReadDir(filepath.Join(s.dir, prefix[:2]))
+	for _, e := range entries {
+		if id := prefix[:2] + e.Name(); len(id) == 40 && id[:len(prefix)] == prefix {
+			matches[id] = true
+		}
+	}
+	for _, p := range s.packList() {
+		for _, id := range p.withPrefix(prefix) {
+			matches[id] = true
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: %s", ErrObjectNotFound, prefix)
+	case 1:
+		for id := range matches {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("short object ID %s is ambiguous", prefix)
+}
+
+// Commit reads and parses a commit
+func (s *ObjectStore) Commit(id string) (*Commit, error) {
+	obj, err := s.typed(id, ObjectCommit)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCommit(id, obj.Data)
+}
+
+// Tree reads and parses a tree
+func (s *ObjectStore) Tree(id string) ([]TreeEntry, error) {
+	obj, err := s.typed(id, ObjectTree)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTree(id, obj.Data)
+}
+
+// Blob reads a blob's contents
+func (s *ObjectStore) Blob(id string) ([]byte, error) {
+	obj, err := s.typed(id, ObjectBlob)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Data, nil
+}
+
+func (s *ObjectStore) typed(id string, want ObjectType) (*Object, error) {
+	obj, err := s.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type != want {
+		return nil, fmt.Errorf("object %s is a %s, not a %s", short(id), obj.Type, want)
+	}
+	return obj, nil
+}
+
+// Peel follows annotated tags until it reaches a non-tag object
+func (s *ObjectStore) Peel(id string) (*Object, error) {
+	for depth := 0; depth < 10; depth++ {
+		obj, err := s.Read(id)
+		if err != nil || obj.Type != ObjectTag {
+			return obj, err
+		}
+		tag, err := ParseTag(id, obj.Data)
+		if err != nil {
+			return nil, err
+		}
+		id = tag.Object
+	}
+	return nil, fmt.Errorf("tag %s: nested too deeply", short(id))
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// objectCache is an LRU of resolved objects bounded by total data size
+type objectCache struct {
+	mu    sync.Mutex
+	max   int
+	size  int
+	order *list.List // front is most recently used
+	items map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key string
+	obj *Object
+}
+
+func newObjectCache(maxBytes int) *objectCache {
+	return &objectCache{max: maxBytes, order: list.New(), items: map[string]*list.Element{}}
+}
+
+func (c *objectCache) get(key string) (*Object, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).obj, true
+}
+
+func (c *objectCache) put(key string, obj *Object) {
+	if len(obj.Data) > c.max/4 {
+		return // one huge blob should not flush everything else
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, obj: obj})
+	c.size += len(obj.Data)
+	for c.size > c.max {
+		el := c.order.Back()
+		e := el.Value.(*cacheEntry)
+		c.order.Remove(el)
+		delete(c.items, e.key)
+		c.size -= len(e.obj.Data)
+	}
+}
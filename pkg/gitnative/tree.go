@@ -0,0 +1,184 @@
+package gitnative
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// walkTree calls fn for every blob below tree, with paths relative to the
+// tree root. Submodule entries are skipped: their commits live elsewhere.
+func (s *ObjectStore) walkTree(treeID, prefix string, fn func(path string, e TreeEntry) error) error {
+	entries, err := s.Tree(treeID)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		p := path.Join(prefix, e.Name)
+		switch {
+		case e.IsTree():
+			if err := s.walkTree(e.ID, p, fn); err != nil {
+				return err
+			}
+		case e.Mode == modeGitlink:
+		default:
+			if err := fn(p, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lookup finds the entry for a slash-separated path below tree
+func (s *ObjectStore) lookup(treeID, p string) (TreeEntry, error) {
+	entry := TreeEntry{Mode: modeTree, ID: treeID}
+	for _, name := range strings.Split(strings.Trim(p, "/"), "/") {
+		if !entry.IsTree() {
+			return TreeEntry{}, ErrFileNotFound
+		}
+		entries, err := s.Tree(entry.ID)
+		if err != nil {
+			return TreeEntry{}, err
+		}
+		i := -1
+		for j := range entries {
+			if entries[j].Name == name {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			return TreeEntry{}, ErrFileNotFound
+		}
+		entry = entries[i]
+	}
+	return entry, nil
+}
+
+// diffTrees compares two trees (either may be empty) and returns the
+// changed blobs. Renames are detected when a deleted and an added path
+// have identical content.
+func (s *ObjectStore) diffTrees(oldTree, newTree string) ([]Change, error) {
+	var added, deleted []Change
+	var changes []Change
+	blobs := map[string]string{} // path -> blob ID for renames
+
+	var walk func(oldTree, newTree, prefix string) error
+	walk = func(oldTree, newTree, prefix string) error {
+		if oldTree == newTree {
+			return nil
+		}
+		oldEntries, err := s.treeEntries(oldTree)
+		if err != nil {
+			return err
+		}
+		newEntries, err := s.treeEntries(newTree)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for name := range oldEntries {
+			names = append(names, name)
+		}
+		for name := range newEntries {
+			if _, ok := oldEntries[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			o, inOld := oldEntries[name]
+			n, inNew := newEntries[name]
+			p := path.Join(prefix, name)
+			if inOld && inNew && o.ID == n.ID && o.Mode == n.Mode {
+				continue
+			}
+
+			oldSub, newSub := "", ""
+			if inOld && o.IsTree() {
+				oldSub = o.ID
+			}
+			if inNew && n.IsTree() {
+				newSub = n.ID
+			}
+			if oldSub != "" || newSub != "" {
+				if err := walk(oldSub, newSub, p); err != nil {
+					return err
+				}
+			}
+
+			oldBlob := inOld && !o.IsTree() && o.Mode != modeGitlink
+			newBlob := inNew && !n.IsTree() && n.Mode != modeGitlink
+			switch {
+			case oldBlob && newBlob:
+				changes = append(changes, Change{Type: ChangeModified, Kind: ClassifyPath(p), Path: p})
+			case oldBlob:
+				deleted = append(deleted, Change{Type: ChangeDeleted, Kind: ClassifyPath(p), Path: p})
+				blobs[p] = o.ID
+			case newBlob:
+				added = append(added, Change{Type: ChangeAdded, Kind: ClassifyPath(p), Path: p})
+				blobs[p] = n.ID
+			}
+		}
+		return nil
+	}
+	if err := walk(oldTree, newTree, ""); err != nil {
+		return nil, err
+	}
+
+	// Pair exact renames, each deleted path with at most one added path
+	byBlob := map[string][]int{}
+	for i, d := range deleted {
+		byBlob[blobs[d.Path]] = append(byBlob[blobs[d.Path]], i)
+	}
+	renamed := map[int]bool{}
+	for _, a := range added {
+		if idx := byBlob[blobs[a.Path]]; len(idx) > 0 {
+			d := deleted[idx[0]]
+			byBlob[blobs[a.Path]] = idx[1:]
+			renamed[idx[0]] = true
+			changes = append(changes, Change{Type: ChangeRenamed, Kind: a.Kind, Path: a.Path, OldPath: d.Path})
+			continue
+		}
+		changes = append(changes, a)
+	}
+	for i, d := range deleted {
+		if !renamed[i] {
+			changes = append(changes, d)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// treeEntries indexes a tree's entries by name; an empty ID is an empty tree
+func (s *ObjectStore) treeEntries(id string) (map[string]TreeEntry, error) {
+	out := map[string]TreeEntry{}
+	if id == "" {
+		return out, nil
+	}
+	entries, err := s.Tree(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		out[e.Name] = e
+	}
+	return out, nil
+}
+
+// commitTree returns the tree of a commit, or "" for an empty commit ID
+func (s *ObjectStore) commitTree(commit string) (string, error) {
+	if commit == "" {
+		return "", nil
+	}
+	c, err := s.Commit(commit)
+	if err != nil {
+		return "", fmt.Errorf("read commit %s: %w", short(commit), err)
+	}
+	return c.Tree, nil
+}
@@ -0,0 +1,663 @@
+package gitnative
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	_ "crypto/sha512" // registers crypto.SHA384 and SHA512
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Errors returned by VerifyCommit
+var (
+	ErrUnsigned      = errors.New("commit is not signed")
+	ErrUnknownSigner = errors.New("signing key is not trusted")
+	ErrBadSignature  = errors.New("bad signature")
+)
+
+// Signature formats git can produce that the keyring verifies
+const (
+	FormatOpenPGP = "openpgp"
+	FormatSSH     = "ssh"
+)
+
+// TrustedKey is a public key commits may be signed with
+type TrustedKey struct {
+	// Name identifies the key's owner in reports
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	// Fingerprint is the hex v4 fingerprint of an OpenPGP key or the
+	// SHA256:... fingerprint of an SSH key
+	Fingerprint string `json:"fingerprint"`
+
+	pub  crypto.PublicKey
+	blob []byte // SSH wire encoding
+}
+
+// Keyring is a set of trusted signing keys. It verifies OpenPGP signatures
+// made with RSA or Ed25519 keys and SSH signatures made with Ed25519, RSA
+// or ECDSA keys, which covers what gpg and ssh-keygen produce by default.
+// Expiry and revocation are not consulted: removing a key from the keyring
+// is how it is revoked.
+type Keyring struct {
+	keys []*TrustedKey
+}
+
+// Keys returns the keys in the order they were added
+func (k *Keyring) Keys() []*TrustedKey {
+	return append([]*TrustedKey(nil), k.keys...)
+}
+
+// AddSSHKey adds a key in authorized_keys format ("ssh-ed25519 AAAA...
+// comment")
+func (k *Keyring) AddSSHKey(name, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return fmt.Errorf("ssh key for %s: want \"<type> <base64>\"", name)
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return fmt.Errorf("ssh key for %s: %v", name, err)
+	}
+	pub, typ, err := parseSSHPublicKey(blob)
+	if err != nil {
+		return fmt.Errorf("ssh key for %s: %v", name, err)
+	}
+	if typ != fields[0] {
+		return fmt.Errorf("ssh key for %s: type %s does not match key data %s", name, fields[0], typ)
+	}
+	k.keys = append(k.keys, &TrustedKey{Name: name, Format: FormatSSH, Fingerprint: sshFingerprint(blob), pub: pub, blob: blob})
+	return nil
+}
+
+// AddOpenPGPKeys adds the primary key and subkeys of an armored public key
+// block. Keys with algorithms that cannot sign are skipped.
+func (k *Keyring) AddOpenPGPKeys(name, armored string) error {
+	data, err := dearmor(armored, "PGP PUBLIC KEY BLOCK")
+	if err != nil {
+		return fmt.Errorf("openpgp key for %s: %v", name, err)
+	}
+	packets, err := readPackets(data)
+	if err != nil {
+		return fmt.Errorf("openpgp key for %s: %v", name, err)
+	}
+	added := 0
+	for _, p := range packets {
+		if p.tag != pgpTagPublicKey && p.tag != pgpTagPublicSubkey {
+			continue
+		}
+		pub, err := parsePGPPublicKey(p.body)
+		if err != nil {
+			return fmt.Errorf("openpgp key for %s: %v", name, err)
+		}
+		if pub == nil {
+			continue
+		}
+		k.keys = append(k.keys, &TrustedKey{Name: name, Format: FormatOpenPGP, Fingerprint: pgpFingerprint(p.body), pub: pub})
+		added++
+	}
+	if added == 0 {
+		return fmt.Errorf("openpgp key for %s: no RSA or Ed25519 key in block", name)
+	}
+	return nil
+}
+
+// VerifyCommit checks c's signature and returns the key that made it
+func (k *Keyring) VerifyCommit(c *Commit) (*TrustedKey, error) {
+	switch {
+	case c.GPGSig == "":
+		return nil, ErrUnsigned
+	case strings.Contains(c.GPGSig, "-----BEGIN PGP SIGNATURE-----"):
+		return k.verifyOpenPGP(c.GPGSig, c.SignedPayload())
+	case strings.Contains(c.GPGSig, "-----BEGIN SSH SIGNATURE-----"):
+		return k.verifySSH(c.GPGSig, c.SignedPayload())
+	}
+	return nil, fmt.Errorf("%w: unsupported signature format", ErrBadSignature)
+}
+
+// dearmor decodes an ASCII-armored block of the given type, checking the
+// OpenPGP CRC-24 when present
+func dearmor(text, typ string) ([]byte, error) {
+	begin, end := "-----BEGIN "+typ+"-----", "-----END "+typ+"-----"
+	_, body, ok := strings.Cut(text, begin)
+	if ok {
+		body, _, ok = strings.Cut(body, end)
+	}
+	if !ok {
+		return nil, fmt.Errorf("no %s armor", typ)
+	}
+	var b64, crc string
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.Contains(line, ":"):
+			// blank line or armor header
+		case len(line) == 5 && line[0] == '=':
+			crc = line[1:]
+		default:
+			b64 += line
+		}
+	}
+	data, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, err
+	}
+	if crc != "" {
+		want, err := base64.StdEncoding.DecodeString(crc)
+		if err != nil || len(want) != 3 || crc24(data) != uint32(want[0])<<16|uint32(want[1])<<8|uint32(want[2]) {
+			return nil, errors.New("armor checksum mismatch")
+		}
+	}
+	return data, nil
+}
+
+func crc24(data []byte) uint32 {
+	crc := uint32(0xb704ce)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864cfb
+			}
+		}
+	}
+	return crc & 0xffffff
+}
+
+// SSH signatures (ssh-keygen -Y sign, PROTOCOL.sshsig)
+
+const sshSigMagic = "SSHSIG"
+
+func (k *Keyring) verifySSH(armored string, payload []byte) (*TrustedKey, error) {
+	blob, err := dearmor(armored, "SSH SIGNATURE")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	r := sshReader{data: blob}
+	magic := r.raw(len(sshSigMagic))
+	version := r.uint32()
+	pubBlob := r.string()
+	namespace := r.string()
+	reserved := r.string()
+	hashAlg := r.string()
+	sig := r.string()
+	if r.err != nil || string(magic) != sshSigMagic || version != 1 {
+		return nil, fmt.Errorf("%w: malformed ssh signature", ErrBadSignature)
+	}
+	if string(namespace) != "git" {
+		return nil, fmt.Errorf("%w: ssh signature namespace %q, want \"git\"", ErrBadSignature, namespace)
+	}
+
+	var key *TrustedKey
+	for _, tk := range k.keys {
+		if tk.Format == FormatSSH && bytes.Equal(tk.blob, pubBlob) {
+			key = tk
+			break
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: ssh key %s", ErrUnknownSigner, sshFingerprint(pubBlob))
+	}
+
+	var h crypto.Hash
+	switch string(hashAlg) {
+	case "sha256":
+		h = crypto.SHA256
+	case "sha512":
+		h = crypto.SHA512
+	default:
+		return nil, fmt.Errorf("%w: ssh signature hash %q", ErrBadSignature, hashAlg)
+	}
+	digest := h.New()
+	digest.Write(payload)
+
+	var signed bytes.Buffer
+	signed.WriteString(sshSigMagic)
+	for _, s := range [][]byte{namespace, reserved, hashAlg, digest.Sum(nil)} {
+		binary.Write(&signed, binary.BigEndian, uint32(len(s)))
+		signed.Write(s)
+	}
+	if err := verifySSHSignature(key.pub, signed.Bytes(), sig); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	return key, nil
+}
+
+func verifySSHSignature(pub crypto.PublicKey, msg, sigBlob []byte) error {
+	r := sshReader{data: sigBlob}
+	algo := string(r.string())
+	sig := r.string()
+	if r.err != nil {
+		return errors.New("malformed signature blob")
+	}
+	switch pub := pub.(type) {
+	case ed25519.PublicKey:
+		if algo != "ssh-ed25519" || !ed25519.Verify(pub, msg, sig) {
+			return errors.New("ed25519 verification failed")
+		}
+		return nil
+	case *rsa.PublicKey:
+		var h crypto.Hash
+		switch algo {
+		case "rsa-sha2-256":
+			h = crypto.SHA256
+		case "rsa-sha2-512":
+			h = crypto.SHA512
+		default:
+			return fmt.Errorf("rsa signature algorithm %q", algo)
+		}
+		d := h.New()
+		d.Write(msg)
+		return rsa.VerifyPKCS1v15(pub, h, d.Sum(nil), sig)
+	case *ecdsa.PublicKey:
+		h := crypto.SHA256
+		switch pub.Curve {
+		case elliptic.P384():
+			h = crypto.SHA384
+		case elliptic.P521():
+			h = crypto.SHA512
+		}
+		d := h.New()
+		d.Write(msg)
+		sr := sshReader{data: sig}
+		rr, ss := sr.mpint(), sr.mpint()
+		if sr.err != nil || !ecdsa.Verify(pub, d.Sum(nil), rr, ss) {
+			return errors.New("ecdsa verification failed")
+		}
+		return nil
+	}
+	return errors.New("unsupported key type")
+}
+
+// parseSSHPublicKey decodes the wire format of an SSH public key
+func parseSSHPublicKey(blob []byte) (crypto.PublicKey, string, error) {
+	r := sshReader{data: blob}
+	typ := string(r.string())
+	var pub crypto.PublicKey
+	switch typ {
+	case "ssh-ed25519":
+		key := r.string()
+		if r.err == nil && len(key) != ed25519.PublicKeySize {
+			return nil, "", errors.New("bad ed25519 key length")
+		}
+		pub = ed25519.PublicKey(key)
+	case "ssh-rsa":
+		e, n := r.mpint(), r.mpint()
+		if r.err == nil {
+			if n.BitLen() < 2048 {
+				return nil, "", fmt.Errorf("rsa key of %d bits is too small", n.BitLen())
+			}
+			pub = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		}
+	case "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384", "ecdsa-sha2-nistp521":
+		curve := map[string]elliptic.Curve{"nistp256": elliptic.P256(), "nistp384": elliptic.P384(), "nistp521": elliptic.P521()}[string(r.string())]
+		point := r.string()
+		if r.err == nil {
+			if curve == nil {
+				return nil, "", errors.New("unknown ecdsa curve")
+			}
+			x, y := elliptic.Unmarshal(curve, point)
+			if x == nil {
+				return nil, "", errors.New("bad ecdsa point")
+			}
+			pub = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	default:
+		if r.err == nil {
+			return nil, "", fmt.Errorf("unsupported key type %q", typ)
+		}
+	}
+	if r.err != nil {
+		return nil, "", errors.New("malformed key")
+	}
+	return pub, typ, nil
+}
+
+func sshFingerprint(blob []byte) string {
+	sum := sha256.Sum256(blob)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// sshReader decodes SSH wire-format fields; the first error sticks
+type sshReader struct {
+	data []byte
+	err  error
+}
+
+func (r *sshReader) raw(n int) []byte {
+	if r.err != nil || len(r.data) < n {
+		r.err = errors.New("short read")
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *sshReader) uint32() uint32 {
+	if b := r.raw(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *sshReader) string() []byte {
+	n := r.uint32()
+	if uint64(n) > uint64(len(r.data)) {
+		r.err = errors.New("short read")
+		return nil
+	}
+	return r.raw(int(n))
+}
+
+func (r *sshReader) mpint() *big.Int {
+	return new(big.Int).SetBytes(r.string())
+}
+
+// OpenPGP signatures (RFC 4880, v4 signatures and keys)
+
+const (
+	pgpTagSignature    = 2
+	pgpTagPublicKey    = 6
+	pgpTagPublicSubkey = 14
+
+	pgpAlgoRSA        = 1
+	pgpAlgoRSASign    = 3
+	pgpAlgoEdDSA      = 22
+	pgpAlgoEd25519    = 27
+	pgpSigBinary      = 0x00
+	pgpSubIssuer      = 16
+	pgpSubIssuerPrint = 33
+)
+
+// oidEd25519 is the curve OID of legacy EdDSA keys
+var oidEd25519 = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0xda, 0x47, 0x0f, 0x01}
+
+type pgpPacket struct {
+	tag  byte
+	body []byte
+}
+
+// readPackets splits an OpenPGP message into packets. Partial body lengths
+// are not supported; keys and signatures never use them.
+func readPackets(data []byte) ([]pgpPacket, error) {
+	var out []pgpPacket
+	for len(data) > 0 {
+		h := data[0]
+		if h&0x80 == 0 {
+			return nil, errors.New("malformed packet header")
+		}
+		var tag byte
+		var n, hdr int
+		if h&0x40 != 0 {
+			tag = h & 0x3f
+			if len(data) < 2 {
+				return nil, errors.New("truncated packet")
+			}
+			switch l := int(data[1]); {
+			case l < 192:
+				n, hdr = l, 2
+			case l < 224:
+				if len(data) < 3 {
+					return nil, errors.New("truncated packet")
+				}
+				n, hdr = (l-192)<<8+int(data[2])+192, 3
+			case l == 255:
+				if len(data) < 6 {
+					return nil, errors.New("truncated packet")
+				}
+				n, hdr = int(binary.BigEndian.Uint32(data[2:6])), 6
+			default:
+				return nil, errors.New("partial packet lengths are not supported")
+			}
+		} else {
+			tag = (h >> 2) & 0x0f
+			switch h & 3 {
+			case 0:
+				if len(data) < 2 {
+					return nil, errors.New("truncated packet")
+				}
+				n, hdr = int(data[1]), 2
+			case 1:
+				if len(data) < 3 {
+					return nil, errors.New("truncated packet")
+				}
+				n, hdr = int(binary.BigEndian.Uint16(data[1:3])), 3
+			case 2:
+				if len(data) < 5 {
+					return nil, errors.New("truncated packet")
+				}
+				n, hdr = int(binary.BigEndian.Uint32(data[1:5])), 5
+			default:
+				n, hdr = len(data)-1, 1
+			}
+		}
+		if n < 0 || hdr+n > len(data) {
+			return nil, errors.New("truncated packet")
+		}
+		out = append(out, pgpPacket{tag: tag, body: data[hdr : hdr+n]})
+		data = data[hdr+n:]
+	}
+	return out, nil
+}
+
+// readMPI reads an OpenPGP multiprecision integer
+func readMPI(b []byte) (value, rest []byte, err error) {
+	if len(b) < 2 {
+		return nil, nil, errors.New("truncated mpi")
+	}
+	n := (int(binary.BigEndian.Uint16(b)) + 7) / 8
+	if len(b) < 2+n {
+		return nil, nil, errors.New("truncated mpi")
+	}
+	return b[2 : 2+n], b[2+n:], nil
+}
+
+// parsePGPPublicKey decodes a v4 public key packet. It returns nil for
+// algorithms that cannot sign or are not supported.
+func parsePGPPublicKey(body []byte) (crypto.PublicKey, error) {
+	if len(body) < 6 || body[0] != 4 {
+		return nil, nil // v3 and v6 keys are not supported
+	}
+	material := body[6:]
+	switch body[5] {
+	case pgpAlgoRSA, pgpAlgoRSASign:
+		n, rest, err := readMPI(material)
+		if err != nil {
+			return nil, err
+		}
+		e, _, err := readMPI(rest)
+		if err != nil {
+			return nil, err
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("rsa key of %d bits is too small", key.N.BitLen())
+		}
+		return key, nil
+	case pgpAlgoEdDSA:
+		if len(material) < 1 || len(material) < 1+int(material[0]) {
+			return nil, errors.New("truncated eddsa key")
+		}
+		oid := material[1 : 1+int(material[0])]
+		if !bytes.Equal(oid, oidEd25519) {
+			return nil, nil
+		}
+		point, _, err := readMPI(material[1+len(oid):])
+		if err != nil {
+			return nil, err
+		}
+		if len(point) != 1+ed25519.PublicKeySize || point[0] != 0x40 {
+			return nil, errors.New("bad ed25519 point")
+		}
+		return ed25519.PublicKey(point[1:]), nil
+	case pgpAlgoEd25519:
+		if len(material) != ed25519.PublicKeySize {
+			return nil, errors.New("bad ed25519 key length")
+		}
+		return ed25519.PublicKey(material), nil
+	}
+	return nil, nil
+}
+
+func pgpFingerprint(body []byte) string {
+	h := sha1.New()
+	h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+	h.Write(body)
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+}
+
+func (k *Keyring) verifyOpenPGP(armored string, payload []byte) (*TrustedKey, error) {
+	data, err := dearmor(armored, "PGP SIGNATURE")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	packets, err := readPackets(data)
+	if err != nil || len(packets) != 1 || packets[0].tag != pgpTagSignature {
+		return nil, fmt.Errorf("%w: want a single openpgp signature packet", ErrBadSignature)
+	}
+	sig := packets[0].body
+	if len(sig) < 6 || sig[0] != 4 {
+		return nil, fmt.Errorf("%w: only v4 openpgp signatures are supported", ErrBadSignature)
+	}
+	sigType, pubAlgo, hashAlgo := sig[1], sig[2], sig[3]
+	hashedEnd := 6 + int(binary.BigEndian.Uint16(sig[4:6]))
+	if len(sig) < hashedEnd+2 {
+		return nil, fmt.Errorf("%w: truncated signature", ErrBadSignature)
+	}
+	unhashedEnd := hashedEnd + 2 + int(binary.BigEndian.Uint16(sig[hashedEnd:]))
+	if len(sig) < unhashedEnd+2 {
+		return nil, fmt.Errorf("%w: truncated signature", ErrBadSignature)
+	}
+	if sigType != pgpSigBinary {
+		return nil, fmt.Errorf("%w: signature type %#x, want a binary document signature", ErrBadSignature, sigType)
+	}
+
+	var h crypto.Hash
+	switch hashAlgo {
+	case 8:
+		h = crypto.SHA256
+	case 9:
+		h = crypto.SHA384
+	case 10:
+		h = crypto.SHA512
+	case 11:
+		h = crypto.SHA224
+	default:
+		return nil, fmt.Errorf("%w: unsupported or weak hash algorithm %d", ErrBadSignature, hashAlgo)
+	}
+
+	issuers := pgpIssuers(sig[6:hashedEnd], sig[hashedEnd+2:unhashedEnd])
+	var key *TrustedKey
+	for _, tk := range k.keys {
+		if tk.Format != FormatOpenPGP {
+			continue
+		}
+		for _, id := range issuers {
+			if strings.HasSuffix(tk.Fingerprint, id) {
+				key = tk
+			}
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: openpgp key %s", ErrUnknownSigner, strings.Join(issuers, ", "))
+	}
+
+	d := h.New()
+	d.Write(payload)
+	d.Write(sig[:hashedEnd])
+	d.Write([]byte{4, 0xff})
+	binary.Write(d, binary.BigEndian, uint32(hashedEnd))
+	digest := d.Sum(nil)
+	if !bytes.Equal(digest[:2], sig[unhashedEnd:unhashedEnd+2]) {
+		return nil, fmt.Errorf("%w: digest mismatch", ErrBadSignature)
+	}
+
+	mpis := sig[unhashedEnd+2:]
+	switch pub := key.pub.(type) {
+	case *rsa.PublicKey:
+		s, _, err := readMPI(mpis)
+		if err != nil || (pubAlgo != pgpAlgoRSA && pubAlgo != pgpAlgoRSASign) {
+			return nil, fmt.Errorf("%w: malformed rsa signature", ErrBadSignature)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, h, digest, leftPad(s, pub.Size())); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadSignature, err)
+		}
+	case ed25519.PublicKey:
+		var raw []byte
+		switch pubAlgo {
+		case pgpAlgoEdDSA:
+			r, rest, err := readMPI(mpis)
+			if err == nil {
+				var s []byte
+				if s, _, err = readMPI(rest); err == nil {
+					raw = append(leftPad(r, 32), leftPad(s, 32)...)
+				}
+			}
+		case pgpAlgoEd25519:
+			raw = mpis
+		}
+		if len(raw) != ed25519.SignatureSize || !ed25519.Verify(pub, digest, raw) {
+			return nil, fmt.Errorf("%w: ed25519 verification failed", ErrBadSignature)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type", ErrBadSignature)
+	}
+	return key, nil
+}
+
+// pgpIssuers returns the issuer fingerprints and key IDs named in the
+// signature's subpackets, in upper-case hex
+func pgpIssuers(areas ...[]byte) []string {
+	var out []string
+	for _, b := range areas {
+		for len(b) > 0 {
+			n, hdr := int(b[0]), 1
+			switch {
+			case n >= 255:
+				if len(b) < 5 {
+					return out
+				}
+				n, hdr = int(binary.BigEndian.Uint32(b[1:5])), 5
+			case n >= 192:
+				if len(b) < 2 {
+					return out
+				}
+				n, hdr = (n-192)<<8+int(b[1])+192, 2
+			}
+			if n < 1 || hdr+n > len(b) {
+				return out
+			}
+			sub := b[hdr : hdr+n]
+			switch typ, value := sub[0]&0x7f, sub[1:]; {
+			case typ == pgpSubIssuerPrint && len(value) == 21 && value[0] == 4:
+				out = append(out, strings.ToUpper(hex.EncodeToString(value[1:])))
+			case typ == pgpSubIssuer && len(value) == 8:
+				out = append(out, strings.ToUpper(hex.EncodeToString(value)))
+			}
+			b = b[hdr+n:]
+		}
+	}
+	return out
+}
+
+func leftPad(b []byte, n int) []byte {
+	if len(b) >= n {
+		return b
+	}
+	out := make([]byte, n)
+	copy(out[n-len(b):], b)
+	return out
+}
@@ -0,0 +1,254 @@
+package gitnative
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatcherOptions configures a Watcher
+type WatcherOptions struct {
+	// Refs lists the refs to follow, as full names or path.Match patterns
+	// such as refs/heads/customer/*. Defaults to the branch HEAD points at.
+	Refs []string
+	// PollInterval is the fallback rescan period (default 5s). It catches
+	// updates inotify misses, e.g. on network filesystems.
+	PollInterval time.Duration
+	// Debounce coalesces the burst of events a single push produces
+	// (default 100ms)
+	Debounce time.Duration
+}
+
+// Watcher follows refs in a repository and reports every commit that moves
+// them, with the paths changed between the old and new commit.
+type Watcher struct {
+	repo   *Repo
+	gitDir string
+	opts   WatcherOptions
+
+	// mu guards known. Only scan writes it, from the Watch goroutine, but
+	// Current may be called from anywhere.
+	mu    sync.Mutex
+	known map[string]string
+}
+
+// NewWatcher snapshots the current refs of the repository at repoPath.
+// Only movements after this point are reported.
+func NewWatcher(repoPath string, opts WatcherOptions) (*Watcher, error) {
+	repo, err := OpenRepo(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	gitDir := repo.GitDir()
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = 100 * time.Millisecond
+	}
+	if len(opts.Refs) == 0 {
+		_, ref, err := ReadHEAD(gitDir)
+		if err != nil && ref == "" {
+			repo.Close()
+			return nil, err
+		}
+		if ref == "" {
+			ref = "HEAD"
+		}
+		opts.Refs = []string{ref}
+	}
+
+	w := &Watcher{repo: repo, gitDir: gitDir, opts: opts}
+	if w.known, err = w.snapshot(); err != nil {
+		repo.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close releases the repository's open pack files
+func (w *Watcher) Close() error {
+	return w.repo.Close()
+}
+
+// GitDir returns the repository's git directory
+func (w *Watcher) GitDir() string {
+	return w.gitDir
+}
+
+// Current returns the last observed SHA of every watched ref
+func (w *Watcher) Current() map[string]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make(map[string]string, len(w.known))
+	for k, v := range w.known {
+		out[k] = v
+	}
+	return out
+}
+
+// Watch blocks until ctx is done, calling handle (from this goroutine) for
+// every ref movement in the order detected. inotify events on .git/refs and
+// packed-refs trigger a rescan; the poll interval is a fallback.
+func (w *Watcher) Watch(ctx context.Context, handle func(CommitEvent)) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("gitnative: inotify unavailable, polling every %v: %v", w.opts.PollInterval, err)
+	} else {
+		defer fsw.Close()
+		w.addWatches(fsw)
+	}
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	var fsEvents <-chan fsnotify.Event
+	var fsErrors <-chan error
+	if fsw != nil {
+		fsEvents, fsErrors = fsw.Events, fsw.Errors
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-fsEvents:
+			if !ok {
+				fsEvents = nil
+				continue
+			}
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					w.addTree(fsw, ev.Name)
+				}
+			}
+			if w.relevant(ev.Name) {
+				debounce.Reset(w.opts.Debounce)
+			}
+		case err, ok := <-fsErrors:
+			if !ok {
+				fsErrors = nil
+				continue
+			}
+			log.Printf("gitnative: watch error: %v", err)
+		case <-debounce.C:
+			w.scan(ctx, handle)
+		case <-ticker.C:
+			w.scan(ctx, handle)
+		}
+	}
+}
+
+func (w *Watcher) addWatches(fsw *fsnotify.Watcher) {
+	if err := fsw.Add(w.gitDir); err != nil {
+		log.Printf("gitnative: cannot watch %s: %v", w.gitDir, err)
+	}
+	w.addTree(fsw, filepath.Join(w.gitDir, "refs"))
+}
+
+// addTree watches dir and every directory below it; inotify is not recursive
+func (w *Watcher) addTree(fsw *fsnotify.Watcher, dir string) {
+	filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			if err := fsw.Add(p); err != nil {
+				log.Printf("gitnative: cannot watch %s: %v", p, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (w *Watcher) relevant(name string) bool {
+	if strings.HasSuffix(name, ".lock") {
+		return false
+	}
+	rel, err := filepath.Rel(w.gitDir, name)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	return rel == "HEAD" || rel == "packed-refs" || strings.HasPrefix(rel, "refs/")
+}
+
+func (w *Watcher) watched(ref string) bool {
+	return MatchRef(w.opts.Refs, ref)
+}
+
+// snapshot reads the current SHA of every watched ref
+func (w *Watcher) snapshot() (map[string]string, error) {
+	refs, err := ListRefs(w.gitDir)
+	if err != nil {
+		return nil, err
+	}
+	if w.watched("HEAD") {
+		if sha, _, err := ReadHEAD(w.gitDir); err == nil {
+			refs["HEAD"] = sha
+		}
+	}
+
+	out := map[string]string{}
+	for name, sha := range refs {
+		if w.watched(name) {
+			out[name] = sha
+		}
+	}
+	return out, nil
+}
+
+// scan compares the refs against the last snapshot and emits an event per
+// moved ref. A ref whose diff fails keeps its old SHA so the next scan
+// retries it.
+func (w *Watcher) scan(ctx context.Context, handle func(CommitEvent)) {
+	current, err := w.snapshot()
+	if err != nil {
+		log.Printf("gitnative: read refs: %v", err)
+		return
+	}
+
+	names := make([]string, 0, len(current)+len(w.known))
+	for name := range current {
+		names = append(names, name)
+	}
+	for name := range w.known {
+		if _, ok := current[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		oldSHA, newSHA := w.known[name], current[name]
+		if oldSHA == newSHA {
+			continue
+		}
+
+		event := CommitEvent{Ref: name, OldSHA: oldSHA, NewSHA: newSHA, DetectedAt: time.Now()}
+		if newSHA != "" {
+			changes, err := w.repo.Diff(ctx, oldSHA, newSHA)
+			if err != nil {
+				log.Printf("gitnative: %s: %v", name, err)
+				continue
+			}
+			event.Changes = changes
+		}
+
+		w.mu.Lock()
+		if newSHA == "" {
+			delete(w.known, name)
+		} else {
+			w.known[name] = newSHA
+		}
+		w.mu.Unlock()
+		handle(event)
+	}
+}
@@ -0,0 +1,301 @@
+package gitnative
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrStaleRef is returned when a ref no longer points at the commit a write
+// was based on
+var ErrStaleRef = errors.New("ref moved since it was read")
+
+// Write stores data as a loose object and returns its ID. Writing an object
+// that already exists is a no-op.
+func (s *ObjectStore) Write(typ ObjectType, data []byte) (string, error) {
+	header := objectHeader(typ, data)
+	id := hashObject(typ, data)
+	if s.exists(id) {
+		return id, nil
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte(header))
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(s.dir, id[:2])
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(dir, "tmp_obj_")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	os.Chmod(tmp.Name(), 0444)
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, id[2:])); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return id, nil
+}
+
+func objectHeader(typ ObjectType, data []byte) string {
+	return typ.String() + " " + strconv.Itoa(len(data)) + "\x00"
+}
+
+// hashObject returns the ID git gives an object
+func hashObject(typ ObjectType, data []byte) string {
+	sum := sha1.New()
+	sum.Write([]byte(objectHeader(typ, data)))
+	sum.Write(data)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// EncodeTree serialises entries in git's tree order, where directories sort
+// as if their name ended in "/"
+func EncodeTree(entries []TreeEntry) ([]byte, error) {
+	sorted := append([]TreeEntry(nil), entries...)
+	key := func(e TreeEntry) string {
+		if e.IsTree() {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(sorted, func(i, j int) bool { return key(sorted[i]) < key(sorted[j]) })
+
+	var buf bytes.Buffer
+	for _, e := range sorted {
+		raw, err := hex.DecodeString(e.ID)
+		if err != nil || len(raw) != 20 {
+			return nil, fmt.Errorf("tree entry %s: bad object ID %q", e.Name, e.ID)
+		}
+		fmt.Fprintf(&buf, "%o %s\x00", e.Mode, e.Name)
+		buf.Write(raw)
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeCommit serialises a commit object
+func EncodeCommit(c *Commit) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "tree %s\n", c.Tree)
+	for _, p := range c.Parents {
+		fmt.Fprintf(&buf, "parent %s\n", p)
+	}
+	fmt.Fprintf(&buf, "author %s\n", c.Author)
+	fmt.Fprintf(&buf, "committer %s\n", c.Committer)
+	buf.WriteString("\n")
+	buf.WriteString(c.Message)
+	return buf.Bytes()
+}
+
+// UpdateRef moves ref from oldSHA to newSHA, failing with ErrStaleRef if it
+// points elsewhere. An empty oldSHA requires the ref not to exist. The ref
+// is locked with a .lock file the same way git does, so concurrent git
+// processes are excluded too.
+func UpdateRef(gitDir, ref, oldSHA, newSHA string) error {
+	if !strings.HasPrefix(ref, "refs/") || strings.Contains(ref, "..") || !isHexSHA(newSHA) {
+		return fmt.Errorf("update %s: invalid ref or target", ref)
+	}
+	refPath := filepath.Join(gitDir, filepath.FromSlash(ref))
+	if err := os.MkdirAll(filepath.Dir(refPath), 0755); err != nil {
+		return err
+	}
+	lock, err := os.OpenFile(refPath+".lock", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%w: %s is locked by another writer", ErrStaleRef, ref)
+		}
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			lock.Close()
+			os.Remove(lock.Name())
+		}
+	}()
+
+	current, err := ResolveRef(gitDir, ref)
+	if errors.Is(err, ErrRefNotFound) {
+		current, err = "", nil
+	}
+	if err != nil {
+		return err
+	}
+	if current != oldSHA {
+		return fmt.Errorf("%w: %s is at %s, expected %s", ErrStaleRef, ref, short(current), short(oldSHA))
+	}
+
+	if _, err := lock.WriteString(newSHA + "\n"); err != nil {
+		return err
+	}
+	if err := lock.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(lock.Name(), refPath); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// CommitRequest describes a commit that edits files on top of Parent
+type CommitRequest struct {
+	// Parent is the commit the edit is based on; empty for a root commit
+	Parent string
+	// MergeParent, if set, makes this a merge commit of Parent and
+	// MergeParent. Files then holds the merge result relative to Parent.
+	MergeParent string
+	Author      Signature
+	Committer   Signature
+	Message     string
+	// Files maps paths to new contents; a nil value deletes the path
+	Files map[string][]byte
+}
+
+// CreateCommit writes the objects for req without moving any ref (see
+// UpdateRef). It returns the new commit, or Parent if the edit is a no-op
+// and not a merge.
+func (r *Repo) CreateCommit(ctx context.Context, req CommitRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	tree, err := r.objects.commitTree(req.Parent)
+	if err != nil {
+		return "", err
+	}
+
+	newTree := tree
+	for _, p := range sortedPaths(req.Files) {
+		clean := path.Clean(strings.Trim(p, "/"))
+		if clean == "." || strings.HasPrefix(clean, "../") || clean == ".." || strings.HasPrefix(clean, ".git/") || clean == ".git" {
+			return "", fmt.Errorf("invalid path %q", p)
+		}
+		if newTree, err = r.objects.setPath(newTree, strings.Split(clean, "/"), req.Files[p]); err != nil {
+			return "", fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	if newTree == tree && req.MergeParent == "" {
+		return req.Parent, nil
+	}
+	if newTree == "" {
+		if newTree, err = r.objects.Write(ObjectTree, nil); err != nil {
+			return "", err
+		}
+	}
+
+	c := &Commit{Tree: newTree, Author: req.Author, Committer: req.Committer, Message: req.Message}
+	if c.Committer.Name == "" {
+		c.Committer = c.Author
+	}
+	if !strings.HasSuffix(c.Message, "\n") {
+		c.Message += "\n"
+	}
+	if req.Parent != "" {
+		c.Parents = []string{req.Parent}
+	}
+	if req.MergeParent != "" {
+		c.Parents = append(c.Parents, req.MergeParent)
+	}
+	return r.objects.Write(ObjectCommit, EncodeCommit(c))
+}
+
+// setPath returns the ID of tree with the blob at parts replaced by data, or
+// removed when data is nil. Empty directories are pruned; an empty result
+// is returned as "".
+func (s *ObjectStore) setPath(tree string, parts []string, data []byte) (string, error) {
+	entries, err := s.treeEntries(tree)
+	if err != nil {
+		return "", err
+	}
+	name := parts[0]
+	existing, exists := entries[name]
+
+	if len(parts) == 1 {
+		if data == nil {
+			if !exists || existing.IsTree() {
+				return "", ErrFileNotFound
+			}
+			delete(entries, name)
+		} else {
+			if exists && existing.IsTree() {
+				return "", errors.New("is a directory")
+			}
+			id, err := s.Write(ObjectBlob, data)
+			if err != nil {
+				return "", err
+			}
+			mode := uint32(0o100644)
+			if exists && existing.Mode == 0o100755 {
+				mode = existing.Mode
+			}
+			entries[name] = TreeEntry{Mode: mode, Name: name, ID: id}
+		}
+	} else {
+		sub := ""
+		if exists {
+			if !existing.IsTree() {
+				return "", fmt.Errorf("%s is a file", name)
+			}
+			sub = existing.ID
+		}
+		newSub, err := s.setPath(sub, parts[1:], data)
+		if err != nil {
+			return "", err
+		}
+		if newSub == sub {
+			return tree, nil
+		}
+		if newSub == "" {
+			delete(entries, name)
+		} else {
+			entries[name] = TreeEntry{Mode: modeTree, Name: name, ID: newSub}
+		}
+	}
+
+	if len(entries) == 0 {
+		return "", nil
+	}
+	list := make([]TreeEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, e)
+	}
+	encoded, err := EncodeTree(list)
+	if err != nil {
+		return "", err
+	}
+	return s.Write(ObjectTree, encoded)
+}
+
+func sortedPaths(files map[string][]byte) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,141 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+)
+
+// TemporalCheck pings the Temporal frontend through the SDK's health API.
+// Without Temporal no workflow can start, so it is critical.
+func TemporalCheck(c client.Client) Check {
+	return Check{
+		Name:     "temporal",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			if _, err := c.CheckHealth(ctx, &client.CheckHealthRequest{}); err != nil {
+				return "", fmt.Errorf("temporal frontend unreachable: %w", err)
+			}
+			return "frontend serving", nil
+		},
+	}
+}
+
+// RepoHeadCheck verifies the runtime repository's HEAD resolves to a commit
+// and reports it as the detail
+func RepoHeadCheck(repoPath string) Check {
+	return Check{
+		Name:     "git_repository",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			gitDir, err := gitnative.GitDir(repoPath)
+			if err != nil {
+				return "", err
+			}
+			sha, ref, err := gitnative.ReadHEAD(gitDir)
+			if err != nil {
+				return "", err
+			}
+			if ref != "" {
+				return fmt.Sprintf("%s@%s", ref, sha), nil
+			}
+			return sha, nil
+		},
+	}
+}
+
+// RegistryCheck fails until count reports at least one loaded entry
+func RegistryCheck(name string, count func() int) Check {
+	return Check{
+		Name:     name + "_registry",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			n := count()
+			if n == 0 {
+				return "", errors.New("registry is empty")
+			}
+			return fmt.Sprintf("%d loaded", n), nil
+		},
+	}
+}
+
+// WorkerCheck reports the number of workers polling the runtime's task
+// queues. Too few workers leaves the fast path working, so the service is
+// degraded rather than down.
+func WorkerCheck(count func(ctx context.Context) (int, error), min int) Check {
+	return Check{
+		Name: "temporal_workers",
+		Run: func(ctx context.Context) (string, error) {
+			n, err := count(ctx)
+			if err != nil {
+				return "", err
+			}
+			detail := fmt.Sprintf("%d polling", n)
+			if n < min {
+				return detail, fmt.Errorf("%d workers polling, want at least %d", n, min)
+			}
+			return detail, nil
+		},
+	}
+}
+
+// TaskQueuePollers counts the workflow pollers on queues, for WorkerCheck
+func TaskQueuePollers(c client.Client, queues ...string) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		total := 0
+		for _, q := range queues {
+			resp, err := c.DescribeTaskQueue(ctx, q, enumspb.TASK_QUEUE_TYPE_WORKFLOW)
+			if err != nil {
+				return 0, fmt.Errorf("describe task queue %s: %w", q, err)
+			}
+			total += len(resp.GetPollers())
+		}
+		return total, nil
+	}
+}
+
+// RuntimeConfig lists the dependencies a runtime is ready only with
+type RuntimeConfig struct {
+	Temporal client.Client
+	// RepoPath is the runtime repository's working directory
+	RepoPath string
+	// Definitions counts the definitions loaded from the followed branch,
+	// e.g. registry.Registry.Loaded
+	Definitions func() int
+	// TaskQueues are polled by this runtime's workers
+	TaskQueues []string
+	// MinWorkers below which the service is degraded (default 1)
+	MinWorkers int
+}
+
+// NewRuntimeChecker wires the Temporal, repository, registry and worker
+// checks for cfg. Every dependency is required: a readiness probe that
+// skips one would report ready without it.
+func NewRuntimeChecker(cfg RuntimeConfig) (*Checker, error) {
+	switch {
+	case cfg.Temporal == nil:
+		return nil, errors.New("health: Temporal client is required")
+	case cfg.RepoPath == "":
+		return nil, errors.New("health: repository path is required")
+	case cfg.Definitions == nil:
+		return nil, errors.New("health: definition count is required")
+	case len(cfg.TaskQueues) == 0:
+		return nil, errors.New("health: at least one task queue is required")
+	}
+	min := cfg.MinWorkers
+	if min <= 0 {
+		min = 1
+	}
+
+	return NewChecker(
+		TemporalCheck(cfg.Temporal),
+		RepoHeadCheck(cfg.RepoPath),
+		RegistryCheck("definition", cfg.Definitions),
+		WorkerCheck(TaskQueuePollers(cfg.Temporal, cfg.TaskQueues...), min),
+	), nil
+}
@@ -0,0 +1,194 @@
+// Package health separates liveness ("the process is running") from
+// readiness ("Temporal is reachable, the git repo is loaded and workers
+// are polling") and reports each dependency individually.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// State is the health of one check or of the whole service
+type State string
+
+const (
+	StateUp       State = "up"
+	StateDegraded State = "degraded"
+	StateDown     State = "down"
+)
+
+// Check is one dependency probe. A failing critical check makes the service
+// not ready; a failing non-critical check only degrades it.
+type Check struct {
+	Name     string
+	Critical bool
+	// Timeout bounds Run (default 2s)
+	Timeout time.Duration
+	// Run returns an optional human-readable detail, such as a commit SHA
+	// or worker count, and an error when the dependency is unhealthy.
+	Run func(ctx context.Context) (detail string, err error)
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name     string `json:"name"`
+	Status   State  `json:"status"`
+	Critical bool   `json:"critical"`
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the body of /health/ready
+type Report struct {
+	Status    State     `json:"status"`
+	Checks    []Result  `json:"checks"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Checker runs readiness checks. Results are cached for CacheTTL so a
+// tight probe loop cannot hammer Temporal or the filesystem.
+type Checker struct {
+	CacheTTL time.Duration
+
+	mu      sync.Mutex
+	checks  []Check
+	started time.Time
+	last    *Report
+}
+
+// NewChecker creates a checker with the given checks and a 1s cache
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{CacheTTL: time.Second, checks: checks, started: time.Now()}
+}
+
+// Add registers another check
+func (c *Checker) Add(check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check)
+	c.last = nil
+}
+
+// Run executes every check concurrently and aggregates the result
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.Lock()
+	if c.last != nil && time.Since(c.last.Timestamp) < c.CacheTTL {
+		report := *c.last
+		c.mu.Unlock()
+		return report
+	}
+	checks := append([]Check(nil), c.checks...)
+	c.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StateUp, Checks: results, Timestamp: time.Now()}
+	if len(checks) == 0 {
+		// A checker with nothing to check cannot vouch for any dependency
+		report.Status = StateDown
+		report.Checks = []Result{{Name: "readiness", Critical: true, Status: StateDown, Error: "no readiness checks registered", Duration: "0s"}}
+	}
+	for _, r := range results {
+		if r.Status == StateUp {
+			continue
+		}
+		if r.Critical {
+			report.Status = StateDown
+		} else if report.Status == StateUp {
+			report.Status = StateDegraded
+		}
+	}
+
+	c.mu.Lock()
+	c.last = &report
+	c.mu.Unlock()
+	return report
+}
+
+func runCheck(ctx context.Context, check Check) (result Result) {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	result = Result{Name: check.Name, Critical: check.Critical, Status: StateUp}
+	defer func() { result.Duration = time.Since(start).String() }()
+
+	type outcome struct {
+		detail string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("check panicked: %v", r)}
+			}
+		}()
+		detail, err := check.Run(ctx)
+		done <- outcome{detail, err}
+	}()
+
+	var detail string
+	var err error
+	select {
+	case o := <-done:
+		detail, err = o.detail, o.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result.Detail = detail
+	if err != nil {
+		result.Status = StateDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LiveHandler serves /health/live: 200 whenever the process can answer
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"status": StateUp,
+			"uptime": time.Since(c.started).Round(time.Second).String(),
+		})
+	})
+}
+
+// ReadyHandler serves /health/ready: 200 when up or degraded, 503 when a
+// critical dependency is down or no checks are registered
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context())
+		status := http.StatusOK
+		if report.Status == StateDown {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
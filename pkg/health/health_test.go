@@ -0,0 +1,144 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+)
+
+func staticCheck(name string, critical bool, err error) Check {
+	return Check{Name: name, Critical: critical, Run: func(context.Context) (string, error) { return "", err }}
+}
+
+func TestReadyStates(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		state  State
+		code   int
+	}{
+		{"all up", []Check{staticCheck("temporal", true, nil), staticCheck("workers", false, nil)}, StateUp, 200},
+		{"non-critical down", []Check{staticCheck("temporal", true, nil), staticCheck("workers", false, errors.New("0 polling"))}, StateDegraded, 200},
+		{"critical down", []Check{staticCheck("temporal", true, errors.New("refused")), staticCheck("workers", false, errors.New("0 polling"))}, StateDown, 503},
+	}
+
+	for _, tt := range tests {
+		c := NewChecker(tt.checks...)
+		rec := httptest.NewRecorder()
+		c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+		var report Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != tt.code || report.Status != tt.state || len(report.Checks) != len(tt.checks) {
+			t.Errorf("%s: got %d %+v", tt.name, rec.Code, report)
+		}
+	}
+}
+
+func TestLiveIgnoresDependencies(t *testing.T) {
+	c := NewChecker(staticCheck("temporal", true, errors.New("refused")))
+	rec := httptest.NewRecorder()
+	c.LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("live status = %d", rec.Code)
+	}
+}
+
+func TestCheckTimeoutAndPanic(t *testing.T) {
+	c := NewChecker(
+		Check{Name: "slow", Critical: true, Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) (string, error) {
+			time.Sleep(time.Second)
+			return "", nil
+		}},
+		Check{Name: "broken", Run: func(context.Context) (string, error) { panic("boom") }},
+	)
+	report := c.Run(context.Background())
+	if report.Status != StateDown || !strings.Contains(report.Checks[0].Error, "deadline") ||
+		!strings.Contains(report.Checks[1].Error, "panicked") {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestRepoHeadCheck(t *testing.T) {
+	repo := t.TempDir()
+	gitDir := filepath.Join(repo, ".git")
+	os.MkdirAll(filepath.Join(gitDir, "refs", "heads"), 0755)
+	os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main\n"), 0644)
+
+	check := RepoHeadCheck(repo)
+	if _, err := check.Run(context.Background()); err == nil {
+		t.Fatal("expected error for unborn branch")
+	}
+
+	sha := strings.Repeat("ab", 20)
+	os.WriteFile(filepath.Join(gitDir, "packed-refs"), []byte("# pack-refs with: peeled\n"+sha+" refs/heads/main\n"), 0644)
+	detail, err := check.Run(context.Background())
+	if err != nil || detail != "refs/heads/main@"+sha {
+		t.Errorf("detail = %q, err = %v", detail, err)
+	}
+}
+
+func TestReadyWithoutChecksIsDown(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewChecker().ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("ready with no checks = %d, want 503", rec.Code)
+	}
+}
+
+func TestRuntimeChecker(t *testing.T) {
+	if _, err := NewRuntimeChecker(RuntimeConfig{RepoPath: "repos"}); err == nil {
+		t.Fatal("expected error without a Temporal client")
+	}
+
+	temporal := mocks.NewClient(t)
+	temporal.On("CheckHealth", mock.Anything, mock.Anything).Return(&client.CheckHealthResponse{}, nil)
+	temporal.On("DescribeTaskQueue", mock.Anything, "volcano-pipelines", enumspb.TASK_QUEUE_TYPE_WORKFLOW).
+		Return(&workflowservice.DescribeTaskQueueResponse{}, nil)
+
+	loaded := 0
+	c, err := NewRuntimeChecker(RuntimeConfig{
+		Temporal:    temporal,
+		RepoPath:    t.TempDir(),
+		Definitions: func() int { return loaded },
+		TaskQueues:  []string{"volcano-pipelines"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := c.Run(context.Background())
+	states := map[string]State{}
+	for _, r := range report.Checks {
+		states[r.Name] = r.Status
+	}
+	want := map[string]State{
+		"temporal":            StateUp,
+		"git_repository":      StateDown,
+		"definition_registry": StateDown,
+		"temporal_workers":    StateDown,
+	}
+	if report.Status != StateDown || len(states) != len(want) {
+		t.Fatalf("report = %+v", report)
+	}
+	for name, state := range want {
+		if states[name] != state {
+			t.Errorf("%s = %s, want %s", name, states[name], state)
+		}
+	}
+}
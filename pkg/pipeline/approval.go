@@ -0,0 +1,409 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
+
+// ApprovalSignal delivers a Decision to a pipeline waiting in an approval
+// stage
+const ApprovalSignal = "approval"
+
+// ApprovalActivity is the name Approvals.Record is registered under
+const ApprovalActivity = "RecordApproval"
+
+// ApprovalsQuery returns the []api.Approval of a pipeline's approval
+// stages so far, so any process can see what a pipeline is waiting for
+const ApprovalsQuery = "approvals"
+
+// ApprovalOutput is the key an approval stage's decision is stored under in
+// the stage's outputs, as stages.<stage>.approval.approved and so on
+const ApprovalOutput = "approval"
+
+// maxDecidedApprovals bounds the decided approvals Approvals remembers
+const maxDecidedApprovals = 1000
+
+// Decision is the payload of ApprovalSignal. Decisions for another stage,
+// from a group not asked, or naming no approver are ignored. Approvals.Decide
+// fills Approver and Group from the authenticated caller; the workflow
+// cannot check them again, so signalling it directly through Temporal must
+// be reserved to the API server and operators.
+type Decision struct {
+	Stage    string `json:"stage"`
+	Approved bool   `json:"approved"`
+	Approver string `json:"approver"`
+	Group    string `json:"group"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Approvals keeps the approvals pipelines are waiting for and delivers
+// decisions to them. Pending approvals are read from the pipelines with
+// ApprovalsQuery, so the API server needs no state shared with workers.
+// Decided approvals are kept as pipelines report them through
+// ApprovalActivity, for as long as the process runs.
+type Approvals struct {
+	signal func(ctx context.Context, workflowID string, d Decision) error
+	// source reads approvals from the pipelines; nil serves only those
+	// reported to this process
+	source approvalSource
+
+	mu        sync.Mutex
+	approvals map[string]*api.Approval
+	// order holds keys by request time
+	order []string
+}
+
+// approvalSource reads approvals from the pipelines waiting on them
+type approvalSource interface {
+	// pipeline returns the approvals of a workflow's current run
+	pipeline(ctx context.Context, workflowID string) ([]api.Approval, error)
+	// running returns the approvals of every running pipeline
+	running(ctx context.Context) ([]api.Approval, error)
+}
+
+// NewApprovals returns Approvals that query and signal pipelines through c
+func NewApprovals(c client.Client) *Approvals {
+	a := newApprovals(func(ctx context.Context, workflowID string, d Decision) error {
+		return c.SignalWorkflow(ctx, workflowID, "", ApprovalSignal, d)
+	})
+	a.source = temporalApprovals{c}
+	return a
+}
+
+// temporalApprovals queries pipelines through Temporal. Listing finds
+// running pipelines through visibility and queries each of them.
+type temporalApprovals struct {
+	c client.Client
+}
+
+func (t temporalApprovals) pipeline(ctx context.Context, workflowID string) ([]api.Approval, error) {
+	v, err := t.c.QueryWorkflow(ctx, workflowID, "", ApprovalsQuery)
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		return nil, fmt.Errorf("%w: workflow %q", api.ErrNotFound, workflowID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []api.Approval
+	if err := v.Get(&out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (t temporalApprovals) running(ctx context.Context) ([]api.Approval, error) {
+	req := &workflowservice.ListWorkflowExecutionsRequest{
+		Query: fmt.Sprintf("WorkflowType = '%s' AND ExecutionStatus = 'Running'", WorkflowType),
+	}
+	var out []api.Approval
+	for {
+		resp, err := t.c.ListWorkflow(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range resp.GetExecutions() {
+			approvals, err := t.pipeline(ctx, e.GetExecution().GetWorkflowId())
+			if errors.Is(err, api.ErrNotFound) {
+				continue // finished since it was listed
+			}
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, approvals...)
+		}
+		if len(resp.GetNextPageToken()) == 0 {
+			return out, nil
+		}
+		req.NextPageToken = resp.GetNextPageToken()
+	}
+}
+
+func newApprovals(signal func(ctx context.Context, workflowID string, d Decision) error) *Approvals {
+	return &Approvals{signal: signal, approvals: map[string]*api.Approval{}}
+}
+
+// RegisterApprovals adds a's activity to a worker polling the task queue
+// pipelines are started on
+func RegisterApprovals(w worker.Registry, a *Approvals) {
+	w.RegisterActivityWithOptions(a.Record, activity.RegisterOptions{Name: ApprovalActivity})
+}
+
+func approvalKey(workflowID, stage string) string {
+	return workflowID + "\x00" + stage
+}
+
+// Record stores the latest state of an approval
+func (a *Approvals) Record(ctx context.Context, rec api.Approval) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := approvalKey(rec.WorkflowID, rec.Stage)
+	if _, ok := a.approvals[key]; !ok {
+		a.order = append(a.order, key)
+	}
+	a.approvals[key] = &rec
+
+	decided := 0
+	for _, k := range a.order {
+		if a.approvals[k].Status != api.ApprovalPending {
+			decided++
+		}
+	}
+	for i := 0; decided > maxDecidedApprovals && i < len(a.order); {
+		if k := a.order[i]; a.approvals[k].Status != api.ApprovalPending {
+			delete(a.approvals, k)
+			a.order = slices.Delete(a.order, i, i+1)
+			decided--
+			continue
+		}
+		i++
+	}
+	return nil
+}
+
+// Approvals lists the approvals matching f, oldest request first. Pending
+// approvals are those of running pipelines.
+func (a *Approvals) Approvals(ctx context.Context, f api.ApprovalFilter) (*api.ApprovalsResponse, error) {
+	var live map[string]bool
+	if a.source != nil {
+		running, err := a.source.running(ctx)
+		if err != nil {
+			return nil, err
+		}
+		live = map[string]bool{}
+		for _, rec := range running {
+			a.Record(ctx, rec)
+			live[approvalKey(rec.WorkflowID, rec.Stage)] = true
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := []api.Approval{}
+	for _, k := range a.order {
+		rec := a.approvals[k]
+		if live != nil && rec.Status == api.ApprovalPending && !live[k] {
+			continue // the pipeline stopped waiting
+		}
+		if (f.Status == "" || rec.Status == f.Status) &&
+			(f.Tenant == "" || rec.CustomerID == f.Tenant) &&
+			(f.Group == "" || slices.Contains(rec.Groups, f.Group)) {
+			out = append(out, *rec)
+		}
+	}
+	return &api.ApprovalsResponse{Success: true, Total: len(out), Approvals: out}, nil
+}
+
+// Decide signals by's decision to the pipeline waiting on the approval,
+// for req.Group or else the first of the approval's groups by belongs to.
+// The approval stays pending until the pipeline records that it accepted
+// it.
+func (a *Approvals) Decide(ctx context.Context, workflowID, stage string, by api.Principal, req api.ApprovalDecisionRequest) (*api.ApprovalResponse, error) {
+	snapshot, ok, err := a.lookup(ctx, workflowID, stage)
+	switch {
+	case err != nil:
+		return nil, err
+	case !ok:
+		return nil, fmt.Errorf("%w: no approval for stage %q of workflow %q", api.ErrNotFound, stage, workflowID)
+	case snapshot.Status != api.ApprovalPending:
+		return nil, fmt.Errorf("%w: stage %q was already %s", api.ErrConflict, stage, snapshot.Status)
+	}
+	group := req.Group
+	if group == "" {
+		if i := slices.IndexFunc(snapshot.Groups, func(g string) bool { return slices.Contains(by.Groups, g) }); i >= 0 {
+			group = snapshot.Groups[i]
+		}
+	}
+	switch {
+	case group == "":
+		return nil, fmt.Errorf("%w: %s is in none of the groups asked to decide stage %q (asked: %v)", api.ErrPermissionDenied, by.Subject, stage, snapshot.Groups)
+	case !slices.Contains(snapshot.Groups, group):
+		return nil, fmt.Errorf("%w: group %q may not decide stage %q (asked: %v)", api.ErrPermissionDenied, group, stage, snapshot.Groups)
+	case !slices.Contains(by.Groups, group):
+		return nil, fmt.Errorf("%w: %s is not a member of %q", api.ErrPermissionDenied, by.Subject, group)
+	}
+	d := Decision{Stage: stage, Approved: req.Decision == "approve", Approver: by.Subject, Group: group, Comment: req.Comment}
+	if err := a.signal(ctx, workflowID, d); err != nil {
+		return nil, fmt.Errorf("signal workflow %q: %w", workflowID, err)
+	}
+	return &api.ApprovalResponse{Success: true, Approval: snapshot}, nil
+}
+
+// lookup returns the approval of a workflow's stage, as the pipeline
+// reports it when there is a source
+func (a *Approvals) lookup(ctx context.Context, workflowID, stage string) (api.Approval, bool, error) {
+	if a.source != nil {
+		approvals, err := a.source.pipeline(ctx, workflowID)
+		if errors.Is(err, api.ErrNotFound) {
+			return api.Approval{}, false, nil
+		}
+		if err != nil {
+			return api.Approval{}, false, fmt.Errorf("query workflow %q: %w", workflowID, err)
+		}
+		for _, rec := range approvals {
+			if rec.Stage == stage {
+				a.Record(ctx, rec)
+				return rec, true, nil
+			}
+		}
+		return api.Approval{}, false, nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	rec, ok := a.approvals[approvalKey(workflowID, stage)]
+	if !ok {
+		return api.Approval{}, false, nil
+	}
+	return *rec, true, nil
+}
+
+// approvals answers ApprovalsQuery from the stage results
+func (r *run) approvals() ([]api.Approval, error) {
+	out := []api.Approval{}
+	for _, s := range r.result.Stages {
+		if s.Approval != nil {
+			out = append(out, *s.Approval)
+		}
+	}
+	return out, nil
+}
+
+// runApproval waits for a decision on an approval stage. After
+// escalate_after the escalate_to groups may decide too; after the timeout
+// the default decision applies. Each change is reported through
+// ApprovalActivity.
+func (r *run) runApproval(ctx workflow.Context, i int, s registry.Stage, sr *StageResult) error {
+	spec := s.Approval
+	logger := workflow.GetLogger(ctx)
+	info := workflow.GetInfo(ctx)
+	now := workflow.Now(ctx)
+	rec := api.Approval{
+		WorkflowID:  info.WorkflowExecution.ID,
+		RunID:       info.WorkflowExecution.RunID,
+		Workflow:    r.def.Name,
+		Stage:       s.Name,
+		CustomerID:  r.in.CustomerID,
+		Message:     spec.Message,
+		Groups:      append([]string{}, spec.Approvers...),
+		Status:      api.ApprovalPending,
+		Default:     registry.ApprovalReject,
+		RequestedAt: now,
+	}
+	if spec.Default != "" {
+		rec.Default = spec.Default
+	}
+	timeout, escalateAfter := r.timeouts[i], r.escalations[i]
+	if timeout > 0 {
+		deadline := now.Add(timeout)
+		rec.Deadline = &deadline
+	}
+	if escalateAfter > 0 {
+		at := now.Add(escalateAfter)
+		rec.EscalateAt = &at
+	}
+	sr.Approval = &rec
+	r.recordApproval(ctx, rec)
+	logger.Info("awaiting approval", "stage", s.Name, "groups", rec.Groups)
+
+	timerCtx, stopTimers := workflow.WithCancel(ctx)
+	defer stopTimers()
+	var decision *Decision
+	escalate, timedOut := false, false
+	sel := workflow.NewSelector(ctx)
+	sel.AddReceive(workflow.GetSignalChannel(ctx, ApprovalSignal), func(c workflow.ReceiveChannel, _ bool) {
+		var d Decision
+		c.Receive(ctx, &d)
+		if d.Stage != s.Name || d.Approver == "" || !slices.Contains(rec.Groups, d.Group) {
+			logger.Warn("approval decision ignored", "stage", s.Name, "for_stage", d.Stage, "group", d.Group)
+			return
+		}
+		decision = &d
+	})
+	if escalateAfter > 0 {
+		sel.AddFuture(workflow.NewTimer(timerCtx, escalateAfter), func(f workflow.Future) {
+			escalate = f.Get(ctx, nil) == nil
+		})
+	}
+	if timeout > 0 {
+		sel.AddFuture(workflow.NewTimer(timerCtx, timeout), func(f workflow.Future) {
+			timedOut = f.Get(ctx, nil) == nil
+		})
+	}
+	sel.AddReceive(ctx.Done(), func(workflow.ReceiveChannel, bool) {})
+
+	for decision == nil && !timedOut && ctx.Err() == nil {
+		sel.Select(ctx)
+		if escalate && !rec.Escalated {
+			rec.Escalated = true
+			rec.Groups = append(rec.Groups, spec.EscalateTo...)
+			logger.Info("approval escalated", "stage", s.Name, "groups", rec.Groups)
+			r.recordApproval(ctx, rec)
+		}
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("cancelled while awaiting approval")
+	}
+	if decision == nil {
+		decision = &Decision{Stage: s.Name, Approved: rec.Default == registry.ApprovalApprove}
+	}
+
+	rec.Decision = &api.ApprovalDecision{
+		Approved:  decision.Approved,
+		Approver:  decision.Approver,
+		Group:     decision.Group,
+		Comment:   decision.Comment,
+		TimedOut:  timedOut,
+		DecidedAt: workflow.Now(ctx),
+	}
+	rec.Status = api.ApprovalRejected
+	if decision.Approved {
+		rec.Status = api.ApprovalApproved
+	}
+	sr.Approval = &rec
+	r.recordApproval(ctx, rec)
+	r.outputs[s.Name] = map[string]interface{}{ApprovalOutput: map[string]interface{}{
+		"approved": decision.Approved,
+		"approver": decision.Approver,
+		"group":    decision.Group,
+		"comment":  decision.Comment,
+	}}
+	logger.Info("approval decided", "stage", s.Name, "status", rec.Status, "approver", decision.Approver, "timed_out", timedOut)
+
+	switch {
+	case decision.Approved:
+		return nil
+	case timedOut:
+		return fmt.Errorf("no decision within %v", timeout)
+	case decision.Comment != "":
+		return fmt.Errorf("rejected by %s (%s): %s", decision.Approver, decision.Group, decision.Comment)
+	}
+	return fmt.Errorf("rejected by %s (%s)", decision.Approver, decision.Group)
+}
+
+// recordApproval reports an approval's state. A failure to report does not
+// fail the pipeline, which keeps accepting decisions by signal.
+func (r *run) recordApproval(ctx workflow.Context, rec api.Approval) {
+	ctx, _ = workflow.NewDisconnectedContext(ctx)
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 5},
+	})
+	if err := workflow.ExecuteActivity(ctx, ApprovalActivity, rec).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("approval not recorded", "stage", rec.Stage, "status", rec.Status, "error", err)
+	}
+}
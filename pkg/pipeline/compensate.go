@@ -0,0 +1,81 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
+
+// Compensation is the outcome of undoing one stage
+type Compensation struct {
+	Stage      string           `json:"stage"`
+	Status     string           `json:"status"`
+	Activities []ActivityResult `json:"activities"`
+	Error      string           `json:"error,omitempty"`
+}
+
+// compensate undoes a failed pipeline, saga style: the compensate
+// activities of every stage that did any work run, latest stage first.
+// A stage that failed part-way is included, so compensations must
+// tolerate undoing work that was only partly done. A failed compensation
+// is recorded and the rest still run.
+func (r *run) compensate(ctx workflow.Context) {
+	logger := workflow.GetLogger(ctx)
+	for j := len(r.result.Stages) - 1; j >= 0; j-- {
+		sr := r.result.Stages[j]
+		i, s, ok := r.stageNamed(sr.Name)
+		if !ok || len(s.Compensate) == 0 || !didWork(sr) {
+			continue
+		}
+		r.touch(ctx, s.Name)
+		logger.Info("compensating stage", "stage", s.Name)
+		c := Compensation{Stage: s.Name, Status: StatusCompleted, Activities: []ActivityResult{}}
+		actCtx := r.activityOptions(ctx, r.timeouts[i])
+		in := r.activityInput(s.Name)
+		for _, step := range s.Compensate {
+			ok, err := stepWhen(step, r.env())
+			var res ActivityResult
+			switch {
+			case err != nil:
+				res = ActivityResult{Name: step.Activity, Status: StatusFailed, Error: err.Error()}
+			case !ok:
+				res = ActivityResult{Name: step.Activity, Status: StatusSkipped}
+			default:
+				res = r.await(ctx, "", step.Activity, workflow.ExecuteActivity(actCtx, step.Activity, in))
+			}
+			c.Activities = append(c.Activities, res)
+			if res.Status == StatusFailed && c.Status != StatusFailed {
+				c.Status, c.Error = StatusFailed, fmt.Sprintf("activity %s: %s", step.Activity, res.Error)
+				logger.Error("compensation failed", "stage", s.Name, "activity", step.Activity, "error", res.Error)
+			}
+		}
+		r.result.Compensations = append(r.result.Compensations, c)
+	}
+	r.touch(ctx, "")
+}
+
+// stageNamed finds a stage of the definition being run
+func (r *run) stageNamed(name string) (int, registry.Stage, bool) {
+	for i, s := range r.def.Stages {
+		if s.Name == name {
+			return i, s, true
+		}
+	}
+	return 0, registry.Stage{}, false
+}
+
+// didWork reports whether any activity of a stage, or any item of its
+// loop, completed
+func didWork(sr StageResult) bool {
+	if sr.Loop != nil {
+		return sr.Loop.Completed > 0
+	}
+	for _, a := range sr.Activities {
+		if a.Status == StatusCompleted {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
+
+// ResolveActivity is the name Definitions.Resolve is registered under
+const ResolveActivity = "ResolveDefinition"
+
+// ResolveRequest asks for a workflow definition
+type ResolveRequest struct {
+	Workflow   string `json:"workflow"`
+	CustomerID string `json:"customer_id,omitempty"`
+	// Commit pins the definition; empty means the commit being served
+	Commit string `json:"commit,omitempty"`
+}
+
+// Resolved is a definition and the commit it was read from
+type Resolved struct {
+	Commit     string                      `json:"commit"`
+	Definition registry.WorkflowDefinition `json:"definition"`
+}
+
+// Definitions serves workflow definitions to pipelines by commit. Its
+// result is recorded in workflow history, so a run replays against the
+// definition it started with however often the branch moves afterwards.
+type Definitions struct {
+	// registries returns the registry following a ref, or nil
+	registries func(ref string) *registry.Registry
+}
+
+// NewDefinitions returns Definitions reading from the registry registries
+// returns for the customer's branch, falling back to main
+func NewDefinitions(registries func(ref string) *registry.Registry) *Definitions {
+	return &Definitions{registries: registries}
+}
+
+// RegisterDefinitions adds d's activity to a worker polling the task queue
+// pipelines are started on
+func RegisterDefinitions(w worker.Registry, d *Definitions) {
+	w.RegisterActivityWithOptions(d.Resolve, activity.RegisterOptions{Name: ResolveActivity})
+}
+
+// Resolve loads req.Workflow at req.Commit, or at the commit currently
+// served. Unknown workflows and commits that do not validate fail without
+// retries.
+func (d *Definitions) Resolve(ctx context.Context, req ResolveRequest) (*Resolved, error) {
+	reg, err := d.registry(req.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	snap := reg.Current()
+	if req.Commit != "" {
+		if snap, err = reg.At(ctx, req.Commit); err != nil {
+			var rerr *registry.ReloadError
+			if errors.As(err, &rerr) {
+				return nil, temporal.NewNonRetryableApplicationError(err.Error(), "InvalidDefinition", nil)
+			}
+			return nil, err
+		}
+	}
+	if snap == nil {
+		return nil, registry.ErrNotLoaded
+	}
+	def, ok := snap.Workflow(req.Workflow)
+	if !ok {
+		msg := fmt.Sprintf("workflow %q is not defined at %.12s", req.Workflow, snap.Commit)
+		return nil, temporal.NewNonRetryableApplicationError(msg, "UnknownWorkflow", nil)
+	}
+	return &Resolved{Commit: snap.Commit, Definition: *def}, nil
+}
+
+func (d *Definitions) registry(customerID string) (*registry.Registry, error) {
+	reg, err := d.lookup(customerID)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), "InvalidArgument", nil)
+	}
+	return reg, nil
+}
+
+// lookup returns the registry of the customer's branch, or main's
+func (d *Definitions) lookup(customerID string) (*registry.Registry, error) {
+	if customerID != "" {
+		ref, err := registry.TenantRef(customerID)
+		if err != nil {
+			return nil, err
+		}
+		if reg := d.registries(ref); reg != nil {
+			return reg, nil
+		}
+	}
+	if reg := d.registries(registry.MainRef); reg != nil {
+		return reg, nil
+	}
+	return nil, fmt.Errorf("no registry serves %s", registry.MainRef)
+}
+
+// ValidateInput implements api.InputValidator: req's parameters must match
+// the inputs schema of the definition the run would start from. Workflow
+// types without a definition are left to the workflow service.
+func (d *Definitions) ValidateInput(ctx context.Context, req api.WorkflowExecuteRequest) error {
+	reg, err := d.lookup(req.CustomerID)
+	if err != nil {
+		return err
+	}
+	snap, err := reg.SnapshotFor(ctx)
+	if err != nil {
+		return err
+	}
+	def, ok := snap.Workflow(req.WorkflowType)
+	if !ok {
+		return nil
+	}
+	return checkInputs(def, req.Parameters)
+}
+
+// checkInputs validates parameters against def's inputs schema; missing
+// parameters are an empty object
+func checkInputs(def *registry.WorkflowDefinition, params map[string]interface{}) error {
+	s, err := def.InputSchema()
+	if err != nil {
+		return fmt.Errorf("workflow %s: inputs: %w", def.Name, err)
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	if err := s.Validate(params); err != nil {
+		return fmt.Errorf("%w: parameters do not match the inputs of workflow %s: %v", api.ErrInvalidArgument, def.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,332 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/expr"
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
+
+// BatchWorkflowType is the name of the child workflow that runs one batch
+// of a loop fanned out with batch_size
+const BatchWorkflowType = "PipelineBatchWorkflow"
+
+// maxLoopErrors bounds the item failures a LoopResult lists
+const maxLoopErrors = 10
+
+// LoopResult summarises the items of a foreach or map stage
+type LoopResult struct {
+	Items     int `json:"items"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	// Skipped items were not started because an earlier one failed or the
+	// stage timed out
+	Skipped int `json:"skipped"`
+	// Batches is the number of child workflows the items were fanned out to
+	Batches int `json:"batches,omitempty"`
+	// Errors are the first failures, in item order
+	Errors []ItemError `json:"errors,omitempty"`
+}
+
+// ItemError is the failure of one loop item
+type ItemError struct {
+	Index    int    `json:"index"`
+	Activity string `json:"activity,omitempty"`
+	Error    string `json:"error"`
+}
+
+// ItemOutcome is the result of one loop item
+type ItemOutcome struct {
+	Status string `json:"status"`
+	// Outputs are the item's activity outputs, kept only by map loops
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+	// Activity is the activity that failed, if any
+	Activity string `json:"activity,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchInput starts a child workflow running some items of a loop stage
+type BatchInput struct {
+	Definition registry.WorkflowDefinition `json:"definition"`
+	Stage      int                         `json:"stage"`
+	// Steps are the stage's activities, after its switch was decided
+	Steps []registry.Step `json:"steps"`
+	Items []interface{}   `json:"items"`
+	// Offset is the index of Items[0] in the stage's whole list
+	Offset int           `json:"offset"`
+	Base   ActivityInput `json:"base"`
+	// Concurrency is this batch's share of the stage's concurrency; 0, as
+	// in histories from before it was set, uses the stage's own
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// BatchWorkflow runs a batch of a loop stage's items with its share of the
+// stage's concurrency. Item failures are returned as outcomes, not as an
+// error.
+func BatchWorkflow(ctx workflow.Context, in BatchInput) ([]ItemOutcome, error) {
+	p, err := newPlan(in.Definition)
+	if err == nil && (in.Stage < 0 || in.Stage >= len(p.def.Stages)) {
+		err = fmt.Errorf("no stage %d", in.Stage)
+	}
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), "InvalidDefinition", nil)
+	}
+	s := p.def.Stages[in.Stage]
+	spec, collect := s.Loop()
+	if spec == nil {
+		return nil, temporal.NewNonRetryableApplicationError("stage "+s.Name+" has no loop", "InvalidDefinition", nil)
+	}
+	l := &loop{
+		spec: spec, collect: collect, stage: s, steps: in.Steps, base: in.Base,
+		actCtx: p.activityOptions(ctx, p.timeouts[in.Stage]), limit: spec.Concurrency,
+	}
+	if in.Concurrency > 0 {
+		l.limit = in.Concurrency
+	}
+	return l.run(ctx, in.Items, in.Offset), nil
+}
+
+// loop runs a foreach or map stage's steps once per item
+type loop struct {
+	spec    *registry.Loop
+	collect bool
+	stage   registry.Stage
+	steps   []registry.Step
+	base    ActivityInput
+	actCtx  workflow.Context
+	// limit bounds the items in flight
+	limit int
+}
+
+// run runs items, at most limit at a time, and stops starting new ones
+// after a failure
+func (l *loop) run(ctx workflow.Context, items []interface{}, offset int) []ItemOutcome {
+	out := make([]ItemOutcome, len(items))
+	for i := range out {
+		out[i].Status = StatusSkipped
+	}
+	bounded(ctx, l.limit, len(items), func(ctx workflow.Context, i int) bool {
+		out[i] = l.item(ctx, offset+i, items[i])
+		return out[i].Status != StatusFailed
+	})
+	return out
+}
+
+// item runs the steps for one item. Its activities see the item's own
+// earlier outputs as the stage's outputs.
+func (l *loop) item(ctx workflow.Context, index int, item interface{}) ItemOutcome {
+	outputs := map[string]interface{}{}
+	in := l.base
+	in.Item, in.Index = item, index
+	in.Outputs = make(map[string]map[string]interface{}, len(l.base.Outputs)+1)
+	for k, v := range l.base.Outputs {
+		in.Outputs[k] = v
+	}
+	in.Outputs[l.stage.Name] = outputs
+
+	res := ItemOutcome{Status: StatusCompleted}
+	fail := func(activity string, err string) ItemOutcome {
+		res.Status, res.Activity, res.Error = StatusFailed, activity, err
+		return res
+	}
+	env := exprEnv(in)
+	env[l.spec.Item()] = item
+	futures := make([]workflow.Future, len(l.steps))
+	for j, step := range l.steps {
+		ok, err := stepWhen(step, env)
+		if err != nil {
+			return fail(step.Activity, err.Error())
+		}
+		if !ok {
+			continue
+		}
+		futures[j] = workflow.ExecuteActivity(l.actCtx, step.Activity, in)
+		if l.stage.Parallel {
+			continue
+		}
+		var out interface{}
+		if err := futures[j].Get(ctx, &out); err != nil {
+			return fail(step.Activity, activityError(err))
+		}
+		futures[j] = nil
+		outputs[step.Activity] = out
+	}
+	for j, f := range futures {
+		if f == nil {
+			continue
+		}
+		var out interface{}
+		if err := f.Get(ctx, &out); err != nil && res.Status != StatusFailed {
+			fail(l.steps[j].Activity, activityError(err))
+		}
+		outputs[l.steps[j].Activity] = out
+	}
+	if l.collect {
+		res.Outputs = outputs
+	}
+	return res
+}
+
+// bounded calls fn for 0..n-1 with at most limit calls in flight, one at a
+// time if limit < 1. Once a call reports failure, or ctx is cancelled, no
+// more are started.
+func bounded(ctx workflow.Context, limit, n int, fn func(ctx workflow.Context, i int) bool) {
+	slots := workflow.NewBufferedChannel(ctx, max(limit, 1))
+	wg := workflow.NewWaitGroup(ctx)
+	failed := false
+	for i := 0; i < n; i++ {
+		slots.Send(ctx, true)
+		if failed || ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		workflow.Go(ctx, func(gctx workflow.Context) {
+			defer wg.Done()
+			if !fn(gctx, i) {
+				failed = true
+			}
+			slots.Receive(gctx, nil)
+		})
+	}
+	wg.Wait(ctx)
+}
+
+// loopItems evaluates a loop's items expression, which must yield a list
+func (r *run) loopItems(spec *registry.Loop) ([]interface{}, error) {
+	e, err := expr.Parse(spec.Items)
+	if err == nil {
+		var v interface{}
+		if v, err = e.Eval(exprEnv(r.activityInput(""))); err == nil {
+			if items, ok := v.([]interface{}); ok {
+				return items, nil
+			}
+			err = fmt.Errorf("yields %T, not a list", v)
+			if v == nil {
+				err = fmt.Errorf("yields null, not a list")
+			}
+		}
+	}
+	return nil, fmt.Errorf("items %q: %v", spec.Items, err)
+}
+
+// runLoop runs a foreach or map stage, in batches of child workflows when
+// the list is longer than batch_size, and records the items' outcomes.
+// A map stage's outputs are lists in item order, followed by the output of
+// its aggregate activity.
+func (r *run) runLoop(ctx, stageCtx, actCtx workflow.Context, i int, s registry.Stage, steps []registry.Step, sr *StageResult) error {
+	spec, collect := s.Loop()
+	items, err := r.loopItems(spec)
+	if err != nil {
+		return err
+	}
+	l := &loop{spec: spec, collect: collect, stage: s, steps: steps, base: r.activityInput(s.Name), actCtx: actCtx, limit: spec.Concurrency}
+	lr := &LoopResult{Items: len(items)}
+	sr.Loop = lr
+
+	var outcomes []ItemOutcome
+	if size := spec.BatchSize; size > 0 && len(items) > size {
+		lr.Batches = (len(items) + size - 1) / size
+		outcomes = r.fanOut(stageCtx, i, l, items, lr.Batches)
+	} else {
+		outcomes = l.run(stageCtx, items, 0)
+	}
+
+	var first error
+	failedAt := map[string]string{}
+	lists := map[string][]interface{}{}
+	for idx, o := range outcomes {
+		switch o.Status {
+		case StatusCompleted:
+			lr.Completed++
+		case StatusFailed:
+			lr.Failed++
+			if len(lr.Errors) < maxLoopErrors {
+				lr.Errors = append(lr.Errors, ItemError{Index: idx, Activity: o.Activity, Error: o.Error})
+			}
+			if first == nil {
+				first = fmt.Errorf("item %d: %s", idx, o.Error)
+				if o.Activity != "" {
+					first = fmt.Errorf("item %d: activity %s: %s", idx, o.Activity, o.Error)
+					failedAt[o.Activity] = first.Error()
+				}
+			}
+		default:
+			lr.Skipped++
+		}
+		if collect {
+			for _, step := range steps {
+				if lists[step.Activity] == nil {
+					lists[step.Activity] = make([]interface{}, len(items))
+				}
+				lists[step.Activity][idx] = o.Outputs[step.Activity]
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, step := range steps {
+		if seen[step.Activity] {
+			continue
+		}
+		seen[step.Activity] = true
+		res := ActivityResult{Name: step.Activity, Status: StatusCompleted}
+		if msg, ok := failedAt[step.Activity]; ok {
+			res.Status, res.Error = StatusFailed, msg
+		}
+		if collect {
+			res.Output = lists[step.Activity]
+			if r.outputs[s.Name] == nil {
+				r.outputs[s.Name] = map[string]interface{}{}
+			}
+			r.outputs[s.Name][step.Activity] = lists[step.Activity]
+		}
+		sr.Activities = append(sr.Activities, res)
+	}
+
+	if first == nil && collect && spec.Aggregate != "" {
+		res := r.await(ctx, s.Name, spec.Aggregate, workflow.ExecuteActivity(actCtx, spec.Aggregate, r.activityInput(s.Name)))
+		sr.Activities = append(sr.Activities, res)
+		if res.Status == StatusFailed {
+			first = fmt.Errorf("aggregate %s: %s", spec.Aggregate, res.Error)
+		}
+	}
+	return first
+}
+
+// fanOut runs items as child workflows of batch_size items each. The
+// stage's concurrency is split between the children running at once, so
+// no more than concurrency items are in flight across all of them. A child
+// that fails fails its whole batch.
+func (r *run) fanOut(ctx workflow.Context, stage int, l *loop, items []interface{}, batches int) []ItemOutcome {
+	size := l.spec.BatchSize
+	out := make([]ItemOutcome, len(items))
+	for i := range out {
+		out[i].Status = StatusSkipped
+	}
+	children := min(max(l.limit, 1), batches)
+	share := max(l.limit/children, 1)
+	bounded(ctx, children, batches, func(ctx workflow.Context, b int) bool {
+		lo, hi := b*size, min((b+1)*size, len(items))
+		in := BatchInput{
+			Definition: r.def, Stage: stage, Steps: l.steps, Items: items[lo:hi], Offset: lo, Base: l.base,
+			Concurrency: share,
+		}
+		var got []ItemOutcome
+		err := workflow.ExecuteChildWorkflow(ctx, BatchWorkflowType, in).Get(ctx, &got)
+		ok := err == nil
+		for j := lo; j < hi; j++ {
+			switch {
+			case err != nil:
+				out[j] = ItemOutcome{Status: StatusFailed, Error: fmt.Sprintf("batch %d: %v", b, err)}
+			case j-lo < len(got):
+				out[j] = got[j-lo]
+				ok = ok && got[j-lo].Status != StatusFailed
+			}
+		}
+		return ok
+	})
+	return out
+}
@@ -0,0 +1,690 @@
+// Package pipeline runs workflow definitions from the runtime repository on
+// Temporal. One generic workflow interprets a definition's stages, so a new
+// pipeline is a YAML commit rather than Go code; workers only register the
+// activities the definitions name.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/expr"
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
+
+// WorkflowType is the name the interpreter is registered under
+const WorkflowType = "PipelineWorkflow"
+
+// MigrateSignal asks a pipeline started from a named definition to move
+// to the latest commit at its next safe point
+const MigrateSignal = "migrate"
+
+// ProgressQuery returns an api.WorkflowProgress for a running pipeline
+const ProgressQuery = "progress"
+
+// FailureType is the Temporal error type of a failed pipeline. Its details
+// hold the pipeline's *Result.
+const FailureType = "PipelineFailed"
+
+// DefaultActivityTimeout bounds each activity attempt in a stage without a
+// timeout
+const DefaultActivityTimeout = 10 * time.Minute
+
+// Statuses of a pipeline, stage or activity
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	// StatusSkipped marks stages and activities whose when was false
+	StatusSkipped = "skipped"
+)
+
+// Input starts a pipeline
+type Input struct {
+	// Workflow names a definition in the runtime repository. The run pins
+	// the commit it is read from, so later commits never change a run in
+	// flight (see Migrate).
+	Workflow string `json:"workflow,omitempty"`
+	// Commit pins the definition; empty means the commit served when the
+	// run starts
+	Commit string `json:"commit,omitempty"`
+	// Definition is run as given when Workflow is empty
+	Definition registry.WorkflowDefinition `json:"definition"`
+	CustomerID string                      `json:"customer_id"`
+	Parameters map[string]interface{}      `json:"parameters,omitempty"`
+	// Migrate moves the run to the latest definition at the next safe point,
+	// between stages, by continuing as new. Without it a run migrates only
+	// when sent MigrateSignal.
+	Migrate bool `json:"migrate,omitempty"`
+	// Resume carries a migrated run's progress into its new run
+	Resume *Checkpoint `json:"resume,omitempty"`
+}
+
+// Checkpoint is a run's progress at a safe point
+type Checkpoint struct {
+	// Next is the index of the first stage still to run
+	Next      int                               `json:"next"`
+	Stages    []StageResult                     `json:"stages"`
+	Hooks     []ActivityResult                  `json:"hooks,omitempty"`
+	Outputs   map[string]map[string]interface{} `json:"outputs,omitempty"`
+	StartedAt time.Time                         `json:"started_at"`
+	// Commits are the earlier commits the run migrated from, oldest first
+	Commits []string `json:"commits"`
+}
+
+// ActivityInput is passed to every activity a pipeline runs
+type ActivityInput struct {
+	Workflow   string                 `json:"workflow"`
+	Stage      string                 `json:"stage"`
+	CustomerID string                 `json:"customer_id"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// Outputs are the results of earlier activities, by stage then activity
+	Outputs map[string]map[string]interface{} `json:"outputs,omitempty"`
+	// Item and Index are the current item of a foreach or map stage
+	Item  interface{} `json:"item,omitempty"`
+	Index int         `json:"index,omitempty"`
+}
+
+// ActivityResult is the outcome of one activity
+type ActivityResult struct {
+	Name   string      `json:"name"`
+	Status string      `json:"status"`
+	Output interface{} `json:"output,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// StageResult is the outcome of one stage
+type StageResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Case is the when of the switch case that ran, or "default"
+	Case       string           `json:"case,omitempty"`
+	Activities []ActivityResult `json:"activities"`
+	// Loop counts the items of a foreach or map stage
+	Loop *LoopResult `json:"loop,omitempty"`
+	// Approval is the request and decision of an approval stage
+	Approval *api.Approval `json:"approval,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Result is what a pipeline returns, or carries as the details of a
+// FailureType error
+type Result struct {
+	Workflow string `json:"workflow"`
+	Version  string `json:"version,omitempty"`
+	// Commit is the commit the definition was read from, if it was named
+	Commit string `json:"commit,omitempty"`
+	// MigratedFrom are earlier commits the run continued from, oldest first
+	MigratedFrom []string      `json:"migrated_from,omitempty"`
+	Status       string        `json:"status"`
+	Stages       []StageResult `json:"stages"`
+	// Hooks are the on_start, on_success and on_failure activities that ran
+	Hooks []ActivityResult `json:"hooks,omitempty"`
+	// Compensations are the stages undone after a failure, in the order
+	// they were undone
+	Compensations []Compensation `json:"compensations,omitempty"`
+	// Outputs are the activity outputs by stage, checked against the
+	// definition's outputs schema when the stages succeed
+	Outputs map[string]map[string]interface{} `json:"outputs,omitempty"`
+	Error   string                            `json:"error,omitempty"`
+}
+
+// Register adds the interpreter, and the child workflow it fans loops out
+// to, to a worker. Workflow stages run the interpreter itself as a child.
+func Register(w worker.Registry) {
+	w.RegisterWorkflowWithOptions(Workflow, workflow.RegisterOptions{Name: WorkflowType})
+	w.RegisterWorkflowWithOptions(BatchWorkflow, workflow.RegisterOptions{Name: BatchWorkflowType})
+}
+
+// Start runs in, by default on the definition's task queue. A named
+// definition is pinned to the context's config commit (api.ConfigCommit)
+// when in.Commit is empty. The deadline from max_duration is enforced by
+// the workflow itself so on_failure hooks still run when it expires.
+func Start(ctx context.Context, c client.Client, opts client.StartWorkflowOptions, in Input) (client.WorkflowRun, error) {
+	if opts.TaskQueue == "" {
+		opts.TaskQueue = in.Definition.TaskQueue
+	}
+	if opts.TaskQueue == "" {
+		return nil, fmt.Errorf("%w: no task queue for pipeline", api.ErrInvalidArgument)
+	}
+	if commit, ok := api.ConfigCommit(ctx); ok && in.Workflow != "" && in.Commit == "" {
+		in.Commit = commit
+	}
+	return c.ExecuteWorkflow(ctx, opts, WorkflowType, in)
+}
+
+// plan is a definition with its durations parsed
+type plan struct {
+	def         registry.WorkflowDefinition
+	maxDuration time.Duration
+	// timeouts are per stage; an approval stage's is its approval timeout
+	timeouts    []time.Duration
+	escalations []time.Duration
+	retry       *temporal.RetryPolicy
+}
+
+func newPlan(def registry.WorkflowDefinition) (*plan, error) {
+	p := &plan{def: def, timeouts: make([]time.Duration, len(def.Stages)), escalations: make([]time.Duration, len(def.Stages))}
+	var err error
+	parse := func(field, s string) time.Duration {
+		if s == "" || err != nil {
+			return 0
+		}
+		d, perr := time.ParseDuration(s)
+		if perr != nil {
+			err = fmt.Errorf("%s: %v", field, perr)
+		}
+		return d
+	}
+	p.maxDuration = parse("max_duration", def.MaxDuration)
+	for i, s := range def.Stages {
+		p.timeouts[i] = parse(fmt.Sprintf("stages[%d].timeout", i), s.Timeout)
+		if a := s.Approval; a != nil {
+			p.timeouts[i] = parse(fmt.Sprintf("stages[%d].approval.timeout", i), a.Timeout)
+			p.escalations[i] = parse(fmt.Sprintf("stages[%d].approval.escalate_after", i), a.EscalateAfter)
+		}
+	}
+	rp := def.RetryPolicy
+	p.retry = &temporal.RetryPolicy{
+		MaximumAttempts:    int32(rp.MaximumAttempts),
+		InitialInterval:    parse("retry_policy.initial_interval", rp.InitialInterval),
+		BackoffCoefficient: rp.BackoffCoefficient,
+		MaximumInterval:    parse("retry_policy.maximum_interval", rp.MaximumInterval),
+	}
+	return p, err
+}
+
+// Workflow interprets in.Definition. Stages run in order, skipping those
+// whose when expression is false; a switch picks the activities of its
+// first matching case. A stage's activities run one after another,
+// stopping at the first failure, or all at once when the stage is parallel.
+// A failed stage fails the pipeline unless it has continue_on_error; the
+// compensate activities of the stages that did work then undo it, latest
+// stage first. on_start hooks run first and fail the pipeline like a
+// stage; on_success or on_failure hooks run at the end and their failures
+// are only recorded.
+//
+// A named definition is fetched by commit through ResolveActivity, which
+// records it in history: replays see the same definition even after the
+// branch has moved, and new runs pick up the latest.
+func Workflow(ctx workflow.Context, in Input) (*Result, error) {
+	def := in.Definition
+	if in.Workflow != "" {
+		resolved, err := resolve(ctx, in, in.Commit)
+		if err != nil {
+			return nil, err
+		}
+		def, in.Commit = resolved.Definition, resolved.Commit
+	}
+	p, err := newPlan(def)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), "InvalidDefinition", nil)
+	}
+	// A migrated run was checked when it first started
+	if in.Resume == nil {
+		if err := checkInputs(&p.def, in.Parameters); err != nil {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), "InvalidInput", nil)
+		}
+	}
+	r := &run{plan: p, in: in, outputs: map[string]map[string]interface{}{}, started: workflow.Now(ctx)}
+	r.result = Result{Workflow: p.def.Name, Version: p.def.Version, Commit: in.Commit, Status: StatusRunning, Stages: []StageResult{}}
+	if cp := in.Resume; cp != nil {
+		r.next, r.started = cp.Next, cp.StartedAt
+		r.result.Stages, r.result.Hooks, r.result.MigratedFrom = cp.Stages, cp.Hooks, cp.Commits
+		if cp.Outputs != nil {
+			r.outputs = cp.Outputs
+		}
+	}
+	r.touch(ctx, "")
+	if err := workflow.SetQueryHandler(ctx, ProgressQuery, r.progress); err != nil {
+		return nil, err
+	}
+	if err := workflow.SetQueryHandler(ctx, ApprovalsQuery, r.approvals); err != nil {
+		return nil, err
+	}
+	r.migrateRequests = workflow.GetSignalChannel(ctx, MigrateSignal)
+	logger := workflow.GetLogger(ctx)
+	logger.Info("pipeline started", "workflow", p.def.Name, "commit", in.Commit, "customer", in.CustomerID)
+
+	// max_duration runs from the original start across migrations
+	deadline := p.maxDuration
+	if deadline > 0 {
+		deadline = max(deadline-workflow.Now(ctx).Sub(r.started), time.Nanosecond)
+	}
+	runCtx, expired := withDeadline(ctx, deadline)
+	err = r.stages(runCtx)
+	if expired() {
+		err = fmt.Errorf("pipeline exceeded its max_duration of %v", p.maxDuration)
+	} else if workflow.IsContinueAsNewError(err) {
+		return nil, err
+	}
+	r.result.Outputs = r.outputs
+	if err == nil {
+		err = r.checkOutputs()
+	}
+
+	if err == nil {
+		r.hooks(ctx, "on_success", p.def.Hooks.OnSuccess, false)
+		r.result.Status = StatusCompleted
+		r.touch(ctx, "")
+		logger.Info("pipeline completed", "workflow", p.def.Name)
+		return &r.result, nil
+	}
+
+	r.result.Status, r.result.Error = StatusFailed, err.Error()
+	r.touch(ctx, "")
+	logger.Error("pipeline failed", "workflow", p.def.Name, "error", err)
+	// Compensations and hooks must run even when the pipeline was cancelled
+	hookCtx, _ := workflow.NewDisconnectedContext(ctx)
+	r.compensate(hookCtx)
+	r.hooks(hookCtx, "on_failure", p.def.Hooks.OnFailure, false)
+	return nil, temporal.NewApplicationError(err.Error(), FailureType, &r.result)
+}
+
+// checkOutputs validates the outputs of a run whose stages succeeded
+func (r *run) checkOutputs() error {
+	s, err := r.def.OutputSchema()
+	if err == nil {
+		err = s.Validate(r.outputs)
+	}
+	if err != nil {
+		return fmt.Errorf("outputs do not match the outputs schema: %v", err)
+	}
+	return nil
+}
+
+// run is the state of one pipeline execution
+type run struct {
+	*plan
+	in      Input
+	result  Result
+	outputs map[string]map[string]interface{}
+	stage   string
+	updated time.Time
+
+	// next is the first stage this run executes; earlier ones ran before a
+	// migration
+	next            int
+	started         time.Time
+	migrateRequests workflow.ReceiveChannel
+}
+
+func (r *run) touch(ctx workflow.Context, stage string) {
+	r.stage, r.updated = stage, workflow.Now(ctx)
+}
+
+func (r *run) progress() (api.WorkflowProgress, error) {
+	done := 0
+	for _, s := range r.result.Stages {
+		if s.Status != StatusRunning {
+			done++
+		}
+	}
+	percent := 100.0
+	if n := len(r.def.Stages); n > 0 {
+		percent = float64(done) * 100 / float64(n)
+	}
+	return api.WorkflowProgress{
+		Status:    r.result.Status,
+		Stage:     r.stage,
+		Percent:   percent,
+		Message:   r.result.Error,
+		Timestamp: r.updated,
+	}, nil
+}
+
+func (r *run) stages(ctx workflow.Context) error {
+	if r.next == 0 {
+		if err := r.hooks(ctx, "on_start", r.def.Hooks.OnStart, true); err != nil {
+			return err
+		}
+	}
+	for i := r.next; i < len(r.def.Stages); i++ {
+		if i > r.next {
+			if err := r.safePoint(ctx, i); err != nil {
+				return err
+			}
+		}
+		s := r.def.Stages[i]
+		if err := r.runStage(ctx, i, s); err != nil && !s.ContinueOnError {
+			return err
+		}
+	}
+	return nil
+}
+
+// safePoint runs between stages. If a migration is due and the latest
+// definition differs, it returns a continue-as-new error that resumes the
+// run at stage next of the latest definition.
+func (r *run) safePoint(ctx workflow.Context, next int) error {
+	if r.in.Workflow == "" {
+		return nil
+	}
+	requested := r.in.Migrate
+	var ignored interface{}
+	for r.migrateRequests.ReceiveAsync(&ignored) {
+		requested = true
+	}
+	if !requested {
+		return nil
+	}
+	logger := workflow.GetLogger(ctx)
+	latest, err := resolve(ctx, r.in, "")
+	if err != nil {
+		logger.Warn("migration skipped: cannot resolve latest definition", "error", err)
+		return nil
+	}
+	if latest.Commit == r.in.Commit || reflect.DeepEqual(latest.Definition, r.def) {
+		return nil
+	}
+	// The stages already run must still be the new definition's first ones
+	if len(latest.Definition.Stages) < next {
+		logger.Warn("migration skipped: latest definition has fewer stages", "commit", latest.Commit)
+		return nil
+	}
+	for i := 0; i < next; i++ {
+		if latest.Definition.Stages[i].Name != r.def.Stages[i].Name {
+			logger.Warn("migration skipped: completed stages were changed", "commit", latest.Commit, "stage", r.def.Stages[i].Name)
+			return nil
+		}
+	}
+
+	logger.Info("migrating pipeline", "from", r.in.Commit, "to", latest.Commit, "completed_stages", next)
+	in := r.in
+	in.Commit = latest.Commit
+	in.Resume = &Checkpoint{
+		Next:      next,
+		Stages:    r.result.Stages,
+		Hooks:     r.result.Hooks,
+		Outputs:   r.outputs,
+		StartedAt: r.started,
+		Commits:   append(append([]string{}, r.result.MigratedFrom...), r.in.Commit),
+	}
+	return workflow.NewContinueAsNewError(ctx, WorkflowType, in)
+}
+
+// resolve fetches the input's named definition at commit
+func resolve(ctx workflow.Context, in Input, commit string) (*Resolved, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 5},
+	})
+	req := ResolveRequest{Workflow: in.Workflow, CustomerID: in.CustomerID, Commit: commit}
+	var resolved Resolved
+	if err := workflow.ExecuteActivity(ctx, ResolveActivity, req).Get(ctx, &resolved); err != nil {
+		return nil, err
+	}
+	return &resolved, nil
+}
+
+// activityOptions applies the definition's task queue and retry policy
+func (p *plan) activityOptions(ctx workflow.Context, timeout time.Duration) workflow.Context {
+	if timeout <= 0 {
+		timeout = DefaultActivityTimeout
+	}
+	return workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		TaskQueue:           p.def.TaskQueue,
+		StartToCloseTimeout: timeout,
+		RetryPolicy:         p.retry,
+	})
+}
+
+func (r *run) activityInput(stage string) ActivityInput {
+	return ActivityInput{
+		Workflow:   r.def.Name,
+		Stage:      stage,
+		CustomerID: r.in.CustomerID,
+		Parameters: r.in.Parameters,
+		Outputs:    r.outputs,
+	}
+}
+
+func (r *run) runStage(ctx workflow.Context, i int, s registry.Stage) error {
+	r.result.Stages = append(r.result.Stages, StageResult{Name: s.Name, Status: StatusRunning, Activities: []ActivityResult{}})
+	sr := &r.result.Stages[len(r.result.Stages)-1]
+	r.touch(ctx, s.Name)
+	logger := workflow.GetLogger(ctx)
+
+	steps, ok, err := r.selectSteps(s, sr)
+	if err == nil && !ok {
+		sr.Status = StatusSkipped
+		logger.Info("stage skipped", "stage", s.Name)
+		return nil
+	}
+	switch {
+	case err != nil:
+	case s.Approval != nil:
+		err = r.runApproval(ctx, i, s, sr)
+	case s.Workflow != nil:
+		err = r.runWorkflow(ctx, i, s, sr)
+	default:
+		logger.Info("stage started", "stage", s.Name, "parallel", s.Parallel)
+		err = r.runSteps(ctx, i, s, steps, sr)
+	}
+
+	sr.Status = StatusCompleted
+	if err != nil {
+		err = fmt.Errorf("stage %s: %w", s.Name, err)
+		sr.Status, sr.Error = StatusFailed, err.Error()
+	}
+	r.touch(ctx, s.Name)
+	return err
+}
+
+// selectSteps decides what a stage runs. It runs nothing if its when is
+// false or no switch case matches.
+func (r *run) selectSteps(s registry.Stage, sr *StageResult) ([]registry.Step, bool, error) {
+	if s.When != "" {
+		if ok, err := r.eval(s.When); err != nil || !ok {
+			return nil, false, err
+		}
+	}
+	if len(s.Switch) == 0 {
+		return s.Activities, true, nil
+	}
+	for _, c := range s.Switch {
+		if c.When == "" {
+			sr.Case = "default"
+			return c.Activities, true, nil
+		}
+		ok, err := r.eval(c.When)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			sr.Case = c.When
+			return c.Activities, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// runSteps runs a stage's activities, skipping those whose when is false.
+// In a parallel stage every when is evaluated before any activity starts.
+func (r *run) runSteps(ctx workflow.Context, i int, s registry.Stage, steps []registry.Step, sr *StageResult) error {
+	stageCtx, expired := withDeadline(ctx, r.timeouts[i])
+	actCtx := r.activityOptions(stageCtx, r.timeouts[i])
+	in := r.activityInput(s.Name)
+
+	var err error
+	if spec, _ := s.Loop(); spec != nil {
+		err = r.runLoop(ctx, stageCtx, actCtx, i, s, steps, sr)
+	} else if s.Parallel {
+		futures := make([]workflow.Future, len(steps))
+		for j, step := range steps {
+			ok, werr := stepWhen(step, r.env())
+			switch {
+			case werr != nil:
+				return werr
+			case ok:
+				futures[j] = workflow.ExecuteActivity(actCtx, step.Activity, in)
+			}
+		}
+		for j, step := range steps {
+			if futures[j] == nil {
+				sr.Activities = append(sr.Activities, ActivityResult{Name: step.Activity, Status: StatusSkipped})
+				continue
+			}
+			res := r.await(ctx, s.Name, step.Activity, futures[j])
+			sr.Activities = append(sr.Activities, res)
+			if res.Status == StatusFailed && err == nil {
+				err = fmt.Errorf("activity %s: %s", step.Activity, res.Error)
+			}
+		}
+	} else {
+		for _, step := range steps {
+			ok, werr := stepWhen(step, r.env())
+			if werr != nil {
+				err = werr
+				break
+			}
+			if !ok {
+				sr.Activities = append(sr.Activities, ActivityResult{Name: step.Activity, Status: StatusSkipped})
+				continue
+			}
+			res := r.await(ctx, s.Name, step.Activity, workflow.ExecuteActivity(actCtx, step.Activity, in))
+			sr.Activities = append(sr.Activities, res)
+			if res.Status == StatusFailed {
+				err = fmt.Errorf("activity %s: %s", step.Activity, res.Error)
+				break
+			}
+			// Later activities in the stage see earlier outputs
+			in = r.activityInput(s.Name)
+		}
+	}
+	if expired() {
+		err = fmt.Errorf("exceeded its %v timeout", r.timeouts[i])
+	}
+	return err
+}
+
+func stepWhen(step registry.Step, env map[string]interface{}) (bool, error) {
+	if step.When == "" {
+		return true, nil
+	}
+	ok, err := evalWhen(step.When, env)
+	if err != nil {
+		return false, fmt.Errorf("activity %s: %w", step.Activity, err)
+	}
+	return ok, nil
+}
+
+// env is what expressions read: the run's parameters and outputs so far
+func (r *run) env() map[string]interface{} {
+	return exprEnv(r.activityInput(""))
+}
+
+func (r *run) eval(src string) (bool, error) {
+	return evalWhen(src, r.env())
+}
+
+// exprEnv binds the names expressions read to an activity's input
+func exprEnv(in ActivityInput) map[string]interface{} {
+	params := in.Parameters
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	outputs := in.Outputs
+	if outputs == nil {
+		outputs = map[string]map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		registry.ExprParams:   params,
+		registry.ExprStages:   outputs,
+		registry.ExprCustomer: in.CustomerID,
+	}
+}
+
+// evalWhen evaluates a when expression, which must yield true or false
+func evalWhen(src string, env map[string]interface{}) (bool, error) {
+	e, err := expr.Parse(src)
+	if err == nil {
+		var ok bool
+		if ok, err = e.Bool(env); err == nil {
+			return ok, nil
+		}
+	}
+	return false, fmt.Errorf("when %q: %v", src, err)
+}
+
+// await waits for an activity and records its output under stage, if any
+func (r *run) await(ctx workflow.Context, stage, name string, f workflow.Future) ActivityResult {
+	res := ActivityResult{Name: name, Status: StatusCompleted}
+	var out interface{}
+	if err := f.Get(ctx, &out); err != nil {
+		res.Status, res.Error = StatusFailed, activityError(err)
+		return res
+	}
+	res.Output = out
+	if stage == "" {
+		return res
+	}
+	if r.outputs[stage] == nil {
+		r.outputs[stage] = map[string]interface{}{}
+	}
+	r.outputs[stage][name] = out
+	return res
+}
+
+// hooks runs a hook list in order. With stopOnError the first failure is
+// returned; otherwise every hook runs and failures are only recorded.
+func (r *run) hooks(ctx workflow.Context, kind string, names []string, stopOnError bool) error {
+	if len(names) == 0 {
+		return nil
+	}
+	actCtx := r.activityOptions(ctx, 0)
+	in := r.activityInput(kind)
+	for _, name := range names {
+		res := r.await(ctx, "", name, workflow.ExecuteActivity(actCtx, name, in))
+		r.result.Hooks = append(r.result.Hooks, res)
+		if res.Status != StatusFailed {
+			continue
+		}
+		err := fmt.Errorf("%s hook %s: %s", kind, name, res.Error)
+		if stopOnError {
+			return err
+		}
+		workflow.GetLogger(ctx).Warn("hook failed", "hook", kind, "activity", name, "error", res.Error)
+	}
+	return nil
+}
+
+// activityError unwraps Temporal's activity error to the cause's message
+func activityError(err error) string {
+	var actErr *temporal.ActivityError
+	if errors.As(err, &actErr) && actErr.Unwrap() != nil {
+		return actErr.Unwrap().Error()
+	}
+	return err.Error()
+}
+
+// withDeadline returns a context cancelled after d (no deadline if d <= 0).
+// The returned func stops the timer and reports whether it fired.
+func withDeadline(ctx workflow.Context, d time.Duration) (workflow.Context, func() bool) {
+	if d <= 0 {
+		return ctx, func() bool { return false }
+	}
+	ctx, cancel := workflow.WithCancel(ctx)
+	timerCtx, stopTimer := workflow.WithCancel(ctx)
+	fired := false
+	timer := workflow.NewTimer(timerCtx, d)
+	workflow.Go(timerCtx, func(gctx workflow.Context) {
+		if timer.Get(gctx, nil) == nil {
+			fired = true
+			cancel()
+		}
+	})
+	return ctx, func() bool {
+		stopTimer()
+		return fired
+	}
+}
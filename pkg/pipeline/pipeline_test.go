@@ -0,0 +1,1334 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/mocks"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
+
+// recorder is a fake worker: every activity name it registers logs its
+// call and returns "<name> done"
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+	seen  map[string]ActivityInput
+	fail  map[string]bool
+}
+
+func (rec *recorder) register(env *testsuite.TestWorkflowEnvironment, names ...string) {
+	for _, name := range names {
+		name := name
+		env.RegisterActivityWithOptions(func(ctx context.Context, in ActivityInput) (string, error) {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			rec.calls = append(rec.calls, name)
+			rec.seen[name] = in
+			if rec.fail[name] {
+				return "", temporal.NewNonRetryableApplicationError(name+" broke", "Test", nil)
+			}
+			return name + " done", nil
+		}, activity.RegisterOptions{Name: name})
+	}
+}
+
+func parse(t *testing.T, yaml string) registry.WorkflowDefinition {
+	t.Helper()
+	def, err := registry.ParseWorkflow("workflows/test.yaml", []byte(yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *def
+}
+
+func runPipeline(t *testing.T, def registry.WorkflowDefinition, setup func(*testsuite.TestWorkflowEnvironment)) (*Result, error) {
+	t.Helper()
+	return runInput(t, Input{Definition: def, CustomerID: "acme-corp", Parameters: map[string]interface{}{"day": "2026-10-01"}}, setup)
+}
+
+// runInput runs a pipeline to completion. A continue-as-new is returned as
+// the error.
+func runInput(t *testing.T, in Input, setup func(*testsuite.TestWorkflowEnvironment)) (*Result, error) {
+	t.Helper()
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflowWithOptions(Workflow, workflow.RegisterOptions{Name: WorkflowType})
+	env.RegisterWorkflowWithOptions(BatchWorkflow, workflow.RegisterOptions{Name: BatchWorkflowType})
+	setup(env)
+	env.ExecuteWorkflow(WorkflowType, in)
+	if !env.IsWorkflowCompleted() {
+		t.Fatal("workflow did not complete")
+	}
+	err := env.GetWorkflowError()
+	if workflow.IsContinueAsNewError(err) {
+		return nil, err
+	}
+	if err == nil {
+		var res Result
+		if err := env.GetWorkflowResult(&res); err != nil {
+			t.Fatal(err)
+		}
+		return &res, nil
+	}
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != FailureType {
+		t.Fatalf("error = %v", err)
+	}
+	var res Result
+	if derr := appErr.Details(&res); derr != nil {
+		t.Fatal(derr)
+	}
+	return &res, err
+}
+
+const dataPipeline = `
+name: CustomerDataPipeline
+version: 1.0.0
+max_duration: 2h
+retry_policy:
+  maximum_attempts: 1
+stages:
+  - name: extract
+    activities: [ExtractFromDatabase, ExtractFromAPI]
+    parallel: true
+    timeout: 15m
+  - name: transform
+    activities: [NormalizeData, EnrichWithMetadata]
+  - name: load
+    activities: [LoadToDataWarehouse]
+hooks:
+  on_start: [LogPipelineStart]
+  on_success: [RecordMetrics]
+  on_failure: [SendAlertToOncall]
+`
+
+var dataPipelineActivities = []string{
+	"ExtractFromDatabase", "ExtractFromAPI", "NormalizeData", "EnrichWithMetadata",
+	"LoadToDataWarehouse", "LogPipelineStart", "RecordMetrics", "SendAlertToOncall",
+}
+
+func TestPipelineRunsDefinition(t *testing.T) {
+	rec := &recorder{seen: map[string]ActivityInput{}}
+	res, err := runPipeline(t, parse(t, dataPipeline), func(env *testsuite.TestWorkflowEnvironment) {
+		rec.register(env, dataPipelineActivities...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != StatusCompleted || len(res.Stages) != 3 || res.Version != "1.0.0" {
+		t.Fatalf("result = %+v", res)
+	}
+	for _, s := range res.Stages {
+		if s.Status != StatusCompleted {
+			t.Errorf("stage %s = %+v", s.Name, s)
+		}
+	}
+
+	got := strings.Join(rec.calls, ",")
+	// The parallel extract activities may start in either order
+	if !strings.HasPrefix(got, "LogPipelineStart,") ||
+		!strings.HasSuffix(got, ",NormalizeData,EnrichWithMetadata,LoadToDataWarehouse,RecordMetrics") ||
+		strings.Contains(got, "SendAlertToOncall") {
+		t.Errorf("calls = %s", got)
+	}
+
+	in := rec.seen["EnrichWithMetadata"]
+	if in.Stage != "transform" || in.CustomerID != "acme-corp" || in.Parameters["day"] != "2026-10-01" {
+		t.Errorf("input = %+v", in)
+	}
+	if in.Outputs["extract"]["ExtractFromAPI"] != "ExtractFromAPI done" || in.Outputs["transform"]["NormalizeData"] != "NormalizeData done" {
+		t.Errorf("outputs = %v", in.Outputs)
+	}
+	if len(res.Hooks) != 2 || res.Hooks[1].Name != "RecordMetrics" {
+		t.Errorf("hooks = %+v", res.Hooks)
+	}
+}
+
+func TestPipelineFailures(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		yaml   string
+		fail   []string
+		stages []string // status per stage
+		ran    string   // an activity that must still have run
+		err    string
+	}{
+		{
+			name:   "sequential stage stops at first failure",
+			yaml:   dataPipeline,
+			fail:   []string{"NormalizeData"},
+			stages: []string{StatusCompleted, StatusFailed},
+			err:    "stage transform: activity NormalizeData: NormalizeData broke",
+		},
+		{
+			name:   "continue_on_error",
+			yaml:   strings.Replace(dataPipeline, "  - name: transform\n", "  - name: transform\n    continue_on_error: true\n", 1),
+			fail:   []string{"EnrichWithMetadata", "LoadToDataWarehouse"},
+			stages: []string{StatusCompleted, StatusFailed, StatusFailed},
+			err:    "stage load: activity LoadToDataWarehouse",
+		},
+		{
+			name:   "parallel stage waits for every activity",
+			yaml:   dataPipeline,
+			fail:   []string{"ExtractFromDatabase"},
+			stages: []string{StatusFailed},
+			ran:    "ExtractFromAPI",
+			err:    "stage extract: activity ExtractFromDatabase",
+		},
+		{
+			name:   "on_start hook",
+			yaml:   dataPipeline,
+			fail:   []string{"LogPipelineStart"},
+			stages: []string{},
+			err:    "on_start hook LogPipelineStart",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recorder{seen: map[string]ActivityInput{}, fail: map[string]bool{}}
+			for _, name := range tc.fail {
+				rec.fail[name] = true
+			}
+			res, err := runPipeline(t, parse(t, tc.yaml), func(env *testsuite.TestWorkflowEnvironment) {
+				rec.register(env, dataPipelineActivities...)
+			})
+			if err == nil || !strings.Contains(res.Error, tc.err) || res.Status != StatusFailed {
+				t.Fatalf("err = %v, result = %+v", err, res)
+			}
+			if len(res.Stages) != len(tc.stages) {
+				t.Fatalf("stages = %+v", res.Stages)
+			}
+			for i, want := range tc.stages {
+				if res.Stages[i].Status != want {
+					t.Errorf("stage %s = %s, want %s", res.Stages[i].Name, res.Stages[i].Status, want)
+				}
+			}
+			calls := strings.Join(rec.calls, ",")
+			if !strings.HasSuffix(calls, "SendAlertToOncall") || strings.Contains(calls, "RecordMetrics") {
+				t.Errorf("calls = %s", calls)
+			}
+			if tc.ran != "" && !strings.Contains(calls, tc.ran) {
+				t.Errorf("%s did not run: %s", tc.ran, calls)
+			}
+		})
+	}
+}
+
+func TestPipelineTimeouts(t *testing.T) {
+	for _, tc := range []struct {
+		name, yaml, err string
+	}{
+		{"stage timeout", `
+name: slow
+stages:
+  - name: crawl
+    activities: [Crawl]
+    timeout: 10m
+  - name: index
+    activities: [Index]
+hooks:
+  on_failure: [Alert]
+`, "stage crawl: exceeded its 10m0s timeout"},
+		{"max_duration", `
+name: slow
+max_duration: 30m
+stages:
+  - name: crawl
+    activities: [Crawl]
+  - name: index
+    activities: [Index]
+hooks:
+  on_failure: [Alert]
+`, "pipeline exceeded its max_duration of 30m0s"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var alerted bool
+			res, err := runPipeline(t, parse(t, tc.yaml), func(env *testsuite.TestWorkflowEnvironment) {
+				env.RegisterActivityWithOptions(func(context.Context, ActivityInput) (string, error) { return "", nil }, activity.RegisterOptions{Name: "Crawl"})
+				env.RegisterActivityWithOptions(func(context.Context, ActivityInput) (string, error) {
+					return "", fmt.Errorf("index must not run")
+				}, activity.RegisterOptions{Name: "Index"})
+				env.RegisterActivityWithOptions(func(context.Context, ActivityInput) (string, error) {
+					alerted = true
+					return "", nil
+				}, activity.RegisterOptions{Name: "Alert"})
+				env.OnActivity("Crawl", mock.Anything, mock.Anything).After(time.Hour).Return("crawled", nil)
+			})
+			if err == nil || res.Error != tc.err {
+				t.Fatalf("err = %v, result = %+v", err, res)
+			}
+			if len(res.Stages) != 1 || res.Stages[0].Status != StatusFailed {
+				t.Errorf("stages = %+v", res.Stages)
+			}
+			if !alerted {
+				t.Error("on_failure hook did not run")
+			}
+		})
+	}
+}
+
+// fakeDefinitions stands in for Definitions, serving YAML by commit
+type fakeDefinitions struct {
+	mu      sync.Mutex
+	commits map[string]string
+	latest  string
+}
+
+func (f *fakeDefinitions) publish(commit, yaml string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commits[commit], f.latest = yaml, commit
+}
+
+func (f *fakeDefinitions) register(t *testing.T, env *testsuite.TestWorkflowEnvironment) {
+	env.RegisterActivityWithOptions(func(ctx context.Context, req ResolveRequest) (*Resolved, error) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		commit := req.Commit
+		if commit == "" {
+			commit = f.latest
+		}
+		return &Resolved{Commit: commit, Definition: parse(t, f.commits[commit])}, nil
+	}, activity.RegisterOptions{Name: ResolveActivity})
+}
+
+const etlV1 = `
+name: etl
+stages:
+  - name: extract
+    activities: [Extract]
+  - name: load
+    activities: [LoadV1]
+hooks:
+  on_start: [Begin]
+`
+
+func TestPipelinePinsDefinitionCommit(t *testing.T) {
+	etlV2 := strings.Replace(etlV1, "LoadV1", "LoadV2", 1)
+	renamed := strings.Replace(etlV2, "name: extract", "name: pull", 1)
+
+	for _, tc := range []struct {
+		name    string
+		update  string // definition committed while extract runs
+		migrate bool
+		signal  bool
+		want    string // commit the load stage runs from
+	}{
+		{name: "runs stay pinned", update: etlV2, want: "c1"},
+		{name: "migrate at safe point", update: etlV2, migrate: true, want: "c2"},
+		{name: "migrate on signal", update: etlV2, signal: true, want: "c2"},
+		{name: "completed stages changed", update: renamed, migrate: true, want: "c1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			defs := &fakeDefinitions{commits: map[string]string{}}
+			defs.publish("c1", etlV1)
+			rec := &recorder{seen: map[string]ActivityInput{}}
+			setup := func(env *testsuite.TestWorkflowEnvironment) {
+				defs.register(t, env)
+				rec.register(env, "Begin", "LoadV1", "LoadV2")
+				env.RegisterActivityWithOptions(func(ctx context.Context, in ActivityInput) (string, error) {
+					defs.publish("c2", tc.update)
+					return "extracted", nil
+				}, activity.RegisterOptions{Name: "Extract"})
+				if tc.signal {
+					env.RegisterDelayedCallback(func() { env.SignalWorkflow(MigrateSignal, nil) }, 0)
+				}
+			}
+
+			in := Input{Workflow: "etl", CustomerID: "acme-corp", Migrate: tc.migrate}
+			res, err := runInput(t, in, setup)
+			if tc.want == "c2" {
+				var can *workflow.ContinueAsNewError
+				if !errors.As(err, &can) {
+					t.Fatalf("err = %v, want continue-as-new", err)
+				}
+				var next Input
+				if err := converter.GetDefaultDataConverter().FromPayloads(can.Input, &next); err != nil {
+					t.Fatal(err)
+				}
+				if next.Commit != "c2" || next.Resume == nil || next.Resume.Next != 1 ||
+					next.Resume.Outputs["extract"]["Extract"] != "extracted" {
+					t.Fatalf("continued with %+v", next)
+				}
+				res, err = runInput(t, next, setup)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.Commit != tc.want || len(res.Stages) != 2 || res.Stages[0].Activities[0].Output != "extracted" {
+				t.Fatalf("result = %+v", res)
+			}
+			wantLoad, wantFrom := "LoadV1", []string(nil)
+			if tc.want == "c2" {
+				wantLoad, wantFrom = "LoadV2", []string{"c1"}
+			}
+			if res.Stages[1].Activities[0].Name != wantLoad || fmt.Sprint(res.MigratedFrom) != fmt.Sprint(wantFrom) {
+				t.Errorf("result = %+v", res)
+			}
+			// on_start runs once, before the migration
+			if calls := strings.Join(rec.calls, ","); calls != "Begin,"+wantLoad {
+				t.Errorf("calls = %s", calls)
+			}
+		})
+	}
+}
+
+func TestPipelineRunsTenantOverlay(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Volcano Test", "GIT_AUTHOR_EMAIL=test@volcano.local",
+			"GIT_COMMITTER_NAME=Volcano Test", "GIT_COMMITTER_EMAIL=test@volcano.local",
+			"GIT_CONFIG_NOSYSTEM=1", "HOME="+dir)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(files map[string]string) string {
+		t.Helper()
+		for p, content := range files {
+			os.MkdirAll(filepath.Join(dir, filepath.Dir(p)), 0755)
+			if err := os.WriteFile(filepath.Join(dir, p), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", "update")
+		return git("rev-parse", "HEAD")
+	}
+
+	git("init", "-q", "-b", "main")
+	commit(map[string]string{"configs/runtime.yaml": "precision: 2\n"})
+	git("checkout", "-q", "-b", "customer/acme-corp")
+	// Only the tenant's addition: the rest of the workflow comes from main
+	tenantHead := commit(map[string]string{"workflows/etl.yaml": `stages:
+  - name: audit
+    activities: [Audit]
+x-overlay:
+  keys:
+    stages: append
+`})
+	git("checkout", "-q", "main")
+	mainHead := commit(map[string]string{"workflows/etl.yaml": etlV1})
+
+	repo, err := gitnative.OpenRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	mainReg := registry.New(repo, registry.MainRef)
+	if _, err := mainReg.Load(ctx, mainHead); err != nil {
+		t.Fatal(err)
+	}
+	tenantRef, _ := registry.TenantRef("acme-corp")
+	tenantReg := registry.New(repo, tenantRef)
+	tenantReg.OverlayOn(mainReg)
+	if _, err := tenantReg.Load(ctx, tenantHead); err != nil {
+		t.Fatal(err)
+	}
+	defs := NewDefinitions(func(ref string) *registry.Registry {
+		return map[string]*registry.Registry{registry.MainRef: mainReg, tenantRef: tenantReg}[ref]
+	})
+
+	run := func(want string) {
+		t.Helper()
+		rec := &recorder{seen: map[string]ActivityInput{}}
+		res, err := runInput(t, Input{Workflow: "etl", CustomerID: "acme-corp"}, func(env *testsuite.TestWorkflowEnvironment) {
+			RegisterDefinitions(env, defs)
+			rec.register(env, "Begin", "Extract", "Transform", "LoadV1", "Audit")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(rec.calls, ","); got != want || res.Commit != tenantHead {
+			t.Errorf("calls = %s at %.8s, want %s at %.8s", got, res.Commit, want, tenantHead)
+		}
+	}
+	run("Begin,Extract,LoadV1,Audit")
+
+	// Main's changes reach the tenant without a tenant commit
+	next := commit(map[string]string{"workflows/etl.yaml": strings.Replace(etlV1, "  - name: load\n",
+		"  - name: transform\n    activities: [Transform]\n  - name: load\n", 1)})
+	if _, err := mainReg.Load(ctx, next); err != nil {
+		t.Fatal(err)
+	}
+	if snap := tenantReg.Current(); snap.MainCommit != next {
+		t.Fatalf("tenant overlays main %.8s, want %.8s", snap.MainCommit, next)
+	}
+	run("Begin,Extract,Transform,LoadV1,Audit")
+}
+
+const branching = `
+name: Branching
+version: 1.0.0
+stages:
+  - name: extract
+    activities:
+      - Extract
+      - activity: Audit
+        when: params.audit == true
+  - name: transform
+    when: stages.extract.Extract.rows > 0
+    activities: [Transform]
+  - name: load
+    switch:
+      - when: params.target == "warehouse"
+        activities: [LoadWarehouse]
+      - when: params.target == "lake"
+        activities: [LoadLake]
+      - activities: [LoadDefault]
+`
+
+func TestPipelineBranches(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		rows   int
+		params map[string]interface{}
+		calls  string
+		skip   string // stage recorded as skipped
+		load   string // case recorded for load
+	}{
+		{name: "all stages", rows: 3, params: map[string]interface{}{"audit": true, "target": "lake"},
+			calls: "Extract,Audit,Transform,LoadLake", load: `params.target == "lake"`},
+		{name: "no rows", rows: 0, params: map[string]interface{}{"target": "warehouse"},
+			calls: "Extract,LoadWarehouse", skip: "transform", load: `params.target == "warehouse"`},
+		{name: "default case", rows: 1,
+			calls: "Extract,Transform,LoadDefault", load: "default"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recorder{seen: map[string]ActivityInput{}}
+			in := Input{Definition: parse(t, branching), Parameters: tc.params}
+			res, err := runInput(t, in, func(env *testsuite.TestWorkflowEnvironment) {
+				rec.register(env, "Audit", "Transform", "LoadWarehouse", "LoadLake", "LoadDefault")
+				env.RegisterActivityWithOptions(func(ctx context.Context, in ActivityInput) (map[string]interface{}, error) {
+					rec.mu.Lock()
+					defer rec.mu.Unlock()
+					rec.calls = append(rec.calls, "Extract")
+					return map[string]interface{}{"rows": tc.rows}, nil
+				}, activity.RegisterOptions{Name: "Extract"})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if calls := strings.Join(rec.calls, ","); calls != tc.calls {
+				t.Errorf("calls = %s, want %s", calls, tc.calls)
+			}
+			if len(res.Stages) != 3 || res.Stages[2].Case != tc.load {
+				t.Fatalf("stages = %+v", res.Stages)
+			}
+			for _, s := range res.Stages {
+				if want := map[bool]string{true: StatusSkipped, false: StatusCompleted}[s.Name == tc.skip]; s.Status != want {
+					t.Errorf("stage %s = %s, want %s", s.Name, s.Status, want)
+				}
+			}
+			if audit := res.Stages[0].Activities[1]; tc.params["audit"] == nil && audit.Status != StatusSkipped {
+				t.Errorf("audit = %+v", audit)
+			}
+		})
+	}
+
+	// A when that does not yield a boolean fails the stage
+	def := parse(t, branching)
+	def.Stages[1].When = "params.target"
+	res, err := runInput(t, Input{Definition: def, Parameters: map[string]interface{}{"target": "lake"}}, func(env *testsuite.TestWorkflowEnvironment) {
+		(&recorder{seen: map[string]ActivityInput{}}).register(env, "Extract", "Audit", "Transform", "LoadWarehouse", "LoadLake", "LoadDefault")
+	})
+	if err == nil || res.Stages[1].Status != StatusFailed || !strings.Contains(res.Stages[1].Error, `when "params.target"`) {
+		t.Errorf("result = %+v, err = %v", res, err)
+	}
+}
+
+const loops = `
+name: Loops
+version: 1.0.0
+stages:
+  - name: extract
+    map:
+      items: params.sources
+      as: source
+      concurrency: 2
+      aggregate: Summarize
+    activities:
+      - Extract
+      - activity: Check
+        when: source.kind == "db"
+  - name: publish
+    foreach:
+      items: stages.extract.Extract
+    activities: [Publish]
+`
+
+// loopWorker fakes the activities of the loops definition
+type loopWorker struct {
+	mu             sync.Mutex
+	running, most  int
+	seen           []ActivityInput
+	summarized     ActivityInput
+	published      []interface{}
+	failPublishing interface{}
+}
+
+func (w *loopWorker) register(env *testsuite.TestWorkflowEnvironment) {
+	env.RegisterActivityWithOptions(func(ctx context.Context, in ActivityInput) (string, error) {
+		w.mu.Lock()
+		w.running++
+		w.most = max(w.most, w.running)
+		w.seen = append(w.seen, in)
+		w.mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		w.mu.Lock()
+		w.running--
+		w.mu.Unlock()
+		source := in.Item.(map[string]interface{})
+		return fmt.Sprintf("%v#%d", source["name"], in.Index), nil
+	}, activity.RegisterOptions{Name: "Extract"})
+	env.RegisterActivityWithOptions(func(ctx context.Context, in ActivityInput) (string, error) {
+		// Later activities of an item see its earlier outputs
+		return fmt.Sprint("checked ", in.Outputs["extract"]["Extract"]), nil
+	}, activity.RegisterOptions{Name: "Check"})
+	env.RegisterActivityWithOptions(func(ctx context.Context, in ActivityInput) (int, error) {
+		w.summarized = in
+		return len(in.Outputs["extract"]["Extract"].([]interface{})), nil
+	}, activity.RegisterOptions{Name: "Summarize"})
+	env.RegisterActivityWithOptions(func(ctx context.Context, in ActivityInput) (string, error) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.published = append(w.published, in.Item)
+		if in.Item == w.failPublishing {
+			return "", temporal.NewNonRetryableApplicationError("rejected", "Test", nil)
+		}
+		return "published", nil
+	}, activity.RegisterOptions{Name: "Publish"})
+}
+
+func sources(n int) []interface{} {
+	out := make([]interface{}, n)
+	for i := range out {
+		kind := "api"
+		if i%2 == 0 {
+			kind = "db"
+		}
+		out[i] = map[string]interface{}{"name": fmt.Sprintf("s%d", i), "kind": kind}
+	}
+	return out
+}
+
+func TestPipelineLoops(t *testing.T) {
+	w := &loopWorker{}
+	in := Input{Definition: parse(t, loops), Parameters: map[string]interface{}{"sources": sources(4)}}
+	res, err := runInput(t, in, w.register)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extract := res.Stages[0]
+	if extract.Loop == nil || !reflect.DeepEqual(*extract.Loop, LoopResult{Items: 4, Completed: 4}) {
+		t.Fatalf("extract loop = %+v", extract.Loop)
+	}
+	if w.most != 2 {
+		t.Errorf("%d extracts ran at once, want 2", w.most)
+	}
+	// Outputs are collected in item order whatever order items finish in
+	want := "[s0#0 s1#1 s2#2 s3#3] [checked s0#0 <nil> checked s2#2 <nil>] 4"
+	if got := fmt.Sprint(extract.Activities[0].Output, extract.Activities[1].Output, extract.Activities[2].Output); got != want ||
+		extract.Activities[2].Name != "Summarize" {
+		t.Errorf("outputs = %s, want %s", got, want)
+	}
+	if w.summarized.Item != nil || len(w.summarized.Outputs["extract"]["Check"].([]interface{})) != 4 {
+		t.Errorf("aggregate input = %+v", w.summarized)
+	}
+	// foreach keeps no outputs
+	publish := res.Stages[1]
+	if publish.Loop.Completed != 4 || publish.Activities[0].Output != nil || len(w.published) != 4 {
+		t.Errorf("publish = %+v", publish)
+	}
+
+	t.Run("failure stops the loop", func(t *testing.T) {
+		w := &loopWorker{failPublishing: "s1#1"}
+		_, err := runInput(t, in, w.register)
+		var appErr *temporal.ApplicationError
+		errors.As(err, &appErr)
+		var res Result
+		if appErr == nil || appErr.Details(&res) != nil {
+			t.Fatalf("err = %v", err)
+		}
+		publish := res.Stages[1]
+		rejected := "rejected (type: Test, retryable: false)"
+		wantLoop := LoopResult{Items: 4, Completed: 1, Failed: 1, Skipped: 2, Errors: []ItemError{{Index: 1, Activity: "Publish", Error: rejected}}}
+		if !reflect.DeepEqual(*publish.Loop, wantLoop) {
+			t.Errorf("loop = %+v", publish.Loop)
+		}
+		if publish.Error != "stage publish: item 1: activity Publish: "+rejected || len(w.published) != 2 {
+			t.Errorf("publish = %+v, published %v", publish, w.published)
+		}
+	})
+
+	t.Run("fan out to child workflows", func(t *testing.T) {
+		def := parse(t, strings.Replace(loops, "concurrency: 2", "concurrency: 2\n      batch_size: 2", 1))
+		w := &loopWorker{}
+		in := Input{Definition: def, Parameters: map[string]interface{}{"sources": sources(5)}}
+		res, err := runInput(t, in, w.register)
+		if err != nil {
+			t.Fatal(err)
+		}
+		extract := res.Stages[0]
+		if extract.Loop.Batches != 3 || extract.Loop.Completed != 5 {
+			t.Errorf("loop = %+v", extract.Loop)
+		}
+		if got := fmt.Sprint(extract.Activities[0].Output); got != "[s0#0 s1#1 s2#2 s3#3 s4#4]" {
+			t.Errorf("outputs = %s", got)
+		}
+		// concurrency bounds the whole stage, not each batch
+		if w.most != 2 {
+			t.Errorf("%d extracts ran at once across batches, want 2", w.most)
+		}
+		if res.Stages[1].Loop.Batches != 0 || len(w.published) != 5 {
+			t.Errorf("publish = %+v", res.Stages[1])
+		}
+	})
+}
+
+const saga = `
+name: Saga
+stages:
+  - name: extract
+    activities: [Extract]
+    compensate: [DropStaging]
+  - name: audit
+    when: params.audit == true
+    activities: [Audit]
+    compensate: [DropAudit]
+  - name: load
+    activities: [LoadWarehouse, LoadIndex, LoadCache]
+    compensate:
+      - DeleteFromWarehouse
+      - activity: DeleteFromIndex
+        when: stages.load.LoadIndex != null
+      - InvalidateCache
+  - name: notify
+    activities: [Notify]
+hooks:
+  on_failure: [Alert]
+`
+
+func TestPipelineCompensation(t *testing.T) {
+	activities := []string{"Extract", "DropStaging", "Audit", "DropAudit", "LoadWarehouse", "LoadIndex", "LoadCache",
+		"DeleteFromWarehouse", "DeleteFromIndex", "InvalidateCache", "Notify", "Alert"}
+	for _, tc := range []struct {
+		name  string
+		fail  []string
+		calls string // after the stages that ran
+		want  string // stage:status of each compensation
+	}{
+		{name: "success needs no compensation", calls: "Notify"},
+		{name: "later failure undoes earlier stages", fail: []string{"Notify"},
+			calls: "Notify,DeleteFromWarehouse,DeleteFromIndex,InvalidateCache,DropStaging,Alert",
+			want:  "load:completed extract:completed"},
+		{name: "failed compensations are recorded and the rest run", fail: []string{"Notify", "DeleteFromWarehouse"},
+			calls: "Notify,DeleteFromWarehouse,DeleteFromIndex,InvalidateCache,DropStaging,Alert",
+			want:  "load:failed extract:completed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recorder{seen: map[string]ActivityInput{}, fail: map[string]bool{}}
+			for _, name := range tc.fail {
+				rec.fail[name] = true
+			}
+			res, err := runPipeline(t, parse(t, saga), func(env *testsuite.TestWorkflowEnvironment) {
+				rec.register(env, activities...)
+			})
+			if (err != nil) != (tc.fail != nil) {
+				t.Fatalf("err = %v", err)
+			}
+			if calls := strings.Join(rec.calls, ","); calls != "Extract,LoadWarehouse,LoadIndex,LoadCache,"+tc.calls {
+				t.Errorf("calls = %s", calls)
+			}
+			var got []string
+			for _, c := range res.Compensations {
+				got = append(got, c.Stage+":"+c.Status)
+			}
+			if strings.Join(got, " ") != tc.want {
+				t.Errorf("compensations = %+v", res.Compensations)
+			}
+			if tc.want != "" && rec.seen["DeleteFromIndex"].Outputs["load"]["LoadIndex"] != "LoadIndex done" {
+				t.Errorf("compensation input = %+v", rec.seen["DeleteFromIndex"])
+			}
+		})
+	}
+
+	// A stage that failed part-way is undone; one that did nothing is not
+	rec := &recorder{seen: map[string]ActivityInput{}, fail: map[string]bool{"LoadIndex": true}}
+	res, _ := runPipeline(t, parse(t, saga), func(env *testsuite.TestWorkflowEnvironment) {
+		rec.register(env, activities...)
+	})
+	if calls := strings.Join(rec.calls, ","); calls != "Extract,LoadWarehouse,LoadIndex,DeleteFromWarehouse,InvalidateCache,DropStaging,Alert" {
+		t.Errorf("calls = %s", calls)
+	}
+	if c := res.Compensations[0]; c.Stage != "load" || c.Activities[1].Status != StatusSkipped {
+		t.Errorf("compensations = %+v", res.Compensations)
+	}
+}
+
+const gated = `
+name: Deploy
+stages:
+  - name: build
+    activities: [Build]
+  - name: signoff
+    approval:
+      approvers: [release-managers]
+      message: Ship build to production?
+      escalate_after: 1h
+      escalate_to: [sre-leads]
+      timeout: 4h
+  - name: deploy
+    activities: [Deploy]
+`
+
+// queriedApprovals reads approvals from the test workflow with
+// ApprovalsQuery, as a process without the worker's records would
+type queriedApprovals struct {
+	env *testsuite.TestWorkflowEnvironment
+}
+
+func (q queriedApprovals) pipeline(ctx context.Context, workflowID string) ([]api.Approval, error) {
+	v, err := q.env.QueryWorkflow(ApprovalsQuery)
+	if err != nil {
+		return nil, err
+	}
+	var out []api.Approval
+	return out, v.Get(&out)
+}
+
+func (q queriedApprovals) running(ctx context.Context) ([]api.Approval, error) {
+	return q.pipeline(ctx, "")
+}
+
+func TestPipelineApprovals(t *testing.T) {
+	type decision struct {
+		at     time.Duration
+		token  string
+		body   string
+		status int
+	}
+	tokens := api.StaticTokens{
+		"ann": {Subject: "ann", Groups: []string{"release-managers"}},
+		"bob": {Subject: "bob", Groups: []string{"sre-leads"}},
+	}
+	for _, tc := range []struct {
+		name      string
+		yaml      string
+		decisions []decision
+		err       string // of the signoff stage
+		escalated bool
+	}{
+		{name: "approved", yaml: gated, decisions: []decision{
+			{30 * time.Minute, "", `{"decision":"approve"}`, 401},
+			{40 * time.Minute, "ann", `{"decision":"approve"}`, 200},
+		}},
+		{name: "escalated group decides after escalation", yaml: gated, decisions: []decision{
+			{30 * time.Minute, "bob", `{"decision":"reject"}`, 403},
+			{40 * time.Minute, "bob", `{"decision":"reject","group":"release-managers"}`, 403},
+			{2 * time.Hour, "bob", `{"decision":"reject","comment":"freeze"}`, 200},
+			{3 * time.Hour, "ann", `{"decision":"approve"}`, 409},
+		}, err: "stage signoff: rejected by bob (sre-leads): freeze", escalated: true},
+		{name: "timeout rejects by default", yaml: gated,
+			err: "stage signoff: no decision within 4h0m0s", escalated: true},
+		{name: "timeout can approve", yaml: strings.Replace(gated, "timeout: 4h", "timeout: 4h\n      default: approve", 1),
+			escalated: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := &recorder{seen: map[string]ActivityInput{}}
+			var pending *api.ApprovalsResponse
+			res, err := runPipeline(t, parse(t, tc.yaml), func(env *testsuite.TestWorkflowEnvironment) {
+				rec.register(env, "Build", "Deploy")
+				signal := func(ctx context.Context, workflowID string, d Decision) error {
+					env.SignalWorkflow(ApprovalSignal, d)
+					return nil
+				}
+				// The API server shares nothing with the worker but the
+				// pipeline itself
+				RegisterApprovals(env, newApprovals(signal))
+				approvals := newApprovals(signal)
+				approvals.source = queriedApprovals{env}
+				srv := api.NewServer(api.Services{Approvals: approvals, Authenticator: tokens})
+				env.RegisterDelayedCallback(func() {
+					pending, _ = approvals.Approvals(context.Background(), api.ApprovalFilter{Group: "release-managers", Status: api.ApprovalPending})
+				}, time.Minute)
+				for _, d := range tc.decisions {
+					d := d
+					env.RegisterDelayedCallback(func() {
+						w := httptest.NewRecorder()
+						r := httptest.NewRequest("POST", "/api/v1/approvals/default-test-workflow-id/signoff", strings.NewReader(d.body))
+						if d.token != "" {
+							r.Header.Set("Authorization", "Bearer "+d.token)
+						}
+						srv.ServeHTTP(w, r)
+						if w.Code != d.status {
+							t.Errorf("decision at %v = %d %s, want %d", d.at, w.Code, w.Body, d.status)
+						}
+					}, d.at)
+				}
+			})
+			if (err != nil) != (tc.err != "") {
+				t.Fatalf("err = %v", err)
+			}
+			if pending == nil || pending.Total != 1 || pending.Approvals[0].Message != "Ship build to production?" {
+				t.Errorf("pending = %+v", pending)
+			}
+			signoff := res.Stages[1]
+			if signoff.Error != tc.err || signoff.Approval == nil || signoff.Approval.Escalated != tc.escalated {
+				t.Fatalf("signoff = %+v", signoff)
+			}
+			approved := tc.err == ""
+			if d := signoff.Approval.Decision; d == nil || d.Approved != approved || d.TimedOut != (tc.decisions == nil) {
+				t.Errorf("decision = %+v", signoff.Approval.Decision)
+			}
+			if ran := strings.Contains(strings.Join(rec.calls, ","), "Deploy"); ran != approved {
+				t.Errorf("calls = %v", rec.calls)
+			}
+		})
+	}
+}
+
+// fakeSchedules stands in for Temporal's schedule client
+type fakeSchedules struct {
+	schedules map[string]*client.Schedule
+	fail      map[string]bool
+}
+
+func (f *fakeSchedules) Create(ctx context.Context, opts client.ScheduleOptions) (client.ScheduleHandle, error) {
+	if f.fail[opts.ID] {
+		return nil, errors.New("unavailable")
+	}
+	if _, ok := f.schedules[opts.ID]; ok {
+		return nil, temporal.ErrScheduleAlreadyRunning
+	}
+	spec := opts.Spec
+	f.schedules[opts.ID] = &client.Schedule{
+		Action: opts.Action,
+		Spec:   &spec,
+		Policy: &client.SchedulePolicies{Overlap: opts.Overlap, CatchupWindow: opts.CatchupWindow},
+		State:  &client.ScheduleState{Note: opts.Note, Paused: opts.Paused},
+	}
+	return f.GetHandle(ctx, opts.ID), nil
+}
+
+func (f *fakeSchedules) List(ctx context.Context, opts client.ScheduleListOptions) (client.ScheduleListIterator, error) {
+	it := &fakeScheduleList{}
+	for id, s := range f.schedules {
+		it.entries = append(it.entries, &client.ScheduleListEntry{ID: id, Note: s.State.Note, Paused: s.State.Paused})
+	}
+	return it, nil
+}
+
+func (f *fakeSchedules) GetHandle(ctx context.Context, id string) client.ScheduleHandle {
+	return &fakeScheduleHandle{f: f, id: id}
+}
+
+type fakeScheduleList struct{ entries []*client.ScheduleListEntry }
+
+func (l *fakeScheduleList) HasNext() bool { return len(l.entries) > 0 }
+
+func (l *fakeScheduleList) Next() (*client.ScheduleListEntry, error) {
+	e := l.entries[0]
+	l.entries = l.entries[1:]
+	return e, nil
+}
+
+// fakeScheduleHandle implements the handle methods Scheduler uses
+type fakeScheduleHandle struct {
+	client.ScheduleHandle
+	f  *fakeSchedules
+	id string
+}
+
+func (h *fakeScheduleHandle) Delete(ctx context.Context) error {
+	delete(h.f.schedules, h.id)
+	return nil
+}
+
+func (h *fakeScheduleHandle) Update(ctx context.Context, opts client.ScheduleUpdateOptions) error {
+	cur, ok := h.f.schedules[h.id]
+	if !ok {
+		return errors.New("schedule not found")
+	}
+	u, err := opts.DoUpdate(client.ScheduleUpdateInput{Description: client.ScheduleDescription{Schedule: *cur}})
+	if err != nil {
+		return err
+	}
+	h.f.schedules[h.id] = u.Schedule
+	return nil
+}
+
+func scheduled(t *testing.T, docs ...string) []*registry.WorkflowDefinition {
+	var defs []*registry.WorkflowDefinition
+	for _, doc := range docs {
+		def := parse(t, doc)
+		defs = append(defs, &def)
+	}
+	return defs
+}
+
+const nightlyAnalytics = `
+name: analytics
+task_queue: analytics
+schedule:
+  cron: "0 2 * * *"
+  timezone: Europe/Berlin
+  overlap: buffer_one
+  parameters:
+    processing_days: 1
+stages: [{name: run, activities: [Analyze]}]
+`
+
+const hourlySync = `
+name: sync
+task_queue: sync
+schedule:
+  every: 1h
+stages: [{name: run, activities: [Sync]}]
+`
+
+func TestSchedulerReconciles(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSchedules{schedules: map[string]*client.Schedule{
+		"someone-elses": {State: &client.ScheduleState{}},
+	}}
+	main := NewScheduler(fake, "")
+	reconcile := func(s *Scheduler, want ScheduleChanges, docs ...string) {
+		t.Helper()
+		got, err := s.Reconcile(ctx, scheduled(t, docs...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("changes = %v, want %v", got, want)
+		}
+	}
+	unscheduled := "name: adhoc\nstages: [{name: run, activities: [Run]}]\n"
+
+	reconcile(main, ScheduleChanges{Created: []string{"analytics", "sync"}}, nightlyAnalytics, hourlySync, unscheduled)
+	nightly := fake.schedules["volcano-pipeline/main/analytics"]
+	action := nightly.Action.(*client.ScheduleWorkflowAction)
+	in := action.Args[0].(Input)
+	if nightly.Spec.CronExpressions[0] != "0 2 * * *" || nightly.Spec.TimeZoneName != "Europe/Berlin" ||
+		nightly.Policy.Overlap != enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ONE ||
+		action.Workflow != WorkflowType || action.TaskQueue != "analytics" ||
+		in.Workflow != "analytics" || in.Parameters["processing_days"] != 1 {
+		t.Errorf("analytics schedule = %+v, action %+v", nightly, action)
+	}
+	hourly := fake.schedules["volcano-pipeline/main/sync"]
+	if hourly.Spec.Intervals[0].Every != time.Hour || hourly.Policy.Overlap != enumspb.SCHEDULE_OVERLAP_POLICY_SKIP {
+		t.Errorf("sync schedule = %+v", hourly)
+	}
+
+	reconcile(main, ScheduleChanges{}, nightlyAnalytics, hourlySync, unscheduled)
+	reconcile(main, ScheduleChanges{Updated: []string{"analytics"}, Paused: []string{"sync"}},
+		strings.Replace(nightlyAnalytics, "0 2", "30 3", 1), strings.Replace(hourlySync, "every: 1h", "every: 1h\n  paused: true", 1))
+	if fake.schedules["volcano-pipeline/main/analytics"].Spec.CronExpressions[0] != "30 3 * * *" ||
+		!fake.schedules["volcano-pipeline/main/sync"].State.Paused {
+		t.Errorf("schedules not updated: %+v", fake.schedules)
+	}
+	reconcile(main, ScheduleChanges{Resumed: []string{"sync"}}, strings.Replace(nightlyAnalytics, "0 2", "30 3", 1), hourlySync)
+
+	// A tenant's schedules are its own: separate IDs, its customer_id and
+	// its timezone, and main's reconcile leaves them alone
+	acme := NewScheduler(fake, "acme")
+	reconcile(acme, ScheduleChanges{Created: []string{"analytics"}}, strings.Replace(nightlyAnalytics, "Europe/Berlin", "America/New_York", 1))
+	tenant := fake.schedules["volcano-pipeline/acme/analytics"]
+	if tenant.Spec.TimeZoneName != "America/New_York" || tenant.Action.(*client.ScheduleWorkflowAction).Args[0].(Input).CustomerID != "acme" {
+		t.Errorf("acme schedule = %+v", tenant)
+	}
+	reconcile(main, ScheduleChanges{Deleted: []string{"analytics", "sync"}}, unscheduled)
+	if _, ok := fake.schedules["volcano-pipeline/acme/analytics"]; !ok {
+		t.Error("main deleted the tenant's schedule")
+	}
+	if _, ok := fake.schedules["someone-elses"]; !ok {
+		t.Error("unmanaged schedule deleted")
+	}
+
+	// One failure does not hold back the rest; a schedule the listing
+	// missed is updated instead
+	fake.fail = map[string]bool{"volcano-pipeline/main/analytics": true}
+	fake.schedules["volcano-pipeline/main/sync"] = &client.Schedule{State: &client.ScheduleState{}}
+	got, err := main.Reconcile(ctx, scheduled(t, nightlyAnalytics, hourlySync))
+	if err == nil || !strings.Contains(err.Error(), "workflow analytics: unavailable") {
+		t.Errorf("err = %v", err)
+	}
+	if !reflect.DeepEqual(got, ScheduleChanges{Updated: []string{"sync"}}) {
+		t.Errorf("changes = %v", got)
+	}
+}
+
+// blockingSchedules holds every List until released, like a Temporal
+// frontend that is down
+type blockingSchedules struct {
+	*fakeSchedules
+	lists   chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingSchedules) List(ctx context.Context, opts client.ScheduleListOptions) (client.ScheduleListIterator, error) {
+	b.lists <- struct{}{}
+	<-b.release
+	return b.fakeSchedules.List(ctx, opts)
+}
+
+func TestSchedulerTrackDoesNotBlockReloads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fake := &blockingSchedules{
+		fakeSchedules: &fakeSchedules{schedules: map[string]*client.Schedule{}},
+		lists:         make(chan struct{}),
+		release:       make(chan struct{}),
+	}
+	var current atomic.Pointer[registry.Snapshot]
+	current.Store(&registry.Snapshot{Commit: "c1"})
+	wake := NewScheduler(fake, "").follow(ctx, "refs/heads/main", current.Load)
+
+	wake()
+	<-fake.lists
+
+	// Reloads while a reconcile hangs return at once
+	reloaded := make(chan struct{})
+	go func() {
+		for i := 2; i <= 5; i++ {
+			current.Store(&registry.Snapshot{Commit: fmt.Sprintf("c%d", i)})
+			wake()
+		}
+		close(reloaded)
+	}()
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("wake blocked behind a running reconcile")
+	}
+
+	// ...and are coalesced into one more pass
+	fake.release <- struct{}{}
+	<-fake.lists
+	fake.release <- struct{}{}
+	select {
+	case <-fake.lists:
+		t.Fatal("reloads were not coalesced")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+const typedPipeline = `
+name: analytics
+inputs:
+  type: object
+  required: [day]
+  properties:
+    day: {type: string, pattern: "^[0-9]{4}-[0-9]{2}-[0-9]{2}$"}
+outputs:
+  type: object
+  required: [report]
+  properties:
+    report:
+      type: object
+      required: [Publish]
+      properties:
+        Publish: {type: string}
+stages:
+  - name: collect
+    activities: [Collect]
+  - name: report
+    activities: [Publish]
+    when: params.publish != false
+hooks:
+  on_failure: [Alert]
+`
+
+func TestPipelineSchemas(t *testing.T) {
+	rec := &recorder{seen: map[string]ActivityInput{}}
+	setup := func(env *testsuite.TestWorkflowEnvironment) { rec.register(env, "Collect", "Publish", "Alert") }
+	def := parse(t, typedPipeline)
+	res, err := runPipeline(t, def, setup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Outputs["report"]["Publish"] != "Publish done" {
+		t.Errorf("outputs = %v", res.Outputs)
+	}
+
+	// Outputs that break the schema fail a run whose stages succeeded
+	rec.calls = nil
+	res, err = runInput(t, Input{Definition: def, Parameters: map[string]interface{}{"day": "2026-10-01", "publish": false}}, setup)
+	want := "outputs do not match the outputs schema: /report: is required"
+	if err == nil || res.Status != StatusFailed || res.Error != want {
+		t.Fatalf("err = %v, result = %+v", err, res)
+	}
+	if got := strings.Join(rec.calls, ","); got != "Collect,Alert" {
+		t.Errorf("calls = %s", got)
+	}
+
+	// Inputs are checked before anything runs, for every way of starting
+	rec.calls = nil
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	setup(env)
+	env.RegisterWorkflowWithOptions(Workflow, workflow.RegisterOptions{Name: WorkflowType})
+	env.ExecuteWorkflow(WorkflowType, Input{Definition: def, Parameters: map[string]interface{}{"day": "yesterday"}})
+	var appErr *temporal.ApplicationError
+	if err := env.GetWorkflowError(); !errors.As(err, &appErr) || appErr.Type() != "InvalidInput" ||
+		!strings.Contains(err.Error(), `parameters do not match the inputs of workflow analytics: /day: must match`) {
+		t.Errorf("err = %v", err)
+	}
+	if len(rec.calls) != 0 {
+		t.Errorf("calls = %v", rec.calls)
+	}
+}
+
+const nightly = `
+name: nightly
+stages:
+  - name: extract
+    activities: [Extract]
+  - name: orders
+    workflow:
+      name: orders
+      parameters: {day: "${params.day}", rows: "${stages.extract.Extract}", full: true, region: eu}
+    compensate: [UndoOrders]
+  - name: report
+    when: stages.orders.orders.load.Load == "Load done"
+    activities: [Report]
+hooks:
+  on_failure: [Alert]
+`
+
+const ordersChild = `
+name: orders
+inputs:
+  type: object
+  required: [day, full]
+stages:
+  - name: load
+    activities: [Load]
+`
+
+func TestPipelineSubWorkflows(t *testing.T) {
+	defs := map[string]string{"nightly": nightly, "orders": ordersChild}
+	for _, fail := range []bool{false, true} {
+		rec := &recorder{seen: map[string]ActivityInput{}, fail: map[string]bool{"Load": fail}}
+		var resolved []string
+		in := Input{Workflow: "nightly", Commit: "c1", CustomerID: "acme-corp", Parameters: map[string]interface{}{"day": "2026-10-01"}}
+		res, err := runInput(t, in, func(env *testsuite.TestWorkflowEnvironment) {
+			env.RegisterActivityWithOptions(func(ctx context.Context, req ResolveRequest) (*Resolved, error) {
+				rec.mu.Lock()
+				defer rec.mu.Unlock()
+				resolved = append(resolved, req.Workflow+"@"+req.Commit)
+				return &Resolved{Commit: "c1", Definition: parse(t, defs[req.Workflow])}, nil
+			}, activity.RegisterOptions{Name: ResolveActivity})
+			rec.register(env, "Extract", "Load", "Report", "UndoOrders", "Alert")
+		})
+		// The child reads its definition from the parent's commit
+		if got := strings.Join(resolved, ","); got != "nightly@c1,orders@c1" {
+			t.Errorf("resolved = %s", got)
+		}
+		load := rec.seen["Load"]
+		// Only ${...} parameters are expressions; region is passed as written
+		want := map[string]interface{}{"day": "2026-10-01", "rows": "Extract done", "full": true, "region": "eu"}
+		if load.CustomerID != "acme-corp" || !reflect.DeepEqual(load.Parameters, want) {
+			t.Errorf("child input = %+v", load)
+		}
+
+		if !fail {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(rec.calls, ","); got != "Extract,Load,Report" {
+				t.Errorf("calls = %s", got)
+			}
+			want := map[string]interface{}{"load": map[string]interface{}{"Load": "Load done"}}
+			if !reflect.DeepEqual(res.Outputs["orders"]["orders"], want) {
+				t.Errorf("outputs = %v", res.Outputs)
+			}
+			if a := res.Stages[1].Activities; len(a) != 1 || a[0].Name != "orders" || a[0].Status != StatusCompleted {
+				t.Errorf("stage = %+v", res.Stages[1])
+			}
+			continue
+		}
+
+		// A failed child undid its own work, so the stage is not compensated
+		if err == nil || res.Stages[1].Status != StatusFailed {
+			t.Fatalf("err = %v, result = %+v", err, res)
+		}
+		if !strings.HasPrefix(res.Error, "stage orders: workflow orders: stage load: activity Load: Load broke") {
+			t.Errorf("error = %q", res.Error)
+		}
+		if got := strings.Join(rec.calls, ","); got != "Extract,Load,Alert" {
+			t.Errorf("calls = %s", got)
+		}
+		if len(res.Compensations) != 0 {
+			t.Errorf("compensations = %+v", res.Compensations)
+		}
+	}
+}
+
+func TestApprovalsQueryTemporal(t *testing.T) {
+	pending := api.Approval{WorkflowID: "wf-1", Workflow: "Deploy", Stage: "signoff", Groups: []string{"release-managers"}, Status: api.ApprovalPending}
+	value := mocks.NewEncodedValue(t)
+	value.On("Get", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*[]api.Approval) = []api.Approval{pending}
+	}).Return(nil)
+	temporal := mocks.NewClient(t)
+	temporal.On("ListWorkflow", mock.Anything, mock.Anything).Return(&workflowservice.ListWorkflowExecutionsResponse{
+		Executions: []*workflowpb.WorkflowExecutionInfo{{Execution: &commonpb.WorkflowExecution{WorkflowId: "wf-1"}}},
+	}, nil)
+	temporal.On("QueryWorkflow", mock.Anything, "wf-1", "", ApprovalsQuery).Return(value, nil)
+	temporal.On("QueryWorkflow", mock.Anything, "wf-gone", "", ApprovalsQuery).Return(nil, serviceerror.NewNotFound("workflow not found"))
+	temporal.On("SignalWorkflow", mock.Anything, "wf-1", "", ApprovalSignal,
+		Decision{Stage: "signoff", Approved: true, Approver: "ann", Group: "release-managers"}).Return(nil)
+
+	// Nothing was recorded in this process: the pipelines are the record
+	approvals := NewApprovals(temporal)
+	ctx := context.Background()
+	list, err := approvals.Approvals(ctx, api.ApprovalFilter{Status: api.ApprovalPending})
+	if err != nil || list.Total != 1 || list.Approvals[0].Stage != "signoff" {
+		t.Fatalf("Approvals = %+v, %v", list, err)
+	}
+	ann := api.Principal{Subject: "ann", Groups: []string{"release-managers"}}
+	req := api.ApprovalDecisionRequest{Decision: "approve"}
+	if _, err := approvals.Decide(ctx, "wf-1", "signoff", ann, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := approvals.Decide(ctx, "wf-gone", "signoff", ann, req); !errors.Is(err, api.ErrNotFound) {
+		t.Errorf("Decide(wf-gone) = %v", err)
+	}
+}
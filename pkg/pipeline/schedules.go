@@ -0,0 +1,330 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
+
+// SchedulePrefix starts the ID of every Temporal Schedule a Scheduler
+// manages. Schedules without it are never touched.
+const SchedulePrefix = "volcano-pipeline/"
+
+// scheduleNote marks a managed schedule's note; the rest of the note is a
+// hash of the definition's schedule, so unchanged schedules are left alone
+const scheduleNote = "managed from git, spec "
+
+var overlapPolicies = map[string]enumspb.ScheduleOverlapPolicy{
+	registry.OverlapSkip:           enumspb.SCHEDULE_OVERLAP_POLICY_SKIP,
+	registry.OverlapBufferOne:      enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ONE,
+	registry.OverlapBufferAll:      enumspb.SCHEDULE_OVERLAP_POLICY_BUFFER_ALL,
+	registry.OverlapCancelOther:    enumspb.SCHEDULE_OVERLAP_POLICY_CANCEL_OTHER,
+	registry.OverlapTerminateOther: enumspb.SCHEDULE_OVERLAP_POLICY_TERMINATE_OTHER,
+	registry.OverlapAllowAll:       enumspb.SCHEDULE_OVERLAP_POLICY_ALLOW_ALL,
+}
+
+// ScheduleChanges names, by workflow, the schedules a reconcile changed
+type ScheduleChanges struct {
+	Created []string `json:"created,omitempty"`
+	Updated []string `json:"updated,omitempty"`
+	Paused  []string `json:"paused,omitempty"`
+	Resumed []string `json:"resumed,omitempty"`
+	Deleted []string `json:"deleted,omitempty"`
+}
+
+// Empty reports whether nothing changed
+func (c ScheduleChanges) Empty() bool {
+	return len(c.Created)+len(c.Updated)+len(c.Paused)+len(c.Resumed)+len(c.Deleted) == 0
+}
+
+func (c ScheduleChanges) String() string {
+	var parts []string
+	for _, l := range []struct {
+		verb  string
+		names []string
+	}{{"created", c.Created}, {"updated", c.Updated}, {"paused", c.Paused}, {"resumed", c.Resumed}, {"deleted", c.Deleted}} {
+		if len(l.names) > 0 {
+			parts = append(parts, l.verb+" "+strings.Join(l.names, ", "))
+		}
+	}
+	if len(parts) == 0 {
+		return "unchanged"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Scheduler keeps the Temporal Schedules of one branch in line with the
+// schedule sections of its workflow definitions. Each tenant branch has
+// its own Scheduler, whose runs carry the tenant as customer_id and whose
+// definitions may set their own timezone.
+type Scheduler struct {
+	client client.ScheduleClient
+	tenant string
+
+	// mu serialises reconciles
+	mu sync.Mutex
+}
+
+// NewScheduler returns a Scheduler for tenant's branch, or main's if
+// tenant is empty
+func NewScheduler(c client.ScheduleClient, tenant string) *Scheduler {
+	return &Scheduler{client: c, tenant: tenant}
+}
+
+// ScheduleID is the ID of the schedule starting workflow for tenant
+func ScheduleID(tenant, workflow string) string {
+	if tenant == "" {
+		tenant = "main"
+	}
+	return SchedulePrefix + tenant + "/" + workflow
+}
+
+// Track reconciles the registry's current snapshot, then every snapshot it
+// swaps in. Reconciles run on their own goroutine until ctx is done, so a
+// slow or unreachable Temporal never holds up a reload; reloads that land
+// while one runs are coalesced into a single pass over the latest
+// snapshot. Failures are logged; the next reload retries them.
+func (s *Scheduler) Track(ctx context.Context, reg *registry.Registry) {
+	wake := s.follow(ctx, reg.Ref(), reg.Current)
+	reg.OnReload(func(context.Context, *registry.Snapshot) { wake() })
+	wake()
+}
+
+// follow starts the goroutine behind Track and returns the non-blocking
+// function that asks it to reconcile current() again
+func (s *Scheduler) follow(ctx context.Context, ref string, current func() *registry.Snapshot) (wake func()) {
+	pending := make(chan struct{}, 1)
+	go func() {
+		var done string // last commit reconciled without errors
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-pending:
+			}
+			snap := current()
+			if snap == nil || snap.Commit == done {
+				continue
+			}
+			if s.reconcileSnapshot(ctx, ref, snap) {
+				done = snap.Commit
+			}
+		}
+	}()
+	return func() {
+		select {
+		case pending <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// reconcileSnapshot reconciles every workflow in snap, logging the outcome,
+// and reports whether it succeeded
+func (s *Scheduler) reconcileSnapshot(ctx context.Context, ref string, snap *registry.Snapshot) bool {
+	defs := make([]*registry.WorkflowDefinition, 0, len(snap.Workflows))
+	for _, name := range snap.WorkflowNames() {
+		def, _ := snap.Workflow(name)
+		defs = append(defs, def)
+	}
+	changes, err := s.Reconcile(ctx, defs)
+	if !changes.Empty() {
+		log.Printf("pipeline: schedules for %s at %.8s: %v", ref, snap.Commit, changes)
+	}
+	if err != nil {
+		log.Printf("pipeline: schedules for %s at %.8s: %v", ref, snap.Commit, err)
+		return false
+	}
+	return true
+}
+
+// Reconcile creates, updates, pauses, resumes and deletes schedules so that
+// exactly the definitions with a schedule have one, in definition order. A
+// failure for one workflow does not stop the others; all failures are
+// returned together.
+func (s *Scheduler) Reconcile(ctx context.Context, defs []*registry.WorkflowDefinition) (ScheduleChanges, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changes ScheduleChanges
+	existing, err := s.list(ctx)
+	if err != nil {
+		return changes, fmt.Errorf("list schedules: %w", err)
+	}
+
+	var errs []error
+	wanted := map[string]bool{}
+	for _, def := range defs {
+		if def.Schedule == nil {
+			continue
+		}
+		name := def.Name
+		id := ScheduleID(s.tenant, name)
+		wanted[id] = true
+		sched, err := s.schedule(def)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("workflow %s: %w", name, err))
+			continue
+		}
+		entry, ok := existing[id]
+		if ok && entry.Note == sched.State.Note {
+			continue
+		}
+		if !ok {
+			err = s.create(ctx, id, sched)
+			if errors.Is(err, temporal.ErrScheduleAlreadyRunning) {
+				// Listings lag behind; the schedule exists after all
+				err = s.update(ctx, id, sched)
+			}
+		} else {
+			err = s.update(ctx, id, sched)
+		}
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("workflow %s: %w", name, err))
+		case !ok:
+			changes.Created = append(changes.Created, name)
+		case sched.State.Paused == entry.Paused:
+			changes.Updated = append(changes.Updated, name)
+		case sched.State.Paused:
+			changes.Paused = append(changes.Paused, name)
+		default:
+			changes.Resumed = append(changes.Resumed, name)
+		}
+	}
+
+	ids := make([]string, 0, len(existing))
+	for id := range existing {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if wanted[id] {
+			continue
+		}
+		if err := s.client.GetHandle(ctx, id).Delete(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("delete schedule %s: %w", id, err))
+			continue
+		}
+		changes.Deleted = append(changes.Deleted, strings.TrimPrefix(id, ScheduleID(s.tenant, "")))
+	}
+	return changes, errors.Join(errs...)
+}
+
+// list returns the schedules this Scheduler manages by ID
+func (s *Scheduler) list(ctx context.Context) (map[string]*client.ScheduleListEntry, error) {
+	it, err := s.client.List(ctx, client.ScheduleListOptions{PageSize: 100})
+	if err != nil {
+		return nil, err
+	}
+	prefix := ScheduleID(s.tenant, "")
+	out := map[string]*client.ScheduleListEntry{}
+	for it.HasNext() {
+		entry, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(entry.ID, prefix) {
+			out[entry.ID] = entry
+		}
+	}
+	return out, nil
+}
+
+// schedule builds the Temporal Schedule for a definition's schedule section
+func (s *Scheduler) schedule(def *registry.WorkflowDefinition) (client.Schedule, error) {
+	spec := def.Schedule
+	sched := client.Schedule{
+		Action: &client.ScheduleWorkflowAction{
+			ID:        "scheduled-" + strings.TrimPrefix(ScheduleID(s.tenant, def.Name), SchedulePrefix),
+			Workflow:  WorkflowType,
+			Args:      []interface{}{Input{Workflow: def.Name, CustomerID: s.tenant, Parameters: spec.Parameters}},
+			TaskQueue: def.TaskQueue,
+		},
+		Spec:   &client.ScheduleSpec{TimeZoneName: spec.Timezone},
+		Policy: &client.SchedulePolicies{Overlap: overlapPolicies[registry.OverlapSkip]},
+		State:  &client.ScheduleState{Paused: spec.Paused},
+	}
+	if def.TaskQueue == "" {
+		return sched, fmt.Errorf("no task queue for scheduled runs")
+	}
+	if spec.Cron != "" {
+		sched.Spec.CronExpressions = []string{spec.Cron}
+	} else {
+		every, err := time.ParseDuration(spec.Every)
+		if err != nil {
+			return sched, fmt.Errorf("schedule every: %w", err)
+		}
+		sched.Spec.Intervals = []client.ScheduleIntervalSpec{{Every: every}}
+	}
+	if spec.Overlap != "" {
+		policy, ok := overlapPolicies[spec.Overlap]
+		if !ok {
+			return sched, fmt.Errorf("unknown overlap policy %q", spec.Overlap)
+		}
+		sched.Policy.Overlap = policy
+	}
+	if spec.CatchupWindow != "" {
+		window, err := time.ParseDuration(spec.CatchupWindow)
+		if err != nil {
+			return sched, fmt.Errorf("schedule catchup_window: %w", err)
+		}
+		sched.Policy.CatchupWindow = window
+	}
+
+	// The note carries a hash of everything above, so a reconcile can tell
+	// from a listing whether the schedule is current
+	data, err := json.Marshal(struct {
+		Spec      *registry.Schedule
+		TaskQueue string
+		Tenant    string
+	}{spec, def.TaskQueue, s.tenant})
+	if err != nil {
+		return sched, err
+	}
+	sum := sha256.Sum256(data)
+	sched.State.Note = scheduleNote + hex.EncodeToString(sum[:6])
+	return sched, nil
+}
+
+func (s *Scheduler) create(ctx context.Context, id string, sched client.Schedule) error {
+	_, err := s.client.Create(ctx, client.ScheduleOptions{
+		ID:             id,
+		Spec:           *sched.Spec,
+		Action:         sched.Action,
+		Overlap:        sched.Policy.Overlap,
+		CatchupWindow:  sched.Policy.CatchupWindow,
+		Note:           sched.State.Note,
+		Paused:         sched.State.Paused,
+		PauseOnFailure: sched.Policy.PauseOnFailure,
+	})
+	return err
+}
+
+func (s *Scheduler) update(ctx context.Context, id string, sched client.Schedule) error {
+	return s.client.GetHandle(ctx, id).Update(ctx, client.ScheduleUpdateOptions{
+		DoUpdate: func(in client.ScheduleUpdateInput) (*client.ScheduleUpdate, error) {
+			next := in.Description.Schedule
+			next.Action, next.Spec, next.Policy = sched.Action, sched.Spec, sched.Policy
+			if next.State == nil {
+				next.State = &client.ScheduleState{}
+			}
+			next.State.Note, next.State.Paused = sched.State.Note, sched.State.Paused
+			return &client.ScheduleUpdate{Schedule: &next}, nil
+		},
+	})
+}
@@ -0,0 +1,88 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/expr"
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
+)
+
+// runWorkflow runs a workflow stage's definition as a child pipeline,
+// pinned to the parent's commit. The child is recorded as the stage's one
+// activity and its outputs become the stage's, under the child's name. A
+// failed child has already run its own compensations, so it counts as no
+// work done.
+func (r *run) runWorkflow(ctx workflow.Context, i int, s registry.Stage, sr *StageResult) error {
+	spec := s.Workflow
+	params, err := workflowParameters(spec.Parameters, r.env())
+	if err != nil {
+		return err
+	}
+	stageCtx, expired := withDeadline(ctx, r.timeouts[i])
+	childCtx := workflow.WithChildOptions(stageCtx, workflow.ChildWorkflowOptions{
+		WorkflowID: workflow.GetInfo(ctx).WorkflowExecution.ID + "/" + s.Name,
+	})
+	in := Input{Workflow: spec.Name, Commit: r.in.Commit, CustomerID: r.in.CustomerID, Parameters: params}
+	workflow.GetLogger(ctx).Info("stage started", "stage", s.Name, "workflow", spec.Name)
+
+	var child struct {
+		Outputs map[string]interface{} `json:"outputs"`
+	}
+	res := ActivityResult{Name: spec.Name, Status: StatusCompleted}
+	if err := workflow.ExecuteChildWorkflow(childCtx, WorkflowType, in).Get(ctx, &child); err != nil {
+		res.Status, res.Error = StatusFailed, childError(err)
+	} else {
+		res.Output = child.Outputs
+		r.outputs[s.Name] = map[string]interface{}{spec.Name: child.Outputs}
+	}
+	sr.Activities = append(sr.Activities, res)
+	switch {
+	case expired():
+		return fmt.Errorf("exceeded its %v timeout", r.timeouts[i])
+	case res.Status == StatusFailed:
+		return fmt.Errorf("workflow %s: %s", spec.Name, res.Error)
+	}
+	return nil
+}
+
+// workflowParameters evaluates a workflow stage's ${expr} parameters in
+// key order and passes the others through
+func workflowParameters(spec map[string]interface{}, env map[string]interface{}) (map[string]interface{}, error) {
+	keys := make([]string, 0, len(spec))
+	for k := range spec {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make(map[string]interface{}, len(spec))
+	for _, k := range keys {
+		src, ok := registry.ParameterExpr(spec[k])
+		if !ok {
+			out[k] = spec[k]
+			continue
+		}
+		e, err := expr.Parse(src)
+		var v interface{}
+		if err == nil {
+			v, err = e.Eval(env)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s %q: %v", k, src, err)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
+
+// childError unwraps Temporal's child workflow error to the cause's message
+func childError(err error) string {
+	var childErr *temporal.ChildWorkflowExecutionError
+	if errors.As(err, &childErr) && childErr.Unwrap() != nil {
+		return childErr.Unwrap().Error()
+	}
+	return err.Error()
+}
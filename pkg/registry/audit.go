@@ -0,0 +1,229 @@
+package registry
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+)
+
+// defaultAuditSize is how many reload records a ReloadLog keeps by default
+const defaultAuditSize = 10000
+
+// ReloadLog is the audit log of reload attempts. Records are kept in
+// memory and, when the log has a file, appended to it as JSON lines so the
+// history survives restarts. It implements api.ReloadHistory.
+type ReloadLog struct {
+	mu      sync.Mutex
+	records []api.ReloadRecord // oldest first
+	nextID  int64
+	max     int
+	file    *os.File
+}
+
+// OpenReloadLog opens the log at file, reading back earlier records. An
+// empty file keeps records in memory only. At most max records are kept
+// (default 10000); the file is compacted to that many when opened.
+func OpenReloadLog(file string, max int) (*ReloadLog, error) {
+	if max <= 0 {
+		max = defaultAuditSize
+	}
+	l := &ReloadLog{max: max, nextID: 1}
+	if file == "" {
+		return l, nil
+	}
+
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16<<20)
+	lines := 0
+	for sc.Scan() {
+		var rec api.ReloadRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s line %d: %v", file, lines+1, err)
+		}
+		l.append(rec)
+		lines++
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if lines > len(l.records) {
+		if err := l.compact(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	l.file = f
+	return l, nil
+}
+
+// compact rewrites f to hold only the records kept in memory
+func (l *ReloadLog) compact(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range l.records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// Close closes the log's file
+func (l *ReloadLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *ReloadLog) append(rec api.ReloadRecord) {
+	if rec.ID >= l.nextID {
+		l.nextID = rec.ID + 1
+	}
+	l.records = append(l.records, rec)
+	if len(l.records) > l.max {
+		l.records = l.records[len(l.records)-l.max:]
+	}
+}
+
+// Record adds rec to the log, assigning its ID
+func (l *ReloadLog) Record(rec api.ReloadRecord) (api.ReloadRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rec.ID = l.nextID
+	l.append(rec)
+	if l.file == nil {
+		return rec, nil
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return rec, err
+	}
+	_, err = l.file.Write(append(line, '\n'))
+	return rec, err
+}
+
+// Reloads implements api.ReloadHistory, newest first
+func (l *ReloadLog) Reloads(ctx context.Context, f api.ReloadFilter) (*api.ReloadsResponse, error) {
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	resp := &api.ReloadsResponse{Success: true, Reloads: []api.ReloadRecord{}}
+	for i := len(l.records) - 1; i >= 0; i-- {
+		rec := l.records[i]
+		if !matchReload(rec, f) {
+			continue
+		}
+		resp.Total++
+		if len(resp.Reloads) < f.Limit {
+			resp.Reloads = append(resp.Reloads, rec)
+		}
+	}
+	return resp, nil
+}
+
+func matchReload(rec api.ReloadRecord, f api.ReloadFilter) bool {
+	switch {
+	case f.Tenant != "" && rec.Tenant != f.Tenant,
+		f.Branch != "" && rec.Branch != f.Branch,
+		f.Outcome != "" && rec.Outcome != f.Outcome,
+		!f.Since.IsZero() && rec.StartedAt.Before(f.Since),
+		!f.Until.IsZero() && rec.StartedAt.After(f.Until):
+		return false
+	case f.File == "":
+		return true
+	}
+	for _, file := range rec.Files {
+		if matchPath(f.File, file.Path) || file.OldPath != "" && matchPath(f.File, file.OldPath) {
+			return true
+		}
+	}
+	for _, issue := range rec.Errors {
+		if matchPath(f.File, issue.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPath(pattern, p string) bool {
+	ok, _ := path.Match(pattern, p)
+	return ok || pattern == p
+}
+
+// LogReloads makes the registry record every reload attempt in l
+func (r *Registry) LogReloads(l *ReloadLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audit = l
+}
+
+// record adds a reload attempt to the audit log. changes may be nil, in
+// which case they are computed from the commits.
+func (r *Registry) record(ctx context.Context, old, commit string, changes []gitnative.Change, start time.Time, err error) {
+	if r.audit == nil {
+		return
+	}
+	rec := api.ReloadRecord{
+		Branch:    strings.TrimPrefix(r.ref, "refs/heads/"),
+		OldCommit: old,
+		NewCommit: commit,
+		Outcome:   api.ReloadApplied,
+		Files:     []api.ReloadFile{},
+		Duration:  time.Since(start).String(),
+		StartedAt: start,
+	}
+	if tenant, ok := strings.CutPrefix(r.ref, tenantPrefix); ok {
+		rec.Tenant = tenant
+	}
+	if c, cerr := r.repo.Commit(commit); cerr == nil {
+		rec.Author = api.CommitAuthor{Name: c.Author.Name, Email: c.Author.Email}
+		rec.Message, _, _ = strings.Cut(strings.TrimSpace(c.Message), "\n")
+	}
+	if changes == nil && commit != "" {
+		changes, _ = r.repo.Diff(ctx, old, commit)
+	}
+	for _, c := range changes {
+		rec.Files = append(rec.Files, api.ReloadFile{Path: c.Path, OldPath: c.OldPath, Change: string(c.Type), Kind: string(c.Kind)})
+	}
+	if err != nil {
+		rec.Outcome = api.ReloadRejected
+		var rerr *ReloadError
+		if errors.As(err, &rerr) {
+			for _, fe := range rerr.Errors {
+				rec.Errors = append(rec.Errors, api.ReloadIssue{Path: fe.Path, Pointer: fe.Pointer, Line: fe.Line, Message: fe.Message})
+			}
+		} else {
+			rec.Errors = []api.ReloadIssue{{Message: err.Error()}}
+		}
+	}
+	if _, err := r.audit.Record(rec); err != nil {
+		log.Printf("registry: audit log: %v", err)
+	}
+}
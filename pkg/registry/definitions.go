@@ -0,0 +1,398 @@
+// Package registry holds the tool, workflow, config and stage template
+// definitions loaded from one commit of the runtime repository, and swaps
+// them atomically when the branch moves.
+package registry
+
+import (
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/schema"
+)
+
+// Tool is a tool definition from tools/*.json
+type Tool struct {
+	Name        string                 `json:"name" yaml:"name"`
+	Version     string                 `json:"version,omitempty" yaml:"version"`
+	Category    string                 `json:"category,omitempty" yaml:"category"`
+	Description string                 `json:"description,omitempty" yaml:"description"`
+	Patterns    []string               `json:"patterns,omitempty" yaml:"patterns"`
+	Rules       []string               `json:"rules,omitempty" yaml:"rules"`
+	Config      map[string]interface{} `json:"config,omitempty" yaml:"config"`
+}
+
+// RetryPolicy mirrors Temporal's retry policy in definition files
+type RetryPolicy struct {
+	MaximumAttempts    int     `json:"maximum_attempts,omitempty" yaml:"maximum_attempts"`
+	InitialInterval    string  `json:"initial_interval,omitempty" yaml:"initial_interval"`
+	BackoffCoefficient float64 `json:"backoff_coefficient,omitempty" yaml:"backoff_coefficient"`
+	MaximumInterval    string  `json:"maximum_interval,omitempty" yaml:"maximum_interval"`
+}
+
+// Names expressions in a definition may read: the run's input parameters,
+// earlier activity outputs by stage then activity, and the customer
+const (
+	ExprParams   = "params"
+	ExprStages   = "stages"
+	ExprCustomer = "customer_id"
+)
+
+// DefaultLoopItem names the current item of a loop that does not set as
+const DefaultLoopItem = "item"
+
+// Loop runs a stage's activities once for every item of a list
+type Loop struct {
+	// Items is an expression yielding the list
+	Items string `json:"items" yaml:"items"`
+	// As names the current item in expressions; DefaultLoopItem if empty
+	As string `json:"as,omitempty" yaml:"as"`
+	// Concurrency bounds the items in flight; 0 runs one at a time
+	Concurrency int `json:"concurrency,omitempty" yaml:"concurrency"`
+	// BatchSize fans a longer list out to child workflows of at most
+	// BatchSize items each, keeping the pipeline's own history small
+	BatchSize int `json:"batch_size,omitempty" yaml:"batch_size"`
+	// Aggregate is an activity run once after every item succeeded, with
+	// the collected outputs. Only map loops collect outputs.
+	Aggregate string `json:"aggregate,omitempty" yaml:"aggregate"`
+}
+
+// Decisions an approval stage applies when nobody decides in time
+const (
+	ApprovalApprove = "approve"
+	ApprovalReject  = "reject"
+)
+
+// Approval makes a stage wait for a human decision instead of running
+// activities
+type Approval struct {
+	// Approvers are the groups asked to decide
+	Approvers []string `json:"approvers" yaml:"approvers"`
+	Message   string   `json:"message,omitempty" yaml:"message"`
+	// Timeout applies Default when nobody decides in time; empty waits as
+	// long as the workflow may run
+	Timeout string `json:"timeout,omitempty" yaml:"timeout"`
+	// Default is ApprovalApprove or ApprovalReject (if empty)
+	Default string `json:"default,omitempty" yaml:"default"`
+	// EscalateAfter lets EscalateTo decide too once it has passed
+	EscalateAfter string   `json:"escalate_after,omitempty" yaml:"escalate_after"`
+	EscalateTo    []string `json:"escalate_to,omitempty" yaml:"escalate_to"`
+}
+
+// SubWorkflow is a stage running another definition, from the same
+// commit, as a child workflow. Its outputs become the stage's outputs
+// under the child's name.
+type SubWorkflow struct {
+	Name string `json:"name" yaml:"name"`
+	// Parameters are the child's input. A string written as ${expr} is
+	// evaluated when the stage starts, keeping the result's type; every
+	// other value, other strings included, is passed as it is.
+	Parameters map[string]interface{} `json:"parameters,omitempty" yaml:"parameters"`
+}
+
+// ParameterExpr returns the expression of a workflow stage parameter
+// written as ${expr}, and false for a literal
+func ParameterExpr(v interface{}) (string, bool) {
+	s, ok := v.(string)
+	if !ok {
+		return "", false
+	}
+	if m := placeholder.FindStringSubmatch(s); m != nil && m[0] == s {
+		return m[1], true
+	}
+	return "", false
+}
+
+// Item is the name the current item is bound to
+func (l *Loop) Item() string {
+	if l.As == "" {
+		return DefaultLoopItem
+	}
+	return l.As
+}
+
+// Stage is one step of a workflow. A bare string in the file is shorthand
+// for a stage with only a name.
+type Stage struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description"`
+	// When skips the stage unless the expression is true
+	When       string `json:"when,omitempty" yaml:"when"`
+	Activities []Step `json:"activities,omitempty" yaml:"activities"`
+	// Switch runs the activities of the first case whose expression is
+	// true, instead of Activities
+	Switch []Case `json:"switch,omitempty" yaml:"switch"`
+	// ForEach runs the activities for every item, keeping only whether
+	// each succeeded
+	ForEach *Loop `json:"foreach,omitempty" yaml:"foreach"`
+	// Map runs the activities for every item and collects their outputs
+	// as lists in item order
+	Map *Loop `json:"map,omitempty" yaml:"map"`
+	// Approval waits for a human decision; such a stage has no activities
+	Approval *Approval `json:"approval,omitempty" yaml:"approval"`
+	// Workflow runs another definition as a child workflow; such a stage
+	// has no activities
+	Workflow *SubWorkflow `json:"workflow,omitempty" yaml:"workflow"`
+	// Compensate undoes the stage's side effects: when the pipeline fails,
+	// these activities run for every stage that did any work, latest
+	// stage first
+	Compensate      []Step `json:"compensate,omitempty" yaml:"compensate"`
+	Parallel        bool   `json:"parallel,omitempty" yaml:"parallel"`
+	Timeout         string `json:"timeout,omitempty" yaml:"timeout"`
+	ContinueOnError bool   `json:"continue_on_error,omitempty" yaml:"continue_on_error"`
+}
+
+// Loop returns the stage's foreach or map loop, if any, and whether it
+// collects outputs
+func (s Stage) Loop() (*Loop, bool) {
+	if s.Map != nil {
+		return s.Map, true
+	}
+	return s.ForEach, false
+}
+
+// Step is one activity of a stage. A bare string in the file is shorthand
+// for a step with only an activity.
+type Step struct {
+	Activity string `json:"activity" yaml:"activity"`
+	// When skips the activity unless the expression is true
+	When string `json:"when,omitempty" yaml:"when"`
+}
+
+// stepFields breaks the UnmarshalYAML/UnmarshalJSON recursion
+type stepFields Step
+
+// UnmarshalYAML accepts either an activity name or a step mapping
+func (s *Step) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = Step{Activity: node.Value}
+		return nil
+	}
+	return node.Decode((*stepFields)(s))
+}
+
+// UnmarshalJSON accepts either an activity name or a step object
+func (s *Step) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*s = Step{Activity: name}
+		return nil
+	}
+	return json.Unmarshal(data, (*stepFields)(s))
+}
+
+// Case is one branch of a stage's switch. The last case may omit When to
+// act as the default.
+type Case struct {
+	When       string `json:"when,omitempty" yaml:"when"`
+	Activities []Step `json:"activities" yaml:"activities"`
+}
+
+// stageFields breaks the UnmarshalYAML/UnmarshalJSON recursion
+type stageFields Stage
+
+// UnmarshalYAML accepts either a stage name or a stage mapping
+func (s *Stage) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = Stage{Name: node.Value}
+		return nil
+	}
+	return node.Decode((*stageFields)(s))
+}
+
+// UnmarshalJSON accepts either a stage name or a stage object
+func (s *Stage) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*s = Stage{Name: name}
+		return nil
+	}
+	return json.Unmarshal(data, (*stageFields)(s))
+}
+
+// Overlap policies of a schedule: what happens when a run is due while the
+// previous one is still running
+const (
+	OverlapSkip           = "skip"
+	OverlapBufferOne      = "buffer_one"
+	OverlapBufferAll      = "buffer_all"
+	OverlapCancelOther    = "cancel_other"
+	OverlapTerminateOther = "terminate_other"
+	OverlapAllowAll       = "allow_all"
+)
+
+// OverlapPolicies lists the overlap values a schedule accepts
+var OverlapPolicies = []string{OverlapSkip, OverlapBufferOne, OverlapBufferAll, OverlapCancelOther, OverlapTerminateOther, OverlapAllowAll}
+
+// Schedule starts a workflow on a timetable. It sets either Cron or Every.
+type Schedule struct {
+	// Cron is a five-field cron expression or a shorthand like @daily
+	Cron string `json:"cron,omitempty" yaml:"cron"`
+	// Every is an interval such as 6h
+	Every string `json:"every,omitempty" yaml:"every"`
+	// Timezone is the IANA zone Cron is read in; UTC if empty. A tenant
+	// branch overrides it for its own runs.
+	Timezone string `json:"timezone,omitempty" yaml:"timezone"`
+	// Overlap is one of OverlapPolicies; OverlapSkip if empty
+	Overlap string `json:"overlap,omitempty" yaml:"overlap"`
+	// CatchupWindow is how late a run missed while the server was down may
+	// still start
+	CatchupWindow string `json:"catchup_window,omitempty" yaml:"catchup_window"`
+	Paused        bool   `json:"paused,omitempty" yaml:"paused"`
+	// Parameters are the input of every scheduled run
+	Parameters map[string]interface{} `json:"parameters,omitempty" yaml:"parameters"`
+}
+
+// Hooks are activities run around a workflow
+type Hooks struct {
+	OnStart   []string `json:"on_start,omitempty" yaml:"on_start"`
+	OnSuccess []string `json:"on_success,omitempty" yaml:"on_success"`
+	OnFailure []string `json:"on_failure,omitempty" yaml:"on_failure"`
+}
+
+// WorkflowDefinition is a workflow from workflows/*.yaml or *.json
+type WorkflowDefinition struct {
+	Name        string      `json:"name" yaml:"name"`
+	Version     string      `json:"version,omitempty" yaml:"version"`
+	Description string      `json:"description,omitempty" yaml:"description"`
+	TaskQueue   string      `json:"task_queue,omitempty" yaml:"task_queue"`
+	MaxDuration string      `json:"max_duration,omitempty" yaml:"max_duration"`
+	RetryPolicy RetryPolicy `json:"retry_policy" yaml:"retry_policy"`
+	Stages      []Stage     `json:"stages,omitempty" yaml:"stages"`
+	Hooks       Hooks       `json:"hooks" yaml:"hooks"`
+	// Schedule starts the workflow on a timetable; runs need a task_queue
+	Schedule *Schedule `json:"schedule,omitempty" yaml:"schedule"`
+	// Inputs is a JSON Schema for the run's parameters
+	Inputs map[string]interface{} `json:"inputs,omitempty" yaml:"inputs"`
+	// Outputs is a JSON Schema for the run's outputs, an object of stages
+	// holding each activity's output
+	Outputs map[string]interface{} `json:"outputs,omitempty" yaml:"outputs"`
+
+	// refs are the task queues, activities and workflows used, for
+	// catalog and cycle checks
+	refs []reference
+}
+
+// InputSchema compiles Inputs; it is nil when the definition has none
+func (w *WorkflowDefinition) InputSchema() (*schema.Schema, error) {
+	return compileSchema(w.Inputs)
+}
+
+// OutputSchema compiles Outputs; it is nil when the definition has none
+func (w *WorkflowDefinition) OutputSchema() (*schema.Schema, error) {
+	return compileSchema(w.Outputs)
+}
+
+func compileSchema(doc map[string]interface{}) (*schema.Schema, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	return schema.Compile(doc)
+}
+
+// FileError is a problem with one file in a commit
+type FileError struct {
+	Path string `json:"path"`
+	// Pointer is the JSON pointer of the offending value, if known
+	Pointer string `json:"pointer,omitempty"`
+	// Line is 1-based, or 0 if unknown
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error formats as path:line: pointer: message, omitting what is unknown
+func (e FileError) Error() string {
+	s := e.Path
+	if e.Line > 0 {
+		s += ":" + strconv.Itoa(e.Line)
+	}
+	if e.Pointer != "" {
+		s += ": " + e.Pointer
+	}
+	return s + ": " + e.Message
+}
+
+// FileErrors are several problems found in one file
+type FileErrors []FileError
+
+func (es FileErrors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// definitionFile reports whether p has an extension the loader parses
+func definitionFile(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// decode unmarshals JSON or YAML by file extension
+func decode(p string, data []byte, v interface{}) error {
+	if strings.EqualFold(path.Ext(p), ".json") {
+		return json.Unmarshal(data, v)
+	}
+	return yaml.Unmarshal(data, v)
+}
+
+// ParseTool parses and validates a tool definition
+func ParseTool(p string, data []byte) (*Tool, error) {
+	var t Tool
+	if err := decode(p, data, &t); err != nil {
+		return nil, FileError{Path: p, Message: err.Error()}
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		return nil, FileError{Path: p, Message: "name is required"}
+	}
+	return &t, nil
+}
+
+// ParseWorkflow parses and validates a workflow definition. Every problem
+// is reported, as FileErrors, with its JSON pointer and line.
+func ParseWorkflow(p string, data []byte) (*WorkflowDefinition, error) {
+	return ParseWorkflowWith(p, data, nil)
+}
+
+// ParseWorkflowWith parses a workflow definition whose use stages take
+// their templates from lib. The definition holds the stages they expand
+// to; problems within them are reported at the use stage.
+func ParseWorkflowWith(p string, data []byte, lib Library) (*WorkflowDefinition, error) {
+	root, err := parseNode(p, data)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{path: p}
+	origins := v.expand(root, lib)
+	errs := len(v.errs)
+	v.workflow(root)
+	v.attribute(origins, errs)
+	if len(v.errs) > 0 {
+		return nil, FileErrors(v.errs)
+	}
+	var w WorkflowDefinition
+	if err := root.Decode(&w); err != nil {
+		return nil, FileError{Path: p, Message: err.Error()}
+	}
+	w.refs = v.refs
+	return &w, nil
+}
+
+// ParseConfig parses a config file, which must be a mapping
+func ParseConfig(p string, data []byte) (map[string]interface{}, error) {
+	var c map[string]interface{}
+	if err := decode(p, data, &c); err != nil {
+		return nil, FileError{Path: p, Message: err.Error()}
+	}
+	if c == nil {
+		c = map[string]interface{}{}
+	}
+	return c, nil
+}
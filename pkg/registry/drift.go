@@ -0,0 +1,348 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+)
+
+// tenantPrefix is the namespace of tenant branches
+const tenantPrefix = "refs/heads/customer/"
+
+// Drift compares tenant branches with main and merges main into them when
+// that needs no judgement. It implements api.DriftService.
+//
+// Files are compared three ways against the commit where the tenant
+// forked: a file only main changed is behind, a file only the tenant
+// changed is overridden, and a file both changed is a conflict. A merge is
+// offered only when there are no conflicts, so it never has to combine two
+// edits of the same file.
+type Drift struct {
+	repo       *gitnative.Repo
+	registries func(ref string) *Registry
+}
+
+// NewDrift returns a Drift for repo. Like the Writer, it refuses to merge
+// into a branch whose registry (from registries, which may be nil)
+// requires signed commits, since the runtime has no key to sign with.
+func NewDrift(repo *gitnative.Repo, registries func(ref string) *Registry) *Drift {
+	if registries == nil {
+		registries = func(string) *Registry { return nil }
+	}
+	return &Drift{repo: repo, registries: registries}
+}
+
+// unsignable returns api.ErrConflict if ref only accepts signed commits
+func (d *Drift) unsignable(ref string) error {
+	if reg := d.registries(ref); reg != nil && reg.RequiresSignatures() {
+		return fmt.Errorf("%w: %s only accepts signed commits; merge main and push a signed commit instead", api.ErrConflict, ref)
+	}
+	return nil
+}
+
+// comparison is a tenant's drift report plus what a merge would change
+type comparison struct {
+	report  api.TenantDrift
+	ref     string
+	main    string
+	updates map[string]string // path -> main's blob ID, "" to delete
+}
+
+// Drift implements api.DriftService
+func (d *Drift) Drift(ctx context.Context, tenant string) (*api.DriftResponse, error) {
+	mainCommit, err := gitnative.ResolveRef(d.repo.GitDir(), MainRef)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", MainRef, err)
+	}
+	tenants := []string{tenant}
+	if tenant == "" {
+		if tenants, err = d.tenants(); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &api.DriftResponse{Success: true, MainCommit: mainCommit, Tenants: []api.TenantDrift{}}
+	for _, t := range tenants {
+		cmp, err := d.compare(ctx, t, mainCommit)
+		if err != nil {
+			return nil, err
+		}
+		resp.Tenants = append(resp.Tenants, cmp.report)
+	}
+	return resp, nil
+}
+
+// tenants lists the tenants that have a branch, sorted
+func (d *Drift) tenants() ([]string, error) {
+	refs, err := gitnative.ListRefs(d.repo.GitDir())
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for ref := range refs {
+		if tenant, ok := strings.CutPrefix(ref, tenantPrefix); ok && tenantPattern.MatchString(tenant) {
+			out = append(out, tenant)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (d *Drift) compare(ctx context.Context, tenant, mainCommit string) (*comparison, error) {
+	if tenant == "" {
+		return nil, fmt.Errorf("%w: tenant is required", api.ErrInvalidArgument)
+	}
+	ref, err := TenantRef(tenant)
+	if err != nil {
+		return nil, err
+	}
+	head, err := gitnative.ResolveRef(d.repo.GitDir(), ref)
+	if errors.Is(err, gitnative.ErrRefNotFound) {
+		return nil, fmt.Errorf("%w: tenant %s has no branch", api.ErrNotFound, tenant)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := d.repo.MergeBase(ctx, mainCommit, head)
+	if err != nil {
+		return nil, err
+	}
+	behindBy, err := d.repo.CountExclusive(ctx, mainCommit, head)
+	if err != nil {
+		return nil, err
+	}
+	mainBlobs, err := d.repo.Blobs(ctx, mainCommit)
+	if err != nil {
+		return nil, err
+	}
+	tenantBlobs, err := d.repo.Blobs(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+	baseBlobs := map[string]string{}
+	if base != "" {
+		if baseBlobs, err = d.repo.Blobs(ctx, base); err != nil {
+			return nil, err
+		}
+	}
+
+	cmp := &comparison{
+		ref:     ref,
+		main:    mainCommit,
+		updates: map[string]string{},
+		report: api.TenantDrift{
+			Tenant:        tenant,
+			Branch:        strings.TrimPrefix(ref, "refs/heads/"),
+			Commit:        head,
+			BaseCommit:    base,
+			CommitsBehind: behindBy,
+			Files:         []api.DriftFile{},
+		},
+	}
+
+	paths := map[string]bool{}
+	for _, blobs := range []map[string]string{mainBlobs, tenantBlobs, baseBlobs} {
+		for p := range blobs {
+			paths[p] = true
+		}
+	}
+	for _, p := range sortedKeys(paths) {
+		m, t, b := mainBlobs[p], tenantBlobs[p], baseBlobs[p]
+		if m == t {
+			continue
+		}
+		f := api.DriftFile{Path: p, Kind: string(gitnative.ClassifyPath(p))}
+		switch {
+		case t == b:
+			f.Status, f.Detail = api.DriftBehind, changeDetail(b, m, "main")
+			cmp.updates[p] = m
+		case m == b:
+			if b == "" {
+				continue // the tenant's own file, not a shared one
+			}
+			f.Status, f.Detail = api.DriftOverridden, changeDetail(b, t, "tenant")
+		default:
+			f.Status, f.Detail = api.DriftConflict, conflictDetail(b, m, t)
+		}
+
+		// Conflicts block a merge whatever the file; otherwise only the
+		// definitions the runtime loads are worth reporting
+		if f.Status != api.DriftConflict && gitnative.ClassifyPath(p) == gitnative.KindOther {
+			continue
+		}
+		switch f.Status {
+		case api.DriftBehind:
+			cmp.report.Behind++
+		case api.DriftOverridden:
+			cmp.report.Overridden++
+		case api.DriftConflict:
+			cmp.report.Conflicts++
+		}
+		cmp.report.Files = append(cmp.report.Files, f)
+	}
+	cmp.report.Mergeable = cmp.report.Conflicts == 0
+	return cmp, nil
+}
+
+// changeDetail describes how one side changed a file since the fork
+func changeDetail(base, now, side string) string {
+	switch {
+	case base == "":
+		return "added on " + side
+	case now == "":
+		return "deleted on " + side
+	}
+	return "modified on " + side
+}
+
+func conflictDetail(base, main, tenant string) string {
+	switch {
+	case base == "":
+		return "added on both"
+	case main == "":
+		return "deleted on main, modified on tenant"
+	case tenant == "":
+		return "modified on main, deleted on tenant"
+	}
+	return "modified on both"
+}
+
+// MergeMain implements api.DriftService. The merge commit takes every
+// behind file from main and keeps the tenant's overrides; it is refused
+// with api.ErrConflict if any file conflicts or the result does not load.
+func (d *Drift) MergeMain(ctx context.Context, tenant string, req api.MergeMainRequest) (*api.MergeMainResponse, error) {
+	mainCommit, err := gitnative.ResolveRef(d.repo.GitDir(), MainRef)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", MainRef, err)
+	}
+	cmp, err := d.compare(ctx, tenant, mainCommit)
+	if err != nil {
+		return nil, err
+	}
+	head := cmp.report.Commit
+	if req.ExpectedHead != "" && req.ExpectedHead != head {
+		return nil, fmt.Errorf("%w: %s is at %s, not %s", api.ErrConflict, cmp.report.Branch, head, req.ExpectedHead)
+	}
+
+	resp := &api.MergeMainResponse{
+		Success:    true,
+		Tenant:     tenant,
+		Branch:     cmp.report.Branch,
+		Commit:     head,
+		Parent:     head,
+		MainCommit: mainCommit,
+		Updated:    []string{},
+	}
+	if cmp.report.CommitsBehind == 0 {
+		return resp, nil
+	}
+	if !cmp.report.Mergeable {
+		var paths []string
+		for _, f := range cmp.report.Files {
+			if f.Status == api.DriftConflict {
+				paths = append(paths, f.Path)
+			}
+		}
+		return nil, fmt.Errorf("%w: %s conflicts with main in %s", api.ErrConflict, cmp.report.Branch, strings.Join(paths, ", "))
+	}
+	if err := d.unsignable(cmp.ref); err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	for _, p := range sortedKeys(cmp.updates) {
+		files[p] = nil
+		if id := cmp.updates[p]; id != "" {
+			data, err := d.repo.Objects().Blob(id)
+			if err != nil {
+				return nil, err
+			}
+			files[p] = data
+		}
+		resp.Updated = append(resp.Updated, p)
+	}
+
+	message := req.Message
+	if message == "" {
+		message = fmt.Sprintf("Merge main into %s", cmp.report.Branch)
+	}
+	commit, err := d.repo.CreateCommit(ctx, gitnative.CommitRequest{
+		Parent:      head,
+		MergeParent: mainCommit,
+		Author:      gitnative.Signature{Name: req.Author.Name, Email: req.Author.Email, When: time.Now()},
+		Message:     message,
+		Files:       files,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	snap, errs := branchSnapshot(ctx, d.repo, cmp.ref, mainCommit, commit)
+	if errs = append(errs, snap.index()...); len(errs) > 0 {
+		return nil, fmt.Errorf("%w: merging main into %s: %v", api.ErrConflict, cmp.report.Branch, &ReloadError{Commit: commit, Errors: errs})
+	}
+	if err := gitnative.UpdateRef(d.repo.GitDir(), cmp.ref, head, commit); err != nil {
+		if errors.Is(err, gitnative.ErrStaleRef) {
+			return nil, fmt.Errorf("%w: %v", api.ErrConflict, err)
+		}
+		return nil, err
+	}
+	resp.Commit, resp.Merged = commit, true
+	return resp, nil
+}
+
+// AutoMerge merges main into every tenant branch that is behind and has no
+// conflicts. Branches that cannot be merged, including those that require
+// signed commits, are skipped and returned in skipped with the reason.
+func (d *Drift) AutoMerge(ctx context.Context, author api.CommitAuthor) (merged []*api.MergeMainResponse, skipped map[string]error, err error) {
+	tenants, err := d.tenants()
+	if err != nil {
+		return nil, nil, err
+	}
+	skipped = map[string]error{}
+	for _, tenant := range tenants {
+		if err := d.unsignable(tenantPrefix + tenant); err != nil {
+			skipped[tenant] = err
+			continue
+		}
+		resp, err := d.MergeMain(ctx, tenant, api.MergeMainRequest{Author: author})
+		switch {
+		case errors.Is(err, api.ErrConflict):
+			skipped[tenant] = err
+		case err != nil:
+			return merged, skipped, err
+		case resp.Merged:
+			merged = append(merged, resp)
+		}
+	}
+	return merged, skipped, nil
+}
+
+// Handler returns a watcher callback that runs AutoMerge whenever main
+// moves. It is opt-in: conflict-free tenants then pick up fixes from main
+// without anyone rebasing them, and the rest are logged for a person.
+func (d *Drift) Handler(ctx context.Context, author api.CommitAuthor) func(gitnative.CommitEvent) {
+	return func(event gitnative.CommitEvent) {
+		if event.Ref != MainRef || event.NewSHA == "" {
+			return
+		}
+		merged, skipped, err := d.AutoMerge(ctx, author)
+		for _, m := range merged {
+			log.Printf("registry: merged main %.8s into %s as %.8s", m.MainCommit, m.Branch, m.Commit)
+		}
+		for _, tenant := range sortedKeys(skipped) {
+			log.Printf("registry: not merging main into tenant %s: %v", tenant, skipped[tenant])
+		}
+		if err != nil {
+			log.Printf("registry: auto-merge: %v", err)
+		}
+	}
+}
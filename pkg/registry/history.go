@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+)
+
+// historySize bounds the snapshots kept for requests pinned to commits other
+// than the one being served
+const historySize = 16
+
+// history is a small LRU of historical snapshots keyed by commit
+type history struct {
+	mu    sync.Mutex
+	order []string // most recently used last
+	snaps map[string]*Snapshot
+}
+
+func (h *history) get(commit string) (*Snapshot, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	snap, ok := h.snaps[commit]
+	if ok {
+		h.touch(commit)
+	}
+	return snap, ok
+}
+
+func (h *history) put(snap *Snapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.snaps == nil {
+		h.snaps = map[string]*Snapshot{}
+	}
+	if _, ok := h.snaps[snap.Commit]; !ok && len(h.order) >= historySize {
+		delete(h.snaps, h.order[0])
+		h.order = h.order[1:]
+	}
+	h.snaps[snap.Commit] = snap
+	h.touch(snap.Commit)
+}
+
+func (h *history) touch(commit string) {
+	for i, c := range h.order {
+		if c == commit {
+			h.order = append(h.order[:i], h.order[i+1:]...)
+			break
+		}
+	}
+	h.order = append(h.order, commit)
+}
+
+// At returns the definitions at commit, which need not be on the followed
+// ref. Historical snapshots are cached; a commit whose definitions do not
+// validate, or that the signing policy refuses, returns a *ReloadError.
+func (r *Registry) At(ctx context.Context, commit string) (*Snapshot, error) {
+	if cur := r.current.Load(); cur != nil && cur.Commit == commit {
+		return cur, nil
+	}
+	if snap, ok := r.history.get(commit); ok && (r.main == nil || snap.MainCommit == r.main.serving()) {
+		return snap, nil
+	}
+	if err := r.admit(ctx, commit); err != nil {
+		return nil, err
+	}
+
+	snap, errs := r.build(ctx, commit)
+	errs = append(errs, snap.index()...)
+	if len(errs) > 0 {
+		return nil, &ReloadError{Commit: commit, Errors: errs}
+	}
+	snap.Ref = ""
+	snap.LoadedAt = time.Now()
+	r.history.put(snap)
+	return snap, nil
+}
+
+// admit applies the signing policy to a pinned commit. The served commit's
+// history is already trusted; any other commit must verify forward from
+// it, exactly as a reload to it would. Refusals are not recorded as
+// rejections since nothing was reloaded.
+func (r *Registry) admit(ctx context.Context, commit string) error {
+	policy := r.signing.Load()
+	if policy == nil {
+		return nil
+	}
+	serving := r.serving()
+	if serving == "" {
+		return ErrNotLoaded
+	}
+	if _, err := policy.Verify(ctx, r.ref, serving, commit); err != nil {
+		return &ReloadError{Commit: commit, Errors: []FileError{{Path: SignersPath, Message: err.Error()}}}
+	}
+	return nil
+}
+
+// SnapshotFor returns the snapshot a request should use: the commit pinned
+// with api.WithConfigCommit, or the current one
+func (r *Registry) SnapshotFor(ctx context.Context) (*Snapshot, error) {
+	if commit, ok := api.ConfigCommit(ctx); ok {
+		return r.At(ctx, commit)
+	}
+	if cur := r.current.Load(); cur != nil {
+		return cur, nil
+	}
+	return nil, ErrNotLoaded
+}
+
+// ResolveConfig implements api.ConfigResolver. The commit a ref names must
+// be reachable from the registry's own ref, so a tenant cannot pin another
+// tenant's branch, and must load cleanly to be served.
+func (r *Registry) ResolveConfig(ctx context.Context, ref string) (string, error) {
+	if ref == "" {
+		cur := r.current.Load()
+		if cur == nil {
+			return "", ErrNotLoaded
+		}
+		return cur.Commit, nil
+	}
+
+	// Refs outside this branch get the same answer as missing ones, so
+	// callers cannot probe for other tenants' branches
+	unknown := fmt.Errorf("%w: config_ref %q does not name a commit on %s", api.ErrInvalidArgument, ref, r.ref)
+	commit, err := r.repo.Resolve(ctx, ref)
+	if errors.Is(err, gitnative.ErrRefNotFound) {
+		return "", unknown
+	}
+	if err != nil {
+		return "", err
+	}
+	head, err := r.repo.Resolve(ctx, r.ref)
+	if err != nil {
+		return "", err
+	}
+	if ok, err := r.repo.IsAncestor(ctx, commit, head); err != nil {
+		return "", err
+	} else if !ok {
+		return "", unknown
+	}
+	if _, err := r.At(ctx, commit); err != nil {
+		var rerr *ReloadError
+		if errors.As(err, &rerr) {
+			return "", fmt.Errorf("%w: config_ref %q: %v", api.ErrInvalidArgument, ref, err)
+		}
+		return "", err
+	}
+	return commit, nil
+}
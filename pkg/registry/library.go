@@ -0,0 +1,368 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StageTemplate is a reusable list of stages from library/*.yaml. A
+// workflow includes it with a stage like {use: extract-validate, with:
+// {table: orders}}; ${param} placeholders in the template's strings are
+// filled from with, or from the parameter's default.
+type StageTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Parameters maps each parameter to its default; a null default makes
+	// the parameter required
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// Stages are the template's stages as written, placeholders included
+	Stages []interface{} `json:"stages"`
+
+	// defaults are the parameters' default nodes, nil for required ones
+	defaults map[string]*yaml.Node
+	stages   *yaml.Node
+}
+
+// Library resolves the stage templates workflows use
+type Library interface {
+	Template(name string) (*StageTemplate, bool)
+}
+
+// maxTemplateDepth bounds templates using templates, as a backstop to the
+// cycle check
+const maxTemplateDepth = 16
+
+var (
+	placeholder = regexp.MustCompile(`\$\{([^}]*)\}`)
+	paramName   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// ParseStageTemplate parses and validates a stage template. Its stages are
+// only checked as a workflow's once a workflow uses them, since their
+// content depends on the parameters.
+func ParseStageTemplate(p string, data []byte) (*StageTemplate, error) {
+	root, err := parseNode(p, data)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{path: p}
+	t := v.template(root)
+	if len(v.errs) > 0 {
+		return nil, FileErrors(v.errs)
+	}
+	var doc struct {
+		Name        string                 `yaml:"name"`
+		Description string                 `yaml:"description"`
+		Parameters  map[string]interface{} `yaml:"parameters"`
+		Stages      []interface{}          `yaml:"stages"`
+	}
+	if err := root.Decode(&doc); err != nil {
+		return nil, FileError{Path: p, Message: err.Error()}
+	}
+	t.Name, t.Description, t.Parameters, t.Stages = doc.Name, doc.Description, doc.Parameters, doc.Stages
+	return t, nil
+}
+
+// template checks a stage template document
+func (v *validator) template(root *yaml.Node) *StageTemplate {
+	t := &StageTemplate{defaults: map[string]*yaml.Node{}}
+	f := v.fields(root, "", "name", "description", "parameters", "stages")
+	if f == nil {
+		return t
+	}
+	if n, ok := f["name"]; ok {
+		v.name(n, "/name")
+	} else {
+		v.fail(root, "/name", "name is required")
+	}
+	if n, ok := f["description"]; ok {
+		v.str(n, "/description")
+	}
+	if n, ok := f["parameters"]; ok {
+		if n.Kind != yaml.MappingNode {
+			v.fail(n, "/parameters", "must be a mapping of parameter to default, not %s", describe(n))
+		} else {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				key, value := n.Content[i], resolve(n.Content[i+1])
+				if !paramName.MatchString(key.Value) {
+					v.fail(key, pointer("/parameters", key.Value), "invalid parameter name %q", key.Value)
+					continue
+				}
+				if value.Tag == "!!null" {
+					value = nil
+				}
+				t.defaults[key.Value] = value
+			}
+		}
+	}
+	n, ok := f["stages"]
+	switch {
+	case !ok:
+		v.fail(root, "/stages", "stages are required")
+	case n.Kind != yaml.SequenceNode || len(n.Content) == 0:
+		v.fail(n, "/stages", "must be a non-empty list of stages")
+	default:
+		t.stages = n
+		v.placeholders(n, "/stages", t)
+	}
+	return t
+}
+
+// placeholders checks that every ${param} below n is a declared parameter
+func (v *validator) placeholders(n *yaml.Node, ptr string, t *StageTemplate) {
+	n = resolve(n)
+	switch n.Kind {
+	case yaml.ScalarNode:
+		for _, m := range placeholder.FindAllStringSubmatch(n.Value, -1) {
+			if _, ok := t.defaults[m[1]]; !ok {
+				declared := strings.Join(t.paramNames(), ", ")
+				if declared == "" {
+					declared = "none"
+				}
+				v.fail(n, ptr, "unknown parameter %q in %s (declared: %s)", m[1], m[0], declared)
+			}
+		}
+	case yaml.SequenceNode:
+		for i, item := range n.Content {
+			v.placeholders(item, pointer(ptr, i), t)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			v.placeholders(n.Content[i+1], pointer(ptr, n.Content[i].Value), t)
+		}
+	}
+}
+
+func (t *StageTemplate) paramNames() []string {
+	names := make([]string, 0, len(t.defaults))
+	for name := range t.defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// origin is where a stage of an expanded workflow was written: the index
+// of the stage in the file and, for stages from a template, the template
+// and the stage's index in its expansion
+type origin struct {
+	stage    int
+	template string
+	index    int
+}
+
+// expand replaces the use stages of a workflow document with their
+// templates' stages, in place, and returns the origin of every stage of
+// the expanded list. Copied nodes take the use stage's line.
+func (v *validator) expand(root *yaml.Node, lib Library) []origin {
+	root = resolve(root)
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	stages := resolve(field(root, "stages"))
+	if stages == nil || stages.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var out []*yaml.Node
+	var origins []origin
+	for i, item := range stages.Content {
+		u := field(item, "use")
+		if u == nil {
+			out = append(out, item)
+			origins = append(origins, origin{stage: i})
+			continue
+		}
+		expanded := v.use(resolve(item), pointer("/stages", i), lib, nil)
+		for j, n := range expanded {
+			out = append(out, n)
+			origins = append(origins, origin{stage: i, template: u.Value, index: j})
+		}
+	}
+	stages.Content = out
+	return origins
+}
+
+// use expands one use stage; stack holds the templates being expanded
+func (v *validator) use(n *yaml.Node, ptr string, lib Library, stack []string) []*yaml.Node {
+	errs := len(v.errs)
+	f := v.fields(n, ptr, "use", "with")
+	u, ok := f["use"]
+	if !ok {
+		if f != nil {
+			v.fail(n, ptr+"/use", "use needs a template name")
+		}
+		return nil
+	}
+	name, ok := v.name(u, ptr+"/use")
+	if !ok {
+		return nil
+	}
+	if contains(stack, name) || len(stack) >= maxTemplateDepth {
+		v.fail(u, ptr+"/use", "templates use each other in a cycle: %s", strings.Join(append(stack, name), " -> "))
+		return nil
+	}
+	var t *StageTemplate
+	if lib != nil {
+		t, _ = lib.Template(name)
+	}
+	if t == nil {
+		v.fail(u, ptr+"/use", "template %q is not defined in library/", name)
+		return nil
+	}
+
+	values := map[string]*yaml.Node{}
+	if w, ok := f["with"]; ok {
+		if w.Kind != yaml.MappingNode {
+			v.fail(w, ptr+"/with", "must be a mapping of parameter to value, not %s", describe(w))
+			return nil
+		}
+		for i := 0; i+1 < len(w.Content); i += 2 {
+			key := w.Content[i]
+			if _, ok := t.defaults[key.Value]; !ok {
+				v.fail(key, pointer(ptr+"/with", key.Value), "template %q has no parameter %q%s", name, key.Value, suggest(key.Value, t.paramNames()))
+				continue
+			}
+			values[key.Value] = resolve(w.Content[i+1])
+		}
+	}
+	for _, param := range t.paramNames() {
+		if _, ok := values[param]; ok {
+			continue
+		}
+		if def := t.defaults[param]; def != nil {
+			values[param] = def
+		} else {
+			v.fail(n, pointer(ptr+"/with", param), "template %q needs parameter %q", name, param)
+		}
+	}
+	if len(v.errs) > errs {
+		return nil
+	}
+
+	var out []*yaml.Node
+	for _, st := range t.stages.Content {
+		c, err := fill(st, values)
+		if err != nil {
+			v.fail(n, ptr, "template %q: %v", name, err)
+			return nil
+		}
+		relocate(c, n.Line, n.Column)
+		if field(c, "use") != nil {
+			out = append(out, v.use(c, ptr, lib, append(stack, name))...)
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// fill copies a template node, replacing its placeholders. A string that
+// is a single placeholder takes the value as it is, keeping its type;
+// placeholders within longer strings are replaced by the value's text.
+func fill(n *yaml.Node, values map[string]*yaml.Node) (*yaml.Node, error) {
+	n = resolve(n)
+	c := *n
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Tag != "!!str" {
+			return &c, nil
+		}
+		if m := placeholder.FindStringSubmatch(n.Value); m != nil && m[0] == n.Value {
+			return deepCopy(values[m[1]]), nil
+		}
+		var err error
+		c.Value = placeholder.ReplaceAllStringFunc(n.Value, func(s string) string {
+			name := s[2 : len(s)-1]
+			value := values[name]
+			if value.Kind != yaml.ScalarNode {
+				err = fmt.Errorf("parameter %s is %s and cannot be part of %q", name, describe(value), n.Value)
+				return s
+			}
+			return value.Value
+		})
+		return &c, err
+	case yaml.SequenceNode, yaml.MappingNode:
+		c.Content = make([]*yaml.Node, len(n.Content))
+		for i, child := range n.Content {
+			var err error
+			if c.Content[i], err = fill(child, values); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &c, nil
+}
+
+func deepCopy(n *yaml.Node) *yaml.Node {
+	n = resolve(n)
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = deepCopy(child)
+	}
+	return &c
+}
+
+// relocate moves every node below n to line and column
+func relocate(n *yaml.Node, line, column int) {
+	n.Line, n.Column = line, column
+	for _, child := range n.Content {
+		relocate(child, line, column)
+	}
+}
+
+// field returns the value of key if n is a mapping that has it
+func field(n *yaml.Node, key string) *yaml.Node {
+	n = resolve(n)
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// attribute rewrites the problems found in an expanded workflow, from
+// errs on, to point at the stages as written: a problem in a template's
+// stage points at the use stage and names the template.
+func (v *validator) attribute(origins []origin, errs int) {
+	if origins == nil {
+		return
+	}
+	rewrite := func(ptr string) (string, string) {
+		rest, ok := strings.CutPrefix(ptr, "/stages/")
+		if !ok {
+			return ptr, ""
+		}
+		index, rest, _ := strings.Cut(rest, "/")
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= len(origins) {
+			return ptr, ""
+		}
+		if rest != "" {
+			rest = "/" + rest
+		}
+		o := origins[i]
+		at := pointer("/stages", o.stage)
+		if o.template == "" {
+			return at + rest, ""
+		}
+		return at, fmt.Sprintf(" (in template %q at /stages/%d%s)", o.template, o.index, rest)
+	}
+	for i := errs; i < len(v.errs); i++ {
+		var note string
+		v.errs[i].Pointer, note = rewrite(v.errs[i].Pointer)
+		v.errs[i].Message += note
+	}
+	for i := range v.refs {
+		v.refs[i].pointer, _ = rewrite(v.refs[i].pointer)
+	}
+}
@@ -0,0 +1,498 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+	"gopkg.in/yaml.v3"
+)
+
+// A tenant branch is layered on main. Every definition file resolves to
+// one of these sources.
+const (
+	SourceMain    = "main"
+	SourceTenant  = "tenant"
+	SourceMerged  = "merged"
+	SourceDeleted = "deleted"
+)
+
+// DeleteMarkerSuffix marks a main file as removed for a tenant: committing
+// tools/calculator.json.deleted to the tenant branch hides
+// tools/calculator.json. Removing a file the branch inherited when it
+// forked does the same.
+const DeleteMarkerSuffix = ".deleted"
+
+// overlayKey is the reserved top-level key of a tenant file holding its
+// merge rules, for example:
+//
+//	x-overlay:
+//	  mode: replace        # ignore main's version of this file entirely
+//	  keys:
+//	    stages: append     # add the tenant's stages after main's
+//	    config.limits: replace
+//
+// By default a tenant file is merged key by key: mappings merge, anything
+// else is replaced, and a null value deletes the key.
+const overlayKey = "x-overlay"
+
+// Merge rule names used in x-overlay
+const (
+	ruleMerge   = "merge"
+	ruleReplace = "replace"
+	ruleAppend  = "append"
+)
+
+// Overlay is a tenant's effective definitions: its branch layered on main
+type Overlay struct {
+	Tenant       string
+	Branch       string
+	MainCommit   string
+	TenantCommit string
+	BaseCommit   string
+	Snapshot     *Snapshot
+	Entries      []api.ProvenanceEntry
+}
+
+// Resolver computes tenant overlays. The last overlay of each tenant is
+// kept until either branch moves. It implements api.ProvenanceService.
+type Resolver struct {
+	repo *gitnative.Repo
+
+	mu    sync.Mutex
+	cache map[string]*Overlay
+}
+
+// NewResolver returns a Resolver for repo
+func NewResolver(repo *gitnative.Repo) *Resolver {
+	return &Resolver{repo: repo, cache: map[string]*Overlay{}}
+}
+
+// Resolve returns the effective definitions for tenant. A tenant without a
+// branch inherits main unchanged. An overlay whose result does not validate
+// (say, main and the tenant both add a tool with the same name) fails with
+// api.ErrConflict.
+func (r *Resolver) Resolve(ctx context.Context, tenant string) (*Overlay, error) {
+	ref, err := TenantRef(tenant)
+	if err != nil {
+		return nil, err
+	}
+	mainCommit, err := gitnative.ResolveRef(r.repo.GitDir(), MainRef)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", MainRef, err)
+	}
+	tenantCommit := ""
+	if ref != MainRef {
+		tenantCommit, err = gitnative.ResolveRef(r.repo.GitDir(), ref)
+		if errors.Is(err, gitnative.ErrRefNotFound) {
+			tenantCommit, err = "", nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	cached := r.cache[tenant]
+	r.mu.Unlock()
+	if cached != nil && cached.MainCommit == mainCommit && cached.TenantCommit == tenantCommit {
+		return cached, nil
+	}
+
+	o, err := r.build(ctx, tenant, ref, mainCommit, tenantCommit)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.cache[tenant] = o
+	r.mu.Unlock()
+	return o, nil
+}
+
+// Provenance implements api.ProvenanceService
+func (r *Resolver) Provenance(ctx context.Context, tenant string) (*api.ProvenanceResponse, error) {
+	o, err := r.Resolve(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	return &api.ProvenanceResponse{
+		Success:      true,
+		Tenant:       o.Tenant,
+		Branch:       o.Branch,
+		MainCommit:   o.MainCommit,
+		TenantCommit: o.TenantCommit,
+		BaseCommit:   o.BaseCommit,
+		Entries:      o.Entries,
+	}, nil
+}
+
+// overlayFiles is the content of every definition file and delete marker
+// at one commit
+type overlayFiles map[string][]byte
+
+func readOverlayFiles(ctx context.Context, repo *gitnative.Repo, commit string) (overlayFiles, error) {
+	out := overlayFiles{}
+	if commit == "" {
+		return out, nil
+	}
+	paths, err := repo.ListFiles(ctx, commit)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		def := strings.TrimSuffix(p, DeleteMarkerSuffix)
+		if gitnative.ClassifyPath(def) == gitnative.KindOther || !definitionFile(def) {
+			continue
+		}
+		data, err := repo.ReadFile(ctx, commit, p)
+		if err != nil {
+			return nil, err
+		}
+		out[p] = data
+	}
+	return out, nil
+}
+
+func (r *Resolver) build(ctx context.Context, tenant, ref, mainCommit, tenantCommit string) (*Overlay, error) {
+	o, errs, err := buildOverlay(ctx, r.repo, ref, mainCommit, tenantCommit)
+	if err != nil {
+		return nil, err
+	}
+	o.Tenant = tenant
+	errs = append(errs, o.Snapshot.index()...)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: overlay of %s on %s: %v", api.ErrConflict, o.Branch, short(mainCommit),
+			&ReloadError{Commit: tenantCommit, Errors: errs})
+	}
+	return o, nil
+}
+
+// branchSnapshot builds the definitions ref serves at commit. A tenant
+// branch is overlaid on mainCommit, or on main's head when that is "", so
+// files holding only the tenant's overrides validate.
+func branchSnapshot(ctx context.Context, repo *gitnative.Repo, ref, mainCommit, commit string) (*Snapshot, []FileError) {
+	if !strings.HasPrefix(ref, tenantPrefix) {
+		return buildSnapshot(ctx, repo, ref, commit)
+	}
+	if mainCommit == "" {
+		var err error
+		mainCommit, err = gitnative.ResolveRef(repo.GitDir(), MainRef)
+		if err != nil && !errors.Is(err, gitnative.ErrRefNotFound) {
+			return newSnapshot(ref, commit), []FileError{{Path: "/", Message: err.Error()}}
+		}
+	}
+	return overlaySnapshot(ctx, repo, ref, mainCommit, commit)
+}
+
+// overlaySnapshot builds the definitions a tenant branch serves at commit:
+// the branch layered on main at mainCommit. Like buildSnapshot, the result
+// is not indexed yet.
+func overlaySnapshot(ctx context.Context, repo *gitnative.Repo, ref, mainCommit, commit string) (*Snapshot, []FileError) {
+	o, errs, err := buildOverlay(ctx, repo, ref, mainCommit, commit)
+	if err != nil {
+		return newSnapshot(ref, commit), []FileError{{Path: "/", Message: err.Error()}}
+	}
+	return o.Snapshot, errs
+}
+
+// buildOverlay layers the definitions at tenantCommit on those at
+// mainCommit. Files that do not merge or parse are returned as errs; the
+// snapshot is not indexed.
+func buildOverlay(ctx context.Context, repo *gitnative.Repo, ref, mainCommit, tenantCommit string) (o *Overlay, errs []FileError, err error) {
+	o = &Overlay{
+		Branch:       strings.TrimPrefix(ref, "refs/heads/"),
+		MainCommit:   mainCommit,
+		TenantCommit: tenantCommit,
+		Entries:      []api.ProvenanceEntry{},
+	}
+	if tenantCommit != "" && mainCommit != "" {
+		if o.BaseCommit, err = repo.MergeBase(ctx, mainCommit, tenantCommit); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	mainFiles, err := readOverlayFiles(ctx, repo, mainCommit)
+	if err != nil {
+		return nil, nil, err
+	}
+	tenantFiles, err := readOverlayFiles(ctx, repo, tenantCommit)
+	if err != nil {
+		return nil, nil, err
+	}
+	baseFiles, err := readOverlayFiles(ctx, repo, o.BaseCommit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	served := tenantCommit
+	if served == "" {
+		served = mainCommit
+	}
+	o.Snapshot = newSnapshot(ref, served)
+	o.Snapshot.MainCommit = mainCommit
+
+	paths := map[string]bool{}
+	for p := range mainFiles {
+		paths[p] = true
+	}
+	for p := range tenantFiles {
+		if !strings.HasSuffix(p, DeleteMarkerSuffix) {
+			paths[p] = true
+		}
+	}
+
+	for _, p := range sortedKeys(paths) {
+		mainData, inMain := mainFiles[p]
+		tenantData, inTenant := tenantFiles[p]
+		baseData, inBase := baseFiles[p]
+		_, marked := tenantFiles[p+DeleteMarkerSuffix]
+
+		entry := api.ProvenanceEntry{Path: p, Kind: string(gitnative.ClassifyPath(p))}
+		var data []byte
+		switch {
+		case marked, !inTenant && inBase:
+			// Removed on the tenant branch; main's copy is hidden
+			if !inMain {
+				continue
+			}
+			entry.Source, entry.Commit, entry.Name = SourceDeleted, tenantCommit, definitionName(p, mainData)
+		case !inTenant, inBase && string(tenantData) == string(baseData):
+			// Untouched by the tenant, so main's current version applies
+			// (or main's deletion, if it removed the file since the fork)
+			if !inMain {
+				continue
+			}
+			entry.Source, entry.Commit, data = SourceMain, mainCommit, mainData
+		default:
+			var base []byte
+			if inBase {
+				base = baseData
+			}
+			var mainDoc []byte
+			if inMain {
+				mainDoc = mainData
+			}
+			merged, overrides, err := overlayFile(p, mainDoc, base, tenantData)
+			if err != nil {
+				errs = append(errs, asFileErrors(p, err)...)
+				continue
+			}
+			entry.Source, entry.Commit, data = SourceTenant, tenantCommit, merged
+			if overrides != nil {
+				entry.Source, entry.Overrides = SourceMerged, overrides
+			}
+		}
+
+		if data != nil {
+			if err := o.Snapshot.add(p, data); err != nil {
+				errs = append(errs, asFileErrors(p, err)...)
+				continue
+			}
+			entry.Name = definitionName(p, data)
+		}
+		o.Entries = append(o.Entries, entry)
+	}
+	return o, errs, nil
+}
+
+// overlayFile applies a tenant file on top of main's version of the same
+// path. base is the file as it was when the tenant branch forked, so keys
+// the tenant never touched keep following main. mainData is nil when only
+// the tenant has the file; then the tenant's file is used with its merge
+// rules stripped and overrides is nil.
+func overlayFile(p string, mainData, baseData, tenantData []byte) (data []byte, overrides []api.KeyOverride, err error) {
+	tenantDoc, err := decodeDoc(p, tenantData)
+	if err != nil {
+		return nil, nil, err
+	}
+	rules, err := parseOverlayRules(tenantDoc)
+	if err != nil {
+		return nil, nil, err
+	}
+	delete(tenantDoc, overlayKey)
+
+	if mainData == nil || rules.replace {
+		out, err := encodeDoc(p, tenantDoc)
+		return out, nil, err
+	}
+	mainDoc, err := decodeDoc(p, mainData)
+	if err != nil {
+		return nil, nil, err
+	}
+	var baseDoc map[string]interface{}
+	if baseData != nil {
+		if baseDoc, err = decodeDoc(p, baseData); err != nil {
+			return nil, nil, err
+		}
+		delete(baseDoc, overlayKey)
+	}
+
+	overrides = []api.KeyOverride{}
+	merged := mergeDoc(mainDoc, baseDoc, tenantDoc, "", rules.keys, &overrides)
+	out, err := encodeDoc(p, merged)
+	return out, overrides, err
+}
+
+type overlayRules struct {
+	replace bool
+	keys    map[string]string
+}
+
+func parseOverlayRules(doc map[string]interface{}) (overlayRules, error) {
+	rules := overlayRules{keys: map[string]string{}}
+	raw, ok := doc[overlayKey]
+	if !ok || raw == nil {
+		return rules, nil
+	}
+	spec, ok := raw.(map[string]interface{})
+	if !ok {
+		return rules, fmt.Errorf("%s must be a mapping", overlayKey)
+	}
+	switch mode := spec["mode"]; mode {
+	case nil, ruleMerge:
+	case ruleReplace:
+		rules.replace = true
+	default:
+		return rules, fmt.Errorf("%s.mode must be merge or replace, not %v", overlayKey, mode)
+	}
+	if keys, ok := spec["keys"]; ok && keys != nil {
+		km, ok := keys.(map[string]interface{})
+		if !ok {
+			return rules, fmt.Errorf("%s.keys must be a mapping", overlayKey)
+		}
+		for k, v := range km {
+			switch v {
+			case ruleMerge, ruleReplace, ruleAppend:
+				rules.keys[k] = v.(string)
+			default:
+				return rules, fmt.Errorf("%s.keys.%s must be merge, replace or append, not %v", overlayKey, k, v)
+			}
+		}
+	}
+	return rules, nil
+}
+
+// mergeDoc applies the tenant's changes relative to base onto main, recording
+// each one in overrides. Keys whose tenant value equals base come from main.
+func mergeDoc(main, base, tenant map[string]interface{}, prefix string, rules map[string]string, overrides *[]api.KeyOverride) map[string]interface{} {
+	out := make(map[string]interface{}, len(main))
+	for k, v := range main {
+		out[k] = v
+	}
+
+	keys := map[string]bool{}
+	for k := range base {
+		keys[k] = true
+	}
+	for k := range tenant {
+		keys[k] = true
+	}
+	for _, k := range sortedKeys(keys) {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		t, inTenant := tenant[k]
+		b, inBase := base[k]
+		if inTenant && inBase && reflect.DeepEqual(t, b) {
+			continue
+		}
+		if !inTenant || t == nil {
+			if _, ok := out[k]; ok {
+				delete(out, k)
+				*overrides = append(*overrides, api.KeyOverride{Key: key, Action: "delete"})
+			}
+			continue
+		}
+
+		m, inMain := main[k]
+		switch rules[key] {
+		case ruleReplace:
+			out[k] = t
+			*overrides = append(*overrides, api.KeyOverride{Key: key, Action: "set"})
+			continue
+		case ruleAppend:
+			if tl, ok := t.([]interface{}); ok {
+				ml, _ := m.([]interface{})
+				bl, _ := b.([]interface{})
+				out[k] = appendNew(ml, tl, bl)
+				*overrides = append(*overrides, api.KeyOverride{Key: key, Action: "append"})
+				continue
+			}
+		}
+		tm, tenantMap := t.(map[string]interface{})
+		mm, mainMap := m.(map[string]interface{})
+		if inMain && tenantMap && mainMap {
+			bm, _ := b.(map[string]interface{})
+			out[k] = mergeDoc(mm, bm, tm, key, rules, overrides)
+			continue
+		}
+		out[k] = t
+		*overrides = append(*overrides, api.KeyOverride{Key: key, Action: "set"})
+	}
+	return out
+}
+
+// appendNew returns main followed by the tenant items that were not in base
+// and are not already in main
+func appendNew(main, tenant, base []interface{}) []interface{} {
+	out := append([]interface{}(nil), main...)
+	contains := func(list []interface{}, v interface{}) bool {
+		for _, x := range list {
+			if reflect.DeepEqual(x, v) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, v := range tenant {
+		if !contains(base, v) && !contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func decodeDoc(p string, data []byte) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if err := decode(p, data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	return doc, nil
+}
+
+// encodeDoc is the inverse of decode, by file extension
+func encodeDoc(p string, doc map[string]interface{}) ([]byte, error) {
+	if strings.EqualFold(path.Ext(p), ".json") {
+		return json.MarshalIndent(doc, "", "  ")
+	}
+	return yaml.Marshal(doc)
+}
+
+// definitionName returns the name field of a definition, if it has one
+func definitionName(p string, data []byte) string {
+	var named struct {
+		Name string `json:"name" yaml:"name"`
+	}
+	if decode(p, data, &named) != nil {
+		return ""
+	}
+	return named.Name
+}
+
+func short(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
@@ -0,0 +1,344 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+)
+
+// ErrNotLoaded is returned before the first commit has loaded
+var ErrNotLoaded = errors.New("registry not loaded")
+
+// maxRejections bounds the rejected-commit history kept in memory
+const maxRejections = 50
+
+// ReloadError reports why a commit was rejected. The registry keeps serving
+// the previous snapshot.
+type ReloadError struct {
+	Commit string
+	Errors []FileError
+}
+
+func (e *ReloadError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Error()
+	}
+	return fmt.Sprintf("commit %.8s rejected: %s", e.Commit, strings.Join(msgs, "; "))
+}
+
+// Rejection records a commit that failed validation
+type Rejection struct {
+	Ref        string      `json:"ref"`
+	Commit     string      `json:"commit"`
+	Serving    string      `json:"serving"`
+	Errors     []FileError `json:"errors"`
+	RejectedAt time.Time   `json:"rejected_at"`
+}
+
+// ReloadResult describes a successful swap
+type ReloadResult struct {
+	Ref       string             `json:"ref"`
+	OldCommit string             `json:"old_commit"`
+	NewCommit string             `json:"new_commit"`
+	Changes   []gitnative.Change `json:"changes"`
+	Duration  time.Duration      `json:"duration"`
+	// SignedBy is the key that signed NewCommit when signatures are required
+	SignedBy *gitnative.TrustedKey `json:"signed_by,omitempty"`
+}
+
+// Registry serves the definitions of the last good commit on one ref
+type Registry struct {
+	repo *gitnative.Repo
+	ref  string
+
+	current atomic.Pointer[Snapshot]
+	// signing is read by pinned lookups without taking mu, which reloads
+	// hold while listeners run
+	signing atomic.Pointer[SigningPolicy]
+
+	// mu serialises reloads and guards rejections
+	mu         sync.Mutex
+	rejections []Rejection
+	audit      *ReloadLog
+	listeners  []func(ctx context.Context, snap *Snapshot)
+
+	// main is the registry a tenant branch is overlaid on, set by OverlayOn
+	main *Registry
+
+	history history
+}
+
+// New returns an empty registry following ref; call Load before serving
+func New(repo *gitnative.Repo, ref string) *Registry {
+	return &Registry{repo: repo, ref: ref}
+}
+
+// Ref returns the ref the registry follows
+func (r *Registry) Ref() string {
+	return r.ref
+}
+
+// Current returns the snapshot being served, or nil before the first load
+func (r *Registry) Current() *Snapshot {
+	return r.current.Load()
+}
+
+// Loaded counts the tools and workflows being served, for readiness checks
+func (r *Registry) Loaded() int {
+	snap := r.Current()
+	if snap == nil {
+		return 0
+	}
+	return len(snap.Tools) + len(snap.Workflows)
+}
+
+// Rejections returns rejected commits, oldest first
+func (r *Registry) Rejections() []Rejection {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Rejection(nil), r.rejections...)
+}
+
+// RequireSignatures makes the registry refuse commits the policy does not
+// admit, recording them as rejections. Call it before the first Load.
+func (r *Registry) RequireSignatures(p *SigningPolicy) {
+	r.signing.Store(p)
+}
+
+// RequiresSignatures reports whether a SigningPolicy is in force
+func (r *Registry) RequiresSignatures() bool {
+	return r.signing.Load() != nil
+}
+
+// OverlayOn makes a tenant branch's registry serve its files layered on
+// the snapshot main serves, as Resolver does, rebuilding whenever main
+// reloads. Call it before the first Load, once main has loaded.
+func (r *Registry) OverlayOn(main *Registry) {
+	r.main = main
+	main.OnReload(r.rebase)
+}
+
+// rebase rebuilds the served tenant commit on main's new snapshot. A
+// result that does not validate is recorded as a rejection and the
+// previous overlay keeps serving.
+func (r *Registry) rebase(ctx context.Context, mainSnap *Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur := r.current.Load()
+	if cur == nil || cur.MainCommit == mainSnap.Commit {
+		return
+	}
+	start := time.Now()
+	_, err := r.load(ctx, cur.Commit, []gitnative.Change{})
+	r.record(ctx, cur.Commit, cur.Commit, []gitnative.Change{}, start, err)
+	if err != nil {
+		log.Printf("registry: %s not rebased on main %.8s: %v", r.ref, mainSnap.Commit, err)
+	}
+}
+
+// Load parses every definition at commit and swaps it in if all of them
+// validate
+func (r *Registry) Load(ctx context.Context, commit string) (res *ReloadResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, start := r.serving(), time.Now()
+	defer func() { r.record(ctx, old, commit, nil, start, err) }()
+
+	signer, err := r.verify(ctx, commit)
+	if err != nil {
+		return nil, err
+	}
+	if res, err = r.load(ctx, commit, nil); res != nil {
+		res.SignedBy = signer
+	}
+	return res, err
+}
+
+// Apply reloads from a watcher event. Only the changed files are parsed when
+// the event starts at the commit being served; otherwise the whole tree is.
+func (r *Registry) Apply(ctx context.Context, event gitnative.CommitEvent) (res *ReloadResult, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cur := r.current.Load()
+	if cur != nil && cur.Commit == event.NewSHA {
+		// Already applied, e.g. by the API that created the commit
+		return &ReloadResult{Ref: r.ref, OldCommit: cur.Commit, NewCommit: cur.Commit}, nil
+	}
+	old, start := r.serving(), time.Now()
+	defer func() {
+		// The event's changes are relative to the served commit unless
+		// events were missed
+		changes := event.Changes
+		if event.OldSHA != old {
+			changes = nil
+		}
+		r.record(ctx, old, event.NewSHA, changes, start, err)
+	}()
+
+	if event.NewSHA == "" {
+		return nil, r.reject(event.OldSHA, []FileError{{Path: event.Ref, Message: "ref was deleted"}})
+	}
+	signer, err := r.verify(ctx, event.NewSHA)
+	if err != nil {
+		return nil, err
+	}
+	if cur == nil || cur.Commit != event.OldSHA || r.main != nil {
+		// An overlay depends on main's files too, so it is rebuilt whole
+		if res, err = r.load(ctx, event.NewSHA, event.Changes); res != nil {
+			res.SignedBy = signer
+		}
+		return res, err
+	}
+
+	next := cur.clone(event.NewSHA)
+	var errs []FileError
+	for _, c := range event.Changes {
+		if c.OldPath != "" {
+			next.remove(c.OldPath)
+		}
+		next.remove(c.Path)
+		if c.Type == gitnative.ChangeDeleted {
+			continue
+		}
+		if err := next.load(ctx, r.repo, c.Path); err != nil {
+			errs = append(errs, asFileErrors(c.Path, err)...)
+		}
+	}
+	if res, err = r.publish(ctx, next, cur, event.Changes, errs, start); res != nil {
+		res.SignedBy = signer
+	}
+	return res, err
+}
+
+// serving returns the commit being served, or "" before the first load
+func (r *Registry) serving() string {
+	if cur := r.current.Load(); cur != nil {
+		return cur.Commit
+	}
+	return ""
+}
+
+// verify applies the signing policy to commit, trusting the history of the
+// commit being served
+func (r *Registry) verify(ctx context.Context, commit string) (*gitnative.TrustedKey, error) {
+	policy := r.signing.Load()
+	if policy == nil {
+		return nil, nil
+	}
+	signer, err := policy.Verify(ctx, r.ref, r.serving(), commit)
+	if err != nil {
+		return nil, r.reject(commit, []FileError{{Path: SignersPath, Message: err.Error()}})
+	}
+	return signer, nil
+}
+
+// Handler adapts Apply to a Watcher callback, ignoring other refs
+func (r *Registry) Handler(ctx context.Context) func(gitnative.CommitEvent) {
+	return func(event gitnative.CommitEvent) {
+		if event.Ref != r.ref {
+			return
+		}
+		if res, err := r.Apply(ctx, event); err != nil {
+			log.Printf("registry: %v", err)
+		} else {
+			log.Printf("registry: %s now at %.8s (%d changes in %v)", r.ref, res.NewCommit, len(res.Changes), res.Duration)
+		}
+	}
+}
+
+func (r *Registry) load(ctx context.Context, commit string, changes []gitnative.Change) (*ReloadResult, error) {
+	start := time.Now()
+	next, errs := r.build(ctx, commit)
+	return r.publish(ctx, next, r.current.Load(), changes, errs, start)
+}
+
+// build parses every definition in the tree of commit, overlaid on the
+// snapshot main serves for a tenant registry
+func (r *Registry) build(ctx context.Context, commit string) (*Snapshot, []FileError) {
+	if r.main == nil {
+		return buildSnapshot(ctx, r.repo, r.ref, commit)
+	}
+	mainCommit := r.main.serving()
+	if mainCommit == "" {
+		return newSnapshot(r.ref, commit), []FileError{{Path: "/", Message: MainRef + " is not loaded"}}
+	}
+	return overlaySnapshot(ctx, r.repo, r.ref, mainCommit, commit)
+}
+
+func buildSnapshot(ctx context.Context, repo *gitnative.Repo, ref, commit string) (*Snapshot, []FileError) {
+	next := newSnapshot(ref, commit)
+	paths, err := repo.ListFiles(ctx, commit)
+	if err != nil {
+		return next, []FileError{{Path: "/", Message: err.Error()}}
+	}
+
+	var errs []FileError
+	for _, p := range paths {
+		if err := next.load(ctx, repo, p); err != nil {
+			errs = append(errs, asFileErrors(p, err)...)
+		}
+	}
+	return next, errs
+}
+
+// OnReload calls fn with every snapshot swapped in from then on. Listeners
+// run in order with further reloads held back, so each sees snapshots in
+// commit order; fn should not block for long.
+func (r *Registry) OnReload(fn func(ctx context.Context, snap *Snapshot)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// publish swaps next in unless it or its indexes have errors
+func (r *Registry) publish(ctx context.Context, next, prev *Snapshot, changes []gitnative.Change, errs []FileError, start time.Time) (*ReloadResult, error) {
+	errs = append(errs, next.index()...)
+	if len(errs) > 0 {
+		return nil, r.reject(next.Commit, errs)
+	}
+
+	next.LoadedAt = time.Now()
+	r.current.Store(next)
+	for _, fn := range r.listeners {
+		fn(ctx, next)
+	}
+
+	res := &ReloadResult{Ref: r.ref, NewCommit: next.Commit, Changes: changes, Duration: time.Since(start)}
+	if prev != nil {
+		res.OldCommit = prev.Commit
+	}
+	return res, nil
+}
+
+func (r *Registry) reject(commit string, errs []FileError) error {
+	rej := Rejection{Ref: r.ref, Commit: commit, Errors: errs, RejectedAt: time.Now()}
+	if cur := r.current.Load(); cur != nil {
+		rej.Serving = cur.Commit
+	}
+	r.rejections = append(r.rejections, rej)
+	if len(r.rejections) > maxRejections {
+		r.rejections = r.rejections[len(r.rejections)-maxRejections:]
+	}
+	return &ReloadError{Commit: commit, Errors: errs}
+}
+
+func asFileErrors(p string, err error) []FileError {
+	var fes FileErrors
+	if errors.As(err, &fes) {
+		return fes
+	}
+	var fe FileError
+	if errors.As(err, &fe) {
+		return []FileError{fe}
+	}
+	return []FileError{{Path: p, Message: err.Error()}}
+}
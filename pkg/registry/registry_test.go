@@ -0,0 +1,1403 @@
+package registry
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+)
+
+type testRepo struct {
+	t    *testing.T
+	path string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := &testRepo{t: t, path: t.TempDir()}
+	r.git("init", "-q", "-b", "main")
+	return r
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.path
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Volcano Test", "GIT_AUTHOR_EMAIL=test@volcano.local",
+		"GIT_COMMITTER_NAME=Volcano Test", "GIT_COMMITTER_EMAIL=test@volcano.local",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+r.path)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *testRepo) write(path, content string) {
+	r.t.Helper()
+	full := filepath.Join(r.path, path)
+	os.MkdirAll(filepath.Dir(full), 0755)
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *testRepo) commit(msg string) string {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", msg)
+	return r.git("rev-parse", "HEAD")
+}
+
+// event builds the CommitEvent the watcher would emit for old..new
+func (r *testRepo) event(old, new string) gitnative.CommitEvent {
+	r.t.Helper()
+	repo, _ := gitnative.OpenRepo(r.path)
+	changes, err := repo.Diff(context.Background(), old, new)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return gitnative.CommitEvent{Ref: "refs/heads/main", OldSHA: old, NewSHA: new, Changes: changes}
+}
+
+const pipelineV1 = `name: DataPipelineWorkflow
+version: 1.0.0
+max_duration: 1h
+retry_policy:
+  maximum_attempts: 3
+stages:
+  - extract
+  - name: load
+    activities: [LoadToDataWarehouse]
+    timeout: 15m
+`
+
+func newLoadedRegistry(t *testing.T) (*testRepo, *Registry, string) {
+	t.Helper()
+	repo := newTestRepo(t)
+	repo.write("tools/calculator.json", `{"name":"Calculator","version":"1.0","patterns":["add"]}`)
+	repo.write("workflows/data-pipeline.yaml", pipelineV1)
+	repo.write("configs/runtime.yaml", "precision: 2\n")
+	repo.write("README.md", "not a definition")
+	first := repo.commit("initial")
+
+	gr, err := gitnative.OpenRepo(repo.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := New(gr, "refs/heads/main")
+	if _, err := reg.Load(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	return repo, reg, first
+}
+
+func TestApplySwapsValidCommit(t *testing.T) {
+	repo, reg, first := newLoadedRegistry(t)
+	before := reg.Current()
+	var notified []string
+	reg.OnReload(func(ctx context.Context, snap *Snapshot) { notified = append(notified, snap.Commit) })
+
+	repo.write("workflows/data-pipeline.yaml", strings.Replace(pipelineV1, "1.0.0", "1.1.0", 1))
+	repo.write("tools/converter.json", `{"name":"Converter"}`)
+	second := repo.commit("update")
+
+	res, err := reg.Apply(context.Background(), repo.event(first, second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.OldCommit != first || res.NewCommit != second {
+		t.Errorf("result = %+v", res)
+	}
+	if len(notified) != 1 || notified[0] != second {
+		t.Errorf("listeners saw %v", notified)
+	}
+
+	cur := reg.Current()
+	if wf, ok := cur.Workflow("DataPipelineWorkflow"); !ok || wf.Version != "1.1.0" {
+		t.Errorf("workflow = %+v", wf)
+	}
+	if got := strings.Join(cur.ToolNames(), ","); got != "Calculator,Converter" {
+		t.Errorf("tools = %s", got)
+	}
+	if cur.Configs["configs/runtime.yaml"]["precision"] != 2 {
+		t.Errorf("configs = %v", cur.Configs)
+	}
+	// The old snapshot is untouched for requests still holding it
+	if wf, _ := before.Workflow("DataPipelineWorkflow"); wf.Version != "1.0.0" {
+		t.Errorf("previous snapshot mutated: %+v", wf)
+	}
+}
+
+func TestApplyRejectsInvalidCommitAtomically(t *testing.T) {
+	repo, reg, first := newLoadedRegistry(t)
+
+	// One good change and two bad ones: nothing may be applied
+	repo.write("tools/converter.json", `{"name":"Converter"}`)
+	repo.write("tools/calculator.json", `{"name": "Calculator", "version": `)
+	repo.write("workflows/data-pipeline.yaml", strings.Replace(pipelineV1, "1h", "one hour", 1))
+	bad := repo.commit("broken")
+
+	_, err := reg.Apply(context.Background(), repo.event(first, bad))
+	var rerr *ReloadError
+	if !errors.As(err, &rerr) || rerr.Commit != bad || len(rerr.Errors) != 2 {
+		t.Fatalf("err = %v", err)
+	}
+	cur := reg.Current()
+	if cur.Commit != first {
+		t.Errorf("serving %s, want %s", cur.Commit, first)
+	}
+	if _, ok := cur.Tool("Converter"); ok {
+		t.Error("partial change applied")
+	}
+
+	rej := reg.Rejections()
+	if len(rej) != 1 || rej[0].Commit != bad || rej[0].Serving != first {
+		t.Fatalf("rejections = %+v", rej)
+	}
+	if rej[0].Errors[0].Path != "tools/calculator.json" || rej[0].Errors[1].Pointer != "/max_duration" || rej[0].Errors[1].Line != 3 {
+		t.Errorf("errors = %+v", rej[0].Errors)
+	}
+
+	// The fix arrives as a diff from the rejected commit, so the whole
+	// tree is reloaded
+	repo.write("tools/calculator.json", `{"name":"Calculator","version":"1.1"}`)
+	repo.write("workflows/data-pipeline.yaml", pipelineV1)
+	fixed := repo.commit("fix")
+	if _, err := reg.Apply(context.Background(), repo.event(bad, fixed)); err != nil {
+		t.Fatal(err)
+	}
+	if tool, ok := reg.Current().Tool("Converter"); !ok || tool.Name != "Converter" {
+		t.Error("fixed commit not loaded")
+	}
+}
+
+func TestDuplicateNamesRejected(t *testing.T) {
+	repo, reg, first := newLoadedRegistry(t)
+	repo.write("tools/calculator-copy.json", `{"name":"Calculator"}`)
+	second := repo.commit("duplicate")
+
+	_, err := reg.Apply(context.Background(), repo.event(first, second))
+	if err == nil || !strings.Contains(err.Error(), `tool "Calculator" is already defined`) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestStageShorthand(t *testing.T) {
+	yamlWF, err := ParseWorkflow("workflows/a.yaml", []byte(pipelineV1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonWF, err := ParseWorkflow("workflows/a.json", []byte(`{"name":"A","stages":["extract",{"name":"load","parallel":true}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, wf := range []*WorkflowDefinition{yamlWF, jsonWF} {
+		if len(wf.Stages) != 2 || wf.Stages[0].Name != "extract" || wf.Stages[1].Name != "load" {
+			t.Errorf("stages = %+v", wf.Stages)
+		}
+	}
+}
+
+func TestResolveConfigServesHistoricalCommits(t *testing.T) {
+	repo, reg, first := newLoadedRegistry(t)
+	repo.git("tag", "-a", "v1.0.0", "-m", "first release")
+
+	repo.write("workflows/data-pipeline.yaml", strings.Replace(pipelineV1, "1.0.0", "2.0.0", 1))
+	second := repo.commit("v2")
+	if _, err := reg.Apply(context.Background(), repo.event(first, second)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for ref, want := range map[string]string{
+		"":                 second,
+		"main":             second,
+		"v1.0.0":           first,
+		first[:10]:         first,
+		"refs/tags/v1.0.0": first,
+	} {
+		got, err := reg.ResolveConfig(ctx, ref)
+		if err != nil || got != want {
+			t.Errorf("ResolveConfig(%q) = %.8s, %v; want %.8s", ref, got, err, want)
+		}
+	}
+
+	if _, err := reg.ResolveConfig(ctx, "no-such-branch"); !errors.Is(err, api.ErrInvalidArgument) {
+		t.Errorf("unknown ref: %v", err)
+	}
+
+	snap, err := reg.SnapshotFor(api.WithConfigCommit(ctx, first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wf, _ := snap.Workflow("DataPipelineWorkflow"); wf.Version != "1.0.0" {
+		t.Errorf("historical workflow = %+v", wf)
+	}
+	if again, _ := reg.At(ctx, first); again != snap {
+		t.Error("historical snapshot not cached")
+	}
+	if cur, _ := reg.SnapshotFor(ctx); cur.Commit != second {
+		t.Errorf("unpinned snapshot at %.8s", cur.Commit)
+	}
+}
+
+func TestResolveConfigStaysOnOwnBranch(t *testing.T) {
+	repo, _, first := newLoadedRegistry(t)
+	repo.git("checkout", "-q", "-b", "customer/acme-corp")
+	repo.write("tools/acme-po-validator.json", `{"name":"ACMEPOValidator"}`)
+	acme := repo.commit("ACME validator")
+	repo.git("checkout", "-q", "-b", "customer/globex-inc", first)
+	repo.write("tools/globex-report.json", `{"name":"GlobexReport"}`)
+	globex := repo.commit("Globex report")
+	repo.git("checkout", "-q", "main")
+	repo.write("configs/runtime.yaml", "precision: 3\n")
+	later := repo.commit("main after the tenants forked")
+
+	gr, _ := gitnative.OpenRepo(repo.path)
+	reg := New(gr, tenantPrefix+"acme-corp")
+	ctx := context.Background()
+	if _, err := reg.Load(ctx, acme); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tenant's own history, including main's up to the fork, resolves
+	for ref, want := range map[string]string{"customer/acme-corp": acme, first: first} {
+		if got, err := reg.ResolveConfig(ctx, ref); err != nil || got != want {
+			t.Errorf("ResolveConfig(%q) = %.8s, %v; want %.8s", ref, got, err, want)
+		}
+	}
+	// Another tenant's branch, by name or commit, and main's later commits
+	// do not
+	for _, ref := range []string{"customer/globex-inc", globex, "main", later} {
+		if got, err := reg.ResolveConfig(ctx, ref); !errors.Is(err, api.ErrInvalidArgument) {
+			t.Errorf("ResolveConfig(%q) = %.8s, %v", ref, got, err)
+		}
+	}
+}
+
+func TestWriterCommitsThroughAPI(t *testing.T) {
+	repo, reg, first := newLoadedRegistry(t)
+	gr, _ := gitnative.OpenRepo(repo.path)
+	writer := NewWriter(gr, func(ref string) *Registry {
+		if ref == reg.Ref() {
+			return reg
+		}
+		return nil
+	})
+	tokens := api.StaticTokens{
+		"ada-token":  {Subject: "Ada Ops", Email: "ada@example.com"},
+		"acme-token": {Subject: "Ann Acme", Email: "ann@acme.example", Tenant: "acme-corp"},
+		"anon-token": {Subject: "Nobody"},
+	}
+	srv := api.NewServer(api.Services{ConfigWriter: writer, Authenticator: tokens})
+
+	// The branch and author come from the token, never the request
+	call := func(method, path, token, body string) (int, api.ConfigWriteResponse, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		var resp api.ConfigWriteResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp, rec.Body.String()
+	}
+	const author = `"author":{"name":"Mallory","email":"mallory@example.com"}`
+
+	// Unauthenticated writes, principals without an email and a tenant
+	// header naming another branch are all refused
+	for _, tt := range []struct {
+		token, tenant string
+		status        int
+	}{
+		{"", "", 401},
+		{"wrong", "", 401},
+		{"anon-token", "", 403},
+		{"acme-token", "globex-inc", 403},
+		{"ada-token", "acme-corp", 403},
+	} {
+		req := httptest.NewRequest("PUT", "/api/v1/config/tools/converter.json",
+			strings.NewReader(`{"content":"{\"name\":\"Converter\"}","message":"m"}`))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		if tt.tenant != "" {
+			req.Header.Set("X-Tenant-ID", tt.tenant)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("token %q tenant %q: %d %s, want %d", tt.token, tt.tenant, rec.Code, rec.Body, tt.status)
+		}
+	}
+	if head := repo.git("rev-parse", "main"); head != first {
+		t.Fatalf("main moved to %s", head)
+	}
+
+	code, resp, body := call("PUT", "/api/v1/config/tools/converter.json", "ada-token",
+		`{"content":"{\"name\":\"Converter\"}","message":"Add converter",`+author+`,"expected_parent":"`+first+`"}`)
+	if code != 200 || !resp.Changed || !resp.Reloaded || resp.Parent != first {
+		t.Fatalf("put: %d %s", code, body)
+	}
+	second := resp.Commit
+	if got := repo.git("log", "-1", "--format=%H|%an|%ae|%s"); got != second+"|Ada Ops|ada@example.com|Add converter" {
+		t.Errorf("head commit = %s", got)
+	}
+	repo.git("fsck", "--strict", "--no-dangling")
+	if _, ok := reg.Current().Tool("Converter"); !ok || reg.Current().Commit != second {
+		t.Error("registry not reloaded")
+	}
+
+	// Stale parent, invalid content and an invalid resulting tree are all
+	// refused without moving the branch
+	for _, tt := range []struct {
+		path, body string
+		status     int
+	}{
+		{"tools/converter.json", `{"content":"{\"name\":\"Converter2\"}","message":"m",` + author + `,"expected_parent":"` + first + `"}`, 409},
+		{"tools/broken.json", `{"content":"{\"name\":","message":"m",` + author + `}`, 400},
+		{"tools/copy.json", `{"content":"{\"name\":\"Calculator\"}","message":"m",` + author + `}`, 400},
+		{"README.md", `{"content":"hi","message":"m",` + author + `}`, 400},
+		{"tools/x.json", `{"content":"{\"name\":\"X\"}",` + author + `}`, 400},
+	} {
+		if code, _, body := call("PUT", "/api/v1/config/"+tt.path, "ada-token", tt.body); code != tt.status {
+			t.Errorf("PUT %s: %d %s, want %d", tt.path, code, body, tt.status)
+		}
+	}
+	if head := repo.git("rev-parse", "main"); head != second {
+		t.Fatalf("main moved to %s", head)
+	}
+
+	code, resp, body = call("DELETE", "/api/v1/config/workflows/data-pipeline.yaml", "ada-token", `{"message":"Retire pipeline",`+author+`}`)
+	if code != 200 || !resp.Changed {
+		t.Fatalf("delete: %d %s", code, body)
+	}
+	if _, ok := reg.Current().Workflow("DataPipelineWorkflow"); ok {
+		t.Error("deleted workflow still served")
+	}
+	if code, _, _ := call("DELETE", "/api/v1/config/workflows/data-pipeline.yaml", "ada-token", `{"message":"again",`+author+`}`); code != 404 {
+		t.Errorf("second delete: %d", code)
+	}
+
+	// A tenant's first write branches off main and leaves main alone
+	mainHead := repo.git("rev-parse", "main")
+	code, resp, body = call("PUT", "/api/v1/config/tools/acme-po-validator.json", "acme-token",
+		`{"content":"{\"name\":\"ACMEPOValidator\"}","message":"Add ACME PO validation rules",`+author+`}`)
+	if code != 200 || resp.Branch != "customer/acme-corp" || resp.Parent != mainHead || resp.Reloaded {
+		t.Fatalf("tenant put: %d %s", code, body)
+	}
+	if repo.git("rev-parse", "customer/acme-corp") != resp.Commit || repo.git("rev-parse", "main") != mainHead {
+		t.Error("tenant write landed on the wrong branch")
+	}
+	if got := repo.git("log", "-1", "--format=%an|%ae", "customer/acme-corp"); got != "Ann Acme|ann@acme.example" {
+		t.Errorf("tenant commit author = %s", got)
+	}
+	repo.git("fsck", "--strict", "--no-dangling")
+}
+
+func TestTenantOverlay(t *testing.T) {
+	repo, _, _ := newLoadedRegistry(t)
+	repo.write("tools/legacy.json", `{"name":"Legacy"}`)
+	repo.commit("add legacy")
+
+	repo.git("checkout", "-q", "-b", "customer/acme-corp")
+	repo.write("configs/runtime.yaml", "precision: 4\n")
+	repo.write("tools/acme-po-validator.json", `{"name":"ACMEPOValidator","rules":["max_amount: 1000000"]}`)
+	repo.write("workflows/data-pipeline.yaml", pipelineV1+"  - acme_audit\nx-overlay:\n  keys:\n    stages: append\n")
+	repo.git("rm", "-q", "tools/legacy.json")
+	repo.commit("ACME customisations")
+
+	repo.git("checkout", "-q", "main")
+	repo.write("configs/runtime.yaml", "precision: 2\nmode: fast\n")
+	repo.write("workflows/data-pipeline.yaml", strings.Replace(pipelineV1, "  - extract\n", "  - extract\n  - transform\n", 1))
+	repo.write("tools/converter.json", `{"name":"Converter"}`)
+	repo.commit("core update")
+
+	gr, _ := gitnative.OpenRepo(repo.path)
+	resolver := NewResolver(gr)
+	srv := api.NewServer(api.Services{Provenance: resolver})
+	provenance := func(tenant string) api.ProvenanceResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/tenants/"+tenant+"/provenance", nil))
+		if rec.Code != 200 {
+			t.Fatalf("provenance %s: %d %s", tenant, rec.Code, rec.Body)
+		}
+		var resp api.ProvenanceResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp
+	}
+	sources := func(resp api.ProvenanceResponse) map[string]string {
+		out := map[string]string{}
+		for _, e := range resp.Entries {
+			out[e.Path] = e.Source
+			if len(e.Overrides) > 0 {
+				var keys []string
+				for _, o := range e.Overrides {
+					keys = append(keys, o.Action+":"+o.Key)
+				}
+				out[e.Path] += " " + strings.Join(keys, ",")
+			}
+		}
+		return out
+	}
+
+	resp := provenance("acme-corp")
+	if resp.BaseCommit == "" || resp.TenantCommit == "" || resp.Branch != "customer/acme-corp" {
+		t.Errorf("commits = %+v", resp)
+	}
+	want := map[string]string{
+		"configs/runtime.yaml":         "merged set:precision",
+		"tools/acme-po-validator.json": "tenant",
+		"tools/calculator.json":        "main",
+		"tools/converter.json":         "main",
+		"tools/legacy.json":            "deleted",
+		"workflows/data-pipeline.yaml": "merged append:stages",
+	}
+	if got := sources(resp); !reflect.DeepEqual(got, want) {
+		t.Errorf("sources = %v\nwant %v", got, want)
+	}
+
+	o, err := resolver.Resolve(context.Background(), "acme-corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := o.Snapshot.Configs["configs/runtime.yaml"]
+	if cfg["precision"] != 4 || cfg["mode"] != "fast" {
+		t.Errorf("config = %v", cfg)
+	}
+	wf, _ := o.Snapshot.Workflow("DataPipelineWorkflow")
+	var stages []string
+	for _, s := range wf.Stages {
+		stages = append(stages, s.Name)
+	}
+	if got := strings.Join(stages, ","); got != "extract,transform,load,acme_audit" {
+		t.Errorf("stages = %s", got)
+	}
+	if got := strings.Join(o.Snapshot.ToolNames(), ","); got != "ACMEPOValidator,Calculator,Converter" {
+		t.Errorf("tools = %s", got)
+	}
+
+	// A delete marker hides a file the tenant never had
+	repo.git("checkout", "-q", "customer/acme-corp")
+	repo.write("tools/converter.json.deleted", "")
+	repo.commit("hide converter")
+	if got := sources(provenance("acme-corp"))["tools/converter.json"]; got != "deleted" {
+		t.Errorf("converter = %s", got)
+	}
+
+	// The tenant's registry serves the same overlay, including files that
+	// hold only the tenant's keys
+	mainReg := New(gr, MainRef)
+	if _, err := mainReg.Load(context.Background(), repo.git("rev-parse", "main")); err != nil {
+		t.Fatal(err)
+	}
+	tenantReg := New(gr, "refs/heads/customer/acme-corp")
+	tenantReg.OverlayOn(mainReg)
+	hidden := repo.git("rev-parse", "HEAD")
+	if _, err := tenantReg.Load(context.Background(), hidden); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(tenantReg.Current().ToolNames(), ","); got != "ACMEPOValidator,Calculator" {
+		t.Errorf("tenant registry tools = %s", got)
+	}
+	repo.git("rm", "-q", "tools/converter.json.deleted")
+	repo.write("tools/converter.json", `{"patterns":["acme"]}`)
+	partial := repo.commit("tune converter")
+	if _, err := tenantReg.Apply(context.Background(), repo.event(hidden, partial)); err != nil {
+		t.Fatal(err)
+	}
+	if tool, ok := tenantReg.Current().Tool("Converter"); !ok || !reflect.DeepEqual(tool.Patterns, []string{"acme"}) {
+		t.Errorf("converter = %+v", tool)
+	}
+
+	// A tenant without a branch sees main as is
+	for path, source := range sources(provenance("globex-inc")) {
+		if source != "main" {
+			t.Errorf("globex %s = %s", path, source)
+		}
+	}
+}
+
+func TestDriftReportAndMerge(t *testing.T) {
+	repo, _, _ := newLoadedRegistry(t)
+	repo.git("branch", "customer/globex-inc")
+	repo.git("checkout", "-q", "-b", "customer/acme-corp")
+	repo.write("configs/runtime.yaml", "precision: 4\n")
+	repo.write("tools/acme-po-validator.json", `{"name":"ACMEPOValidator"}`)
+	repo.commit("ACME overrides")
+	repo.git("checkout", "-q", "customer/globex-inc")
+	repo.write("tools/calculator.json", `{"name":"Calculator","version":"1.0-globex"}`)
+	repo.commit("Globex calculator")
+
+	repo.git("checkout", "-q", "main")
+	repo.write("tools/calculator.json", `{"name":"Calculator","version":"1.1","patterns":["add"]}`)
+	repo.write("workflows/data-pipeline.yaml", strings.Replace(pipelineV1, "1.0.0", "1.0.1", 1))
+	repo.commit("Fix calculator")
+	repo.write("README.md", "updated")
+	mainHead := repo.commit("Docs")
+
+	gr, _ := gitnative.OpenRepo(repo.path)
+	drift := NewDrift(gr, nil)
+	files := func(td api.TenantDrift) string {
+		var out []string
+		for _, f := range td.Files {
+			out = append(out, f.Status+" "+f.Path)
+		}
+		return strings.Join(out, ", ")
+	}
+
+	report, err := drift.Drift(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Tenants) != 2 || report.MainCommit != mainHead {
+		t.Fatalf("report = %+v", report)
+	}
+	acme, globex := report.Tenants[0], report.Tenants[1]
+	if got := files(acme); got != "overridden configs/runtime.yaml, behind tools/calculator.json, behind workflows/data-pipeline.yaml" {
+		t.Errorf("acme files = %s", got)
+	}
+	if !acme.Mergeable || acme.CommitsBehind != 2 {
+		t.Errorf("acme = %+v", acme)
+	}
+	if got := files(globex); got != "conflict tools/calculator.json, behind workflows/data-pipeline.yaml" {
+		t.Errorf("globex files = %s", got)
+	}
+	if globex.Mergeable || globex.Conflicts != 1 {
+		t.Errorf("globex = %+v", globex)
+	}
+
+	// Tenants may only merge into their own branch; main's maintainers may
+	// merge into any
+	srv := api.NewServer(api.Services{Drift: drift, Authenticator: api.StaticTokens{
+		"ops-token":  {Subject: "Ops Bot", Email: "ops@example.com"},
+		"acme-token": {Subject: "Ann Acme", Email: "ann@acme.example", Tenant: "acme-corp"},
+	}})
+	for token, status := range map[string]int{"": 401, "acme-token": 403, "ops-token": 409} {
+		req := httptest.NewRequest("POST", "/api/v1/tenants/globex-inc/merge", strings.NewReader(`{}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Errorf("globex merge with %q: %d %s, want %d", token, rec.Code, rec.Body, status)
+		}
+	}
+
+	// The runtime cannot sign a merge into a branch that requires signatures
+	signed := New(gr, tenantPrefix+"acme-corp")
+	signed.RequireSignatures(NewSigningPolicy(gr))
+	guarded := NewDrift(gr, func(ref string) *Registry {
+		if ref == signed.Ref() {
+			return signed
+		}
+		return nil
+	})
+	acmeHead := repo.git("rev-parse", "customer/acme-corp")
+	if _, err := guarded.MergeMain(context.Background(), "acme-corp", api.MergeMainRequest{Author: api.CommitAuthor{Name: "Ops Bot", Email: "ops@example.com"}}); !errors.Is(err, api.ErrConflict) {
+		t.Errorf("merge into signed branch: %v", err)
+	}
+	if gm, gs, err := guarded.AutoMerge(context.Background(), api.CommitAuthor{Name: "Ops Bot", Email: "ops@example.com"}); err != nil || len(gm) != 0 || gs["acme-corp"] == nil {
+		t.Errorf("guarded auto-merge: merged = %+v, skipped = %v, err = %v", gm, gs, err)
+	}
+	if repo.git("rev-parse", "customer/acme-corp") != acmeHead {
+		t.Error("signed branch was moved")
+	}
+
+	merged, skipped, err := drift.AutoMerge(context.Background(), api.CommitAuthor{Name: "Ops Bot", Email: "ops@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 1 || merged[0].Tenant != "acme-corp" || len(skipped) != 1 || skipped["globex-inc"] == nil {
+		t.Fatalf("merged = %+v, skipped = %v", merged, skipped)
+	}
+	if got := strings.Join(merged[0].Updated, ","); got != "README.md,tools/calculator.json,workflows/data-pipeline.yaml" {
+		t.Errorf("updated = %s", got)
+	}
+	repo.git("fsck", "--strict", "--no-dangling")
+	repo.git("merge-base", "--is-ancestor", "main", "customer/acme-corp")
+	if got := repo.git("show", "customer/acme-corp:configs/runtime.yaml"); got != "precision: 4" {
+		t.Errorf("override lost: %s", got)
+	}
+	if got := repo.git("show", "customer/acme-corp:tools/calculator.json"); !strings.Contains(got, "1.1") {
+		t.Errorf("fix not merged: %s", got)
+	}
+
+	after, _ := drift.Drift(context.Background(), "acme-corp")
+	if td := after.Tenants[0]; td.CommitsBehind != 0 || files(td) != "overridden configs/runtime.yaml" {
+		t.Errorf("after merge = %+v", td)
+	}
+}
+
+func TestSyncFastForwardsFromRemote(t *testing.T) {
+	upstream, _, first := newLoadedRegistry(t)
+	runtime := &testRepo{t: t, path: t.TempDir()}
+	runtime.git("clone", "-q", upstream.path, ".")
+
+	gr, err := gitnative.OpenRepo(runtime.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := New(gr, MainRef)
+	if _, err := reg.Load(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	syncer := NewSyncer(gr, SyncOptions{Remote: gitnative.Remote{URL: upstream.path}}, func(ref string) *Registry {
+		if ref == MainRef {
+			return reg
+		}
+		return nil
+	})
+	branch := func(resp *api.SyncResponse) api.BranchSync {
+		t.Helper()
+		if len(resp.Branches) != 1 {
+			t.Fatalf("branches = %+v", resp.Branches)
+		}
+		return resp.Branches[0]
+	}
+
+	upstream.write("tools/converter.json", `{"name":"Converter"}`)
+	second := upstream.commit("Add converter")
+	resp, err := syncer.Sync(context.Background(), "api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := branch(resp); b.Status != api.SyncUpdated || b.OldCommit != first || b.NewCommit != second || !b.Reloaded {
+		t.Fatalf("sync = %+v", b)
+	}
+	if _, ok := reg.Current().Tool("Converter"); !ok || runtime.git("rev-parse", "main") != second {
+		t.Error("runtime not at the remote commit")
+	}
+	runtime.git("fsck", "--strict", "--no-dangling")
+
+	// A commit that does not load is fetched but not applied
+	upstream.write("tools/copy.json", `{"name":"Calculator"}`)
+	upstream.commit("Duplicate calculator")
+	resp, _ = syncer.Sync(context.Background(), "api", nil)
+	if b := branch(resp); b.Status != api.SyncRejected || b.Error == "" {
+		t.Errorf("invalid commit = %+v", b)
+	}
+	if runtime.git("rev-parse", "main") != second || reg.Current().Commit != second {
+		t.Error("invalid commit applied")
+	}
+	upstream.git("rm", "-q", "tools/copy.json")
+	third := upstream.commit("Remove duplicate")
+
+	// Push webhooks must be signed; pushes to other branches are no-ops
+	srv := api.NewServer(api.Services{Sync: syncer, WebhookSecret: "s3cret", Authenticator: api.StaticTokens{
+		"acme-token": {Subject: "Ann Acme", Tenant: "acme-corp"},
+	}})
+	// Only main's maintainers can sync through the API
+	for token, status := range map[string]int{"": 401, "acme-token": 403} {
+		req := httptest.NewRequest("POST", "/api/v1/sync", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != status {
+			t.Errorf("sync with %q: %d %s, want %d", token, rec.Code, rec.Body, status)
+		}
+	}
+	hook := func(payload, secret string) (int, api.SyncResponse) {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(payload))
+		req := httptest.NewRequest("POST", "/api/v1/webhooks/git", strings.NewReader(payload))
+		req.Header.Set("X-GitHub-Event", "push")
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		var resp api.SyncResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+	push := `{"ref":"refs/heads/main","after":"` + third + `"}`
+	if code, _ := hook(push, "wrong"); code != 401 {
+		t.Errorf("bad signature: %d", code)
+	}
+	if code, resp := hook(`{"ref":"refs/heads/feature"}`, "s3cret"); code != 200 || len(resp.Branches) != 0 {
+		t.Errorf("unrelated branch: %d %+v", code, resp)
+	}
+	code, resp2 := hook(push, "s3cret")
+	if code != 200 || resp2.Trigger != "webhook" {
+		t.Fatalf("webhook: %d %+v", code, resp2)
+	}
+	if b := branch(&resp2); b.Status != api.SyncUpdated || b.NewCommit != third {
+		t.Errorf("webhook sync = %+v", b)
+	}
+
+	// Local commits the remote lacks are never discarded
+	runtime.write("configs/local.yaml", "x: 1\n")
+	local := runtime.commit("Local hotfix")
+	upstream.write("configs/runtime.yaml", "precision: 3\n")
+	upstream.commit("Precision 3")
+	resp, _ = syncer.Sync(context.Background(), "schedule", nil)
+	if b := branch(resp); b.Status != api.SyncDiverged {
+		t.Errorf("diverged = %+v", b)
+	}
+	if runtime.git("rev-parse", "main") != local {
+		t.Error("diverged branch was moved")
+	}
+}
+
+func TestSigningPolicy(t *testing.T) {
+	repo := newTestRepo(t)
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	sshKey := func() (key, pub string) {
+		key = filepath.Join(t.TempDir(), "id")
+		if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", key).CombinedOutput(); err != nil {
+			t.Fatalf("ssh-keygen: %v\n%s", err, out)
+		}
+		b, _ := os.ReadFile(key + ".pub")
+		return key, strings.TrimSpace(string(b))
+	}
+	ada, adaPub := sshKey()
+	bob, bobPub := sshKey()
+	signedCommit := func(key, msg string) string {
+		repo.git("add", "-A")
+		repo.git("-c", "gpg.format=ssh", "-c", "user.signingkey="+key, "commit", "-S", "-q", "-m", msg)
+		return repo.git("rev-parse", "HEAD")
+	}
+	signers := func(pubs ...string) string {
+		out := "signers:\n"
+		for i, p := range pubs {
+			out += "  - name: signer-" + string(rune('a'+i)) + "\n    ssh: " + p + "\n"
+		}
+		return out
+	}
+
+	repo.write(SignersPath, signers(adaPub))
+	repo.write("tools/calculator.json", `{"name":"Calculator"}`)
+	first := signedCommit(ada, "initial")
+
+	gr, _ := gitnative.OpenRepo(repo.path)
+	reg := New(gr, MainRef)
+	policy := NewSigningPolicy(gr)
+	var alerts []SignatureViolation
+	policy.Alert = func(v SignatureViolation) { alerts = append(alerts, v) }
+	reg.RequireSignatures(policy)
+	res, err := reg.Load(context.Background(), first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SignedBy == nil || res.SignedBy.Name != "signer-a" {
+		t.Fatalf("signed by %+v", res.SignedBy)
+	}
+
+	refused := func(commit string, want error) {
+		t.Helper()
+		_, err := reg.Apply(context.Background(), repo.event(first, commit))
+		var re *ReloadError
+		if !errors.As(err, &re) || re.Errors[0].Path != SignersPath || !strings.Contains(re.Errors[0].Message, want.Error()) {
+			t.Errorf("apply %.8s: %v, want %v", commit, err, want)
+		}
+		if reg.Current().Commit != first {
+			t.Errorf("refused commit %.8s is being served", commit)
+		}
+		repo.git("reset", "-q", "--hard", first)
+	}
+
+	// Unsigned commits and keys that add themselves to the allowlist
+	repo.write("tools/converter.json", `{"name":"Converter"}`)
+	unsigned := repo.commit("unsigned")
+	refused(unsigned, gitnative.ErrUnsigned)
+	repo.write(SignersPath, signers(adaPub, bobPub))
+	refused(signedCommit(bob, "let me in"), gitnative.ErrUnknownSigner)
+	if len(alerts) != 2 || alerts[1].Offender == "" || len(reg.Rejections()) != 2 {
+		t.Errorf("alerts = %+v", alerts)
+	}
+
+	// A trusted key can add another, which may then sign
+	repo.write(SignersPath, signers(adaPub, bobPub))
+	signedCommit(ada, "Trust Bob")
+	repo.write("tools/converter.json", `{"name":"Converter"}`)
+	third := signedCommit(bob, "Add converter")
+	res, err = reg.Apply(context.Background(), repo.event(first, third))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.SignedBy.Name != "signer-b" || reg.Current().Commit != third {
+		t.Errorf("result = %+v", res)
+	}
+
+	// Pinning a request to a commit cannot sidestep the policy, even once
+	// the branch has been pushed to it, while the served commit's history
+	// stays available
+	repo.git("update-ref", "refs/heads/main", unsigned)
+	if _, err := reg.ResolveConfig(context.Background(), unsigned); !errors.Is(err, api.ErrInvalidArgument) ||
+		!strings.Contains(err.Error(), gitnative.ErrUnsigned.Error()) {
+		t.Errorf("ResolveConfig(unsigned) = %v", err)
+	}
+	repo.git("update-ref", "refs/heads/main", third)
+	if _, err := reg.At(context.Background(), unsigned); err == nil {
+		t.Error("At served an unsigned commit")
+	}
+	if got, err := reg.ResolveConfig(context.Background(), first); err != nil || got != first {
+		t.Errorf("ResolveConfig(first) = %.8s, %v", got, err)
+	}
+
+	// A key dropped from the allowlist cannot sign on top of the history
+	// from before it was dropped
+	repo.write(SignersPath, signers(adaPub))
+	revoked := signedCommit(ada, "Drop Bob")
+	if _, err := reg.Apply(context.Background(), repo.event(third, revoked)); err != nil {
+		t.Fatal(err)
+	}
+	repo.git("checkout", "-q", third)
+	repo.write("tools/sneaky.json", `{"name":"Sneaky"}`)
+	forked := signedCommit(bob, "Sneak a tool in")
+	repo.git("checkout", "-q", "main")
+	if _, err := reg.Load(context.Background(), forked); err == nil || reg.Current().Commit != revoked {
+		t.Errorf("Load(forked) = %v, serving %.8s", err, reg.Current().Commit)
+	}
+	if _, err := reg.ResolveConfig(context.Background(), forked); !errors.Is(err, api.ErrInvalidArgument) ||
+		!strings.Contains(err.Error(), "does not name a commit on") {
+		t.Errorf("ResolveConfig(forked) = %v", err)
+	}
+	repo.git("update-ref", "refs/heads/main", forked)
+	if _, err := reg.ResolveConfig(context.Background(), forked); !errors.Is(err, api.ErrInvalidArgument) ||
+		!strings.Contains(err.Error(), "does not descend") {
+		t.Errorf("ResolveConfig(forked) on main = %v", err)
+	}
+	repo.git("update-ref", "refs/heads/main", revoked)
+
+	// The runtime cannot sign, so API writes are refused
+	srv := api.NewServer(api.Services{
+		ConfigWriter:  NewWriter(gr, func(string) *Registry { return reg }),
+		Authenticator: api.StaticTokens{"a-token": {Subject: "A", Email: "a@example.com"}},
+	})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/api/v1/config/tools/x.json", strings.NewReader(
+		`{"content":"{\"name\":\"X\"}","message":"m"}`))
+	req.Header.Set("Authorization", "Bearer a-token")
+	srv.ServeHTTP(rec, req)
+	if rec.Code != 409 {
+		t.Errorf("write: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestReloadAuditLog(t *testing.T) {
+	repo, reg, first := newLoadedRegistry(t)
+	file := filepath.Join(t.TempDir(), "reloads.jsonl")
+	audit, err := OpenReloadLog(file, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.LogReloads(audit)
+
+	repo.write("tools/converter.json", `{"name":"Converter"}`)
+	good := repo.commit("Add converter\n\nDetails.")
+	if _, err := reg.Apply(context.Background(), repo.event(first, good)); err != nil {
+		t.Fatal(err)
+	}
+	repo.write("tools/calculator.json", `{"name": `)
+	bad := repo.commit("Break calculator")
+	reg.Apply(context.Background(), repo.event(good, bad))
+
+	repo.git("checkout", "-q", "-b", "customer/acme-corp", good)
+	gr, _ := gitnative.OpenRepo(repo.path)
+	tenantReg := New(gr, "refs/heads/customer/acme-corp")
+	tenantReg.LogReloads(audit)
+	if _, err := tenantReg.Load(context.Background(), good); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := api.NewServer(api.Services{Reloads: audit})
+	query := func(q string) (int, api.ReloadsResponse) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/reloads?"+q, nil))
+		var resp api.ReloadsResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec.Code, resp
+	}
+
+	code, all := query("")
+	if code != 200 || all.Total != 3 || all.Reloads[0].Tenant != "acme-corp" {
+		t.Fatalf("all: %d %+v", code, all)
+	}
+	applied := all.Reloads[2]
+	if applied.OldCommit != first || applied.NewCommit != good || applied.Outcome != api.ReloadApplied ||
+		applied.Author.Email != "test@volcano.local" || applied.Message != "Add converter" ||
+		len(applied.Files) != 1 || applied.Files[0].Change != "added" {
+		t.Errorf("applied = %+v", applied)
+	}
+
+	_, rejected := query("outcome=rejected&branch=main")
+	if rejected.Total != 1 || rejected.Reloads[0].NewCommit != bad || rejected.Reloads[0].Errors[0].Path != "tools/calculator.json" {
+		t.Errorf("rejected = %+v", rejected)
+	}
+	for q, want := range map[string]int{
+		"file=tools/calculator.json": 2, // the tenant's first load lists every file
+		"file=tools/converter.json":  2,
+		"file=configs/runtime.yaml":  1,
+		"tenant=acme-corp":           1,
+		"since=2999-01-01T00:00:00Z": 0,
+		"until=2000-01-01T00:00:00Z": 0,
+		"since=2000-01-01T00:00:00Z": 3,
+	} {
+		if code, resp := query(q); code != 200 || resp.Total != want {
+			t.Errorf("%s: %d total %d, want %d", q, code, resp.Total, want)
+		}
+	}
+	if _, resp := query("limit=1"); len(resp.Reloads) != 1 || resp.Total != 3 {
+		t.Errorf("limit: %+v", resp)
+	}
+	for _, q := range []string{"since=yesterday", "limit=x", "limit=5000", "outcome=maybe"} {
+		if code, _ := query(q); code != 400 {
+			t.Errorf("%s: %d, want 400", q, code)
+		}
+	}
+
+	// The log survives a restart and keeps numbering
+	audit.Close()
+	reopened, err := OpenReloadLog(file, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	resp, _ := reopened.Reloads(context.Background(), api.ReloadFilter{})
+	if resp.Total != 2 || resp.Reloads[0].ID != 3 {
+		t.Fatalf("reopened = %+v", resp)
+	}
+	rec, _ := reopened.Record(api.ReloadRecord{NewCommit: good})
+	if rec.ID != 4 {
+		t.Errorf("next id = %d", rec.ID)
+	}
+}
+
+func TestValidateWorkflowDefinitions(t *testing.T) {
+	files := map[string][]byte{
+		"configs/catalog.yaml": []byte("task_queues: [pipelines]\nactivities: [Extract, Load]\n"),
+		"workflows/ok.yaml": []byte(`name: ok
+task_queue: pipelines
+max_duration: 1h
+x-owner: data-team
+stages:
+  - extract
+  - name: load
+    activities: [Extract, Load]
+    timeout: 30m
+`),
+		"workflows/bad.json": []byte(`{
+  "name": "bad",
+  "task_queue": "nowhere",
+  "max_duraton": "1h",
+  "retry_policy": {"maximum_attempts": 500, "initial_interval": "10s", "maximum_interval": "1s"},
+  "stages": [
+    {"name": "a", "timeout": "soon", "parallel": "yes"},
+    {"name": "a", "activities": ["Extract", "Transform"]}
+  ]
+}`),
+		"workflows/broken.yaml": []byte("name: broken\nstages: [a\n"),
+		"README.md":             []byte("not a definition"),
+	}
+
+	type want struct {
+		path, pointer string
+		line          int
+		message       string
+	}
+	wants := []want{
+		{"workflows/bad.json", "/max_duraton", 4, `did you mean "max_duration"`},
+		{"workflows/bad.json", "/retry_policy/maximum_attempts", 5, "between 0 and 100"},
+		{"workflows/bad.json", "/retry_policy/maximum_interval", 5, "shorter than initial_interval"},
+		{"workflows/bad.json", "/stages/0/parallel", 7, "true or false"},
+		{"workflows/bad.json", "/stages/0/timeout", 7, `invalid duration "soon"`},
+		{"workflows/bad.json", "/stages/1", 8, `duplicate stage "a"`},
+		{"workflows/broken.yaml", "", 1, "did not find expected"},
+	}
+	check := func(errs []FileError, wants []want) {
+		t.Helper()
+		if len(errs) != len(wants) {
+			t.Fatalf("errors = %v", errs)
+		}
+		for i, w := range wants {
+			e := errs[i]
+			if e.Path != w.path || e.Pointer != w.pointer || e.Line != w.line || !strings.Contains(e.Message, w.message) {
+				t.Errorf("error %d = %+v, want %+v", i, e, w)
+			}
+		}
+	}
+	errs := ValidateFiles(files)
+	check(errs, wants)
+
+	// Structural errors stop a file before its references are checked;
+	// once fixed, the missing queue and activity are reported
+	files["workflows/bad.json"] = []byte(`{"name": "bad", "task_queue": "nowhere", "stages": [{"name": "a", "activities": ["Transform"]}]}`)
+	delete(files, "workflows/broken.yaml")
+	errs = ValidateFiles(files)
+	if len(errs) != 2 || errs[0].Pointer != "/task_queue" ||
+		errs[1].Error() != `workflows/bad.json:1: /stages/0/activities/0: activity "Transform" is not in configs/catalog.yaml` {
+		t.Fatalf("errors = %v", errs)
+	}
+
+	// Without a catalog references are not checked
+	delete(files, "configs/catalog.yaml")
+	if errs := ValidateFiles(files); len(errs) != 0 {
+		t.Errorf("errors without catalog = %v", errs)
+	}
+
+	// Expressions parse and only read parameters, the customer and the
+	// outputs of earlier stages
+	files = map[string][]byte{"workflows/branch.yaml": []byte(`name: branch
+stages:
+  - name: extract
+    when: stages.extract.rows > 0
+    activities:
+      - activity: Extract
+        when: customer_id == "acme-corp" &&
+  - name: load
+    activities: [Load]
+    switch:
+      - activities: [Load]
+      - when: stages.load.Load.ok
+        activities: [Load]
+  - name: report
+    when: env.debug
+    activities:
+      - activity: Report
+        when: stages.report.Report == null || len(stages.extract) > 0
+`)}
+	wants = []want{
+		{"workflows/branch.yaml", "/stages/0/when", 4, `stage "extract" has not run`},
+		{"workflows/branch.yaml", "/stages/0/activities/0/when", 7, "column 30: unexpected end"},
+		{"workflows/branch.yaml", "/stages/1/switch", 11, "either activities or a switch"},
+		{"workflows/branch.yaml", "/stages/1/switch/0/when", 11, "only the last case"},
+		{"workflows/branch.yaml", "/stages/2/when", 15, `"env"`},
+	}
+	check(ValidateFiles(files), wants)
+
+	// Loops bind their item for the stage's activities only, not for its
+	// items or compensations
+	files = map[string][]byte{"workflows/loops.yaml": []byte(`name: loops
+stages:
+  - name: extract
+    map:
+      items: params.sources
+      as: source
+      concurrency: 4
+      aggregate: Merge
+    activities:
+      - activity: Extract
+        when: source.enabled
+  - name: publish
+    when: len(stages.extract.Extract) > 0
+    foreach:
+      items: stages.publish.Extract
+      as: params
+      batch_size: -1
+      aggregate: Merge
+    map:
+      items: source.tables
+    activities:
+      - activity: Publish
+        when: item.ready
+  - name: undo
+    foreach:
+      items: params.batches
+    activities: [Load]
+    compensate:
+      - activity: Unload
+        when: item.loaded
+`)}
+	wants = []want{
+		{"workflows/loops.yaml", "/stages/1/foreach/items", 15, `stage "publish" has not run`},
+		{"workflows/loops.yaml", "/stages/1/foreach/as", 16, `"params" is already a name`},
+		{"workflows/loops.yaml", "/stages/1/foreach/batch_size", 17, "between 0 and 10000"},
+		{"workflows/loops.yaml", "/stages/1/foreach/aggregate", 18, "only map"},
+		{"workflows/loops.yaml", "/stages/1/map", 20, "either foreach or map"},
+		{"workflows/loops.yaml", "/stages/1/map/items", 20, `unknown name "source"`},
+		{"workflows/loops.yaml", "/stages/2/compensate/0/when", 30, `unknown name "item"`},
+	}
+	check(ValidateFiles(files), wants)
+
+	files = map[string][]byte{"workflows/gated.yaml": []byte(`name: gated
+max_duration: 2h
+stages:
+  - name: signoff
+    approval:
+      approvers: []
+      timeout: 3h
+      default: maybe
+      escalate_after: 1h
+    activities: [Deploy]
+  - name: review
+    approval:
+      approvers: [release-managers]
+      timeout: 1h
+      escalate_after: 1h
+      escalate_to: [sre-leads]
+`)}
+	wants = []want{
+		{"workflows/gated.yaml", "/stages/0/approval/approvers", 6, "non-empty list of groups"},
+		{"workflows/gated.yaml", "/stages/0/approval/timeout", 7, "exceeds the limit of 2h0m0s"},
+		{"workflows/gated.yaml", "/stages/0/approval/default", 8, "approve or reject"},
+		{"workflows/gated.yaml", "/stages/0/approval/escalate_to", 6, "escalate_after needs escalate_to"},
+		{"workflows/gated.yaml", "/stages/0/activities", 10, "an approval stage has no activities"},
+		{"workflows/gated.yaml", "/stages/1/approval/escalate_after", 15, "shorter than the timeout of 1h0m0s"},
+	}
+	check(ValidateFiles(files), wants)
+
+	files = map[string][]byte{
+		"workflows/nightly.yaml": []byte(`name: nightly
+schedule:
+  cron: "0 2 * *"
+  every: 1h
+  timezone: Mars/Olympus
+  overlap: queue
+  paused: yes please
+  parameters: [1]
+`),
+		"workflows/hourly.yaml": []byte(`name: hourly
+task_queue: analytics
+schedule:
+  timezone: Europe/Berlin
+`),
+		"workflows/ok.yaml": []byte(`name: ok
+task_queue: analytics
+schedule:
+  cron: "@daily"
+  timezone: America/New_York
+  overlap: buffer_one
+  catchup_window: 1h
+  parameters: {processing_days: 1}
+`),
+	}
+	wants = []want{
+		{"workflows/hourly.yaml", "/schedule/cron", 4, "needs cron or every"},
+		{"workflows/nightly.yaml", "/schedule/every", 4, "either cron or every"},
+		{"workflows/nightly.yaml", "/schedule/cron", 3, `invalid cron "0 2 * *"`},
+		{"workflows/nightly.yaml", "/schedule/timezone", 5, `unknown timezone "Mars/Olympus"`},
+		{"workflows/nightly.yaml", "/schedule/overlap", 6, `one of skip, buffer_one`},
+		{"workflows/nightly.yaml", "/schedule/paused", 7, "true or false"},
+		{"workflows/nightly.yaml", "/schedule/parameters", 8, "must be a mapping"},
+		{"workflows/nightly.yaml", "/task_queue", 3, "needs a task_queue"},
+	}
+	check(ValidateFiles(files), wants)
+
+	files = map[string][]byte{"workflows/typed.yaml": []byte(`name: typed
+task_queue: analytics
+inputs:
+  type: object
+  required: [processing_days]
+  properties:
+    processing_days: {type: integer, minimum: 1}
+    region: {$ref: "#/$defs/region"}
+outputs: [report]
+schedule:
+  every: 24h
+  parameters:
+    processing_days: 0
+`)}
+	wants = []want{
+		{"workflows/typed.yaml", "/inputs/properties/region/$ref", 8, `unsupported keyword "$ref"`},
+		{"workflows/typed.yaml", "/outputs", 9, "must be a JSON Schema mapping"},
+	}
+	check(ValidateFiles(files), wants)
+
+	// Once the schema compiles, scheduled runs are held to it
+	files["workflows/typed.yaml"] = []byte(strings.Replace(strings.Replace(string(files["workflows/typed.yaml"]),
+		`    region: {$ref: "#/$defs/region"}
+`, "", 1), "outputs: [report]\n", "", 1))
+	wants = []want{
+		{"workflows/typed.yaml", "/schedule/parameters/processing_days", 11, "must be at least 1, not 0"},
+	}
+	check(ValidateFiles(files), wants)
+	files["workflows/typed.yaml"] = []byte(strings.Replace(string(files["workflows/typed.yaml"]), "  parameters:\n    processing_days: 0\n", "", 1))
+	wants = []want{
+		{"workflows/typed.yaml", "/schedule/parameters/processing_days", 9, "is required"},
+	}
+	check(ValidateFiles(files), wants)
+}
+
+const extractValidate = `name: extract-validate
+description: Pull a table and check it
+parameters:
+  table: null
+  prefix: ""
+  timeout: 15m
+  lenient: false
+stages:
+  - name: ${prefix}extract
+    description: Extract ${table}
+    activities: [ExtractFromDatabase]
+    timeout: ${timeout}
+  - name: ${prefix}validate
+    when: stages.${prefix}extract.ExtractFromDatabase.rows > 0
+    activities: [ValidateSchema]
+    continue_on_error: ${lenient}
+`
+
+func TestStageLibrary(t *testing.T) {
+	files := map[string][]byte{
+		"library/extract-validate.yaml": []byte(extractValidate),
+		"workflows/orders.yaml": []byte(`name: orders
+stages:
+  - use: extract-validate
+    with: {table: orders}
+  - use: extract-validate
+    with: {table: refunds, prefix: refunds_, timeout: 30m, lenient: true}
+  - name: load
+    activities: [Load]
+`),
+		"workflows/nightly.yaml": []byte(`name: nightly
+stages:
+  - name: orders
+    workflow:
+      name: orders
+      parameters: {day: "${params.day}", full: true, region: eu}
+  - name: report
+    when: stages.orders.orders.load.Load != null
+    activities: [Report]
+`),
+	}
+	if errs := ValidateFiles(files); len(errs) != 0 {
+		t.Fatalf("errors = %v", errs)
+	}
+	snap := newSnapshot("", "")
+	for p, data := range files {
+		if err := snap.add(p, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snap.index()
+	orders, _ := snap.Workflow("orders")
+	var names []string
+	for _, s := range orders.Stages {
+		names = append(names, s.Name)
+	}
+	if strings.Join(names, ",") != "extract,validate,refunds_extract,refunds_validate,load" {
+		t.Errorf("stages = %v", names)
+	}
+	if s := orders.Stages[2]; s.Description != "Extract refunds" || s.Timeout != "30m" {
+		t.Errorf("stage = %+v", s)
+	}
+	// A whole-value placeholder keeps the value's type
+	if !orders.Stages[3].ContinueOnError || orders.Stages[1].ContinueOnError {
+		t.Errorf("continue_on_error = %v, %v", orders.Stages[1].ContinueOnError, orders.Stages[3].ContinueOnError)
+	}
+	nightly, _ := snap.Workflow("nightly")
+	if w := nightly.Stages[0].Workflow; w == nil || w.Name != "orders" || w.Parameters["full"] != true {
+		t.Errorf("workflow stage = %+v", w)
+	}
+	if tmpl, ok := snap.Template("extract-validate"); !ok || tmpl.Parameters["timeout"] != "15m" {
+		t.Errorf("template = %+v", tmpl)
+	}
+
+	type want struct {
+		path, pointer string
+		line          int
+		message       string
+	}
+	check := func(errs []FileError, wants []want) {
+		t.Helper()
+		if len(errs) != len(wants) {
+			t.Fatalf("errors = %v", errs)
+		}
+		for i, w := range wants {
+			e := errs[i]
+			if e.Path != w.path || e.Pointer != w.pointer || e.Line != w.line || !strings.Contains(e.Message, w.message) {
+				t.Errorf("error %d = %+v, want %+v", i, e, w)
+			}
+		}
+	}
+
+	// Problems in expanded stages are reported at the use stage
+	files = map[string][]byte{
+		"library/extract-validate.yaml": []byte(extractValidate),
+		"library/a.yaml":                []byte("name: a\nstages: [{use: b}]\n"),
+		"library/b.yaml":                []byte("name: b\nstages: [{use: a}]\n"),
+		"library/bad.yaml": []byte(`name: bad
+parameters:
+  1x: 1
+stages:
+  - name: x
+    description: ${missing}
+`),
+		"workflows/broken.yaml": []byte(`name: broken
+stages:
+  - use: extract-validate
+    with: {tabel: x}
+  - use: nowhere
+  - use: a
+  - use: extract-validate
+    with: {table: t, timeout: soon}
+  - name: report
+    workflow:
+      name: orders
+      parameters: {rows: "${stages.validate.x}", list: [1], day: "${day}", region: eu}
+    activities: [Report]
+`),
+	}
+	check(ValidateFiles(files), []want{
+		{"library/bad.yaml", "/parameters/1x", 3, `invalid parameter name "1x"`},
+		{"library/bad.yaml", "/stages/0/description", 6, `unknown parameter "missing" in ${missing} (declared: none)`},
+		{"workflows/broken.yaml", "/stages/0/with/tabel", 4, `no parameter "tabel" (did you mean "table"?)`},
+		{"workflows/broken.yaml", "/stages/0/with/table", 3, `template "extract-validate" needs parameter "table"`},
+		{"workflows/broken.yaml", "/stages/1/use", 5, `template "nowhere" is not defined`},
+		{"workflows/broken.yaml", "/stages/2/use", 6, "templates use each other in a cycle: a -> b -> a"},
+		{"workflows/broken.yaml", "/stages/3", 7, `invalid duration "soon" (use units like 30s, 15m or 2h) (in template "extract-validate" at /stages/0/timeout)`},
+		{"workflows/broken.yaml", "/stages/4/workflow/parameters/list", 12, "must be an expression or a literal, not a list"},
+		{"workflows/broken.yaml", "/stages/4/workflow/parameters/day", 12, `unknown name "day"`},
+		{"workflows/broken.yaml", "/stages/4/activities", 13, "a workflow stage has no activities"},
+	})
+
+	// Without a library every template is undefined
+	_, err := ParseWorkflow("workflows/orders.yaml", []byte("name: orders\nstages:\n  - use: extract-validate\n    with: {table: orders}\n"))
+	var fes FileErrors
+	if !errors.As(err, &fes) {
+		t.Fatalf("ParseWorkflow without a library = %v", err)
+	}
+	check(fes, []want{{"workflows/orders.yaml", "/stages/0/use", 3, `template "extract-validate" is not defined`}})
+
+	// Workflow stages must name a defined workflow, without cycles
+	files = map[string][]byte{
+		"workflows/a.yaml":    []byte("name: A\nstages:\n  - name: b\n    workflow: {name: B}\n"),
+		"workflows/b.yaml":    []byte("name: B\nstages:\n  - name: a\n    workflow: {name: A}\n  - name: c\n    workflow: {name: Orders}\n"),
+		"workflows/self.yaml": []byte("name: Self\nstages:\n  - name: again\n    workflow: {name: Self}\n"),
+	}
+	check(ValidateFiles(files), []want{
+		{"workflows/b.yaml", "/stages/1/workflow/name", 6, `workflow "Orders" is not defined`},
+		{"workflows/b.yaml", "/stages/0/workflow/name", 4, "workflows run each other in a cycle: A -> B -> A"},
+		{"workflows/self.yaml", "/stages/0/workflow/name", 4, "cycle: Self -> Self"},
+	})
+
+	// A commit changing only a template re-expands the workflows using it
+	repo, reg, first := newLoadedRegistry(t)
+	repo.write("library/extract-validate.yaml", extractValidate)
+	repo.write("workflows/orders.yaml", "name: orders\nstages:\n  - use: extract-validate\n    with: {table: orders}\n")
+	second := repo.commit("use the library")
+	if _, err := reg.Apply(context.Background(), repo.event(first, second)); err != nil {
+		t.Fatal(err)
+	}
+	repo.write("library/extract-validate.yaml", strings.Replace(extractValidate, "timeout: 15m", "timeout: 20m", 1))
+	third := repo.commit("allow slower extracts")
+	if _, err := reg.Apply(context.Background(), repo.event(second, third)); err != nil {
+		t.Fatal(err)
+	}
+	if wf, ok := reg.Current().Workflow("orders"); !ok || wf.Stages[0].Timeout != "20m" {
+		t.Errorf("workflow = %+v", wf)
+	}
+}
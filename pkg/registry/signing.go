@@ -0,0 +1,176 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+)
+
+// SignersPath is the allowlist of keys that may sign runtime commits. It
+// lives in the repository so that changes to it are reviewed and signed
+// like any other change.
+const SignersPath = "security/signers.yaml"
+
+// signersFile is the format of SignersPath:
+//
+//	signers:
+//	  - name: Ada Ops
+//	    ssh: ssh-ed25519 AAAAC3Nza... ada@example.com
+//	  - name: Release Bot
+//	    openpgp: |
+//	      -----BEGIN PGP PUBLIC KEY BLOCK-----
+//	      ...
+type signersFile struct {
+	Signers []struct {
+		Name    string `yaml:"name"`
+		SSH     string `yaml:"ssh"`
+		OpenPGP string `yaml:"openpgp"`
+	} `yaml:"signers"`
+}
+
+// SignatureViolation describes a commit a SigningPolicy refused
+type SignatureViolation struct {
+	Ref string
+	// Commit is the commit that was to be loaded; Offender is the commit in
+	// its history that failed, often the same one
+	Commit   string
+	Offender string
+	Author   gitnative.Signature
+	Err      error
+}
+
+// SigningPolicy only admits commits signed by keys in the allowlist at
+// SignersPath. Trust flows along history: each new commit is checked
+// against the allowlist of its first parent, so a key has to be on the
+// list before it can sign, and a commit that edits the list must be signed
+// by a key that was already on it. New commits must descend from the
+// trusted one, so a key removed from the list cannot sign on top of the
+// history from before its removal.
+type SigningPolicy struct {
+	repo *gitnative.Repo
+	// Alert is called for every refused commit; the default logs it
+	Alert func(SignatureViolation)
+
+	mu       sync.Mutex
+	keyrings map[string]*gitnative.Keyring // by allowlist content
+}
+
+// NewSigningPolicy returns a policy reading allowlists from repo
+func NewSigningPolicy(repo *gitnative.Repo) *SigningPolicy {
+	return &SigningPolicy{repo: repo, keyrings: map[string]*gitnative.Keyring{}}
+}
+
+// Verify checks every commit reachable from commit but not from trusted,
+// and that commit descends from trusted unless it is part of trusted's
+// history, and returns the key that signed commit. With no trusted commit (a
+// registry's first load) only commit itself is checked, against its own
+// allowlist: whoever deploys the runtime vouches for its starting point.
+// Refusals are passed to Alert before being returned.
+func (p *SigningPolicy) Verify(ctx context.Context, ref, trusted, commit string) (*gitnative.TrustedKey, error) {
+	key, offender, err := p.verify(ctx, trusted, commit)
+	if err != nil {
+		v := SignatureViolation{Ref: ref, Commit: commit, Offender: offender, Err: err}
+		if c, cerr := p.repo.Commit(offender); cerr == nil {
+			v.Author = c.Author
+		}
+		if p.Alert != nil {
+			p.Alert(v)
+		} else {
+			log.Printf("registry: ALERT refusing %.8s on %s: commit %.8s by %s <%s>: %v", v.Commit, v.Ref, v.Offender, v.Author.Name, v.Author.Email, v.Err)
+		}
+	}
+	return key, err
+}
+
+func (p *SigningPolicy) verify(ctx context.Context, trusted, commit string) (*gitnative.TrustedKey, string, error) {
+	ids := []string{commit}
+	if trusted != "" {
+		var err error
+		if ids, err = p.repo.Exclusive(ctx, commit, trusted); err != nil {
+			return nil, commit, err
+		}
+	}
+
+	var tip *gitnative.TrustedKey
+	for _, id := range ids {
+		c, err := p.repo.Commit(id)
+		if err != nil {
+			return nil, id, err
+		}
+		allowlist := id
+		if trusted != "" {
+			if len(c.Parents) == 0 {
+				return nil, id, fmt.Errorf("root commit %.8s is outside the trusted history", id)
+			}
+			allowlist = c.Parents[0]
+		}
+		keyring, err := p.keyring(ctx, allowlist)
+		if err != nil {
+			return nil, id, err
+		}
+		key, err := keyring.VerifyCommit(c)
+		if err != nil {
+			return nil, id, fmt.Errorf("commit %.8s: %w", id, err)
+		}
+		if id == commit {
+			tip = key
+		}
+	}
+	if trusted != "" && len(ids) > 0 {
+		ok, err := p.repo.IsAncestor(ctx, trusted, commit)
+		if err != nil {
+			return nil, commit, err
+		}
+		if !ok {
+			return nil, commit, fmt.Errorf("commit %.8s does not descend from trusted commit %.8s", commit, trusted)
+		}
+	}
+	return tip, "", nil
+}
+
+// keyring parses the allowlist at commit
+func (p *SigningPolicy) keyring(ctx context.Context, commit string) (*gitnative.Keyring, error) {
+	data, err := p.repo.ReadFile(ctx, commit, SignersPath)
+	if errors.Is(err, gitnative.ErrFileNotFound) {
+		return nil, fmt.Errorf("no signer allowlist at %s in %.8s", SignersPath, commit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if kr, ok := p.keyrings[string(data)]; ok {
+		return kr, nil
+	}
+	var file signersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s at %.8s: %v", SignersPath, commit, err)
+	}
+	kr := &gitnative.Keyring{}
+	for i, s := range file.Signers {
+		if s.Name == "" {
+			return nil, fmt.Errorf("%s at %.8s: signer %d has no name", SignersPath, commit, i+1)
+		}
+		if s.SSH != "" {
+			err = kr.AddSSHKey(s.Name, s.SSH)
+		}
+		if s.OpenPGP != "" && err == nil {
+			err = kr.AddOpenPGPKeys(s.Name, s.OpenPGP)
+		}
+		if s.SSH == "" && s.OpenPGP == "" {
+			err = fmt.Errorf("signer %s has no ssh or openpgp key", s.Name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s at %.8s: %v", SignersPath, commit, err)
+		}
+	}
+	p.keyrings[string(data)] = kr
+	return kr, nil
+}
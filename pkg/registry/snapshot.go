@@ -0,0 +1,212 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+)
+
+// Snapshot is every definition at one commit. A published snapshot is never
+// modified; reloads build a new one and swap it in.
+type Snapshot struct {
+	Commit   string    `json:"commit"`
+	Ref      string    `json:"ref"`
+	LoadedAt time.Time `json:"loaded_at"`
+	// MainCommit is the main commit a tenant branch's files are overlaid
+	// on, or "" when the snapshot is of a single branch
+	MainCommit string `json:"main_commit,omitempty"`
+
+	// Definitions keyed by repository path
+	Tools     map[string]*Tool                  `json:"tools"`
+	Workflows map[string]*WorkflowDefinition    `json:"workflows"`
+	Configs   map[string]map[string]interface{} `json:"configs"`
+	Templates map[string]*StageTemplate         `json:"templates"`
+
+	// sources are the workflow files, parsed once every template is known
+	sources map[string][]byte
+
+	toolsByName     map[string]string
+	workflowsByName map[string]string
+	templatesByName map[string]string
+}
+
+func newSnapshot(ref, commit string) *Snapshot {
+	return &Snapshot{
+		Commit:    commit,
+		Ref:       ref,
+		Tools:     map[string]*Tool{},
+		Workflows: map[string]*WorkflowDefinition{},
+		Configs:   map[string]map[string]interface{}{},
+		Templates: map[string]*StageTemplate{},
+		sources:   map[string][]byte{},
+	}
+}
+
+// Tool looks up a tool by name
+func (s *Snapshot) Tool(name string) (*Tool, bool) {
+	p, ok := s.toolsByName[name]
+	if !ok {
+		return nil, false
+	}
+	return s.Tools[p], true
+}
+
+// Workflow looks up a workflow definition by name
+func (s *Snapshot) Workflow(name string) (*WorkflowDefinition, bool) {
+	p, ok := s.workflowsByName[name]
+	if !ok {
+		return nil, false
+	}
+	return s.Workflows[p], true
+}
+
+// Template looks up a stage template by name
+func (s *Snapshot) Template(name string) (*StageTemplate, bool) {
+	p, ok := s.templatesByName[name]
+	if !ok {
+		return nil, false
+	}
+	return s.Templates[p], true
+}
+
+// ToolNames returns the names of all tools, sorted
+func (s *Snapshot) ToolNames() []string {
+	return sortedKeys(s.toolsByName)
+}
+
+// WorkflowNames returns the names of all workflows, sorted
+func (s *Snapshot) WorkflowNames() []string {
+	return sortedKeys(s.workflowsByName)
+}
+
+// clone copies the path maps so the copy can be edited; the definitions
+// themselves are shared, they are replaced rather than mutated. Workflows
+// are parsed again by index, as the templates they use may change.
+func (s *Snapshot) clone(commit string) *Snapshot {
+	next := newSnapshot(s.Ref, commit)
+	for k, v := range s.Tools {
+		next.Tools[k] = v
+	}
+	for k, v := range s.sources {
+		next.sources[k] = v
+	}
+	for k, v := range s.Configs {
+		next.Configs[k] = v
+	}
+	for k, v := range s.Templates {
+		next.Templates[k] = v
+	}
+	return next
+}
+
+func (s *Snapshot) remove(p string) {
+	delete(s.Tools, p)
+	delete(s.sources, p)
+	delete(s.Configs, p)
+	delete(s.Templates, p)
+}
+
+// load parses the file at p from the snapshot's commit into the snapshot
+func (s *Snapshot) load(ctx context.Context, repo *gitnative.Repo, p string) error {
+	if gitnative.ClassifyPath(p) == gitnative.KindOther || !definitionFile(p) {
+		return nil
+	}
+	data, err := repo.ReadFile(ctx, s.Commit, p)
+	if err != nil {
+		return FileError{Path: p, Message: err.Error()}
+	}
+	return s.add(p, data)
+}
+
+// add parses data as the definition at p. Workflows are only checked for
+// syntax here; index parses them once every template is known.
+func (s *Snapshot) add(p string, data []byte) error {
+	switch gitnative.ClassifyPath(p) {
+	case gitnative.KindTool:
+		t, err := ParseTool(p, data)
+		if err != nil {
+			return err
+		}
+		s.Tools[p] = t
+	case gitnative.KindWorkflow:
+		if _, err := parseNode(p, data); err != nil {
+			return err
+		}
+		s.sources[p] = data
+	case gitnative.KindLibrary:
+		t, err := ParseStageTemplate(p, data)
+		if err != nil {
+			return err
+		}
+		s.Templates[p] = t
+	case gitnative.KindConfig:
+		if p == CatalogPath {
+			if err := validateCatalog(p, data); err != nil {
+				return err
+			}
+		}
+		c, err := ParseConfig(p, data)
+		if err != nil {
+			return err
+		}
+		s.Configs[p] = c
+	}
+	return nil
+}
+
+// index parses the workflows and builds the name lookups, reporting names
+// defined by two files, references missing from the catalog and workflow
+// stages that cannot run
+func (s *Snapshot) index() []FileError {
+	var errs []FileError
+	s.toolsByName = map[string]string{}
+	for _, p := range sortedKeys(s.Tools) {
+		name := s.Tools[p].Name
+		if other, ok := s.toolsByName[name]; ok {
+			errs = append(errs, FileError{Path: p, Message: fmt.Sprintf("tool %q is already defined in %s", name, other)})
+			continue
+		}
+		s.toolsByName[name] = p
+	}
+	s.templatesByName = map[string]string{}
+	for _, p := range sortedKeys(s.Templates) {
+		name := s.Templates[p].Name
+		if other, ok := s.templatesByName[name]; ok {
+			errs = append(errs, FileError{Path: p, Message: fmt.Sprintf("template %q is already defined in %s", name, other)})
+			continue
+		}
+		s.templatesByName[name] = p
+	}
+	s.Workflows = map[string]*WorkflowDefinition{}
+	for _, p := range sortedKeys(s.sources) {
+		w, err := ParseWorkflowWith(p, s.sources[p], s)
+		if err != nil {
+			errs = append(errs, asFileErrors(p, err)...)
+			continue
+		}
+		s.Workflows[p] = w
+	}
+	s.workflowsByName = map[string]string{}
+	for _, p := range sortedKeys(s.Workflows) {
+		name := s.Workflows[p].Name
+		if other, ok := s.workflowsByName[name]; ok {
+			errs = append(errs, FileError{Path: p, Message: fmt.Sprintf("workflow %q is already defined in %s", name, other)})
+			continue
+		}
+		s.workflowsByName[name] = p
+	}
+	errs = append(errs, s.checkReferences()...)
+	return append(errs, s.checkSubWorkflows()...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,187 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+)
+
+// SyncOptions configures a Syncer
+type SyncOptions struct {
+	Remote gitnative.Remote
+	// Branches are the refs kept in step with the remote, as full names or
+	// patterns (default: main and every tenant branch)
+	Branches []string
+	// Interval is the polling period of Run (default 1m)
+	Interval time.Duration
+	// Signing, if set, must admit a remote commit before a branch moves to
+	// it. A branch new to this repository is trusted from local main.
+	Signing *SigningPolicy
+}
+
+// Syncer keeps local branches in step with a remote, replacing a git-sync
+// sidecar. Each sync fetches, fast-forwards every branch whose new commit
+// loads cleanly and reloads the registry serving it. Branches are never
+// rewound or force-updated: one with local commits the remote lacks is
+// reported as diverged and left alone. It implements api.SyncService.
+type Syncer struct {
+	repo       *gitnative.Repo
+	opts       SyncOptions
+	registries func(ref string) *Registry
+
+	mu sync.Mutex // one sync at a time
+}
+
+// NewSyncer returns a Syncer for repo. registries works as for NewWriter.
+func NewSyncer(repo *gitnative.Repo, opts SyncOptions, registries func(ref string) *Registry) *Syncer {
+	if len(opts.Branches) == 0 {
+		opts.Branches = []string{MainRef, tenantPrefix + "*"}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if registries == nil {
+		registries = func(string) *Registry { return nil }
+	}
+	return &Syncer{repo: repo, opts: opts, registries: registries}
+}
+
+// Run syncs immediately and then every Interval until ctx is done. Failed
+// syncs are logged and retried on the next tick.
+func (s *Syncer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if resp, err := s.Sync(ctx, "schedule", nil); err != nil {
+			log.Printf("registry: sync: %v", err)
+		} else {
+			logSync(resp)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func logSync(resp *api.SyncResponse) {
+	for _, b := range resp.Branches {
+		switch b.Status {
+		case api.SyncUpdated:
+			log.Printf("registry: %s fast-forwarded %.8s..%.8s from %s (%s)", b.Branch, b.OldCommit, b.NewCommit, resp.Remote, resp.Trigger)
+		case api.SyncRejected, api.SyncDiverged:
+			log.Printf("registry: %s not synced to %.8s (%s): %s", b.Branch, b.NewCommit, b.Status, b.Error)
+		}
+	}
+}
+
+// Sync implements api.SyncService. refs outside the configured branches
+// are ignored, so a webhook for an unrelated branch is a no-op.
+func (s *Syncer) Sync(ctx context.Context, trigger string, refs []string) (*api.SyncResponse, error) {
+	patterns := s.opts.Branches
+	if refs != nil {
+		patterns = nil
+		for _, ref := range refs {
+			if gitnative.MatchRef(s.opts.Branches, ref) {
+				patterns = append(patterns, ref)
+			}
+		}
+	}
+	resp := &api.SyncResponse{
+		Success:  true,
+		Trigger:  trigger,
+		Remote:   s.opts.Remote.Redacted(),
+		Branches: []api.BranchSync{},
+		SyncedAt: time.Now(),
+	}
+	if len(patterns) == 0 {
+		return resp, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fetched, err := s.repo.Fetch(ctx, s.opts.Remote, patterns)
+	if err != nil {
+		return nil, err
+	}
+	resp.FetchedObjects = fetched.Objects
+	for _, ref := range sortedKeys(fetched.Refs) {
+		b, err := s.advance(ctx, ref, fetched.Refs[ref])
+		if err != nil {
+			return nil, err
+		}
+		resp.Branches = append(resp.Branches, b)
+	}
+	return resp, nil
+}
+
+// advance fast-forwards ref to commit if the commit is a descendant of the
+// current head, passes the signing policy and its definitions load
+func (s *Syncer) advance(ctx context.Context, ref, commit string) (api.BranchSync, error) {
+	b := api.BranchSync{Branch: ref, NewCommit: commit, Status: api.SyncUnchanged}
+	head, err := gitnative.ResolveRef(s.repo.GitDir(), ref)
+	if errors.Is(err, gitnative.ErrRefNotFound) {
+		head, err = "", nil
+	}
+	if err != nil {
+		return b, err
+	}
+	b.OldCommit = head
+	if head == commit {
+		return b, nil
+	}
+
+	if head != "" {
+		ok, err := s.repo.IsAncestor(ctx, head, commit)
+		if err != nil {
+			return b, err
+		}
+		if !ok {
+			b.Status, b.Error = api.SyncDiverged, fmt.Sprintf("%.12s is not an ancestor of the remote commit", head)
+			return b, nil
+		}
+	}
+
+	if s.opts.Signing != nil {
+		trusted := head
+		if trusted == "" {
+			trusted, _ = gitnative.ResolveRef(s.repo.GitDir(), MainRef)
+		}
+		if _, err := s.opts.Signing.Verify(ctx, ref, trusted, commit); err != nil {
+			b.Status, b.Error = api.SyncRejected, err.Error()
+			return b, nil
+		}
+	}
+
+	snap, errs := branchSnapshot(ctx, s.repo, ref, "", commit)
+	if errs = append(errs, snap.index()...); len(errs) > 0 {
+		b.Status, b.Error = api.SyncRejected, (&ReloadError{Commit: commit, Errors: errs}).Error()
+		return b, nil
+	}
+
+	if err := gitnative.UpdateRef(s.repo.GitDir(), ref, head, commit); err != nil {
+		if errors.Is(err, gitnative.ErrStaleRef) {
+			b.Status, b.Error = api.SyncDiverged, err.Error()
+			return b, nil
+		}
+		return b, err
+	}
+	b.Status = api.SyncUpdated
+
+	if reg := s.registries(ref); reg != nil {
+		changes, err := s.repo.Diff(ctx, head, commit)
+		if err == nil {
+			_, err = reg.Apply(ctx, gitnative.CommitEvent{Ref: ref, OldSHA: head, NewSHA: commit, Changes: changes, DetectedAt: time.Now()})
+		}
+		b.Reloaded = err == nil
+	}
+	return b, nil
+}
@@ -0,0 +1,25 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+)
+
+// MainRef is the shared branch every tenant inherits from
+const MainRef = "refs/heads/main"
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// TenantRef returns the branch serving tenant: main for the empty tenant,
+// customer/<tenant> otherwise
+func TenantRef(tenant string) (string, error) {
+	if tenant == "" {
+		return MainRef, nil
+	}
+	if !tenantPattern.MatchString(tenant) {
+		return "", fmt.Errorf("%w: tenant %q must be lowercase letters, digits and dashes", api.ErrInvalidArgument, tenant)
+	}
+	return "refs/heads/customer/" + tenant, nil
+}
@@ -0,0 +1,960 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	// Schedule timezones validate the same without a system zone database
+	_ "time/tzdata"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/expr"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+	"github.com/Caia-Tech/volcano-llm/pkg/schema"
+)
+
+// CatalogPath lists the task queues and activities the runtime's workers
+// provide, as task_queues and activities lists. When the file exists,
+// workflow definitions may only reference what it lists.
+const CatalogPath = "configs/catalog.yaml"
+
+// Reference kinds: task queues and activities are checked against the
+// catalog, workflows against the other definitions
+const (
+	refTaskQueue = "task queue"
+	refActivity  = "activity"
+	refWorkflow  = "workflow"
+)
+
+// reference is a name a definition uses that must exist elsewhere
+type reference struct {
+	kind    string
+	name    string
+	pointer string
+	line    int
+}
+
+// Bounds enforced on workflow definitions
+const (
+	maxRetryAttempts   = 100
+	maxBackoffCoeff    = 100
+	maxWorkflowTimeout = 365 * 24 * time.Hour
+	maxLoopConcurrency = 1000
+	maxLoopBatchSize   = 10000
+)
+
+// ValidateFiles checks a tree of definition files given as repository
+// path -> content, including the checks that span files (duplicate names,
+// catalog references). Files that are not definitions are ignored. It is
+// what a pre-commit hook or CI job runs on a working tree.
+func ValidateFiles(files map[string][]byte) []FileError {
+	snap := newSnapshot("", "")
+	var errs []FileError
+	for _, p := range sortedKeys(files) {
+		if gitnative.ClassifyPath(p) == gitnative.KindOther || !definitionFile(p) {
+			continue
+		}
+		if err := snap.add(p, files[p]); err != nil {
+			errs = append(errs, asFileErrors(p, err)...)
+		}
+	}
+	return byPath(append(errs, snap.index()...))
+}
+
+// ValidateCommit checks every definition at commit, exactly as a reload
+// of that commit would
+func ValidateCommit(ctx context.Context, repo *gitnative.Repo, commit string) []FileError {
+	snap, errs := buildSnapshot(ctx, repo, "", commit)
+	return byPath(append(errs, snap.index()...))
+}
+
+// byPath orders problems by file, keeping each file's in the order found
+func byPath(errs []FileError) []FileError {
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs
+}
+
+var yamlLine = regexp.MustCompile(`line (\d+): (.*)`)
+
+// parseNode parses a YAML or JSON document, keeping line numbers
+func parseNode(p string, data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		if m := yamlLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return nil, FileError{Path: p, Line: line, Message: m[2]}
+		}
+		return nil, FileError{Path: p, Message: err.Error()}
+	}
+	if len(doc.Content) == 0 {
+		return nil, FileError{Path: p, Message: "empty document"}
+	}
+	return doc.Content[0], nil
+}
+
+// validator walks a parsed document, collecting every problem rather than
+// stopping at the first
+type validator struct {
+	path string
+	errs []FileError
+	refs []reference
+}
+
+func (v *validator) fail(n *yaml.Node, ptr, format string, args ...interface{}) {
+	fe := FileError{Path: v.path, Pointer: ptr, Message: fmt.Sprintf(format, args...)}
+	if n != nil {
+		fe.Line = n.Line
+	}
+	v.errs = append(v.errs, fe)
+}
+
+func (v *validator) ref(n *yaml.Node, ptr, kind, name string) {
+	v.refs = append(v.refs, reference{kind: kind, name: name, pointer: ptr, line: n.Line})
+}
+
+// pointer appends a key to a JSON pointer, escaping it per RFC 6901
+func pointer(ptr string, key interface{}) string {
+	s := strings.NewReplacer("~", "~0", "/", "~1").Replace(fmt.Sprint(key))
+	return ptr + "/" + s
+}
+
+func resolve(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+func describe(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	switch n.Tag {
+	case "!!str":
+		return fmt.Sprintf("the string %q", n.Value)
+	case "!!null":
+		return "null"
+	}
+	return n.Value
+}
+
+// fields checks n is a mapping whose keys are all known, and returns the
+// non-null values by key. Keys starting with x- are extensions and allowed.
+func (v *validator) fields(n *yaml.Node, ptr string, known ...string) map[string]*yaml.Node {
+	n = resolve(n)
+	if n.Kind != yaml.MappingNode {
+		v.fail(n, ptr, "must be a mapping, not %s", describe(n))
+		return nil
+	}
+	out := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+		switch {
+		case strings.HasPrefix(key.Value, "x-"):
+			continue
+		case !contains(known, key.Value):
+			v.fail(key, pointer(ptr, key.Value), "unknown field %q%s", key.Value, suggest(key.Value, known))
+			continue
+		}
+		if value.Tag != "!!null" {
+			out[key.Value] = value
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// suggest names a known field that is at most two edits from key, to catch
+// typos like max_duraton
+func suggest(key string, known []string) string {
+	for _, k := range known {
+		if editDistance(key, k) <= 2 {
+			return fmt.Sprintf(" (did you mean %q?)", k)
+		}
+	}
+	return ""
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func (v *validator) str(n *yaml.Node, ptr string) (string, bool) {
+	if n.Kind != yaml.ScalarNode || n.Tag != "!!str" {
+		v.fail(n, ptr, "must be a string, not %s", describe(n))
+		return "", false
+	}
+	return n.Value, true
+}
+
+// name is a required string without whitespace
+func (v *validator) name(n *yaml.Node, ptr string) (string, bool) {
+	s, ok := v.str(n, ptr)
+	if ok && (s == "" || strings.ContainsAny(s, " \t\n")) {
+		v.fail(n, ptr, "must be a non-empty name without spaces")
+		return "", false
+	}
+	return s, ok
+}
+
+func (v *validator) duration(n *yaml.Node, ptr string, max time.Duration) (time.Duration, bool) {
+	s, ok := v.str(n, ptr)
+	if !ok {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	switch {
+	case err != nil:
+		v.fail(n, ptr, "invalid duration %q (use units like 30s, 15m or 2h)", s)
+	case d <= 0:
+		v.fail(n, ptr, "must be positive")
+	case max > 0 && d > max:
+		v.fail(n, ptr, "%v exceeds the limit of %v", d, max)
+	default:
+		return d, true
+	}
+	return 0, false
+}
+
+func (v *validator) integer(n *yaml.Node, ptr string, lo, hi int) (int, bool) {
+	i, err := strconv.Atoi(n.Value)
+	if n.Kind != yaml.ScalarNode || n.Tag != "!!int" || err != nil {
+		v.fail(n, ptr, "must be an integer, not %s", describe(n))
+		return 0, false
+	}
+	if i < lo || i > hi {
+		v.fail(n, ptr, "must be between %d and %d, not %d", lo, hi, i)
+		return 0, false
+	}
+	return i, true
+}
+
+func (v *validator) number(n *yaml.Node, ptr string, lo, hi float64) (float64, bool) {
+	f, err := strconv.ParseFloat(n.Value, 64)
+	if n.Kind != yaml.ScalarNode || (n.Tag != "!!int" && n.Tag != "!!float") || err != nil {
+		v.fail(n, ptr, "must be a number, not %s", describe(n))
+		return 0, false
+	}
+	if f < lo || f > hi {
+		v.fail(n, ptr, "must be between %g and %g, not %g", lo, hi, f)
+		return 0, false
+	}
+	return f, true
+}
+
+func (v *validator) boolean(n *yaml.Node, ptr string) {
+	if n.Kind != yaml.ScalarNode || n.Tag != "!!bool" {
+		v.fail(n, ptr, "must be true or false, not %s", describe(n))
+	}
+}
+
+// names checks a list of names, recording each as a reference of kind
+func (v *validator) names(n *yaml.Node, ptr, kind string) {
+	if n.Kind != yaml.SequenceNode {
+		v.fail(n, ptr, "must be a list, not %s", describe(n))
+		return
+	}
+	seen := map[string]bool{}
+	for i, item := range n.Content {
+		item = resolve(item)
+		ip := pointer(ptr, i)
+		s, ok := v.name(item, ip)
+		switch {
+		case !ok:
+		case seen[s]:
+			v.fail(item, ip, "%s %q is listed twice", kind, s)
+		default:
+			seen[s] = true
+			v.ref(item, ip, kind, s)
+		}
+	}
+}
+
+// workflow checks a workflow definition document
+func (v *validator) workflow(root *yaml.Node) {
+	f := v.fields(root, "", "name", "version", "description", "task_queue", "max_duration", "retry_policy", "stages", "hooks", "schedule", "inputs", "outputs")
+	if f == nil {
+		return
+	}
+	if n, ok := f["name"]; ok {
+		v.name(n, "/name")
+	} else {
+		v.fail(root, "/name", "name is required")
+	}
+	if n, ok := f["version"]; ok && (n.Kind != yaml.ScalarNode || n.Tag == "!!bool") {
+		v.fail(n, "/version", "must be a version string, not %s", describe(n))
+	}
+	if n, ok := f["description"]; ok {
+		v.str(n, "/description")
+	}
+	if n, ok := f["task_queue"]; ok {
+		if q, ok := v.name(n, "/task_queue"); ok {
+			v.ref(n, "/task_queue", refTaskQueue, q)
+		}
+	}
+	maxDuration := maxWorkflowTimeout
+	if n, ok := f["max_duration"]; ok {
+		if d, ok := v.duration(n, "/max_duration", maxWorkflowTimeout); ok {
+			maxDuration = d
+		}
+	}
+	if n, ok := f["retry_policy"]; ok {
+		v.retryPolicy(n, "/retry_policy")
+	}
+	if n, ok := f["stages"]; ok {
+		v.stages(n, "/stages", maxDuration)
+	}
+	if n, ok := f["hooks"]; ok {
+		hooks := v.fields(n, "/hooks", "on_start", "on_success", "on_failure")
+		for _, k := range []string{"on_start", "on_success", "on_failure"} {
+			if n, ok := hooks[k]; ok {
+				v.names(n, "/hooks/"+k, refActivity)
+			}
+		}
+	}
+	var inputs *schema.Schema
+	if n, ok := f["inputs"]; ok {
+		inputs = v.jsonSchema(n, "/inputs")
+	}
+	if n, ok := f["outputs"]; ok {
+		v.jsonSchema(n, "/outputs")
+	}
+	if n, ok := f["schedule"]; ok {
+		params := v.schedule(n, "/schedule")
+		if _, ok := f["task_queue"]; !ok {
+			v.fail(n, "/task_queue", "a scheduled workflow needs a task_queue to start on")
+		}
+		// Scheduled runs must pass the workflow's own input checks
+		if inputs != nil && n.Kind == yaml.MappingNode && (params == nil || params.Kind == yaml.MappingNode) {
+			v.conforms(inputs, params, n, "/schedule/parameters")
+		}
+	}
+}
+
+// jsonSchema checks an inputs or outputs schema and returns it compiled
+func (v *validator) jsonSchema(n *yaml.Node, ptr string) *schema.Schema {
+	if n.Kind != yaml.MappingNode {
+		v.fail(n, ptr, "must be a JSON Schema mapping, not %s", describe(n))
+		return nil
+	}
+	var doc interface{}
+	if err := n.Decode(&doc); err != nil {
+		v.fail(n, ptr, "%v", err)
+		return nil
+	}
+	s, err := schema.Compile(doc)
+	var errs schema.Errors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			v.fail(nodeAt(n, e.Pointer), ptr+e.Pointer, "%s", e.Message)
+		}
+		return nil
+	}
+	return s
+}
+
+// conforms checks the value of n, an empty object if n is nil, against s.
+// Problems are reported at the value's line, or parent's when it is nil.
+func (v *validator) conforms(s *schema.Schema, n, parent *yaml.Node, ptr string) {
+	value := interface{}(map[string]interface{}{})
+	at := parent
+	if n != nil {
+		if err := n.Decode(&value); err != nil {
+			v.fail(n, ptr, "%v", err)
+			return
+		}
+		at = n
+	}
+	var errs schema.Errors
+	if errors.As(s.Validate(value), &errs) {
+		for _, e := range errs {
+			line := at
+			if n != nil {
+				line = nodeAt(n, e.Pointer)
+			}
+			v.fail(line, ptr+e.Pointer, "%s", e.Message)
+		}
+	}
+}
+
+// nodeAt finds the node a JSON pointer below n names, or the deepest
+// node on the way to it
+func nodeAt(n *yaml.Node, ptr string) *yaml.Node {
+	if ptr == "" {
+		return n
+	}
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	for _, key := range strings.Split(ptr[1:], "/") {
+		key = unescape.Replace(key)
+		n = resolve(n)
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == key {
+					next = n.Content[i+1]
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(n.Content) {
+				next = n.Content[i]
+			}
+		}
+		if next == nil {
+			return n
+		}
+		n = next
+	}
+	return n
+}
+
+// cronShorthands are the named schedules a cron may use instead of fields
+var cronShorthands = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+var cronField = regexp.MustCompile(`^[0-9A-Za-z*?,/#-]+$`)
+
+// schedule checks a workflow's schedule and returns its parameters, if any
+func (v *validator) schedule(n *yaml.Node, ptr string) *yaml.Node {
+	f := v.fields(n, ptr, "cron", "every", "timezone", "overlap", "catchup_window", "paused", "parameters")
+	if f == nil {
+		return nil
+	}
+	c, every := f["cron"], f["every"]
+	switch {
+	case c == nil && every == nil:
+		v.fail(n, ptr+"/cron", "a schedule needs cron or every")
+	case c != nil && every != nil:
+		v.fail(every, ptr+"/every", "a schedule has either cron or every, not both")
+	}
+	if c != nil {
+		if s, ok := v.str(c, ptr+"/cron"); ok && !validCron(s) {
+			v.fail(c, ptr+"/cron", "invalid cron %q (use five fields like \"0 2 * * *\" or one of %s)", s, strings.Join(cronShorthands, ", "))
+		}
+	}
+	if every != nil {
+		if d, ok := v.duration(every, ptr+"/every", maxWorkflowTimeout); ok && d < time.Second {
+			v.fail(every, ptr+"/every", "must be at least 1s")
+		}
+	}
+	if tz, ok := f["timezone"]; ok {
+		if s, ok := v.str(tz, ptr+"/timezone"); ok {
+			if _, err := time.LoadLocation(s); err != nil || s == "" || s == "Local" {
+				v.fail(tz, ptr+"/timezone", "unknown timezone %q (use an IANA name like Europe/Berlin)", s)
+			}
+		}
+	}
+	if o, ok := f["overlap"]; ok {
+		if s, ok := v.str(o, ptr+"/overlap"); ok && !contains(OverlapPolicies, s) {
+			v.fail(o, ptr+"/overlap", "must be one of %s, not %q", strings.Join(OverlapPolicies, ", "), s)
+		}
+	}
+	if w, ok := f["catchup_window"]; ok {
+		v.duration(w, ptr+"/catchup_window", maxWorkflowTimeout)
+	}
+	if p, ok := f["paused"]; ok {
+		v.boolean(p, ptr+"/paused")
+	}
+	if p, ok := f["parameters"]; ok && p.Kind != yaml.MappingNode {
+		v.fail(p, ptr+"/parameters", "must be a mapping, not %s", describe(p))
+	}
+	return f["parameters"]
+}
+
+// validCron reports whether s is a five-field cron expression or a
+// shorthand. The server checks the fields' values.
+func validCron(s string) bool {
+	if contains(cronShorthands, s) {
+		return true
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return false
+	}
+	for _, f := range fields {
+		if !cronField.MatchString(f) {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *validator) retryPolicy(n *yaml.Node, ptr string) {
+	f := v.fields(n, ptr, "maximum_attempts", "initial_interval", "backoff_coefficient", "maximum_interval")
+	if a, ok := f["maximum_attempts"]; ok {
+		v.integer(a, ptr+"/maximum_attempts", 0, maxRetryAttempts)
+	}
+	var initial time.Duration
+	if d, ok := f["initial_interval"]; ok {
+		initial, _ = v.duration(d, ptr+"/initial_interval", 0)
+	}
+	if c, ok := f["backoff_coefficient"]; ok {
+		v.number(c, ptr+"/backoff_coefficient", 1, maxBackoffCoeff)
+	}
+	if d, ok := f["maximum_interval"]; ok {
+		if max, ok := v.duration(d, ptr+"/maximum_interval", 0); ok && initial > 0 && max < initial {
+			v.fail(d, ptr+"/maximum_interval", "must not be shorter than initial_interval (%v)", initial)
+		}
+	}
+}
+
+func (v *validator) stages(n *yaml.Node, ptr string, maxDuration time.Duration) {
+	if n.Kind != yaml.SequenceNode {
+		v.fail(n, ptr, "must be a list, not %s", describe(n))
+		return
+	}
+	seen := map[string]int{}
+	for i, item := range n.Content {
+		item = resolve(item)
+		sp := pointer(ptr, i)
+		var name string
+		var nameNode *yaml.Node
+		if item.Kind == yaml.ScalarNode {
+			// Shorthand: a bare stage name
+			name, _ = v.name(item, sp)
+			nameNode = item
+		} else {
+			f := v.fields(item, sp, "name", "description", "when", "activities", "switch", "foreach", "map", "approval", "workflow", "compensate", "parallel", "timeout", "continue_on_error")
+			if f == nil {
+				continue
+			}
+			if nameNode = f["name"]; nameNode != nil {
+				name, _ = v.name(nameNode, sp+"/name")
+			} else {
+				v.fail(item, sp+"/name", "stage name is required")
+			}
+			if d, ok := f["description"]; ok {
+				v.str(d, sp+"/description")
+			}
+			// Expressions may read stages that ran before; steps may also
+			// read earlier steps of their own stage and the loop's item
+			sc := scope{stages: map[string]bool{}}
+			for s := range seen {
+				sc.stages[s] = true
+			}
+			if w, ok := f["when"]; ok {
+				v.expression(w, sp+"/when", sc)
+			}
+			for _, kind := range []string{"foreach", "map"} {
+				if l, ok := f[kind]; ok {
+					if _, both := f["foreach"]; both && kind == "map" {
+						v.fail(l, sp+"/map", "a stage has either foreach or map, not both")
+					}
+					sc.item = v.loop(l, sp+"/"+kind, kind == "map", sc)
+				}
+			}
+			if w, ok := f["workflow"]; ok {
+				v.subWorkflow(w, sp+"/workflow", sc)
+				for _, k := range []string{"activities", "switch", "foreach", "map", "approval", "parallel"} {
+					if n, ok := f[k]; ok {
+						v.fail(n, sp+"/"+k, "a workflow stage has no %s", k)
+					}
+				}
+			}
+			sc.stages[name] = true
+			if a, ok := f["activities"]; ok {
+				v.steps(a, sp+"/activities", sc)
+			}
+			if sw, ok := f["switch"]; ok {
+				if _, both := f["activities"]; both {
+					v.fail(sw, sp+"/switch", "a stage has either activities or a switch, not both")
+				}
+				v.cases(sw, sp+"/switch", sc)
+			}
+			if a, ok := f["approval"]; ok {
+				v.approval(a, sp+"/approval", maxDuration)
+				for _, k := range []string{"activities", "switch", "foreach", "map", "compensate", "parallel", "timeout"} {
+					if n, ok := f[k]; ok {
+						v.fail(n, sp+"/"+k, "an approval stage has no %s", k)
+					}
+				}
+			}
+			// Compensations run once per stage, outside any loop item
+			if c, ok := f["compensate"]; ok {
+				v.steps(c, sp+"/compensate", scope{stages: sc.stages})
+			}
+			for _, k := range []string{"parallel", "continue_on_error"} {
+				if b, ok := f[k]; ok {
+					v.boolean(b, sp+"/"+k)
+				}
+			}
+			if t, ok := f["timeout"]; ok {
+				if d, ok := v.duration(t, sp+"/timeout", 0); ok && d > maxDuration {
+					v.fail(t, sp+"/timeout", "%v exceeds the workflow's max_duration of %v", d, maxDuration)
+				}
+			}
+		}
+		if name == "" {
+			continue
+		}
+		if first, dup := seen[name]; dup {
+			v.fail(nameNode, sp, "duplicate stage %q (also stage %d)", name, first)
+			continue
+		}
+		seen[name] = i
+	}
+}
+
+// steps checks a stage's activities: names or {activity, when} mappings
+func (v *validator) steps(n *yaml.Node, ptr string, sc scope) {
+	if n.Kind != yaml.SequenceNode {
+		v.fail(n, ptr, "must be a list, not %s", describe(n))
+		return
+	}
+	for i, item := range n.Content {
+		item = resolve(item)
+		ip := pointer(ptr, i)
+		if item.Kind == yaml.ScalarNode {
+			if s, ok := v.name(item, ip); ok {
+				v.ref(item, ip, refActivity, s)
+			}
+			continue
+		}
+		f := v.fields(item, ip, "activity", "when")
+		if f == nil {
+			continue
+		}
+		if a, ok := f["activity"]; !ok {
+			v.fail(item, ip+"/activity", "activity is required")
+		} else if s, ok := v.name(a, ip+"/activity"); ok {
+			v.ref(a, ip+"/activity", refActivity, s)
+		}
+		if w, ok := f["when"]; ok {
+			v.expression(w, ip+"/when", sc)
+		}
+	}
+}
+
+// cases checks a stage's switch
+func (v *validator) cases(n *yaml.Node, ptr string, sc scope) {
+	if n.Kind != yaml.SequenceNode || len(n.Content) == 0 {
+		v.fail(n, ptr, "must be a non-empty list of cases")
+		return
+	}
+	for i, item := range n.Content {
+		cp := pointer(ptr, i)
+		f := v.fields(item, cp, "when", "activities")
+		if f == nil {
+			continue
+		}
+		if w, ok := f["when"]; ok {
+			v.expression(w, cp+"/when", sc)
+		} else if i < len(n.Content)-1 {
+			v.fail(item, cp+"/when", "only the last case may omit when")
+		}
+		if a, ok := f["activities"]; ok {
+			v.steps(a, cp+"/activities", sc)
+		} else {
+			v.fail(item, cp+"/activities", "activities are required")
+		}
+	}
+}
+
+// loop checks a foreach or map and returns the name its item is bound to.
+// The item list is evaluated before the stage runs.
+func (v *validator) loop(n *yaml.Node, ptr string, collect bool, sc scope) string {
+	f := v.fields(n, ptr, "items", "as", "concurrency", "batch_size", "aggregate")
+	if f == nil {
+		return ""
+	}
+	if items, ok := f["items"]; ok {
+		v.expression(items, ptr+"/items", sc)
+	} else {
+		v.fail(n, ptr+"/items", "items is required")
+	}
+	item := DefaultLoopItem
+	if as, ok := f["as"]; ok {
+		if s, ok := v.name(as, ptr+"/as"); ok {
+			switch s {
+			case ExprParams, ExprStages, ExprCustomer:
+				v.fail(as, ptr+"/as", "%q is already a name in expressions", s)
+			default:
+				item = s
+			}
+		}
+	}
+	if c, ok := f["concurrency"]; ok {
+		v.integer(c, ptr+"/concurrency", 0, maxLoopConcurrency)
+	}
+	if b, ok := f["batch_size"]; ok {
+		v.integer(b, ptr+"/batch_size", 0, maxLoopBatchSize)
+	}
+	if a, ok := f["aggregate"]; ok {
+		if !collect {
+			v.fail(a, ptr+"/aggregate", "only map collects outputs to aggregate")
+		} else if s, ok := v.name(a, ptr+"/aggregate"); ok {
+			v.ref(a, ptr+"/aggregate", refActivity, s)
+		}
+	}
+	return item
+}
+
+// subWorkflow checks a workflow stage. Its parameters are evaluated before
+// the stage runs.
+func (v *validator) subWorkflow(n *yaml.Node, ptr string, sc scope) {
+	f := v.fields(n, ptr, "name", "parameters")
+	if f == nil {
+		return
+	}
+	if w, ok := f["name"]; !ok {
+		v.fail(n, ptr+"/name", "name is required")
+	} else if s, ok := v.name(w, ptr+"/name"); ok {
+		v.ref(w, ptr+"/name", refWorkflow, s)
+	}
+	p, ok := f["parameters"]
+	if !ok {
+		return
+	}
+	if p.Kind != yaml.MappingNode {
+		v.fail(p, ptr+"/parameters", "must be a mapping, not %s", describe(p))
+		return
+	}
+	for i := 0; i+1 < len(p.Content); i += 2 {
+		key, value := p.Content[i], resolve(p.Content[i+1])
+		pp := pointer(ptr+"/parameters", key.Value)
+		switch {
+		case value.Kind != yaml.ScalarNode:
+			v.fail(value, pp, "must be an expression or a literal, not %s", describe(value))
+		case value.Tag == "!!str":
+			if src, ok := ParameterExpr(value.Value); ok {
+				v.source(value, pp, src, sc)
+			}
+		}
+	}
+}
+
+// approval checks an approval stage's settings
+func (v *validator) approval(n *yaml.Node, ptr string, maxDuration time.Duration) {
+	f := v.fields(n, ptr, "approvers", "message", "timeout", "default", "escalate_after", "escalate_to")
+	if f == nil {
+		return
+	}
+	if a, ok := f["approvers"]; ok {
+		v.groups(a, ptr+"/approvers")
+	} else {
+		v.fail(n, ptr+"/approvers", "approvers are required")
+	}
+	if m, ok := f["message"]; ok {
+		v.str(m, ptr+"/message")
+	}
+	var timeout time.Duration
+	if t, ok := f["timeout"]; ok {
+		if d, ok := v.duration(t, ptr+"/timeout", maxDuration); ok {
+			timeout = d
+		}
+	}
+	if d, ok := f["default"]; ok {
+		if s, ok := v.str(d, ptr+"/default"); ok && s != ApprovalApprove && s != ApprovalReject {
+			v.fail(d, ptr+"/default", "must be %s or %s, not %q", ApprovalApprove, ApprovalReject, s)
+		}
+	}
+	e, after := f["escalate_after"], f["escalate_to"]
+	switch {
+	case e != nil && after == nil:
+		v.fail(n, ptr+"/escalate_to", "escalate_after needs escalate_to")
+	case e == nil && after != nil:
+		v.fail(n, ptr+"/escalate_after", "escalate_to needs escalate_after")
+	}
+	if e != nil {
+		if d, ok := v.duration(e, ptr+"/escalate_after", maxDuration); ok && timeout > 0 && d >= timeout {
+			v.fail(e, ptr+"/escalate_after", "must be shorter than the timeout of %v", timeout)
+		}
+	}
+	if after != nil {
+		v.groups(after, ptr+"/escalate_to")
+	}
+}
+
+// groups checks a non-empty list of approver groups
+func (v *validator) groups(n *yaml.Node, ptr string) {
+	if n.Kind != yaml.SequenceNode || len(n.Content) == 0 {
+		v.fail(n, ptr, "must be a non-empty list of groups")
+		return
+	}
+	for i, item := range n.Content {
+		v.name(resolve(item), pointer(ptr, i))
+	}
+}
+
+// scope is what an expression may read besides params and customer_id:
+// the outputs of some stages and, inside a loop, the current item
+type scope struct {
+	stages map[string]bool
+	item   string
+}
+
+// expression checks a when or items expression against its scope
+func (v *validator) expression(n *yaml.Node, ptr string, sc scope) {
+	if src, ok := v.str(n, ptr); ok {
+		v.source(n, ptr, src, sc)
+	}
+}
+
+// source checks expression src, written at n
+func (v *validator) source(n *yaml.Node, ptr, src string, sc scope) {
+	e, err := expr.Parse(src)
+	if err != nil {
+		v.fail(n, ptr, "invalid expression: %v", err)
+		return
+	}
+	for _, path := range e.Paths() {
+		switch path[0] {
+		case ExprParams, ExprCustomer:
+		case ExprStages:
+			if len(path) > 1 && !sc.stages[path[1]] {
+				v.fail(n, ptr, "stage %q has not run when this is evaluated", path[1])
+			}
+		case sc.item:
+		default:
+			v.fail(n, ptr, "unknown name %q (expressions read %s)", path[0], sc.names())
+		}
+	}
+}
+
+func (sc scope) names() string {
+	if sc.item == "" {
+		return fmt.Sprintf("%s, %s and %s", ExprParams, ExprStages, ExprCustomer)
+	}
+	return fmt.Sprintf("%s, %s, %s and %s", ExprParams, ExprStages, ExprCustomer, sc.item)
+}
+
+// validateCatalog checks the shape of CatalogPath
+func validateCatalog(p string, data []byte) error {
+	root, err := parseNode(p, data)
+	if err != nil {
+		return err
+	}
+	v := &validator{path: p}
+	v.catalog(root)
+	if len(v.errs) > 0 {
+		return FileErrors(v.errs)
+	}
+	return nil
+}
+
+func (v *validator) catalog(root *yaml.Node) {
+	f := v.fields(root, "", "task_queues", "activities")
+	if n, ok := f["task_queues"]; ok {
+		v.names(n, "/task_queues", refTaskQueue)
+	}
+	if n, ok := f["activities"]; ok {
+		v.names(n, "/activities", refActivity)
+	}
+}
+
+// checkReferences reports workflow references missing from the catalog.
+// Without a catalog nothing is checked.
+func (s *Snapshot) checkReferences() []FileError {
+	cfg, ok := s.Configs[CatalogPath]
+	if !ok {
+		return nil
+	}
+	known := map[string]map[string]bool{refTaskQueue: {}, refActivity: {}}
+	for key, kind := range map[string]string{"task_queues": refTaskQueue, "activities": refActivity} {
+		list, _ := cfg[key].([]interface{})
+		for _, name := range list {
+			known[kind][fmt.Sprint(name)] = true
+		}
+	}
+
+	var errs []FileError
+	for _, p := range sortedKeys(s.Workflows) {
+		for _, ref := range s.Workflows[p].refs {
+			if names, ok := known[ref.kind]; ok && !names[ref.name] {
+				errs = append(errs, FileError{Path: p, Pointer: ref.pointer, Line: ref.line,
+					Message: fmt.Sprintf("%s %q is not in %s", ref.kind, ref.name, CatalogPath)})
+			}
+		}
+	}
+	return errs
+}
+
+// checkSubWorkflows reports workflow stages running a workflow that is not
+// defined, and workflows that run each other in a cycle
+func (s *Snapshot) checkSubWorkflows() []FileError {
+	var errs []FileError
+	calls := map[string][]reference{}
+	for _, p := range sortedKeys(s.Workflows) {
+		w := s.Workflows[p]
+		if s.workflowsByName[w.Name] != p {
+			continue
+		}
+		for _, ref := range w.refs {
+			if ref.kind != refWorkflow {
+				continue
+			}
+			if _, ok := s.workflowsByName[ref.name]; !ok {
+				errs = append(errs, FileError{Path: p, Pointer: ref.pointer, Line: ref.line,
+					Message: fmt.Sprintf("workflow %q is not defined%s", ref.name, suggest(ref.name, s.WorkflowNames()))})
+				continue
+			}
+			calls[w.Name] = append(calls[w.Name], ref)
+		}
+	}
+
+	// A depth-first walk reports each cycle once, at the call closing it
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		path = append(path, name)
+		for _, ref := range calls[name] {
+			switch state[ref.name] {
+			case unvisited:
+				visit(ref.name)
+			case visiting:
+				i := slices.Index(path, ref.name)
+				cycle := append(append([]string{}, path[i:]...), ref.name)
+				errs = append(errs, FileError{Path: s.workflowsByName[name], Pointer: ref.pointer, Line: ref.line,
+					Message: "workflows run each other in a cycle: " + strings.Join(cycle, " -> ")})
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+	for _, name := range sortedKeys(calls) {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+	return errs
+}
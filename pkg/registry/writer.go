@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/api"
+	"github.com/Caia-Tech/volcano-llm/pkg/gitnative"
+)
+
+// Writer commits configuration edits from the API to tenant branches. It
+// implements api.ConfigWriter.
+type Writer struct {
+	repo *gitnative.Repo
+	// registries returns the registry following a ref, or nil
+	registries func(ref string) *Registry
+}
+
+// NewWriter returns a Writer for repo. After each commit the registry that
+// registries returns for the branch (if any) is reloaded, exactly as if the
+// watcher had seen the commit.
+func NewWriter(repo *gitnative.Repo, registries func(ref string) *Registry) *Writer {
+	if registries == nil {
+		registries = func(string) *Registry { return nil }
+	}
+	return &Writer{repo: repo, registries: registries}
+}
+
+// WriteConfig creates or replaces the file at p
+func (w *Writer) WriteConfig(ctx context.Context, p string, req api.ConfigWriteRequest) (*api.ConfigWriteResponse, error) {
+	return w.commit(ctx, p, []byte(req.Content), req)
+}
+
+// DeleteConfig removes the file at p
+func (w *Writer) DeleteConfig(ctx context.Context, p string, req api.ConfigWriteRequest) (*api.ConfigWriteResponse, error) {
+	return w.commit(ctx, p, nil, req)
+}
+
+// commit writes content to p (nil deletes it) on the tenant's branch. The
+// whole resulting tree must validate before the branch is moved.
+func (w *Writer) commit(ctx context.Context, p string, content []byte, req api.ConfigWriteRequest) (*api.ConfigWriteResponse, error) {
+	p = path.Clean(strings.Trim(p, "/"))
+	if strings.HasPrefix(p, "..") || gitnative.ClassifyPath(p) == gitnative.KindOther || !definitionFile(p) {
+		return nil, fmt.Errorf("%w: %s is not a tool, workflow, config or library file", api.ErrInvalidArgument, p)
+	}
+	if content != nil {
+		if err := parseByKind(p, content); err != nil {
+			return nil, fmt.Errorf("%w: %v", api.ErrInvalidArgument, err)
+		}
+	}
+
+	ref, err := TenantRef(api.Tenant(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if reg := w.registries(ref); reg != nil && reg.RequiresSignatures() {
+		// The runtime has no key of its own to sign with
+		return nil, fmt.Errorf("%w: %s only accepts signed commits; push a signed commit instead", api.ErrConflict, ref)
+	}
+	head, err := gitnative.ResolveRef(w.repo.GitDir(), ref)
+	base := head
+	if errors.Is(err, gitnative.ErrRefNotFound) && ref != MainRef {
+		// A tenant's first write branches off main
+		head = ""
+		base, err = gitnative.ResolveRef(w.repo.GitDir(), MainRef)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if req.ExpectedParent != "" && req.ExpectedParent != base {
+		return nil, fmt.Errorf("%w: %s is at %s, not %s", api.ErrConflict, ref, base, req.ExpectedParent)
+	}
+	if content == nil {
+		if _, err := w.repo.ReadFile(ctx, base, p); errors.Is(err, gitnative.ErrFileNotFound) {
+			return nil, fmt.Errorf("%w: %s does not exist on %s", api.ErrNotFound, p, ref)
+		}
+	}
+
+	now := time.Now()
+	commit, err := w.repo.CreateCommit(ctx, gitnative.CommitRequest{
+		Parent:  base,
+		Author:  gitnative.Signature{Name: req.Author.Name, Email: req.Author.Email, When: now},
+		Message: req.Message,
+		Files:   map[string][]byte{p: content},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.ConfigWriteResponse{Success: true, Branch: strings.TrimPrefix(ref, "refs/heads/"), Path: p, Commit: commit, Parent: base}
+	if commit == base {
+		return resp, nil
+	}
+
+	mainCommit := ""
+	if reg := w.registries(MainRef); reg != nil {
+		mainCommit = reg.serving()
+	}
+	snap, errs := branchSnapshot(ctx, w.repo, ref, mainCommit, commit)
+	if errs = append(errs, snap.index()...); len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %v", api.ErrInvalidArgument, &ReloadError{Commit: commit, Errors: errs})
+	}
+
+	if err := gitnative.UpdateRef(w.repo.GitDir(), ref, head, commit); err != nil {
+		if errors.Is(err, gitnative.ErrStaleRef) {
+			return nil, fmt.Errorf("%w: %v", api.ErrConflict, err)
+		}
+		return nil, err
+	}
+	resp.Changed = true
+
+	if reg := w.registries(ref); reg != nil {
+		changes, err := w.repo.Diff(ctx, base, commit)
+		if err == nil {
+			_, err = reg.Apply(ctx, gitnative.CommitEvent{Ref: ref, OldSHA: base, NewSHA: commit, Changes: changes, DetectedAt: now})
+		}
+		resp.Reloaded = err == nil
+	}
+	return resp, nil
+}
+
+// parseByKind validates content as the definition type its path implies.
+// A workflow is only parsed here: the templates it uses are checked with
+// the rest of the tree.
+func parseByKind(p string, content []byte) error {
+	var err error
+	switch gitnative.ClassifyPath(p) {
+	case gitnative.KindTool:
+		_, err = ParseTool(p, content)
+	case gitnative.KindWorkflow:
+		_, err = parseNode(p, content)
+	case gitnative.KindConfig:
+		_, err = ParseConfig(p, content)
+	case gitnative.KindLibrary:
+		_, err = ParseStageTemplate(p, content)
+	}
+	return err
+}
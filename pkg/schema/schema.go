@@ -0,0 +1,583 @@
+// Package schema validates JSON values against JSON Schema, as used by
+// workflow definitions to type their inputs and outputs:
+//
+//	type: object
+//	required: [processing_days]
+//	additionalProperties: false
+//	properties:
+//	  processing_days: {type: integer, minimum: 1, maximum: 90}
+//	  test_mode: {type: boolean}
+//	  region: {enum: [eu, us]}
+//
+// It supports the keywords type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, uniqueItems, minLength,
+// maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// multipleOf, minProperties, maxProperties, allOf, anyOf, oneOf and not,
+// plus true and false as schemas. title, description, default, examples,
+// format, $schema, $comment and x- keywords are annotations and not
+// checked. Any other keyword fails Compile rather than being silently
+// ignored, so a typo or an unsupported feature like $ref is caught when the
+// definition loads.
+//
+// Validation is deterministic and has no side effects, so it is safe inside
+// Temporal workflow code.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is a problem at a JSON pointer: into the schema for Compile, into
+// the value for Validate
+type Error struct {
+	Pointer string
+	Message string
+}
+
+func (e Error) Error() string {
+	if e.Pointer == "" {
+		return e.Message
+	}
+	return e.Pointer + ": " + e.Message
+}
+
+// Errors are every problem found, in document order
+type Errors []Error
+
+func (es Errors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Schema is a compiled schema
+type Schema struct {
+	// never is the false schema; a nil *Schema or true accepts anything
+	never bool
+
+	types    []string
+	enum     []interface{}
+	konst    interface{}
+	hasConst bool
+
+	properties  map[string]*Schema
+	required    []string
+	additional  *Schema
+	minProps    *int
+	maxProps    *int
+	items       *Schema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum    *float64
+	maximum    *float64
+	exclMin    *float64
+	exclMax    *float64
+	multipleOf *float64
+
+	allOf []*Schema
+	anyOf []*Schema
+	oneOf []*Schema
+	not   *Schema
+}
+
+var typeNames = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+var annotations = []string{"title", "description", "default", "examples", "format", "$schema", "$comment"}
+
+// Compile checks a schema document, decoded from JSON or YAML, and returns
+// it ready to validate with. All problems are returned as Errors.
+func Compile(doc interface{}) (*Schema, error) {
+	c := &compiler{}
+	s := c.schema(normalize(doc), "")
+	if len(c.errs) > 0 {
+		return nil, c.errs
+	}
+	return s, nil
+}
+
+type compiler struct {
+	errs Errors
+}
+
+func (c *compiler) fail(ptr, format string, args ...interface{}) {
+	c.errs = append(c.errs, Error{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *compiler) schema(doc interface{}, ptr string) *Schema {
+	switch doc := doc.(type) {
+	case bool:
+		return &Schema{never: !doc}
+	case map[string]interface{}:
+		return c.object(doc, ptr)
+	}
+	c.fail(ptr, "must be a schema object or a boolean, not %s", describe(doc))
+	return nil
+}
+
+func (c *compiler) object(doc map[string]interface{}, ptr string) *Schema {
+	s := &Schema{}
+	for _, key := range sortedKeys(doc) {
+		v, kp := doc[key], pointer(ptr, key)
+		switch key {
+		case "type":
+			s.types = c.types(v, kp)
+		case "enum":
+			list, ok := v.([]interface{})
+			if !ok || len(list) == 0 {
+				c.fail(kp, "must be a non-empty list")
+			}
+			s.enum = list
+		case "const":
+			s.konst, s.hasConst = v, true
+		case "properties":
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				c.fail(kp, "must be an object, not %s", describe(v))
+				continue
+			}
+			s.properties = map[string]*Schema{}
+			for _, name := range sortedKeys(m) {
+				s.properties[name] = c.schema(m[name], pointer(kp, name))
+			}
+		case "required":
+			s.required = c.strings(v, kp)
+		case "additionalProperties":
+			s.additional = c.schema(v, kp)
+		case "items":
+			s.items = c.schema(v, kp)
+		case "minItems":
+			s.minItems = c.count(v, kp)
+		case "maxItems":
+			s.maxItems = c.count(v, kp)
+		case "minLength":
+			s.minLength = c.count(v, kp)
+		case "maxLength":
+			s.maxLength = c.count(v, kp)
+		case "minProperties":
+			s.minProps = c.count(v, kp)
+		case "maxProperties":
+			s.maxProps = c.count(v, kp)
+		case "uniqueItems":
+			b, ok := v.(bool)
+			if !ok {
+				c.fail(kp, "must be true or false, not %s", describe(v))
+			}
+			s.uniqueItems = b
+		case "pattern":
+			src, ok := v.(string)
+			if !ok {
+				c.fail(kp, "must be a string, not %s", describe(v))
+				continue
+			}
+			re, err := regexp.Compile(src)
+			if err != nil {
+				c.fail(kp, "invalid pattern: %v", err)
+				continue
+			}
+			s.pattern = re
+		case "minimum":
+			s.minimum = c.number(v, kp)
+		case "maximum":
+			s.maximum = c.number(v, kp)
+		case "exclusiveMinimum":
+			s.exclMin = c.number(v, kp)
+		case "exclusiveMaximum":
+			s.exclMax = c.number(v, kp)
+		case "multipleOf":
+			if s.multipleOf = c.number(v, kp); s.multipleOf != nil && *s.multipleOf <= 0 {
+				c.fail(kp, "must be greater than 0")
+			}
+		case "allOf", "anyOf", "oneOf":
+			list, ok := v.([]interface{})
+			if !ok || len(list) == 0 {
+				c.fail(kp, "must be a non-empty list of schemas")
+				continue
+			}
+			subs := make([]*Schema, len(list))
+			for i, sub := range list {
+				subs[i] = c.schema(sub, pointer(kp, i))
+			}
+			switch key {
+			case "allOf":
+				s.allOf = subs
+			case "anyOf":
+				s.anyOf = subs
+			default:
+				s.oneOf = subs
+			}
+		case "not":
+			s.not = c.schema(v, kp)
+		default:
+			if !strings.HasPrefix(key, "x-") && !contains(annotations, key) {
+				c.fail(kp, "unsupported keyword %q", key)
+			}
+		}
+	}
+	return s
+}
+
+func (c *compiler) types(v interface{}, ptr string) []string {
+	var names []string
+	switch v := v.(type) {
+	case string:
+		names = []string{v}
+	case []interface{}:
+		names = c.strings(v, ptr)
+	default:
+		c.fail(ptr, "must be a type name or a list of them, not %s", describe(v))
+		return nil
+	}
+	for _, name := range names {
+		if !contains(typeNames, name) {
+			c.fail(ptr, "unknown type %q (use %s)", name, strings.Join(typeNames, ", "))
+		}
+	}
+	return names
+}
+
+func (c *compiler) strings(v interface{}, ptr string) []string {
+	list, ok := v.([]interface{})
+	if !ok {
+		c.fail(ptr, "must be a list of strings, not %s", describe(v))
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			c.fail(pointer(ptr, i), "must be a string, not %s", describe(item))
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func (c *compiler) number(v interface{}, ptr string) *float64 {
+	f, ok := v.(float64)
+	if !ok {
+		c.fail(ptr, "must be a number, not %s", describe(v))
+		return nil
+	}
+	return &f
+}
+
+func (c *compiler) count(v interface{}, ptr string) *int {
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		c.fail(ptr, "must be a non-negative integer, not %s", describe(v))
+		return nil
+	}
+	n := int(f)
+	return &n
+}
+
+// Validate checks v, which may be any value that encodes to JSON, and
+// returns every problem found. A nil schema accepts anything.
+func (s *Schema) Validate(v interface{}) error {
+	if s == nil {
+		return nil
+	}
+	var errs Errors
+	s.validate(normalize(v), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *Schema) validate(v interface{}, ptr string, errs *Errors) {
+	if s == nil {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, Error{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+	}
+	if s.never {
+		fail("is not allowed")
+		return
+	}
+	if len(s.types) > 0 && !s.hasType(v) {
+		fail("must be %s, not %s", typeList(s.types), describe(v))
+		return
+	}
+	if s.enum != nil && !containsValue(s.enum, v) {
+		options := make([]string, len(s.enum))
+		for i, e := range s.enum {
+			options[i] = literal(e)
+		}
+		fail("must be one of %s, not %s", strings.Join(options, ", "), literal(v))
+	}
+	if s.hasConst && !equal(s.konst, v) {
+		fail("must be %s, not %s", literal(s.konst), literal(v))
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		s.validateObject(v, ptr, errs, fail)
+	case []interface{}:
+		s.validateArray(v, ptr, errs, fail)
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.minLength != nil && n < *s.minLength {
+			fail("must be at least %d characters, not %d", *s.minLength, n)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			fail("must be at most %d characters, not %d", *s.maxLength, n)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match %q", s.pattern.String())
+		}
+	case float64:
+		switch {
+		case s.minimum != nil && v < *s.minimum:
+			fail("must be at least %g, not %g", *s.minimum, v)
+		case s.maximum != nil && v > *s.maximum:
+			fail("must be at most %g, not %g", *s.maximum, v)
+		case s.exclMin != nil && v <= *s.exclMin:
+			fail("must be greater than %g, not %g", *s.exclMin, v)
+		case s.exclMax != nil && v >= *s.exclMax:
+			fail("must be less than %g, not %g", *s.exclMax, v)
+		}
+		if m := s.multipleOf; m != nil {
+			if q := v / *m; q != math.Trunc(q) {
+				fail("must be a multiple of %g, not %g", *m, v)
+			}
+		}
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(v, ptr, errs)
+	}
+	if s.anyOf != nil && s.matches(s.anyOf, v) == 0 {
+		fail("must match at least one of the anyOf schemas")
+	}
+	if s.oneOf != nil {
+		if n := s.matches(s.oneOf, v); n != 1 {
+			fail("must match exactly one of the oneOf schemas, not %d", n)
+		}
+	}
+	if s.not != nil && s.not.Validate(v) == nil {
+		fail("must not match the not schema")
+	}
+}
+
+func (s *Schema) validateObject(v map[string]interface{}, ptr string, errs *Errors, fail func(string, ...interface{})) {
+	if s.minProps != nil && len(v) < *s.minProps {
+		fail("must have at least %d properties, not %d", *s.minProps, len(v))
+	}
+	if s.maxProps != nil && len(v) > *s.maxProps {
+		fail("must have at most %d properties, not %d", *s.maxProps, len(v))
+	}
+	for _, name := range s.required {
+		if _, ok := v[name]; !ok {
+			*errs = append(*errs, Error{Pointer: pointer(ptr, name), Message: "is required"})
+		}
+	}
+	for _, name := range sortedKeys(v) {
+		if sub, ok := s.properties[name]; ok {
+			sub.validate(v[name], pointer(ptr, name), errs)
+			continue
+		}
+		if s.additional == nil {
+			continue
+		}
+		if s.additional.never {
+			msg := "is not allowed"
+			if len(s.properties) > 0 {
+				msg = fmt.Sprintf("is not allowed (known: %s)", strings.Join(sortedKeys(s.properties), ", "))
+			}
+			*errs = append(*errs, Error{Pointer: pointer(ptr, name), Message: msg})
+			continue
+		}
+		s.additional.validate(v[name], pointer(ptr, name), errs)
+	}
+}
+
+func (s *Schema) validateArray(v []interface{}, ptr string, errs *Errors, fail func(string, ...interface{})) {
+	if s.minItems != nil && len(v) < *s.minItems {
+		fail("must have at least %d items, not %d", *s.minItems, len(v))
+	}
+	if s.maxItems != nil && len(v) > *s.maxItems {
+		fail("must have at most %d items, not %d", *s.maxItems, len(v))
+	}
+	if s.uniqueItems {
+	dup:
+		for i := range v {
+			for j := i + 1; j < len(v); j++ {
+				if equal(v[i], v[j]) {
+					fail("items %d and %d are equal", i, j)
+					break dup
+				}
+			}
+		}
+	}
+	for i, item := range v {
+		s.items.validate(item, pointer(ptr, i), errs)
+	}
+}
+
+// matches counts the schemas v is valid against
+func (s *Schema) matches(subs []*Schema, v interface{}) int {
+	n := 0
+	for _, sub := range subs {
+		var errs Errors
+		sub.validate(v, "", &errs)
+		if len(errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Schema) hasType(v interface{}) bool {
+	for _, t := range s.types {
+		switch t {
+		case "null":
+			if v == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "object":
+			if _, ok := v.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]interface{}); ok {
+				return true
+			}
+		case "number":
+			if _, ok := v.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalize gives v the shape encoding/json decodes to, so Go values,
+// YAML documents and JSON bodies validate alike
+func normalize(v interface{}) interface{} {
+	switch v.(type) {
+	case nil, bool, string, float64:
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func containsValue(list []interface{}, v interface{}) bool {
+	for _, x := range list {
+		if equal(x, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+func typeList(types []string) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		switch t {
+		case "null":
+			names[i] = "null"
+		case "integer", "object", "array":
+			names[i] = "an " + t
+		default:
+			names[i] = "a " + t
+		}
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+func describe(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return "the number " + strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return "the string " + strconv.Quote(v)
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// literal renders a value as JSON for messages
+func literal(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// pointer appends a key to a JSON pointer, escaping it per RFC 6901
+func pointer(ptr string, key interface{}) string {
+	s := strings.NewReplacer("~", "~0", "/", "~1").Replace(fmt.Sprint(key))
+	return ptr + "/" + s
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
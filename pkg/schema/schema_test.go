@@ -0,0 +1,149 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func compileYAML(t *testing.T, src string) *Schema {
+	t.Helper()
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Compile(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+const analyticsInputs = `
+type: object
+required: [processing_days]
+additionalProperties: false
+properties:
+  processing_days: {type: integer, minimum: 1, maximum: 90}
+  test_mode: {type: boolean, description: skip publishing}
+  tenant_test: {type: [string, "null"], pattern: "^[a-z-]+$"}
+  region: {enum: [eu, us]}
+  sources:
+    type: array
+    minItems: 1
+    uniqueItems: true
+    items: {type: string, maxLength: 8}
+`
+
+func TestValidate(t *testing.T) {
+	s := compileYAML(t, analyticsInputs)
+	for _, tc := range []struct {
+		value string
+		want  string
+	}{
+		{`{"processing_days": 3}`, ""},
+		{`{"processing_days": 3.0, "test_mode": true, "tenant_test": null, "region": "eu", "sources": ["db", "api"]}`, ""},
+		{`{}`, "/processing_days: is required"},
+		{`{"processing_days": "3"}`, `/processing_days: must be an integer, not the string "3"`},
+		{`{"processing_days": 2.5}`, "/processing_days: must be an integer, not the number 2.5"},
+		{`{"processing_days": 0}`, "/processing_days: must be at least 1, not 0"},
+		{`{"processing_days": 365}`, "/processing_days: must be at most 90, not 365"},
+		{`{"processing_days": 1, "dry_run": true}`, "/dry_run: is not allowed (known: processing_days, region, sources, tenant_test, test_mode)"},
+		{`{"processing_days": 1, "tenant_test": "Acme"}`, `/tenant_test: must match "^[a-z-]+$"`},
+		{`{"processing_days": 1, "tenant_test": 7}`, "/tenant_test: must be a string or null, not the number 7"},
+		{`{"processing_days": 1, "region": "apac"}`, `/region: must be one of "eu", "us", not "apac"`},
+		{`{"processing_days": 1, "sources": []}`, "/sources: must have at least 1 items, not 0"},
+		{`{"processing_days": 1, "sources": ["db", "db"]}`, "/sources: items 0 and 1 are equal"},
+		{`{"processing_days": 1, "sources": ["warehouse"]}`, "/sources/0: must be at most 8 characters, not 9"},
+		{`{"test_mode": "yes"}`, `/processing_days: is required; /test_mode: must be a boolean, not the string "yes"`},
+		{`[1]`, "must be an object, not an array"},
+	} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(tc.value), &v); err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		if err := s.Validate(v); err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	s := compileYAML(t, `
+oneOf:
+  - {type: integer, multipleOf: 5}
+  - {type: integer, exclusiveMaximum: 3}
+not: {const: 0}
+`)
+	for value, want := range map[interface{}]string{
+		10: "",
+		2:  "",
+		7:  "must match exactly one of the oneOf schemas, not 0",
+		0:  "must match exactly one of the oneOf schemas, not 2; must not match the not schema",
+	} {
+		got := ""
+		if err := s.Validate(value); err != nil {
+			got = err.Error()
+		}
+		if got != want {
+			t.Errorf("%v: got %q, want %q", value, got, want)
+		}
+	}
+
+	// Go values validate like their JSON encoding
+	s = compileYAML(t, "anyOf: [{type: string}, {type: object, properties: {n: {type: integer}}}]")
+	if err := s.Validate(map[string]int{"n": 1}); err != nil {
+		t.Error(err)
+	}
+	if err := s.Validate(false); err == nil || err.Error() != "must match at least one of the anyOf schemas" {
+		t.Errorf("err = %v", err)
+	}
+	if err := compileYAML(t, "false").Validate(nil); err == nil {
+		t.Error("false schema accepted a value")
+	}
+	var none *Schema
+	if err := none.Validate(42); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	var doc interface{}
+	yaml.Unmarshal([]byte(`
+type: objekt
+required: processing_days
+properties:
+  days: {type: integer, minimum: one}
+  ref: {$ref: "#/$defs/day"}
+  items: [1]
+items: {pattern: "("}
+minItems: -1
+x-owner: data-team
+format: date
+`), &doc)
+	_, err := Compile(doc)
+	want := []string{
+		`/items/pattern: invalid pattern`,
+		"/minItems: must be a non-negative integer, not the number -1",
+		`/properties/days/minimum: must be a number, not the string "one"`,
+		"/properties/items: must be a schema object or a boolean, not an array",
+		`/properties/ref/$ref: unsupported keyword "$ref"`,
+		`/required: must be a list of strings, not the string "processing_days"`,
+		`/type: unknown type "objekt"`,
+	}
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != len(want) {
+		t.Fatalf("err = %v", err)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(errs[i].Error(), w) {
+			t.Errorf("error %d = %q, want %q", i, errs[i], w)
+		}
+	}
+}
@@ -1,3 +1,5 @@
+//go:build ignore
+
 package main
 
 import (
@@ -5,12 +7,17 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"os"
 	"time"
 
+	"go.temporal.io/sdk/activity"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/worker"
 	"go.temporal.io/sdk/workflow"
+
+	"github.com/Caia-Tech/volcano-llm/pkg/pipeline"
+	"github.com/Caia-Tech/volcano-llm/pkg/registry"
 )
 
 // Test 1: Fast deterministic math calculation (simulated)
@@ -40,26 +47,39 @@ func testDeterministicExecution() {
 	log.Println("")
 }
 
-// DataPipelineWorkflow simulates a complex data processing workflow
-func DataPipelineWorkflow(ctx workflow.Context, customerID string) (string, error) {
-	logger := workflow.GetLogger(ctx)
-	logger.Info("Starting data pipeline workflow", "customerID", customerID)
-	
-	stages := []string{"Extract", "Validate", "Transform", "Load", "Report"}
-	results := []string{}
-	
-	for _, stage := range stages {
-		logger.Info("Executing stage", "stage", stage)
-		
-		// Simulate stage execution
-		workflow.Sleep(ctx, 500*time.Millisecond)
-		
-		result := fmt.Sprintf("%s completed at %s", stage, workflow.Now(ctx).Format("15:04:05"))
-		results = append(results, result)
+// pipelineDefinition is the YAML pipeline run by the generic interpreter
+const pipelineDefinition = "examples/advanced/data-pipeline/workflow.yaml"
+
+// registerPipelineActivities gives every activity the definition names a
+// stand-in implementation, so the YAML runs without any pipeline-specific Go
+func registerPipelineActivities(w worker.Worker, def *registry.WorkflowDefinition) {
+	names := append([]string{}, def.Hooks.OnStart...)
+	names = append(names, def.Hooks.OnSuccess...)
+	names = append(names, def.Hooks.OnFailure...)
+	for _, s := range def.Stages {
+		steps := append(append([]registry.Step{}, s.Activities...), s.Compensate...)
+		for _, c := range s.Switch {
+			steps = append(steps, c.Activities...)
+		}
+		for _, step := range steps {
+			names = append(names, step.Activity)
+		}
+		if spec, _ := s.Loop(); spec != nil && spec.Aggregate != "" {
+			names = append(names, spec.Aggregate)
+		}
+	}
+	registered := map[string]bool{}
+	for _, name := range names {
+		if registered[name] {
+			continue
+		}
+		registered[name] = true
+		name := name
+		w.RegisterActivityWithOptions(func(ctx context.Context, in pipeline.ActivityInput) (string, error) {
+			time.Sleep(100 * time.Millisecond)
+			return fmt.Sprintf("%s finished %s for %s", name, in.Stage, in.CustomerID), nil
+		}, activity.RegisterOptions{Name: name})
 	}
-	
-	finalResult := fmt.Sprintf("Pipeline completed for customer %s with %d stages", customerID, len(stages))
-	return finalResult, nil
 }
 
 // LongRunningWorkflow with pause/resume capability
@@ -160,7 +180,6 @@ func main() {
 	
 	// Create and start worker
 	w := worker.New(c, "volcano-llm-tests", worker.Options{})
-	w.RegisterWorkflow(DataPipelineWorkflow)
 	w.RegisterWorkflow(LongRunningWorkflow)
 	w.RegisterWorkflow(ErrorHandlingWorkflow)
 	w.RegisterActivity(RetryableActivity)
@@ -178,23 +197,39 @@ func main() {
 	log.Println("⚡ TEST 2: Complex Workflow Routing")
 	log.Println("===================================")
 	
-	pipelineOptions := client.StartWorkflowOptions{
-		ID:        fmt.Sprintf("data-pipeline-%d", time.Now().Unix()),
-		TaskQueue: "volcano-llm-tests",
+	data, err := os.ReadFile(pipelineDefinition)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", pipelineDefinition, err)
 	}
-	
+	def, err := registry.ParseWorkflow("workflows/data-pipeline.yaml", data)
+	if err != nil {
+		log.Fatalf("Invalid pipeline definition: %v", err)
+	}
+	pw := worker.New(c, def.TaskQueue, worker.Options{})
+	pipeline.Register(pw)
+	registerPipelineActivities(pw, def)
+	if err := pw.Start(); err != nil {
+		log.Fatalf("Pipeline worker failed: %v", err)
+	}
+	defer pw.Stop()
+
 	start := time.Now()
-	pipelineWE, err := c.ExecuteWorkflow(context.Background(), pipelineOptions, DataPipelineWorkflow, "enterprise-corp")
+	in := pipeline.Input{Definition: *def, CustomerID: "enterprise-corp"}
+	pipelineOptions := client.StartWorkflowOptions{ID: fmt.Sprintf("data-pipeline-%d", time.Now().Unix())}
+	pipelineWE, err := pipeline.Start(context.Background(), c, pipelineOptions, in)
 	if err != nil {
 		log.Printf("❌ Failed to start pipeline workflow: %v", err)
 	} else {
-		var result string
+		var result pipeline.Result
 		err = pipelineWE.Get(context.Background(), &result)
 		duration := time.Since(start)
-		
-		log.Printf("✅ Data Pipeline Workflow completed")
-		log.Printf("   Duration: %v", duration)
-		log.Printf("   Result: %s", result)
+		if err != nil {
+			log.Printf("❌ Data Pipeline Workflow failed: %v", err)
+		} else {
+			log.Printf("✅ Data Pipeline Workflow completed")
+			log.Printf("   Duration: %v", duration)
+			log.Printf("   Result: %s %s ran %d stages", result.Workflow, result.Status, len(result.Stages))
+		}
 		log.Printf("   View in UI: http://localhost:8088/namespaces/default/workflows/%s", pipelineWE.GetID())
 	}
 	log.Println("")